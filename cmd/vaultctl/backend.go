@@ -0,0 +1,85 @@
+package main
+
+import (
+	"discordvault/internal/vault"
+	"io"
+	"time"
+)
+
+// backend runs vaultctl's operations: through a vault server's API, or
+// directly against Discord and the database with --direct.
+type backend interface {
+	upload(name string, r io.Reader, folder int, policy string) (*file, error)
+	// download returns the file's download name, plaintext size, and
+	// contents.
+	download(id int) (string, int64, io.ReadCloser, error)
+	files(query string) ([]file, error)
+	remove(id int) error
+	verify(id int) (*verifyResult, error)
+	createFolder(name string, parent int) (int, error)
+	// importChannel starts copying a Discord channel's attachments into
+	// folder and returns the import's ID for importStatus.
+	importChannel(channelID string, folder int) (string, error)
+	importStatus(id string) (*importStatus, error)
+	// migrate starts copying the files of another storage provider into
+	// the vault and returns the migration's ID for migrationStatus.
+	migrate(m *migration) (string, error)
+	migrationStatus(id string) (*migrationStatus, error)
+	// exportFiles lists every file the caller can read with its path in
+	// an export.
+	exportFiles() ([]vault.ExportFile, error)
+	close() error
+}
+
+// file is the part of a vault file record vaultctl shows.
+type file struct {
+	ID        int
+	Name      string
+	Size      int64
+	Version   int
+	FolderID  int
+	CreatedAt time.Time
+	Health    string
+}
+
+// verifyResult is a verification report with the health it recorded.
+type verifyResult struct {
+	Chunks                      int
+	Missing, Corrupted, Rebuilt []int
+	Match                       bool
+	Health                      string
+}
+
+// importStatus is the progress of a channel import.
+type importStatus struct {
+	State       string
+	Messages    int
+	Attachments int
+	Skipped     int
+	Imported    int
+	Failed      int
+	Bytes       int64
+	Error       string
+}
+
+// migration says where a migration copies files from and to.
+type migration struct {
+	Source      string `json:"source"`
+	Endpoint    string `json:"endpoint,omitempty"`
+	Region      string `json:"region,omitempty"`
+	AccessKey   string `json:"access_key,omitempty"`
+	SecretKey   string `json:"secret_key,omitempty"`
+	FolderID    int    `json:"folder_id"`
+	Concurrency int    `json:"concurrency"`
+}
+
+// migrationStatus is the progress of a migration.
+type migrationStatus struct {
+	State    string
+	Objects  int
+	Skipped  int
+	Migrated int
+	Failed   int
+	Bytes    int64
+	Error    string
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"discordvault/internal/database"
+	"discordvault/internal/vault"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// client sends API requests to one vault server.
+type client struct {
+	base string
+	key  string
+	http *http.Client
+}
+
+func newClient(base, key string) *client {
+	return &client{base: strings.TrimRight(base, "/"), key: key, http: &http.Client{}}
+}
+
+// do sends a request and returns the response when it succeeded. Error
+// responses are returned as errors carrying the server's message.
+func (c *client) do(method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.key != "" {
+		req.Header.Set("X-API-Key", c.key)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// call sends in as a JSON body, if not nil, and decodes the JSON response
+// into out, if not nil.
+func (c *client) call(method, path string, in, out any) error {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body, contentType = bytes.NewReader(data), "application/json"
+	}
+	resp, err := c.do(method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// upload streams r as a multipart upload and moves the file into folder.
+func (c *client) upload(name string, r io.Reader, folder int, policy string) (*file, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile("file", name)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	endpoint := "/api/upload"
+	if policy != "" {
+		endpoint += "?on_duplicate=" + url.QueryEscape(policy)
+	}
+	resp, err := c.do("POST", endpoint, mw.FormDataContentType(), pr)
+	if err != nil {
+		pr.CloseWithError(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var stored file
+	if err := json.NewDecoder(resp.Body).Decode(&stored); err != nil {
+		return nil, err
+	}
+
+	if folder != 0 {
+		move := map[string]any{"operations": []map[string]any{{"op": "move", "file_id": stored.ID, "folder_id": folder}}}
+		if err := c.call("POST", "/api/batch", move, nil); err != nil {
+			return &stored, fmt.Errorf("moving into folder %d: %w", folder, err)
+		}
+		stored.FolderID = folder
+	}
+	return &stored, nil
+}
+
+func (c *client) download(id int) (string, int64, io.ReadCloser, error) {
+	resp, err := c.do("GET", fmt.Sprintf("/api/download/%d", id), "", nil)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	name := strconv.Itoa(id)
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		name = filepath.Base(params["filename"])
+	}
+	return name, resp.ContentLength, resp.Body, nil
+}
+
+func (c *client) files(query string) ([]file, error) {
+	path := "/api/files"
+	if query != "" {
+		path += "?q=" + url.QueryEscape(query)
+	}
+	var files []file
+	err := c.call("GET", path, nil, &files)
+	return files, err
+}
+
+func (c *client) remove(id int) error {
+	return c.call("POST", fmt.Sprintf("/api/delete/%d", id), nil, nil)
+}
+
+func (c *client) verify(id int) (*verifyResult, error) {
+	var result verifyResult
+	err := c.call("POST", fmt.Sprintf("/api/files/%d/verify", id), nil, &result)
+	return &result, err
+}
+
+func (c *client) createFolder(name string, parent int) (int, error) {
+	var folder struct{ ID int }
+	err := c.call("POST", "/api/folders", map[string]any{"name": name, "parent_id": parent}, &folder)
+	return folder.ID, err
+}
+
+func (c *client) importChannel(channelID string, folder int) (string, error) {
+	var started struct{ ID string }
+	err := c.call("POST", "/api/admin/import", map[string]any{"channel_id": channelID, "folder_id": folder}, &started)
+	return started.ID, err
+}
+
+func (c *client) importStatus(id string) (*importStatus, error) {
+	var status importStatus
+	err := c.call("GET", "/api/admin/import/"+url.PathEscape(id), nil, &status)
+	return &status, err
+}
+
+func (c *client) migrate(m *migration) (string, error) {
+	var started struct{ ID string }
+	err := c.call("POST", "/api/admin/migrate", m, &started)
+	return started.ID, err
+}
+
+func (c *client) migrationStatus(id string) (*migrationStatus, error) {
+	var status migrationStatus
+	err := c.call("GET", "/api/admin/migrate/"+url.PathEscape(id), nil, &status)
+	return &status, err
+}
+
+func (c *client) exportFiles() ([]vault.ExportFile, error) {
+	var files []database.FileMetadata
+	if err := c.call("GET", "/api/files", nil, &files); err != nil {
+		return nil, err
+	}
+	var folders []database.Folder
+	if err := c.call("GET", "/api/folders", nil, &folders); err != nil {
+		return nil, err
+	}
+	return vault.ExportPaths(files, folders), nil
+}
+
+func (c *client) close() error { return nil }
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// runUpload uploads files, and directories with everything in them as
+// folders of the same names.
+func runUpload(c backend, args []string) error {
+	fs := flag.NewFlagSet("upload", flag.ContinueOnError)
+	folder := fs.Int("folder", 0, "ID of the folder to upload into (default: vault root)")
+	policy := fs.String("on-duplicate", "", "suffix, version, or reject when a name is taken (default: the server's)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: vaultctl upload [--folder ID] [--on-duplicate POLICY] PATH...")
+	}
+
+	for _, path := range fs.Args() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			if err := uploadFile(c, path, *folder, *policy); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := uploadDir(c, path, *folder, *policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadDir creates a folder for root under parent and uploads the tree
+// below it.
+func uploadDir(c backend, root string, parent int, policy string) error {
+	folders := make(map[string]int) // directory -> folder ID
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name, parentID := d.Name(), parent
+			if path == root {
+				abs, err := filepath.Abs(root)
+				if err != nil {
+					return err
+				}
+				name = filepath.Base(abs)
+			} else {
+				parentID = folders[filepath.Dir(path)]
+			}
+			id, err := c.createFolder(name, parentID)
+			if err != nil {
+				return fmt.Errorf("folder %s: %w", path, err)
+			}
+			folders[path] = id
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return uploadFile(c, path, folders[filepath.Dir(path)], policy)
+	})
+}
+
+// uploadFile streams one file to the vault and moves it into folder.
+func uploadFile(c backend, path string, folder int, policy string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Base(path)
+	bar := newProgress(name, info.Size())
+	stored, err := c.upload(name, bar.Reader(f), folder, policy)
+	bar.finish()
+	if err != nil {
+		return fmt.Errorf("upload of %s: %w", path, err)
+	}
+	fmt.Printf("%d\t%s\n", stored.ID, stored.Name)
+	return nil
+}
+
+// runDownload saves files under their vault names, or the -o path when
+// downloading one file.
+func runDownload(c backend, args []string) error {
+	fs := flag.NewFlagSet("download", flag.ContinueOnError)
+	out := fs.String("o", "", "where to save the file (one file only; - for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 || (*out != "" && fs.NArg() > 1) {
+		return errors.New("usage: vaultctl download [-o PATH] ID...")
+	}
+	for _, arg := range fs.Args() {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid file ID %q", arg)
+		}
+		if err := download(c, id, *out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func download(c backend, id int, out string) error {
+	name, size, body, err := c.download(id)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if out == "-" {
+		_, err := io.Copy(os.Stdout, body)
+		return err
+	}
+	if out == "" {
+		out = name
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	bar := newProgress(name, size)
+	_, err = io.Copy(io.MultiWriter(f, bar), body)
+	bar.finish()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out)
+		return fmt.Errorf("download of #%d: %w", id, err)
+	}
+	fmt.Println(out)
+	return nil
+}
+
+// runList prints the caller's files, or those matching query.
+func runList(c backend, query string) error {
+	files, err := c.files(query)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSIZE\tUPLOADED\tHEALTH\tNAME")
+	for _, f := range files {
+		health := f.Health
+		if health == "" {
+			health = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", f.ID, formatBytes(f.Size), f.CreatedAt.Local().Format("2006-01-02 15:04"), health, f.Name)
+	}
+	return tw.Flush()
+}
+
+func runRemove(c backend, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: vaultctl rm ID...")
+	}
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid file ID %q", arg)
+		}
+		if err := c.remove(id); err != nil {
+			return err
+		}
+		fmt.Printf("deleted %d\n", id)
+	}
+	return nil
+}
+
+// runVerify checks files and fails if any is corrupt. Degraded files can
+// still be restored completely.
+func runVerify(c backend, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: vaultctl verify ID...")
+	}
+	damaged := 0
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid file ID %q", arg)
+		}
+		report, err := c.verify(id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d\t%s\t%d chunks, %d missing, %d corrupted, %d rebuilt\n",
+			id, report.Health, report.Chunks, len(report.Missing), len(report.Corrupted), len(report.Rebuilt))
+		if report.Health == "corrupt" {
+			damaged++
+		}
+	}
+	if damaged > 0 {
+		return fmt.Errorf("%d of %d files are corrupt", damaged, len(args))
+	}
+	return nil
+}
+
+// runImport copies the attachments posted to a Discord channel into the
+// vault and waits until the import is finished.
+func runImport(c backend, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	folder := fs.Int("folder", 0, "ID of the folder to import into (default: vault root)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: vaultctl import [--folder ID] CHANNEL_ID")
+	}
+	id, err := c.importChannel(fs.Arg(0), *folder)
+	if err != nil {
+		return err
+	}
+
+	bar := newProgress("import", 0)
+	for {
+		status, err := c.importStatus(id)
+		if err != nil {
+			return err
+		}
+		if bar.tty {
+			fmt.Fprintf(os.Stderr, "\r%-10s %d messages, %d of %d attachments imported", status.State, status.Messages, status.Imported, status.Attachments)
+		}
+		if status.State != "scanning" && status.State != "importing" {
+			if bar.tty {
+				fmt.Fprintln(os.Stderr)
+			}
+			fmt.Printf("imported %d files (%s), %d already imported, %d failed\n", status.Imported, formatBytes(status.Bytes), status.Skipped, status.Failed)
+			if status.State != "done" {
+				return fmt.Errorf("import %s: %s", status.State, status.Error)
+			}
+			if status.Failed > 0 {
+				return fmt.Errorf("%d attachments could not be imported", status.Failed)
+			}
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// runMigrate copies an S3 bucket or a web server's directory listing into
+// the vault. S3 credentials come from the usual AWS environment variables.
+func runMigrate(c backend, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	folder := fs.Int("folder", 0, "ID of the folder to migrate into (default: vault root)")
+	concurrency := fs.Int("concurrency", 0, "files copied at once (default 4)")
+	endpoint := fs.String("endpoint", os.Getenv("AWS_ENDPOINT_URL"), "S3 endpoint of a non-AWS provider")
+	region := fs.String("region", os.Getenv("AWS_REGION"), "S3 region")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: vaultctl migrate [--folder ID] [--concurrency N] [--endpoint URL] [--region REGION] SOURCE")
+	}
+	id, err := c.migrate(&migration{
+		Source:      fs.Arg(0),
+		Endpoint:    *endpoint,
+		Region:      *region,
+		AccessKey:   os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:   os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		FolderID:    *folder,
+		Concurrency: *concurrency,
+	})
+	if err != nil {
+		return err
+	}
+
+	bar := newProgress("migrate", 0)
+	for {
+		status, err := c.migrationStatus(id)
+		if err != nil {
+			return err
+		}
+		if bar.tty {
+			fmt.Fprintf(os.Stderr, "\r%-10s %d of %d files, %s", status.State, status.Migrated+status.Skipped+status.Failed, status.Objects, formatBytes(status.Bytes))
+		}
+		if status.State != "listing" && status.State != "migrating" {
+			if bar.tty {
+				fmt.Fprintln(os.Stderr)
+			}
+			fmt.Printf("migrated %d files (%s), %d unchanged, %d failed\n", status.Migrated, formatBytes(status.Bytes), status.Skipped, status.Failed)
+			if status.State != "done" {
+				return fmt.Errorf("migration %s: %s", status.State, status.Error)
+			}
+			if status.Failed > 0 {
+				return fmt.Errorf("%d files could not be migrated; run the same command again to retry them", status.Failed)
+			}
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"discordvault/internal/cloud"
+	"discordvault/internal/config"
+	"discordvault/internal/database"
+	"discordvault/internal/jobs"
+	"discordvault/internal/vault"
+	"fmt"
+	"io"
+	"os/user"
+
+	"github.com/joho/godotenv"
+)
+
+// direct runs operations in this process against Discord and the metadata
+// database, configured like the server from .env and the environment.
+type direct struct {
+	vault    *vault.Service
+	importer *jobs.Importer
+	migrator *jobs.Migrator
+}
+
+func openDirect() (*direct, error) {
+	godotenv.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	owner := "cli"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		owner = u.Username
+	}
+	s, err := vault.Open(cfg, owner)
+	if err != nil {
+		return nil, err
+	}
+	return &direct{
+		vault:    s,
+		importer: &jobs.Importer{Bot: s.Bot, DB: s.DB},
+		migrator: &jobs.Migrator{Bot: s.Bot, DB: s.DB},
+	}, nil
+}
+
+func (d *direct) upload(name string, r io.Reader, folder int, policy string) (*file, error) {
+	stored, err := d.vault.Upload(context.Background(), name, r, folder, policy)
+	if stored == nil {
+		return nil, err
+	}
+	f := toFile(stored)
+	return &f, err
+}
+
+func (d *direct) download(id int) (string, int64, io.ReadCloser, error) {
+	stored, err := d.vault.File(id)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	body, err := d.vault.OpenFile(context.Background(), id)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	return d.vault.Bot.DownloadName(stored), stored.Size, body, nil
+}
+
+func (d *direct) files(query string) ([]file, error) {
+	stored, err := d.vault.Files(query)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]file, len(stored))
+	for i := range stored {
+		files[i] = toFile(&stored[i])
+	}
+	return files, nil
+}
+
+func (d *direct) remove(id int) error {
+	return d.vault.Delete(id)
+}
+
+func (d *direct) verify(id int) (*verifyResult, error) {
+	report, health, err := d.vault.Verify(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	return &verifyResult{
+		Chunks:    report.Chunks,
+		Missing:   report.Missing,
+		Corrupted: report.Corrupted,
+		Rebuilt:   report.Rebuilt,
+		Match:     report.Match,
+		Health:    health,
+	}, nil
+}
+
+func (d *direct) createFolder(name string, parent int) (int, error) {
+	return d.vault.CreateFolder(name, parent)
+}
+
+func (d *direct) importChannel(channelID string, folder int) (string, error) {
+	if folder != 0 {
+		if _, err := d.vault.DB.GetFolder(folder); err != nil {
+			return "", fmt.Errorf("folder %d: %w", folder, vault.ErrNotFound)
+		}
+	}
+	return d.importer.Start(context.Background(), channelID, folder)
+}
+
+func (d *direct) importStatus(id string) (*importStatus, error) {
+	status, ok := d.importer.Status(id)
+	if !ok {
+		return nil, fmt.Errorf("import %s: %w", id, vault.ErrNotFound)
+	}
+	return &importStatus{
+		State:       status.State,
+		Messages:    status.Messages,
+		Attachments: status.Attachments,
+		Skipped:     status.Skipped,
+		Imported:    status.Imported,
+		Failed:      status.Failed,
+		Bytes:       status.Bytes,
+		Error:       status.Error,
+	}, nil
+}
+
+func (d *direct) migrate(m *migration) (string, error) {
+	if m.FolderID != 0 {
+		if _, err := d.vault.DB.GetFolder(m.FolderID); err != nil {
+			return "", fmt.Errorf("folder %d: %w", m.FolderID, vault.ErrNotFound)
+		}
+	}
+	src, err := cloud.Open(m.Source, cloud.S3Options{Endpoint: m.Endpoint, Region: m.Region, AccessKey: m.AccessKey, SecretKey: m.SecretKey}, nil)
+	if err != nil {
+		return "", err
+	}
+	return d.migrator.Start(context.Background(), src, d.vault.Owner, m.FolderID, m.Concurrency)
+}
+
+func (d *direct) migrationStatus(id string) (*migrationStatus, error) {
+	status, ok := d.migrator.Status(id)
+	if !ok {
+		return nil, fmt.Errorf("migration %s: %w", id, vault.ErrNotFound)
+	}
+	return &migrationStatus{
+		State:    status.State,
+		Objects:  status.Objects,
+		Skipped:  status.Skipped,
+		Migrated: status.Migrated,
+		Failed:   status.Failed,
+		Bytes:    status.Bytes,
+		Error:    status.Error,
+	}, nil
+}
+
+func (d *direct) exportFiles() ([]vault.ExportFile, error) {
+	return d.vault.ExportFiles()
+}
+
+func (d *direct) close() error {
+	return d.vault.Close()
+}
+
+func toFile(f *database.FileMetadata) file {
+	return file{
+		ID:        f.ID,
+		Name:      f.Name,
+		Size:      f.Size,
+		Version:   f.Version,
+		FolderID:  f.FolderID,
+		CreatedAt: f.CreatedAt,
+		Health:    f.Health,
+	}
+}
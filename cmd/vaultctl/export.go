@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"discordvault/internal/vault"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exportSource reads an export through a backend.
+type exportSource struct {
+	backend
+}
+
+func (s exportSource) ExportFiles() ([]vault.ExportFile, error) {
+	return s.exportFiles()
+}
+
+// OpenFile closes the download when ctx is cancelled, so an interrupted
+// export does not wait for the current file to finish.
+func (s exportSource) OpenFile(ctx context.Context, id int) (io.ReadCloser, error) {
+	_, _, body, err := s.download(id)
+	if err != nil {
+		return nil, err
+	}
+	context.AfterFunc(ctx, func() { body.Close() })
+	return body, nil
+}
+
+// runExport writes every file of the vault below a local directory, in
+// their folders. Running it again with the same directory resumes an
+// interrupted export and retries the files that failed.
+func runExport(c backend, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("out", "", "directory to export into")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" || fs.NArg() != 0 {
+		return errors.New("usage: vaultctl export --out DIR")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	bar := newProgress("export", 0)
+	progress, err := vault.Export(ctx, exportSource{c}, *out, func(p vault.ExportProgress) {
+		if bar.tty {
+			fmt.Fprintf(os.Stderr, "\r%d of %d files, %s", p.Exported+p.Skipped+p.Failed, p.Files, formatBytes(p.Bytes))
+		}
+	})
+	if bar.tty && progress.Files > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+	fmt.Printf("exported %d files (%s) to %s, %d already exported, %d failed\n", progress.Exported, formatBytes(progress.Bytes), *out, progress.Skipped, progress.Failed)
+	if ctx.Err() != nil {
+		return errors.New("interrupted; run the same command again to resume")
+	}
+	if err != nil {
+		return err
+	}
+	if progress.Failed > 0 {
+		return fmt.Errorf("%d files could not be exported; run the same command again to retry them", progress.Failed)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Command vaultctl is a command-line client for a DiscordVault server. It
+// talks to the REST API with an API key, so scripts need neither curl nor
+// hand-built multipart requests.
+//
+//	vaultctl [--url URL] [--key KEY] COMMAND [ARGS]
+//	vaultctl --direct COMMAND [ARGS]
+//
+// The server URL and key default to VAULT_URL and VAULT_API_KEY. With
+// --direct no server is needed: vaultctl reads the server's configuration
+// from .env and the environment and talks to Discord and the metadata
+// database itself.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `usage: vaultctl [--url URL] [--key KEY] COMMAND [ARGS]
+       vaultctl --direct COMMAND [ARGS]
+
+Commands:
+  upload [--folder ID] [--on-duplicate POLICY] PATH...   upload files, directories recursively
+  download [-o PATH] ID...                               download files
+  ls                                                     list your files
+  search QUERY                                           find files by name or tag
+  rm ID...                                               delete files
+  verify ID...                                           check every chunk and the SHA-256
+  sync [--folder ID] [--delete] [--once] [--state PATH] DIR...
+                                                         keep directories uploaded as they change
+  import [--folder ID] CHANNEL_ID                        copy a Discord channel's attachments into the vault
+  migrate [--folder ID] [--concurrency N] [--endpoint URL] [--region REGION] SOURCE
+                                                         copy an S3 bucket (s3://bucket/prefix) or a web
+                                                         directory listing into the vault
+  tui                                                    browse, search, upload, download, and delete interactively
+  export --out DIR                                       write every file to DIR in its folders, resuming if run again
+
+The server URL and API key default to VAULT_URL (http://localhost:8080)
+and VAULT_API_KEY. --direct works without a server, using its .env to
+reach Discord and the metadata database directly.
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "vaultctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("vaultctl", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	url := fs.String("url", envOr("VAULT_URL", "http://localhost:8080"), "vault server URL")
+	key := fs.String("key", os.Getenv("VAULT_API_KEY"), "API key")
+	direct := fs.Bool("direct", false, "use Discord and the database directly instead of a server")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no command given")
+	}
+
+	var c backend = newClient(*url, *key)
+	if *direct {
+		d, err := openDirect()
+		if err != nil {
+			return err
+		}
+		c = d
+	}
+	defer c.close()
+
+	command, rest := fs.Arg(0), fs.Args()[1:]
+	switch command {
+	case "upload":
+		return runUpload(c, rest)
+	case "download":
+		return runDownload(c, rest)
+	case "ls":
+		return runList(c, "")
+	case "search":
+		if len(rest) != 1 {
+			return errors.New("usage: vaultctl search QUERY")
+		}
+		return runList(c, rest[0])
+	case "rm":
+		return runRemove(c, rest)
+	case "verify":
+		return runVerify(c, rest)
+	case "sync":
+		return runSync(c, rest)
+	case "import":
+		return runImport(c, rest)
+	case "migrate":
+		return runMigrate(c, rest)
+	case "tui":
+		return runTUI(c, rest)
+	case "export":
+		return runExport(c, rest)
+	case "help":
+		fs.Usage()
+		return nil
+	}
+	fs.Usage()
+	return fmt.Errorf("unknown command %q", command)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
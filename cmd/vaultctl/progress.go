@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const barWidth = 30
+
+// progress draws a progress bar for one transfer on stderr, when stderr is
+// a terminal. It counts the bytes passing through Reader or Write.
+type progress struct {
+	name  string
+	total int64 // 0 = unknown
+	done  int64
+	drawn time.Time
+	tty   bool
+}
+
+func newProgress(name string, total int64) *progress {
+	info, err := os.Stderr.Stat()
+	return &progress{name: name, total: total, tty: err == nil && info.Mode()&os.ModeCharDevice != 0}
+}
+
+// Reader counts what is read from r.
+func (p *progress) Reader(r io.Reader) io.Reader {
+	return readerFunc(func(b []byte) (int, error) {
+		n, err := r.Read(b)
+		p.add(n)
+		return n, err
+	})
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.add(len(b))
+	return len(b), nil
+}
+
+func (p *progress) add(n int) {
+	p.done += int64(n)
+	if p.tty && time.Since(p.drawn) >= 100*time.Millisecond {
+		p.draw()
+	}
+}
+
+func (p *progress) draw() {
+	p.drawn = time.Now()
+	name := p.name
+	if len(name) > 24 {
+		name = name[:21] + "..."
+	}
+	if p.total <= 0 {
+		fmt.Fprintf(os.Stderr, "\r%-24s %s", name, formatBytes(p.done))
+		return
+	}
+	frac := min(float64(p.done)/float64(p.total), 1)
+	filled := int(frac * barWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	fmt.Fprintf(os.Stderr, "\r%-24s [%s] %3.0f%% %s / %s", name, bar, frac*100, formatBytes(p.done), formatBytes(p.total))
+}
+
+// finish draws the final state and ends the line.
+func (p *progress) finish() {
+	if p.tty {
+		p.draw()
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(b []byte) (int, error) { return f(b) }
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// settle is how long a directory must be quiet before its changes are
+// synced, so files still being written are not uploaded half-way.
+const settle = 2 * time.Second
+
+// syncState is what vaultctl sync remembers between runs, by absolute path
+// of each synced directory.
+type syncState struct {
+	Dirs map[string]*dirState `json:"dirs"`
+}
+
+// dirState maps one synced directory onto the vault. Keys are slash
+// separated paths relative to the directory, "." for the directory itself.
+type dirState struct {
+	Folders map[string]int         `json:"folders"`
+	Files   map[string]*syncedFile `json:"files"`
+}
+
+// syncedFile is the last uploaded state of a local file.
+type syncedFile struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"` // name in the vault, new versions are uploaded under it
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+}
+
+// syncer mirrors local directories into vault folders.
+type syncer struct {
+	c         backend
+	parent    int  // folder the synced directories are created under
+	delete    bool // delete vault files removed locally
+	statePath string
+	state     syncState
+	watcher   *fsnotify.Watcher
+}
+
+// runSync uploads new and changed files of the given directories and, unless
+// --once is set, keeps watching them for changes until interrupted.
+func runSync(c backend, args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	folder := fs.Int("folder", 0, "ID of the folder to sync into (default: vault root)")
+	del := fs.Bool("delete", false, "delete vault files whose local file was removed")
+	once := fs.Bool("once", false, "sync once and exit instead of watching")
+	statePath := fs.String("state", "", "sync state file (default: vaultctl/sync.json in the user config directory)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: vaultctl sync [--folder ID] [--delete] [--once] [--state PATH] DIR...")
+	}
+	if *statePath == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return err
+		}
+		*statePath = filepath.Join(dir, "vaultctl", "sync.json")
+	}
+
+	s := &syncer{c: c, parent: *folder, delete: *del, statePath: *statePath}
+	if err := s.load(); err != nil {
+		return err
+	}
+	var roots []string
+	for _, arg := range fs.Args() {
+		root, err := filepath.Abs(arg)
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(root); err != nil {
+			return err
+		} else if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", arg)
+		}
+		roots = append(roots, root)
+	}
+
+	if !*once {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+		defer watcher.Close()
+		s.watcher = watcher
+	}
+	for _, root := range roots {
+		s.scan(root, root)
+	}
+	if *once {
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("[SYNC] Watching %s", strings.Join(roots, ", "))
+	return s.watch(ctx, roots)
+}
+
+// watch collects changed paths until the directories are quiet for settle
+// and then syncs them.
+func (s *syncer) watch(ctx context.Context, roots []string) error {
+	pending := make(map[string]bool)
+	timer := time.NewTimer(settle)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Name == s.statePath || event.Name == s.statePath+".tmp" {
+				continue
+			}
+			pending[event.Name] = true
+			timer.Reset(settle)
+		case err, ok := <-s.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("[SYNC ERR] Watcher: %v", err)
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// Events were lost, so look at everything again.
+				for _, root := range roots {
+					pending[root] = true
+				}
+				timer.Reset(settle)
+			}
+		case <-timer.C:
+			for path := range pending {
+				if root := rootOf(roots, path); root != "" {
+					s.scan(root, path)
+				}
+				delete(pending, path)
+			}
+		}
+	}
+}
+
+// rootOf returns the synced directory containing path, or "".
+func rootOf(roots []string, path string) string {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return root
+		}
+	}
+	return ""
+}
+
+// scan syncs path, a file or directory inside root: what exists is uploaded
+// if new or changed, what was tracked under path and is gone is handled as
+// removed, as long as root itself still exists.
+func (s *syncer) scan(root, path string) {
+	dir := s.dir(root)
+	rel := relPath(root, path)
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if p == s.statePath || p == s.statePath+".tmp" {
+			return nil
+		}
+		if d.IsDir() {
+			if s.watcher != nil {
+				if err := s.watcher.Add(p); err != nil {
+					log.Printf("[SYNC ERR] Cannot watch %s: %v", p, err)
+				}
+			}
+			if _, err := s.folder(root, dir, relPath(root, p)); err != nil {
+				log.Printf("[SYNC ERR] Folder for %s: %v", p, err)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		key := relPath(root, p)
+		seen[key] = true
+		if err := s.syncFile(root, dir, key); err != nil {
+			log.Printf("[SYNC ERR] %s: %v", p, err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[SYNC ERR] %s: %v", path, err)
+		return
+	}
+	// A root that was moved or unmounted is not a deletion of everything
+	// in it; only paths missing from a root that is still there are.
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		log.Printf("[SYNC ERR] %s is missing or not a directory; leaving its files in the vault", root)
+		return
+	}
+
+	for key, f := range dir.Files {
+		if seen[key] || !(rel == "." || key == rel || strings.HasPrefix(key, rel+"/")) {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(root, filepath.FromSlash(key))); err == nil {
+			continue // still there, but not a regular file anymore
+		}
+		if !s.delete {
+			continue
+		}
+		if err := s.c.remove(f.ID); err != nil {
+			log.Printf("[SYNC ERR] Deleting %s (#%d): %v", key, f.ID, err)
+			continue
+		}
+		log.Printf("[SYNC] Deleted %s (#%d)", key, f.ID)
+		delete(dir.Files, key)
+		s.save()
+	}
+}
+
+// syncFile uploads the file at key when it is new or its content changed.
+// Changed files become new versions of the vault file.
+func (s *syncer) syncFile(root string, dir *dirState, key string) error {
+	path := filepath.Join(root, filepath.FromSlash(key))
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	synced := dir.Files[key]
+	if synced != nil && synced.Size == info.Size() && synced.ModTime.Equal(info.ModTime()) {
+		return nil
+	}
+	if info.Size() == 0 {
+		return nil // the vault does not store empty files
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return err
+	}
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if synced != nil && synced.SHA256 == sum {
+		synced.Size, synced.ModTime = info.Size(), info.ModTime()
+		s.save()
+		return nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	folder, err := s.folder(root, dir, parentKey(key))
+	if err != nil {
+		return err
+	}
+	name, policy := filepath.Base(path), "suffix"
+	if synced != nil {
+		name, policy = synced.Name, "version"
+	}
+	stored, err := s.c.upload(name, f, folder, policy)
+	if err != nil {
+		return err
+	}
+	if synced == nil {
+		log.Printf("[SYNC] Uploaded %s as #%d %s", key, stored.ID, stored.Name)
+	} else {
+		log.Printf("[SYNC] Uploaded %s as #%d %s version %d", key, stored.ID, stored.Name, stored.Version)
+	}
+	dir.Files[key] = &syncedFile{ID: stored.ID, Name: stored.Name, Size: info.Size(), ModTime: info.ModTime(), SHA256: sum}
+	s.save()
+	return nil
+}
+
+// folder returns the folder mirroring the directory at key, creating it and
+// its parents when needed.
+func (s *syncer) folder(root string, dir *dirState, key string) (int, error) {
+	if id, ok := dir.Folders[key]; ok {
+		return id, nil
+	}
+	name, parent := filepath.Base(root), s.parent
+	if key != "." {
+		var err error
+		if parent, err = s.folder(root, dir, parentKey(key)); err != nil {
+			return 0, err
+		}
+		name = filepath.Base(filepath.FromSlash(key))
+	}
+	id, err := s.c.createFolder(name, parent)
+	if err != nil {
+		return 0, err
+	}
+	dir.Folders[key] = id
+	s.save()
+	return id, nil
+}
+
+func (s *syncer) dir(root string) *dirState {
+	dir := s.state.Dirs[root]
+	if dir == nil {
+		dir = &dirState{Folders: make(map[string]int), Files: make(map[string]*syncedFile)}
+		s.state.Dirs[root] = dir
+	}
+	return dir
+}
+
+func (s *syncer) load() error {
+	s.state.Dirs = make(map[string]*dirState)
+	data, err := os.ReadFile(s.statePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return fmt.Errorf("sync state %s: %w", s.statePath, err)
+	}
+	if s.state.Dirs == nil {
+		s.state.Dirs = make(map[string]*dirState)
+	}
+	for _, dir := range s.state.Dirs {
+		if dir.Folders == nil {
+			dir.Folders = make(map[string]int)
+		}
+		if dir.Files == nil {
+			dir.Files = make(map[string]*syncedFile)
+		}
+	}
+	return nil
+}
+
+// save writes the state after every change, through a temporary file so an
+// interrupted write never loses it.
+func (s *syncer) save() {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.statePath), 0o700)
+	}
+	if err == nil {
+		tmp := s.statePath + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, s.statePath)
+		}
+	}
+	if err != nil {
+		log.Printf("[SYNC ERR] Saving state: %v", err)
+	}
+}
+
+func relPath(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return "."
+	}
+	return filepath.ToSlash(rel)
+}
+
+func parentKey(key string) string {
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		return key[:i]
+	}
+	return "."
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// runTUI browses the vault in a full-screen terminal UI.
+func runTUI(c backend, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: vaultctl tui")
+	}
+	// Log lines of --direct would tear up the screen; errors are shown in
+	// the status line instead.
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	_, err := tea.NewProgram(&tui{c: c}, tea.WithAltScreen()).Run()
+	return err
+}
+
+type tuiMode int
+
+const (
+	modeBrowse tuiMode = iota
+	modeSearch
+	modeUpload
+	modeDelete
+)
+
+var (
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	headerStyle   = lipgloss.NewStyle().Bold(true)
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+)
+
+// transfer is an upload or download running in the background. done is
+// updated by the transfer's goroutine and read when the screen is drawn.
+type transfer struct {
+	name   string
+	upload bool
+	total  int64
+	done   atomic.Int64
+}
+
+func (t *transfer) Write(b []byte) (int, error) {
+	t.done.Add(int64(len(b)))
+	return len(b), nil
+}
+
+type (
+	filesMsg struct {
+		files []file
+		err   error
+	}
+	transferDoneMsg struct {
+		t      *transfer
+		status string
+		err    error
+	}
+	deletedMsg struct {
+		f   file
+		err error
+	}
+	tickMsg struct{}
+)
+
+// tui is the bubbletea model of vaultctl tui.
+type tui struct {
+	c         backend
+	files     []file
+	query     string
+	cursor    int
+	offset    int
+	width     int
+	height    int
+	mode      tuiMode
+	input     string
+	status    string
+	failed    bool
+	transfers []*transfer
+}
+
+func (m *tui) Init() tea.Cmd {
+	return tea.Batch(m.load(), tick())
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(200*time.Millisecond, func(time.Time) tea.Msg { return tickMsg{} })
+}
+
+func (m *tui) load() tea.Cmd {
+	query := m.query
+	return func() tea.Msg {
+		files, err := m.c.files(query)
+		return filesMsg{files, err}
+	}
+}
+
+func (m *tui) setStatus(status string, err error) {
+	m.status, m.failed = status, err != nil
+	if err != nil {
+		m.status = err.Error()
+	}
+}
+
+func (m *tui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tickMsg:
+		return m, tick()
+	case filesMsg:
+		if msg.err != nil {
+			m.setStatus("", msg.err)
+			return m, nil
+		}
+		m.files = msg.files
+		m.cursor = max(min(m.cursor, len(m.files)-1), 0)
+	case transferDoneMsg:
+		for i, t := range m.transfers {
+			if t == msg.t {
+				m.transfers = append(m.transfers[:i], m.transfers[i+1:]...)
+				break
+			}
+		}
+		m.setStatus(msg.status, msg.err)
+		if msg.t.upload && msg.err == nil {
+			return m, m.load()
+		}
+	case deletedMsg:
+		m.setStatus(fmt.Sprintf("Deleted %s", msg.f.Name), msg.err)
+		return m, m.load()
+	case tea.KeyMsg:
+		return m.key(msg)
+	}
+	return m, nil
+}
+
+func (m *tui) key(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.Type == tea.KeyCtrlC {
+		return m, tea.Quit
+	}
+	switch m.mode {
+	case modeSearch, modeUpload:
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.mode = modeBrowse
+		case tea.KeyEnter:
+			mode, input := m.mode, strings.TrimSpace(m.input)
+			m.mode = modeBrowse
+			if mode == modeSearch {
+				m.query, m.cursor = input, 0
+				return m, m.load()
+			}
+			if input != "" {
+				return m, m.upload(input)
+			}
+		case tea.KeyBackspace:
+			if r := []rune(m.input); len(r) > 0 {
+				m.input = string(r[:len(r)-1])
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			m.input += string(msg.Runes)
+		}
+		return m, nil
+	case modeDelete:
+		m.mode = modeBrowse
+		if msg.String() == "y" && len(m.files) > 0 {
+			f := m.files[m.cursor]
+			return m, func() tea.Msg { return deletedMsg{f, m.c.remove(f.ID)} }
+		}
+		m.setStatus("Not deleted", nil)
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = max(min(m.cursor+1, len(m.files)-1), 0)
+	case "pgup":
+		m.cursor = max(m.cursor-m.rows(), 0)
+	case "pgdown":
+		m.cursor = max(min(m.cursor+m.rows(), len(m.files)-1), 0)
+	case "home", "g":
+		m.cursor = 0
+	case "end", "G":
+		m.cursor = max(len(m.files)-1, 0)
+	case "/":
+		m.mode, m.input = modeSearch, m.query
+	case "esc":
+		if m.query != "" {
+			m.query, m.cursor = "", 0
+			return m, m.load()
+		}
+	case "u":
+		m.mode, m.input = modeUpload, ""
+	case "d", "enter":
+		if len(m.files) > 0 {
+			return m, m.download(m.files[m.cursor])
+		}
+	case "x", "delete":
+		if len(m.files) > 0 {
+			m.mode = modeDelete
+		}
+	case "r":
+		return m, m.load()
+	}
+	return m, nil
+}
+
+// upload sends a local file to the vault root in the background.
+func (m *tui) upload(path string) tea.Cmd {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		m.setStatus("", err)
+		return nil
+	}
+	info, err := f.Stat()
+	if err == nil && !info.Mode().IsRegular() {
+		err = fmt.Errorf("%s is not a file", path)
+	}
+	if err != nil {
+		f.Close()
+		m.setStatus("", err)
+		return nil
+	}
+
+	t := &transfer{name: filepath.Base(path), upload: true, total: info.Size()}
+	m.transfers = append(m.transfers, t)
+	return func() tea.Msg {
+		defer f.Close()
+		stored, err := m.c.upload(t.name, io.TeeReader(f, t), 0, "")
+		if err != nil {
+			return transferDoneMsg{t: t, err: fmt.Errorf("upload of %s: %w", t.name, err)}
+		}
+		return transferDoneMsg{t: t, status: fmt.Sprintf("Uploaded %s as #%d", stored.Name, stored.ID)}
+	}
+}
+
+// download saves a file into the working directory in the background,
+// without overwriting anything there.
+func (m *tui) download(f file) tea.Cmd {
+	t := &transfer{name: f.Name, total: f.Size}
+	m.transfers = append(m.transfers, t)
+	return func() tea.Msg {
+		name, _, body, err := m.c.download(f.ID)
+		if err != nil {
+			return transferDoneMsg{t: t, err: err}
+		}
+		defer body.Close()
+		out, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return transferDoneMsg{t: t, err: err}
+		}
+		_, err = io.Copy(io.MultiWriter(out, t), body)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(name)
+			return transferDoneMsg{t: t, err: fmt.Errorf("download of #%d: %w", f.ID, err)}
+		}
+		return transferDoneMsg{t: t, status: "Saved " + name}
+	}
+}
+
+// rows is how many files fit on the screen below the header and above the
+// transfers and the status, prompt, and help lines.
+func (m *tui) rows() int {
+	return max(m.height-2-len(m.transfers)-3, 1)
+}
+
+func (m *tui) View() string {
+	var b strings.Builder
+	title := fmt.Sprintf("DiscordVault  %d files", len(m.files))
+	if m.query != "" {
+		title += fmt.Sprintf(" matching %q", m.query)
+	}
+	b.WriteString(headerStyle.Render(title) + "\n")
+	b.WriteString(dimStyle.Render(fmt.Sprintf("%-6s %10s  %-16s  %-8s  %s", "ID", "SIZE", "UPLOADED", "HEALTH", "NAME")) + "\n")
+
+	rows := m.rows()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+	for i := m.offset; i < m.offset+rows; i++ {
+		if i >= len(m.files) {
+			b.WriteString("\n")
+			continue
+		}
+		f := m.files[i]
+		health := f.Health
+		if health == "" {
+			health = "-"
+		}
+		line := fmt.Sprintf("%-6d %10s  %-16s  %-8s  %s", f.ID, formatBytes(f.Size), f.CreatedAt.Local().Format("2006-01-02 15:04"), health, f.Name)
+		if r := []rune(line); m.width > 0 && len(r) > m.width {
+			line = string(r[:m.width])
+		}
+		if i == m.cursor {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	for _, t := range m.transfers {
+		b.WriteString(t.line() + "\n")
+	}
+	if m.failed {
+		b.WriteString(errorStyle.Render(m.status) + "\n")
+	} else {
+		b.WriteString(m.status + "\n")
+	}
+	switch m.mode {
+	case modeSearch:
+		b.WriteString("Search: " + m.input + "█\n")
+	case modeUpload:
+		b.WriteString("Upload file: " + m.input + "█\n")
+	case modeDelete:
+		b.WriteString(fmt.Sprintf("Delete %s? (y/n)\n", m.files[m.cursor].Name))
+	default:
+		b.WriteString("\n")
+	}
+	b.WriteString(dimStyle.Render("↑/↓ move  / search  esc clear  u upload  d download  x delete  r refresh  q quit"))
+	return b.String()
+}
+
+// line draws a transfer's progress bar.
+func (t *transfer) line() string {
+	arrow := "↓"
+	if t.upload {
+		arrow = "↑"
+	}
+	name := t.name
+	if len(name) > 24 {
+		name = name[:21] + "..."
+	}
+	done := t.done.Load()
+	frac := 1.0
+	if t.total > 0 {
+		frac = min(float64(done)/float64(t.total), 1)
+	}
+	filled := int(frac * barWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	return fmt.Sprintf("%s %-24s [%s] %3.0f%% %s / %s", arrow, name, bar, frac*100, formatBytes(done), formatBytes(t.total))
+}
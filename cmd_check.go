@@ -0,0 +1,211 @@
+package main
+
+import (
+	"discordvault/internal/config"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"discordvault/internal/proxy"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// channelPermissions are what the bot needs in every channel it posts to.
+var channelPermissions = []struct {
+	bit  int64
+	name string
+}{
+	{discordgo.PermissionViewChannel, "View Channel"},
+	{discordgo.PermissionSendMessages, "Send Messages"},
+	{discordgo.PermissionAttachFiles, "Attach Files"},
+	{discordgo.PermissionReadMessageHistory, "Read Message History"},
+}
+
+// runCheck implements `discordvault check`, which validates the
+// configuration and everything it points at: the bot token, the channels,
+// the keys, and the database. It changes nothing and fails if any check
+// does, so a deployment can run it before starting the vault.
+func runCheck(args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: discordvault check")
+	}
+	c := &checker{}
+	cfg, err := config.Load()
+	if err != nil {
+		c.fail("config", "%v", err)
+		return c.result()
+	}
+	c.ok("config", "loaded")
+
+	c.checkKeys(cfg)
+	c.checkDatabase(cfg)
+	proxy.Set(cfg.Proxy)
+	c.checkDiscord(cfg)
+	return c.result()
+}
+
+// checker prints the outcome of each check as it runs.
+type checker struct {
+	failed int
+}
+
+func (c *checker) ok(subject, format string, args ...any) {
+	fmt.Printf("ok    %-16s %s\n", subject, fmt.Sprintf(format, args...))
+}
+
+func (c *checker) warn(subject, format string, args ...any) {
+	fmt.Printf("warn  %-16s %s\n", subject, fmt.Sprintf(format, args...))
+}
+
+func (c *checker) fail(subject, format string, args ...any) {
+	c.failed++
+	fmt.Printf("FAIL  %-16s %s\n", subject, fmt.Sprintf(format, args...))
+}
+
+func (c *checker) result() error {
+	if c.failed > 0 {
+		return fmt.Errorf("%d problem(s) found", c.failed)
+	}
+	fmt.Println("All checks passed.")
+	return nil
+}
+
+// checkKeys confirms ENCRYPTION_KEY unlocks the signing key, which was
+// sealed with the key the vault was created with.
+func (c *checker) checkKeys(cfg *config.Config) {
+	c.ok("encryption key", "%d bytes", len(cfg.EncryptionKey))
+	signer, err := crypto.LoadSigner(cfg.SigningKeyPath, cfg.EncryptionKey)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		c.warn("signing key", "%s does not exist yet; a new vault identity is created on first start. Restore the file if this vault has one.", cfg.SigningKeyPath)
+	case err != nil:
+		c.fail("signing key", "%s: %v. Set the ENCRYPTION_KEY this vault was created with.", cfg.SigningKeyPath, err)
+	default:
+		c.ok("signing key", "fingerprint %s", signer.Fingerprint())
+	}
+}
+
+func (c *checker) checkDatabase(cfg *config.Config) {
+	if path, ok := database.SQLitePath(cfg.DatabaseURL); ok {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			dir := filepath.Dir(path)
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				c.fail("database", "directory %s of %s does not exist", dir, path)
+			} else {
+				c.ok("database", "%s does not exist yet and is created on first start", path)
+			}
+			return
+		}
+	}
+	backend, current, latest, err := database.Inspect(cfg.DatabaseURL)
+	switch {
+	case err != nil:
+		c.fail("database", "%v. Check DATABASE_URL and that the database server is reachable.", err)
+	case current > latest:
+		c.fail("database", "%s schema version %d is newer than this build knows (%d); upgrade discordvault or run an older build's `migrate down`.", backend, current, latest)
+	case current < latest:
+		c.ok("database", "%s reachable; %d migration(s) pending, applied on start", backend, latest-current)
+	default:
+		c.ok("database", "%s reachable, schema version %d", backend, current)
+	}
+}
+
+// checkDiscord logs in with every bot token over REST, without going online,
+// and checks each channel the vault posts to exists and lets the bots post
+// attachments there.
+func (c *checker) checkDiscord(cfg *config.Config) {
+	session, bot := c.login("bot token", cfg.DiscordToken)
+	if bot == nil {
+		return
+	}
+	bots := []*discordgo.User{bot}
+	for i, token := range cfg.StorageTokens {
+		if _, user := c.login(fmt.Sprintf("storage token %d", i+1), token); user != nil {
+			bots = append(bots, user)
+		}
+	}
+
+	channels := cfg.StorageChannels()
+	for _, id := range []string{cfg.BackupChannelID, cfg.DigestChannelID} {
+		if !slices.Contains(channels, id) {
+			channels = append(channels, id)
+		}
+	}
+	for _, id := range channels {
+		subject := "channel " + id
+		ch, err := session.Channel(id)
+		if err != nil {
+			switch restStatus(err) {
+			case http.StatusNotFound, http.StatusForbidden:
+				c.fail(subject, "not found or not visible to %s. Check the ID and that the bot is a member of its server.", bot.Username)
+			default:
+				c.fail(subject, "%v", err)
+			}
+			continue
+		}
+		subject = "#" + ch.Name
+		ok := true
+		for _, user := range bots {
+			perms, err := session.UserChannelPermissions(user.ID, id)
+			if err != nil {
+				c.fail(subject, "permissions of %s could not be read: %v", user.Username, err)
+				ok = false
+				continue
+			}
+			var missing []string
+			for _, p := range channelPermissions {
+				if perms&p.bit == 0 {
+					missing = append(missing, p.name)
+				}
+			}
+			if len(missing) > 0 {
+				c.fail(subject, "%s lacks %s. Grant it in the channel's permissions.", user.Username, strings.Join(missing, ", "))
+				ok = false
+			}
+		}
+		if len(bots) > 1 {
+			if perms, err := session.UserChannelPermissions(bot.ID, id); err == nil && perms&discordgo.PermissionManageMessages == 0 {
+				c.warn(subject, "%s lacks Manage Messages, so it cannot delete chunks posted by the storage tokens.", bot.Username)
+			}
+		}
+		if ok {
+			c.ok(subject, "bots can read and post attachments")
+		}
+	}
+}
+
+// login checks a bot token with a REST call and returns the session and
+// the bot's user, or nil if the token does not work.
+func (c *checker) login(subject, token string) (*discordgo.Session, *discordgo.User) {
+	session, err := discordgo.New("Bot " + token)
+	if err != nil {
+		c.fail(subject, "%v", err)
+		return nil, nil
+	}
+	session.Client.Transport = proxy.Transport()
+	user, err := session.User("@me")
+	if err != nil {
+		if restStatus(err) == http.StatusUnauthorized {
+			c.fail(subject, "rejected by Discord. Copy the token again from the Developer Portal, or reset it there.")
+		} else {
+			c.fail(subject, "Discord could not be reached: %v", err)
+		}
+		return nil, nil
+	}
+	c.ok(subject, "logged in as %s", user.Username)
+	return session, user
+}
+
+func restStatus(err error) int {
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		return restErr.Response.StatusCode
+	}
+	return 0
+}
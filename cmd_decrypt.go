@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/sha256"
+	"discordvault/internal/chunkname"
+	"discordvault/internal/crypto"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runDecrypt implements the offline recovery tool:
+//
+//	discordvault decrypt --key KEY chunk1.vault chunk2.vault ... -o file
+//
+// It needs nothing but the manually downloaded chunk attachments (in part
+// order) and the vault's ENCRYPTION_KEY, so data stays recoverable even
+// without metadata.db or a running bot. Chunks that still have their
+// attachment name are checked against the hash it carries first.
+func runDecrypt(args []string) error {
+	fs := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	key := fs.String("key", "", "32-character ENCRYPTION_KEY (defaults to $ENCRYPTION_KEY)")
+	keyFile := fs.String("key-file", "", "read the key from a file instead")
+	out := fs.String("o", "", "output file (defaults to stdout)")
+	expect := fs.String("sha256", "", "expected SHA-256 of the reconstructed file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: discordvault decrypt [--key KEY | --key-file PATH] [--sha256 HEX] chunk1.vault [chunk2.vault ...] -o output")
+		fs.PrintDefaults()
+	}
+
+	// Allow flags before, between, and after the chunk paths.
+	var chunks []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		chunks = append(chunks, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(chunks) == 0 {
+		fs.Usage()
+		return errors.New("no chunk files given")
+	}
+
+	secret := *key
+	if *keyFile != "" {
+		data, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return err
+		}
+		secret = strings.TrimRight(string(data), "\r\n")
+	}
+	if secret == "" {
+		secret = os.Getenv("ENCRYPTION_KEY")
+	}
+	if len(secret) != 32 {
+		return fmt.Errorf("key must be exactly 32 bytes (got %d)", len(secret))
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	hasher := sha256.New()
+	var total int64
+	for idx, path := range chunks {
+		encrypted, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if name, err := chunkname.Parse(filepath.Base(path)); err == nil {
+			if sum := sha256.Sum256(encrypted); hex.EncodeToString(sum[:]) != name.SHA256 {
+				return fmt.Errorf("chunk %d (%s) does not match the hash in its name: damaged or incomplete download", idx+1, path)
+			}
+		}
+		plain, err := crypto.Decrypt(encrypted, []byte(secret))
+		if err != nil {
+			return fmt.Errorf("chunk %d (%s) failed to decrypt: wrong key, wrong order, or corrupted data: %w", idx+1, path, err)
+		}
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+		hasher.Write(plain)
+		total += int64(len(plain))
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	fmt.Fprintf(os.Stderr, "Recovered %d bytes from %d chunk(s), sha256 %s\n", total, len(chunks), sum)
+	if *expect != "" && !strings.EqualFold(*expect, sum) {
+		return fmt.Errorf("checksum mismatch: expected %s", *expect)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"discordvault/internal/config"
+	"discordvault/internal/database"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// runDuplicates implements `discordvault duplicates [--consolidate]`, listing
+// files with identical content and optionally aliasing each group onto its
+// oldest file.
+func runDuplicates(args []string) error {
+	fs := flag.NewFlagSet("duplicates", flag.ContinueOnError)
+	consolidate := fs.Bool("consolidate", false, "alias every group onto its oldest file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New("usage: discordvault duplicates [--consolidate]")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	db, err := database.Open(cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Conn.Close()
+
+	groups, err := db.DuplicateGroups()
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		fmt.Println("No duplicates found.")
+		return nil
+	}
+
+	reclaimable := 0
+	for _, g := range groups {
+		vault := ""
+		if g.GuildID != database.DefaultVault {
+			vault = "  guild " + g.GuildID
+		}
+		fmt.Printf("%s  %d bytes  %d messages, %d reclaimable%s\n", g.Hash, g.Size, g.Messages, g.Reclaimable, vault)
+		for i, f := range g.Files {
+			marker := " "
+			if i == 0 {
+				marker = "*"
+			}
+			fmt.Printf("  %s #%-6d %s (v%d, owner %s)\n", marker, f.ID, f.Name, f.Version, f.OwnerID)
+		}
+		reclaimable += g.Reclaimable
+	}
+	fmt.Printf("%d groups, %d chunk messages reclaimable. * marks the copy that is kept.\n", len(groups), reclaimable)
+	if !*consolidate {
+		return nil
+	}
+
+	released := 0
+	for _, g := range groups {
+		n, err := db.ConsolidateDuplicates(g.GuildID, g.Hash)
+		if err != nil {
+			return fmt.Errorf("consolidating %s: %w", g.Hash, err)
+		}
+		released += n
+	}
+	fmt.Printf("Released %d chunk messages; the next compaction run deletes them from Discord.\n", released)
+	return nil
+}
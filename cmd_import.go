@@ -0,0 +1,77 @@
+package main
+
+import (
+	"discordvault/internal/config"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runImportManifest implements `discordvault import-manifest manifest.json`,
+// rebuilding the metadata store from a signed /api/export manifest.
+func runImportManifest(args []string) error {
+	fs := flag.NewFlagSet("import-manifest", flag.ContinueOnError)
+	publicKey := fs.String("public-key", "", "trusted vault public key (defaults to this vault's identity key)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: discordvault import-manifest [--public-key KEY] manifest.json")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var envelope crypto.Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("not a manifest file: %w", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	trusted := *publicKey
+	if trusted == "" {
+		if _, err := os.Stat(cfg.SigningKeyPath); err == nil {
+			signer, err := crypto.LoadOrCreateSigner(cfg.SigningKeyPath, cfg.EncryptionKey)
+			if err != nil {
+				return err
+			}
+			trusted = signer.PublicKeyString()
+		} else {
+			log.Println("[WARN] No local identity key or --public-key given; only checking manifest integrity, not origin.")
+		}
+	}
+
+	var manifest database.Manifest
+	if err := envelope.Open(trusted, &manifest); err != nil {
+		return err
+	}
+	if manifest.Format != database.ManifestFormat {
+		return fmt.Errorf("unsupported manifest format %q", manifest.Format)
+	}
+	if manifest.ChannelID != cfg.ChannelID {
+		log.Printf("[WARN] Manifest references channel %s but DISCORD_CHANNEL_ID is %s", manifest.ChannelID, cfg.ChannelID)
+	}
+
+	db, err := database.Open(cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Conn.Close()
+
+	imported, err := db.ImportManifest(&manifest)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d of %d files from manifest created %s\n", imported, len(manifest.Files), manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	return nil
+}
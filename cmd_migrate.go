@@ -0,0 +1,61 @@
+package main
+
+import (
+	"discordvault/internal/config"
+	"discordvault/internal/database"
+	"fmt"
+	"strconv"
+)
+
+// runMigrate implements `discordvault migrate [status|up|down <version>]`.
+// Only up applies pending migrations; status and down leave them alone.
+func runMigrate(args []string) error {
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	url := config.DatabaseURL()
+
+	switch action {
+	case "status":
+	case "up":
+		db, err := database.Open(url)
+		if err != nil {
+			return err
+		}
+		db.Conn.Close()
+	case "down":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: migrate down <target-version>")
+		}
+		target, err := strconv.Atoi(args[1])
+		if err != nil || target < 0 {
+			return fmt.Errorf("invalid target version %q", args[1])
+		}
+		_, current, _, err := database.Inspect(url)
+		if err != nil {
+			return err
+		}
+		if target > current {
+			return fmt.Errorf("schema is at version %d; use migrate up to move forward", current)
+		}
+		db, err := database.Connect(url)
+		if err != nil {
+			return err
+		}
+		err = db.MigrateTo(target)
+		db.Conn.Close()
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown migrate action %q (want status, up, or down)", action)
+	}
+
+	backend, current, latest, err := database.Inspect(url)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Schema version: %d (latest: %d, backend: %s)\n", current, latest, backend)
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"discordvault/internal/config"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"discordvault/internal/qr"
+	"discordvault/internal/sharelink"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// runShare implements `discordvault share [--expires 24h] [--qr] FILE_ID`,
+// issuing a share link from the host without going through the web API.
+func runShare(args []string) error {
+	fs := flag.NewFlagSet("share", flag.ContinueOnError)
+	expires := fs.Duration("expires", 0, "link lifetime, e.g. 24h (default: never expires)")
+	notify := fs.Bool("notify", false, "notify the file's owner on every download")
+	withQR := fs.Bool("qr", false, "also print the link as a QR code")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: discordvault share [--expires 24h] [--notify] [--qr] FILE_ID")
+	}
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid file ID %q", fs.Arg(0))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	signer, err := crypto.LoadOrCreateSigner(cfg.SigningKeyPath, cfg.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	db, err := database.Open(cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Conn.Close()
+
+	file, err := db.GetFile(id)
+	if err != nil {
+		return fmt.Errorf("file %d not found", id)
+	}
+	share, _, err := sharelink.Create(db, signer, file, file.OwnerID, *expires, *notify)
+	if err != nil {
+		return err
+	}
+
+	url := sharelink.URL(cfg.PublicURL, share.Token)
+	if *withQR {
+		code, err := qr.Encode(url)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(os.Stdout, code.ANSI())
+	}
+	fmt.Println(url)
+	if share.ExpiresAt != nil {
+		fmt.Printf("Expires %s\n", share.ExpiresAt.Format("2006-01-02 15:04:05 MST"))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"discordvault/internal/database"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxChoices is the most suggestions Discord shows for an autocomplete option.
+const maxChoices = 25
+
+// fileIDOption is the "id" option of commands that act on one file. It is a
+// string so users can type part of a name; picking a suggestion submits the
+// numeric ID.
+func fileIDOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:         discordgo.ApplicationCommandOptionString,
+		Name:         "id",
+		Description:  "File name or ID",
+		Required:     true,
+		Autocomplete: true,
+	}
+}
+
+// handleAutocomplete suggests the caller's files in the interaction's vault
+// whose name or tag matches what they typed so far.
+func (b *Bot) handleAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var query string
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Focused {
+			query = strings.TrimSpace(opt.StringValue())
+		}
+	}
+
+	owner := b.browserOwner(i)
+	var files []database.FileMetadata
+	if query == "" {
+		files, _, _ = b.DB.PageFiles(b.vaultOf(i), owner, maxChoices, 0)
+	} else {
+		files, _, _ = b.DB.SearchFiles(b.vaultOf(i), query, owner, maxChoices, 0)
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(files))
+	for _, f := range files {
+		label := fmt.Sprintf("#%d %s (%s)", f.ID, f.Name, formatBytes(f.Size))
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: truncate(label, 100), Value: strconv.Itoa(f.ID)})
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	})
+}
+
+// fileOption resolves the "id" option to one of the caller's files. It
+// accepts an ID (with or without "#") or the exact name of a current file,
+// and returns the raw input for error messages.
+func (b *Bot) fileOption(i *discordgo.InteractionCreate) (*database.FileMetadata, string) {
+	var input string
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "id" {
+			input = strings.TrimSpace(opt.StringValue())
+		}
+	}
+
+	var file *database.FileMetadata
+	if id, err := strconv.Atoi(strings.TrimPrefix(input, "#")); err == nil {
+		file, _ = b.DB.GetFile(id)
+	} else {
+		file, _ = b.DB.GetFileByName(b.vaultOf(i), input)
+	}
+	if file == nil || !b.canManage(i, file) {
+		return nil, input
+	}
+	return file, input
+}
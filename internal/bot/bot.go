@@ -1,67 +1,164 @@
 package bot
 
 import (
-	"bytes"
-	"crypto/sha256"
+	"discordvault/internal/chunkcache"
 	"discordvault/internal/config"
 	"discordvault/internal/crypto"
 	"discordvault/internal/database"
-	"encoding/hex"
+	"discordvault/internal/health"
+	"discordvault/internal/membudget"
+	"discordvault/internal/notify"
+	"discordvault/internal/proxy"
+	"discordvault/internal/retry"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 const (
-	ChunkSize = 7 * 1024 * 1024 // 7MB - Safe for all Discord servers
+	ChunkSize = 7 * 1024 * 1024 // 7MB - Safe for all Discord servers, and the size of files stored before sizes were recorded
 )
 
 type Bot struct {
-	Session *discordgo.Session
-	Config  *config.Config
-	DB      *database.Database
+	Session   *discordgo.Session
+	Config    *config.Config
+	DB        *database.Database
+	Templates *notify.Templates
+	Health    *health.Monitor
+	Signer    *crypto.Signer
+	Mailer    *notify.Mailer // nil unless SMTP_HOST is set
+	Bridges   []notify.Bridge
+	Retry     *retry.Policy     // repeats storage calls that failed for a passing reason
+	Cache     *chunkcache.Cache // nil unless CHUNK_CACHE_DIR is set
+	Memory    *membudget.Budget // chunks buffered across all transfers, nil = unlimited
+	StartedAt time.Time
+
+	sizer     chunkSizer
+	cooldowns cooldowns
+	storage   tokenPool
+	shardTurn atomic.Uint64 // SHARD_STRATEGY=round-robin
 }
 
-func New(cfg *config.Config, db *database.Database) (*Bot, error) {
+func New(cfg *config.Config, db *database.Database, signer *crypto.Signer) (*Bot, error) {
 	dg, err := discordgo.New("Bot " + cfg.DiscordToken)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Bot{
-		Session: dg,
-		Config:  cfg,
-		DB:      db,
-	}, nil
+	templates, err := notify.Load(cfg.NotifyTemplatesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	monitor := health.NewMonitor()
+	dg.Dialer = proxy.Dialer()
+	dg.Client.Transport = monitor.Transport(proxy.Transport())
+
+	b := &Bot{
+		Session:   dg,
+		Config:    cfg,
+		DB:        db,
+		Templates: templates,
+		Health:    monitor,
+		Signer:    signer,
+		Retry:     &retry.Policy{Attempts: cfg.RetryAttempts, Base: cfg.RetryBackoff, Max: cfg.RetryMaxBackoff},
+		Memory:    membudget.New(cfg.MaxBufferedChunks),
+	}
+	monitor.OnChange = b.notifyHealth
+	own := &storageToken{session: dg}
+	dg.Client.Transport = own.pacer.transport(dg.Client.Transport)
+	b.storage.tokens = []*storageToken{own}
+	for i, token := range cfg.StorageTokens {
+		t, err := b.newStorageToken(token)
+		if err != nil {
+			return nil, fmt.Errorf("storage token %d: %w", i+1, err)
+		}
+		b.storage.tokens = append(b.storage.tokens, t)
+	}
+	if cfg.ChunkCacheDir != "" {
+		if b.Cache, err = chunkcache.Open(cfg.ChunkCacheDir, cfg.ChunkCacheSize); err != nil {
+			return nil, fmt.Errorf("chunk cache: %w", err)
+		}
+		log.Printf("[BOT] Caching chunks in %s", b.Cache)
+	}
+	if cfg.SMTPHost != "" {
+		b.Mailer = &notify.Mailer{Host: cfg.SMTPHost, Port: cfg.SMTPPort, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.SMTPFrom}
+	}
+	if cfg.SlackWebhookURL != "" {
+		b.Bridges = append(b.Bridges, &notify.Slack{WebhookURL: cfg.SlackWebhookURL})
+	}
+	if cfg.MatrixHomeserver != "" {
+		b.Bridges = append(b.Bridges, &notify.Matrix{Homeserver: cfg.MatrixHomeserver, RoomID: cfg.MatrixRoomID, AccessToken: cfg.MatrixAccessToken})
+	}
+	return b, nil
+}
+
+// gatewayStale is how long the gateway may go without a heartbeat ack,
+// about four missed heartbeats, before the bot counts as hung.
+const gatewayStale = 3 * time.Minute
+
+// Alive reports whether the gateway session is still acknowledging
+// heartbeats. It feeds the systemd watchdog.
+func (b *Bot) Alive() bool {
+	b.Session.RLock()
+	defer b.Session.RUnlock()
+	return time.Since(b.Session.LastHeartbeatAck) < gatewayStale
 }
 
 func (b *Bot) Start() error {
 	b.Session.AddHandler(b.interactionCreate)
+	b.Session.AddHandler(b.messageCreate)
+	b.Session.AddHandler(b.guildCreate)
+	b.Session.AddHandler(b.guildUpdate)
+	b.Session.AddHandler(b.guildDelete)
 
 	err := b.Session.Open()
 	if err != nil {
 		return err
 	}
 
+	b.StartedAt = time.Now()
 	b.Session.UpdateGameStatus(0, "Locking away secrets... 🔒")
 	log.Printf("[BOT] Online as: %v", b.Session.State.User.String())
+	b.storage.lookupUsers(b.Session.State.User)
+	if len(b.storage.tokens) > 1 {
+		log.Printf("[BOT] Spreading chunk traffic over %d bot tokens", len(b.storage.tokens))
+	}
 
 	commands := []*discordgo.ApplicationCommand{
 		{Name: "help", Description: "Show available commands"},
 		{Name: "ping", Description: "Check bot latency"},
-		{Name: "list", Description: "List all stored files"},
-		{Name: "upload", Description: "Upload a file to the vault", Options: []*discordgo.ApplicationCommandOption{
-			{Type: discordgo.ApplicationCommandOptionAttachment, Name: "file", Description: "File to upload", Required: true},
+		{Name: "list", Description: "List all stored files", Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "page_size", Description: fmt.Sprintf("Files per page (1-%d, default %d)", maxPageSize, defaultPageSize)},
+		}},
+		{Name: "stats", Description: "Show vault statistics"},
+		{Name: "search", Description: "Find files by name or tag", Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "query", Description: "Part of a file name, or a tag", Required: true},
+		}},
+		{Name: "upload", Description: "Upload files to the vault", Options: uploadOptions()},
+		{Name: "info", Description: "Show file details and chunk health", Options: []*discordgo.ApplicationCommandOption{fileIDOption()}},
+		{Name: "verify", Description: "Download every chunk and check the file's SHA-256", Options: []*discordgo.ApplicationCommandOption{fileIDOption()}},
+		{Name: "download", Description: "Retrieve a file from the vault", Options: []*discordgo.ApplicationCommandOption{fileIDOption()}},
+		{Name: "share", Description: "Create a public share link", Options: []*discordgo.ApplicationCommandOption{
+			fileIDOption(),
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "expires_hours", Description: "Hours until the link expires (default: never)"},
+			{Type: discordgo.ApplicationCommandOptionBoolean, Name: "qr", Description: "Attach the link as a QR code"},
 		}},
-		{Name: "delete", Description: "Delete a file from the vault", Options: []*discordgo.ApplicationCommandOption{
-			{Type: discordgo.ApplicationCommandOptionInteger, Name: "id", Description: "File ID", Required: true},
+		{Name: "delete", Description: "Delete a file from the vault", Options: []*discordgo.ApplicationCommandOption{fileIDOption()}},
+		{Name: saveCommand, Type: discordgo.MessageApplicationCommand},
+		{Name: "timezone", Description: "Set the timezone used for dates", Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "zone", Description: "IANA timezone, e.g. Europe/Helsinki", Required: true},
+			{Type: discordgo.ApplicationCommandOptionString, Name: "scope", Description: "Apply to yourself or the whole server", Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "me", Value: database.ScopeUser},
+				{Name: "server", Value: database.ScopeGuild},
+			}},
 		}},
 	}
+	localizeCommands(commands)
 
 	for _, v := range commands {
 		_, err := b.Session.ApplicationCommandCreate(b.Session.State.User.ID, "", v)
@@ -73,51 +170,39 @@ func (b *Bot) Start() error {
 	return nil
 }
 
-func (b *Bot) NotifyUpload(filename string, size int64, parts int, method string) {
-	b.Session.ChannelMessageSend(b.Config.ChannelID, fmt.Sprintf("📤 **%s Upload Complete**\n**File:** `%s`\n**Size:** `%s`\n**Parts:** %d\n**Time:** `%s`\n**Status:** Encrypted & Locked",
-		method, filename, formatBytes(size), parts, time.Now().Format("15:04:05")))
-}
-
-func (b *Bot) checkPermission(i *discordgo.InteractionCreate) bool {
-	if len(b.Config.AllowedUsers) == 0 {
-		return true
-	}
-	userID := ""
-	if i.Member != nil {
-		userID = i.Member.User.ID
-	} else if i.User != nil {
-		userID = i.User.ID
+func (b *Bot) interactionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type == discordgo.InteractionMessageComponent {
+		b.componentInteraction(s, i)
+		return
 	}
-	for _, id := range b.Config.AllowedUsers {
-		if id == userID {
-			return true
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		if b.checkPermission(i, i.ApplicationCommandData().Name) {
+			b.handleAutocomplete(s, i)
 		}
+		return
 	}
-	return false
-}
-
-func (b *Bot) interactionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	if i.Type != discordgo.InteractionApplicationCommand {
 		return
 	}
 
-	user := i.Member.User
-	if user == nil {
-		user = i.User
-	}
+	user := interactionUser(i)
 	log.Printf("[BOT] Command /%s by %s", i.ApplicationCommandData().Name, user.Username)
 
-	if !b.checkPermission(i) {
+	if !b.checkPermission(i, i.ApplicationCommandData().Name) {
 		log.Printf("[BOT WARN] Unauthorized access attempt by %s", user.Username)
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "⛔ Access Denied.",
+				Content: b.t(i, "⛔ Access Denied."),
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		})
 		return
 	}
+	if left := b.throttle(user.ID, i.ApplicationCommandData().Name, b.isAdmin(i)); left > 0 {
+		b.slowDown(i, i.ApplicationCommandData().Name, left)
+		return
+	}
 
 	switch i.ApplicationCommandData().Name {
 	case "help":
@@ -125,122 +210,130 @@ func (b *Bot) interactionCreate(s *discordgo.Session, i *discordgo.InteractionCr
 	case "ping":
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{Content: "Pong! 🏓"},
+			Data: &discordgo.InteractionResponseData{Content: b.t(i, "Pong! 🏓"), Flags: b.replyFlags(i)},
 		})
 	case "list":
 		b.handleList(s, i)
 	case "upload":
 		b.handleUpload(s, i)
+	case "info":
+		b.handleInfo(s, i)
+	case "verify":
+		b.handleVerify(s, i)
+	case "download":
+		b.handleDownload(s, i)
+	case "share":
+		b.handleShare(s, i)
 	case "delete":
 		b.handleDelete(s, i)
+	case "timezone":
+		b.handleTimezone(s, i)
+	case "stats":
+		b.handleStats(s, i)
+	case "search":
+		b.handleSearch(s, i)
+	case saveCommand:
+		b.handleSaveToVault(s, i)
+	}
+}
+
+// componentInteraction routes button presses on messages the bot sent.
+func (b *Bot) componentInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch customID := i.MessageComponentData().CustomID; {
+	case strings.HasPrefix(customID, searchPrefix):
+		if b.checkPermission(i, "search") {
+			b.handleSearchPage(s, i)
+		}
+	case strings.HasPrefix(customID, listPrefix):
+		if b.checkPermission(i, "list") {
+			b.handleListPage(s, i)
+		}
 	}
 }
 
 func (b *Bot) handleHelp(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	embed := &discordgo.MessageEmbed{
 		Title:       "Discord Vault 🛡️",
-		Description: "High-security file storage using Discord and AES-256.",
+		Description: b.t(i, "High-security file storage using Discord and AES-256."),
 		Color:       0x3b82f6,
 		Fields: []*discordgo.MessageEmbedField{
-			{Name: "/upload", Value: "Store a file securely (max 25MB via Bot)"},
-			{Name: "/list", Value: "List all secured assets"},
-			{Name: "/delete [id]", Value: "Purge an asset from the vault"},
+			{Name: "/upload", Value: b.t(i, "Store up to 10 files securely, or all files of a message")},
+			{Name: "/list", Value: b.t(i, "List all secured assets")},
+			{Name: "/search [query]", Value: b.t(i, "Find assets by name or tag")},
+			{Name: "/info [id]", Value: b.t(i, "Asset details and chunk health")},
+			{Name: "/verify [id]", Value: b.t(i, "Full integrity check against the stored SHA-256")},
+			{Name: "/download [id]", Value: b.t(i, "Retrieve an asset (attached, or a one-time link by DM)")},
+			{Name: "/share [id]", Value: b.t(i, "Create a public link, optionally as a QR code")},
+			{Name: "/delete [id]", Value: b.t(i, "Purge an asset from the vault")},
+			{Name: "/timezone [zone]", Value: b.t(i, "Show dates in your timezone")},
+			{Name: "/stats", Value: b.t(i, "Vault totals and recent activity")},
+			{Name: b.t(i, "Apps → Save to Vault"), Value: b.t(i, "Store a message's attachments (right-click the message)")},
+			{Name: b.t(i, "Direct message"), Value: b.t(i, "Send files to the bot in a DM to store them")},
 		},
 	}
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed}},
+		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed}, Flags: b.replyFlags(i)},
 	})
 }
 
-func (b *Bot) handleUpload(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	options := i.ApplicationCommandData().Options
-	attachment := i.ApplicationCommandData().Resolved.Attachments[options[0].Value.(string)]
-
-	log.Printf("[BOT] Processing upload from Discord: %s", attachment.Filename)
+func (b *Bot) handleDelete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	file, input := b.fileOption(i)
+	if file == nil {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: b.t(i, "❌ No file **%s** in your vault.", input),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+	id := file.ID
+	log.Printf("[BOT] Manual purge requested for ID: %d", id)
 
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{Content: "⏳ Processing & Encrypting..."},
+		Data: &discordgo.InteractionResponseData{Content: b.t(i, "💣 Purging..."), Flags: b.replyFlags(i)},
 	})
 
-	resp, err := http.Get(attachment.URL)
-	if err != nil {
-		log.Printf("[BOT ERR] Failed to fetch attachment: %v", err)
-		b.followup(i, "❌ Failed to fetch file.")
-		return
-	}
-	defer resp.Body.Close()
-
-	data, _ := io.ReadAll(resp.Body)
-
-	encrypted, err := crypto.Encrypt(data, b.Config.EncryptionKey)
-	if err != nil {
-		log.Printf("[BOT ERR] Encryption failed: %v", err)
-		b.followup(i, "❌ Encryption failed.")
-		return
-	}
-
-	log.Printf("[BOT] Saving encrypted payload to storage channel...")
-	msg, err := b.Session.ChannelFileSend(b.Config.ChannelID, fmt.Sprintf("%x.vault", sha256.Sum256(encrypted)), bytes.NewReader(encrypted))
-	if err != nil {
-		log.Printf("[BOT ERR] Discord storage failed: %v", err)
-		b.followup(i, "❌ Could not save to storage channel.")
+	if b.Config.PurgeGrace > 0 {
+		if err := b.DB.SoftDeleteFile(id, interactionUser(i).ID); err != nil {
+			log.Printf("[BOT ERR] SoftDeleteFile %d failed: %v", id, err)
+			b.followup(i, b.t(i, "❌ Database error."))
+			return
+		}
+		log.Printf("[BOT] ID %d deleted; chunks kept for %s.", id, b.Config.PurgeGrace)
+		go b.NotifyDelete(file, "Bot", interactionUser(i).ID)
+		b.followup(i, b.t(i, "🧹 Purge complete. An admin can still restore it for %s.", b.Config.PurgeGrace))
 		return
 	}
 
-	hash := sha256.Sum256(data)
-	hashStr := hex.EncodeToString(hash[:])
-
-	fileID, err := b.DB.SaveFile(attachment.Filename, int64(attachment.Size), hashStr)
-	if err != nil {
-		log.Printf("[BOT ERR] DB Save failed: %v", err)
-		b.followup(i, "❌ Database error.")
-		return
+	chunks, _ := b.DB.ExclusiveChunks(id)
+	for _, c := range chunks {
+		b.DeleteChunk(c)
 	}
 
-	b.DB.SaveChunk(fileID, msg.ID, 1)
-	log.Printf("[BOT] Success! Saved %s (ID: %d)", attachment.Filename, fileID)
-
-	// Send notification log like web upload
-	go b.NotifyUpload(attachment.Filename, int64(attachment.Size), 1, "Bot")
-
-	b.followup(i, fmt.Sprintf("✅ Object secured. ID: **#%d**", fileID))
+	b.DB.DeleteFile(id)
+	log.Printf("[BOT] ID %d purged.", id)
+	go b.NotifyDelete(file, "Bot", interactionUser(i).ID)
+	b.followup(i, b.t(i, "🧹 Purge complete."))
 }
 
-func (b *Bot) handleList(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	files, _ := b.DB.ListFiles()
-	var sb strings.Builder
-	sb.WriteString("📂 **Vault Assets:**\n\n")
-	if len(files) == 0 {
-		sb.WriteString("*Empty*")
+// canManage reports whether the file is in the interaction's vault and the
+// user owns it or holds the admin override.
+func (b *Bot) canManage(i *discordgo.InteractionCreate, file *database.FileMetadata) bool {
+	if file.GuildID != b.vaultOf(i) {
+		return false
 	}
-	for _, f := range files {
-		sb.WriteString(fmt.Sprintf("`#%d` **%s** (%s)\n", f.ID, f.Name, formatBytes(f.Size)))
-	}
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{Content: sb.String()},
-	})
+	return file.OwnerID == interactionUser(i).ID || b.isAdmin(i)
 }
 
-func (b *Bot) handleDelete(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	id := int(i.ApplicationCommandData().Options[0].IntValue())
-	log.Printf("[BOT] Manual purge requested for ID: %d", id)
-
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{Content: "💣 Purging..."},
-	})
-
-	chunks, _ := b.DB.GetChunks(id)
-	for _, c := range chunks {
-		s.ChannelMessageDelete(b.Config.ChannelID, c.MessageID)
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
 	}
-
-	b.DB.DeleteFile(id)
-	log.Printf("[BOT] ID %d purged.", id)
-	b.followup(i, "🧹 Purge complete.")
+	return i.User
 }
 
 func (b *Bot) followup(i *discordgo.InteractionCreate, content string) {
@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"discordvault/internal/database"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultPageSize is how many files /list and /search show per page.
+const defaultPageSize = 10
+
+// fileBrowser renders one page of a file listing as an embed, with
+// Previous/Next buttons when there is more than one page. pageID builds the
+// custom ID a button uses to request another page.
+func (b *Bot) fileBrowser(i *discordgo.InteractionCreate, title string, files []database.FileMetadata, total, page, pageSize int, pageID func(page int) string) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	var sb strings.Builder
+	if total == 0 {
+		sb.WriteString(b.t(i, "*Empty*"))
+	}
+	loc := b.location(interactionUser(i).ID, i.GuildID)
+	for _, f := range files {
+		sb.WriteString(fmt.Sprintf("`#%d` **%s** (%s) · %s%s\n", f.ID, f.Name, formatBytes(f.Size), f.CreatedAt.In(loc).Format("2006-01-02 15:04"), b.healthMark(i, f.Health)))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: sb.String(),
+		Color:       0x3b82f6,
+	}
+	pages := (total + pageSize - 1) / pageSize
+	if pages <= 1 {
+		return embed, nil
+	}
+
+	embed.Footer = &discordgo.MessageEmbedFooter{Text: b.t(i, "Page %d of %d · %d files", page+1, pages, total)}
+	return embed, []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    b.t(i, "◀ Previous"),
+				Style:    discordgo.SecondaryButton,
+				CustomID: pageID(page - 1),
+				Disabled: page == 0,
+			},
+			discordgo.Button{
+				Label:    b.t(i, "Next ▶"),
+				Style:    discordgo.SecondaryButton,
+				CustomID: pageID(page + 1),
+				Disabled: page >= pages-1,
+			},
+		}},
+	}
+}
+
+// healthMark flags files the last scrub found damaged.
+func (b *Bot) healthMark(i *discordgo.InteractionCreate, health string) string {
+	switch health {
+	case database.HealthDegraded:
+		return " · 🩹 " + b.t(i, "degraded")
+	case database.HealthCorrupt:
+		return " · ❌ " + b.t(i, "corrupt")
+	}
+	return ""
+}
+
+// browserOwner limits listings to the caller's files unless they are an admin.
+func (b *Bot) browserOwner(i *discordgo.InteractionCreate) string {
+	if !b.isAdmin(i) {
+		return interactionUser(i).ID
+	}
+	return ""
+}
+
+// updateBrowser replaces the browser message a button was pressed on.
+func (b *Bot) updateBrowser(i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) {
+	b.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+		},
+	})
+}
@@ -0,0 +1,350 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"discordvault/internal/config"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"discordvault/internal/erasure"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// ChunkPool posts the encrypted chunks of one upload to a storage channel,
+// or its shards with SHARD_CHANNELS, with up to CHUNK_PARALLELISM in
+// flight, and hands them back in part order however the posts finish.
+// After the first failure nothing more is sent. Every chunk counts against
+// the bot's memory budget from Reserve, before it is read, until it is
+// posted.
+//
+// With ERASURE_CODING the pool also posts the parity chunks of every group
+// of chunks; Commit records them once the file is saved. Without it, an
+// upload replacing a stored file can reuse the chunks they have in common
+// (DeltaFrom and Reuse).
+type ChunkPool struct {
+	bot      *Bot
+	channels []string // the storage channel and its shards
+	key      string   // name the channel is hashed from with SHARD_STRATEGY=hash
+	slots    chan struct{}
+	wg       sync.WaitGroup
+
+	code    *erasure.Code // nil without parity
+	encoder *erasure.Encoder
+	group   int
+	ctx     context.Context // of the last Submit, for the final group
+
+	mu       sync.Mutex
+	chunks   []database.ChunkMetadata // by part, zero until stored
+	parity   []database.ParityChunk
+	err      error
+	reserved bool // a buffer of the memory budget not yet taken by Submit
+
+	base   map[string]database.ChunkMetadata // digest -> chunk of the stored file, see DeltaFrom
+	digest string                            // of the part last offered to Reuse, for Submit
+	reused int
+}
+
+// NewChunkPool starts an empty pool for an upload to channelID.
+func (b *Bot) NewChunkPool(channelID string) *ChunkPool {
+	p := &ChunkPool{
+		bot:      b,
+		channels: b.Config.Shards(channelID),
+		slots:    make(chan struct{}, max(b.Config.ChunkParallelism, 1)),
+		ctx:      context.Background(),
+	}
+	if b.Config.ParityData > 0 {
+		code, err := erasure.New(b.Config.ParityData, b.Config.ParityChunks)
+		if err != nil {
+			log.Printf("[BOT ERR] Erasure coding unavailable, storing without parity: %v", err)
+		} else {
+			p.code, p.encoder = code, code.NewEncoder()
+		}
+	}
+	return p
+}
+
+// ShardBy names the upload, keeping all its chunks in the shard the name
+// hashes to with SHARD_STRATEGY=hash. Call it before the first Submit.
+func (p *ChunkPool) ShardBy(name string) {
+	p.key = name
+}
+
+// channel returns the channel the next chunk is posted to.
+func (p *ChunkPool) channel() string {
+	n := uint64(len(p.channels))
+	if n == 1 {
+		return p.channels[0]
+	}
+	if p.bot.Config.ShardStrategy == config.ShardHash {
+		sum := sha256.Sum256([]byte(p.key))
+		return p.channels[binary.BigEndian.Uint64(sum[:8])%n]
+	}
+	return p.channels[p.bot.shardTurn.Add(1)%n]
+}
+
+// SkipParity stores the upload without parity chunks, for uploads sent in
+// several requests whose groups one pool cannot see whole.
+func (p *ChunkPool) SkipParity() {
+	p.code, p.encoder = nil, nil
+}
+
+// DeltaFrom lets Reuse take chunks of the current file named name in the
+// vault guildID, if owner stored it, instead of posting the same bytes
+// again. Pools with parity never reuse chunks, since parity is computed
+// from the ciphertext of every chunk. Call it before the first Submit.
+func (p *ChunkPool) DeltaFrom(guildID, name, owner string) {
+	if p.code != nil {
+		return
+	}
+	file, err := p.bot.DB.GetFileByName(guildID, name)
+	if err != nil || file.OwnerID != owner {
+		return
+	}
+	chunks, err := p.bot.DB.GetChunks(file.ID)
+	if err != nil {
+		log.Printf("[BOT ERR] Chunks of #%d could not be loaded for a delta upload: %v", file.ID, err)
+		return
+	}
+	p.base = make(map[string]database.ChunkMetadata, len(chunks))
+	for _, c := range chunks {
+		if c.Digest != "" {
+			p.base[c.Digest] = c
+		}
+	}
+}
+
+// Reuse offers the plaintext of the next part before it is encrypted. If
+// the file given to DeltaFrom has a chunk holding the same bytes, the pool
+// takes that chunk as the part and Reuse returns true; the caller skips
+// encrypting and submitting it. Otherwise the part's digest is recorded
+// with the chunk the next Submit posts.
+func (p *ChunkPool) Reuse(plain []byte) bool {
+	digest := crypto.Digest(plain, p.bot.Config.EncryptionKey)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.base[digest]; ok && p.code == nil {
+		c.PartNum = len(p.chunks) + 1
+		p.chunks = append(p.chunks, c)
+		p.reused++
+		return true
+	}
+	p.digest = digest
+	return false
+}
+
+// Reserve waits for a free buffer in the memory budget for the next chunk.
+// Call it before reading the chunk; Submit takes the buffer over, and Wait
+// or Discard return one that was not used.
+func (p *ChunkPool) Reserve(ctx context.Context) error {
+	p.mu.Lock()
+	reserved := p.reserved
+	p.mu.Unlock()
+	if reserved {
+		return nil
+	}
+	if err := p.bot.Memory.Acquire(ctx); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.reserved = true
+	p.mu.Unlock()
+	return nil
+}
+
+// unreserve returns the buffer of a Reserve no chunk was submitted for.
+func (p *ChunkPool) unreserve() {
+	p.mu.Lock()
+	reserved := p.reserved
+	p.reserved = false
+	p.mu.Unlock()
+	if reserved {
+		p.bot.Memory.Release()
+	}
+}
+
+// Submit queues encrypted as the next part. It blocks while the pool is
+// full and returns the error of an earlier part, if any, or ctx's error.
+// done, if set, is called from the posting goroutine once the chunk is
+// stored.
+func (p *ChunkPool) Submit(ctx context.Context, encrypted []byte, done func(database.ChunkMetadata)) error {
+	if err := p.Reserve(ctx); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.reserved = false
+	p.mu.Unlock()
+	if err := p.acquire(ctx); err != nil {
+		p.bot.Memory.Release()
+		return err
+	}
+	p.mu.Lock()
+	part := len(p.chunks) + 1
+	p.chunks = append(p.chunks, database.ChunkMetadata{})
+	digest := p.digest
+	p.digest = ""
+	p.mu.Unlock()
+	channelID := p.channel()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() { <-p.slots }()
+		defer p.bot.Memory.Release()
+
+		chunk, err := p.store(ctx, channelID, encrypted)
+		p.mu.Lock()
+		if err != nil {
+			if p.err == nil {
+				p.err = fmt.Errorf("chunk %d: %w", part, err)
+			}
+			p.mu.Unlock()
+			return
+		}
+		chunk.PartNum = part
+		chunk.Digest = digest
+		p.chunks[part-1] = chunk
+		p.mu.Unlock()
+		if done != nil {
+			done(chunk)
+		}
+	}()
+
+	if p.encoder != nil {
+		p.ctx = ctx
+		p.encoder.Add(encrypted)
+		if p.encoder.Count() == p.code.Data {
+			return p.flushParity(ctx)
+		}
+	}
+	return nil
+}
+
+// acquire takes a slot in the pool, unless an earlier post failed.
+func (p *ChunkPool) acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		<-p.slots
+		return p.err
+	}
+	return nil
+}
+
+func (p *ChunkPool) store(ctx context.Context, channelID string, encrypted []byte) (database.ChunkMetadata, error) {
+	return p.bot.StoreChunk(ctx, channelID, encrypted)
+}
+
+// flushParity posts the parity chunks of the current group. They are not
+// replicated: they already stand in for lost chunks.
+func (p *ChunkPool) flushParity(ctx context.Context) error {
+	group := p.group
+	p.group++
+	for shard, data := range p.encoder.Parity() {
+		if err := p.acquire(ctx); err != nil {
+			return err
+		}
+		channelID := p.channel()
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			defer func() { <-p.slots }()
+
+			t, err := p.bot.pace(ctx, channelID)
+			var chunk database.ChunkMetadata
+			if err == nil {
+				chunk, err = p.bot.postChunk(t, channelID, data)
+			}
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if err != nil {
+				if p.err == nil {
+					p.err = fmt.Errorf("parity chunk %d of group %d: %w", shard, group, err)
+				}
+				return
+			}
+			p.parity = append(p.parity, database.ParityChunk{ChunkMetadata: chunk, Group: group, Shard: shard})
+		}()
+	}
+	return nil
+}
+
+// Wait posts the parity of a last, short group, blocks until every
+// submitted chunk is posted, and returns them in part order, or the first
+// error.
+func (p *ChunkPool) Wait() ([]database.ChunkMetadata, error) {
+	p.unreserve()
+	if p.encoder != nil && p.encoder.Count() > 0 && p.ctx.Err() == nil {
+		p.flushParity(p.ctx)
+	}
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.reused > 0 {
+		log.Printf("[BOT] %s: %d of %d chunks unchanged since the stored version, reused", p.key, p.reused, len(p.chunks))
+	}
+	return p.chunks, nil
+}
+
+// Commit records the parity chunks of the upload saved as file. Parity
+// that cannot be recorded is deleted; the file is then kept without it.
+func (p *ChunkPool) Commit(file *database.FileMetadata) {
+	if p.code == nil {
+		return
+	}
+	p.mu.Lock()
+	parity := p.parity
+	p.mu.Unlock()
+	if err := p.bot.DB.SaveParity(file.ID, p.code.Data, p.code.Parity, parity); err != nil {
+		log.Printf("[BOT ERR] Parity of #%d could not be recorded, storing it without: %v", file.ID, err)
+		p.discard(parityChunks(parity))
+		return
+	}
+	file.ParityData, file.ParityChunks = p.code.Data, p.code.Parity
+}
+
+// Discard waits for chunks still in flight and deletes every chunk the pool
+// stored, parity included, for uploads whose metadata is not committed.
+// Reused chunks belong to their file and are kept.
+func (p *ChunkPool) Discard() {
+	p.unreserve()
+	p.wg.Wait()
+	p.mu.Lock()
+	chunks := parityChunks(p.parity)
+	for _, c := range p.chunks {
+		if c.MessageID != "" && c.ID == 0 {
+			chunks = append(chunks, c)
+		}
+	}
+	p.mu.Unlock()
+	p.discard(chunks)
+}
+
+// discard deletes stored chunks, per channel they were posted to.
+func (p *ChunkPool) discard(chunks []database.ChunkMetadata) {
+	byChannel := make(map[string][]string)
+	for _, c := range chunks {
+		byChannel[c.ChannelID] = append(byChannel[c.ChannelID], c.MessageID)
+	}
+	for channelID, ids := range byChannel {
+		p.bot.DiscardChunks(channelID, ids)
+	}
+}
+
+func parityChunks(parity []database.ParityChunk) []database.ChunkMetadata {
+	chunks := make([]database.ChunkMetadata, len(parity))
+	for i, c := range parity {
+		chunks[i] = c.ChunkMetadata
+	}
+	return chunks
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"context"
+	"discordvault/internal/config"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// minChunkSize is the smallest chunk adaptive sizing shrinks to.
+	minChunkSize = 1024 * 1024
+
+	// chunkStep is the granularity of adaptive chunk sizes.
+	chunkStep = 256 * 1024
+
+	// chunkTarget is how long posting one chunk should take. Discord's REST
+	// client gives up after 20 seconds, so this leaves room for slow moments.
+	chunkTarget = 8 * time.Second
+)
+
+// boostChunkSizes are the chunk sizes CHUNK_SIZE=auto uses at each boost
+// tier of a guild: its attachment limit less 1MB for encryption overhead, as
+// 7MB is for the 8MB limit of a guild without boosts.
+var boostChunkSizes = map[discordgo.PremiumTier]int{
+	discordgo.PremiumTierNone: ChunkSize,
+	discordgo.PremiumTier1:    24 * 1024 * 1024,
+	discordgo.PremiumTier2:    49 * 1024 * 1024,
+	discordgo.PremiumTier3:    99 * 1024 * 1024,
+}
+
+// chunkSizer adapts the chunk size of new uploads to the measured uplink
+// when CHUNK_SIZING=adaptive: it shrinks after timeouts and slow chunks and
+// grows back toward MaxChunkSize on fast links.
+type chunkSizer struct {
+	mu   sync.Mutex
+	size int
+
+	tiered atomic.Int64 // CHUNK_SIZE=auto: the size the storage guilds allow, 0 until known
+}
+
+// MaxChunkSize returns the largest plaintext chunk of new uploads:
+// CHUNK_SIZE, or with CHUNK_SIZE=auto the size the boost tier of every
+// storage guild allows. Until the guilds are known it is ChunkSize.
+func (b *Bot) MaxChunkSize() int {
+	if b.Config.ChunkSize > 0 {
+		return int(b.Config.ChunkSize)
+	}
+	if size := b.sizer.tiered.Load(); size > 0 {
+		return int(size)
+	}
+	return ChunkSize
+}
+
+// NextChunkSize returns the plaintext chunk size for a new upload. A file
+// keeps the size it started with, so every chunk but its last is the same
+// length; the size is recorded with the file.
+func (b *Bot) NextChunkSize() int {
+	limit := b.MaxChunkSize()
+	if b.Config.ChunkSizing != config.SizingAdaptive {
+		return limit
+	}
+	b.sizer.mu.Lock()
+	defer b.sizer.mu.Unlock()
+	if b.sizer.size == 0 || b.sizer.size > limit {
+		b.sizer.size = limit
+	}
+	return b.sizer.size
+}
+
+// detectChunkSize sets the chunk size of CHUNK_SIZE=auto from the boost
+// tiers of the guilds holding the storage channels. Chunks may go to any of
+// them, so the lowest tier decides; a channel whose guild is not known yet
+// counts as unboosted. It runs whenever a guild becomes available or
+// changes, so a lapsed boost lowers the size before uploads fail on it.
+func (b *Bot) detectChunkSize() {
+	if b.Config.ChunkSize > 0 {
+		return
+	}
+	size := boostChunkSizes[discordgo.PremiumTier3]
+	for _, channelID := range b.Config.StorageChannels() {
+		tierSize := ChunkSize
+		if ch, err := b.Session.State.Channel(channelID); err == nil {
+			if g, err := b.Session.State.Guild(ch.GuildID); err == nil {
+				if s, ok := boostChunkSizes[g.PremiumTier]; ok {
+					tierSize = s
+				}
+			}
+		}
+		size = min(size, tierSize)
+	}
+	if old := b.sizer.tiered.Swap(int64(size)); old != int64(size) {
+		log.Printf("[BOT] Storage guild boost tier allows %s chunks", formatBytes(int64(size)))
+	}
+}
+
+func (b *Bot) guildCreate(s *discordgo.Session, g *discordgo.GuildCreate) { b.detectChunkSize() }
+func (b *Bot) guildUpdate(s *discordgo.Session, g *discordgo.GuildUpdate) { b.detectChunkSize() }
+func (b *Bot) guildDelete(s *discordgo.Session, g *discordgo.GuildDelete) { b.detectChunkSize() }
+
+// observeChunk feeds one chunk post into adaptive sizing: n bytes that took
+// took, or failed with err.
+func (b *Bot) observeChunk(n int, took time.Duration, err error) {
+	if b.Config.ChunkSizing != config.SizingAdaptive {
+		return
+	}
+	b.sizer.mu.Lock()
+	defer b.sizer.mu.Unlock()
+
+	limit := b.MaxChunkSize()
+	current := min(b.sizer.size, limit)
+	if current == 0 {
+		current = limit
+	}
+	next := current
+	switch {
+	case err != nil && isTimeout(err):
+		next = current / 2
+	case err != nil:
+		return
+	case took > 0:
+		ideal := int(float64(n) / took.Seconds() * chunkTarget.Seconds())
+		next = min(max(ideal, current/2), current*2)
+	}
+	next = min(max(next/chunkStep*chunkStep, minChunkSize), limit)
+
+	if next != current {
+		log.Printf("[BOT] Adaptive chunk size: %s -> %s", formatBytes(int64(current)), formatBytes(int64(next)))
+	}
+	b.sizer.size = next
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"discordvault/internal/i18n"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// cooldownSweep is how many users may be tracked before expired entries are
+// dropped.
+const cooldownSweep = 1024
+
+// cooldowns remembers until when each user must wait before running a
+// command again, per COMMAND_COOLDOWNS.
+type cooldowns struct {
+	mu    sync.Mutex
+	until map[string]time.Time // userID + "/" + command
+}
+
+// cooldownCommand is the COMMAND_COOLDOWNS key of a command. Save to Vault
+// and DM uploads store files just like /upload and share its cooldown.
+func cooldownCommand(command string) string {
+	if command == saveCommand {
+		return "upload"
+	}
+	return command
+}
+
+// throttle starts the cooldown of command for userID and returns zero, or
+// returns how long the user still has to wait. Admins are never throttled.
+func (b *Bot) throttle(userID, command string, admin bool) time.Duration {
+	command = cooldownCommand(command)
+	wait := b.Config.Live().Cooldowns[command]
+	if wait <= 0 || admin {
+		return 0
+	}
+
+	b.cooldowns.mu.Lock()
+	defer b.cooldowns.mu.Unlock()
+	now := time.Now()
+	if b.cooldowns.until == nil {
+		b.cooldowns.until = make(map[string]time.Time)
+	}
+	key := userID + "/" + command
+	if until, ok := b.cooldowns.until[key]; ok && now.Before(until) {
+		return until.Sub(now)
+	}
+	if len(b.cooldowns.until) >= cooldownSweep {
+		for k, until := range b.cooldowns.until {
+			if now.After(until) {
+				delete(b.cooldowns.until, k)
+			}
+		}
+	}
+	b.cooldowns.until[key] = now.Add(wait)
+	return 0
+}
+
+// slowDown tells the user when they may run command again.
+func (b *Bot) slowDown(i *discordgo.InteractionCreate, command string, left time.Duration) {
+	log.Printf("[BOT WARN] /%s by %s throttled (%s left)", command, interactionUser(i).Username, left.Round(time.Second))
+	b.respondEphemeral(i, slowDownMessage(b.locale(i), left))
+}
+
+// slowDownMessage says when a throttled command may run again, as a Discord
+// timestamp that counts down in the user's client.
+func slowDownMessage(locale string, left time.Duration) string {
+	again := fmt.Sprintf("<t:%d:R>", time.Now().Add(left).Unix()+1)
+	return i18n.T(locale, "🐢 Slow down! You can use this command again %s.", again)
+}
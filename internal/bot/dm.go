@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"discordvault/internal/database"
+	"discordvault/internal/i18n"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// messageCreate vaults files sent to the bot in a direct message, which is
+// quicker than /upload on mobile. The bot replies with the new file IDs.
+func (b *Bot) messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.GuildID != "" || m.Author == nil || m.Author.Bot || len(m.Attachments) == 0 {
+		return
+	}
+	userID := m.Author.ID
+	locale := b.dmLocale()
+	log.Printf("[BOT] DM upload of %d file(s) by %s", len(m.Attachments), m.Author.Username)
+
+	if !b.dmPermitted(userID, "upload") {
+		log.Printf("[BOT WARN] Unauthorized DM upload by %s", m.Author.Username)
+		s.ChannelMessageSendReply(m.ChannelID, i18n.T(locale, "⛔ Access Denied."), m.Reference())
+		return
+	}
+	if left := b.throttle(userID, "upload", b.Config.IsAdmin(userID)); left > 0 {
+		log.Printf("[BOT WARN] DM upload by %s throttled (%s left)", m.Author.Username, left.Round(time.Second))
+		s.ChannelMessageSendReply(m.ChannelID, slowDownMessage(locale, left), m.Reference())
+		return
+	}
+	if !b.Config.IsAdmin(userID) && b.TransferCapReached(userID) {
+		s.ChannelMessageSendReply(m.ChannelID, i18n.T(locale, "📉 You have reached your monthly transfer cap."), m.Reference())
+		return
+	}
+
+	content := i18n.T(locale, "⏳ Processing & Encrypting...")
+	if len(m.Attachments) > 1 {
+		content = i18n.T(locale, "⏳ Processing & Encrypting %d files...", len(m.Attachments))
+	}
+	reply, err := s.ChannelMessageSendReply(m.ChannelID, content, m.Reference())
+	if err != nil {
+		log.Printf("[BOT ERR] DM reply failed: %v", err)
+		return
+	}
+
+	results := b.storeAttachments(m.Attachments, database.DefaultVault, userID, locale, func(status string) {
+		s.ChannelMessageEdit(m.ChannelID, reply.ID, status)
+	})
+	empty := ""
+	s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel: m.ChannelID,
+		ID:      reply.ID,
+		Content: &empty,
+		Embeds:  &[]*discordgo.MessageEmbed{resultsEmbed(locale, results)},
+	})
+}
@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"discordvault/internal/database"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// AttachmentLimit is the largest file /download re-attaches directly; bigger
+// files are delivered as a one-time link. It matches the lowest upload limit
+// Discord applies to bots.
+const AttachmentLimit = 8 * 1024 * 1024
+
+func (b *Bot) handleDownload(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	userID := interactionUser(i).ID
+	file, input := b.fileOption(i)
+	if file == nil {
+		b.respondEphemeral(i, b.t(i, "❌ No file **%s** in your vault.", input))
+		return
+	}
+	if !b.isAdmin(i) && b.TransferCapReached(userID) {
+		b.respondEphemeral(i, b.t(i, "📉 You have reached your monthly transfer cap."))
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: b.t(i, "⏳ Reconstructing..."),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	if file.Size > AttachmentLimit {
+		b.sendDownloadLink(i, file, userID)
+		return
+	}
+
+	var buf bytes.Buffer
+	written, err := b.WriteFile(context.Background(), &buf, file)
+	if err != nil || written != file.Size {
+		log.Printf("[BOT ERR] Reconstruction of #%d failed: %v (%d of %d bytes)", file.ID, err, written, file.Size)
+		b.followup(i, b.t(i, "❌ Could not reconstruct the file."))
+		return
+	}
+	if err := b.DB.RecordTransfer(database.UsageUser, userID, 0, written); err != nil {
+		log.Printf("[BOT ERR] Transfer accounting failed: %v", err)
+	}
+
+	content := fmt.Sprintf("📦 **%s** (%s)", file.Name, formatBytes(file.Size))
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+		Files:   []*discordgo.File{{Name: b.DownloadName(file), ContentType: "application/octet-stream", Reader: &buf}},
+	})
+	if err != nil {
+		log.Printf("[BOT ERR] Attaching #%d failed, sending a link instead: %v", file.ID, err)
+		b.sendDownloadLink(i, file, userID)
+		return
+	}
+	log.Printf("[BOT] Delivered %s to %s as attachment", file.Name, userID)
+}
+
+// sendDownloadLink DMs the user a single-use link to the web server. The
+// transfer is counted when the link is used.
+func (b *Bot) sendDownloadLink(i *discordgo.InteractionCreate, file *database.FileMetadata, userID string) {
+	if b.Config.DownloadLinkTTL <= 0 {
+		b.followup(i, b.t(i, "❌ **%s** is too large to attach. Download it from the web vault.", file.Name))
+		return
+	}
+
+	tokenBytes := make([]byte, 24)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		b.followup(i, b.t(i, "❌ Token generation failed."))
+		return
+	}
+	token := &database.DownloadToken{
+		Token:     hex.EncodeToString(tokenBytes),
+		FileID:    file.ID,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(b.Config.DownloadLinkTTL),
+	}
+	if err := b.DB.CreateDownloadToken(token); err != nil {
+		log.Printf("[BOT ERR] Download token creation failed: %v", err)
+		b.followup(i, b.t(i, "❌ Database error."))
+		return
+	}
+
+	// The link is wrapped in <> so Discord does not fetch it for a preview,
+	// which would use it up
+	content := b.t(i, "📥 **%s** (%s)\n<%s/d/%s>\nThis link works once and expires at %s.",
+		file.Name, formatBytes(file.Size), b.Config.PublicURL, token.Token,
+		formatTime(token.ExpiresAt, b.location(userID, i.GuildID)))
+	channel, err := b.Session.UserChannelCreate(userID)
+	if err == nil {
+		_, err = b.Session.ChannelMessageSend(channel.ID, content)
+	}
+	if err != nil {
+		log.Printf("[BOT ERR] Download link DM to %s failed: %v", userID, err)
+		b.followup(i, b.t(i, "❌ Could not DM you the link. Check that DMs from server members are allowed."))
+		return
+	}
+	log.Printf("[BOT] Sent download link for %s to %s", file.Name, userID)
+	b.followup(i, b.t(i, "📬 The file is too large to attach, so a one-time download link was sent to your DMs."))
+}
+
+func (b *Bot) respondEphemeral(i *discordgo.InteractionCreate, content string) {
+	b.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// DownloadName renders DOWNLOAD_FILENAME for file. Path separators and
+// control characters are replaced so the result is always a bare file name.
+func (b *Bot) DownloadName(file *database.FileMetadata) string {
+	ext := path.Ext(file.Name)
+	name := strings.NewReplacer(
+		"{name}", file.Name,
+		"{base}", strings.TrimSuffix(file.Name, ext),
+		"{ext}", ext,
+		"{id}", strconv.Itoa(file.ID),
+		"{version}", strconv.Itoa(file.Version),
+		"{date}", file.CreatedAt.In(b.Config.Location).Format("2006-01-02"),
+	).Replace(b.Config.DownloadFilename)
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		return file.Name
+	}
+	return name
+}
@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"discordvault/internal/database"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxInfoChunks caps the chunk lines listed in /info to stay within Discord's
+// embed field limit.
+const maxInfoChunks = 15
+
+// Chunk health states reported by CheckChunk.
+const (
+	ChunkOK       = "ok"
+	ChunkMissing  = "missing"
+	ChunkMismatch = "size mismatch"
+	ChunkReplica  = "replica only" // the chunk itself is damaged, a replica is intact
+)
+
+// CheckChunk looks up a chunk's message and compares the attachment size with
+// the recorded ciphertext size, without downloading it. A damaged chunk with
+// an intact replica is reported as ChunkReplica.
+func (b *Bot) CheckChunk(c database.ChunkMetadata) string {
+	status := b.checkMessage(b.ChunkChannel(c), c.MessageID, c.Size)
+	if status == ChunkOK {
+		return status
+	}
+	replicas, _ := b.DB.Replicas(c.MessageID)
+	for _, r := range replicas {
+		if b.checkMessage(r.ChannelID, r.MessageID, c.Size) == ChunkOK {
+			return ChunkReplica
+		}
+	}
+	return status
+}
+
+func (b *Bot) checkMessage(channelID, messageID string, size int64) string {
+	var msg *discordgo.Message
+	err := b.Retry.Do("chunk "+messageID+" lookup", func() (err error) {
+		msg, err = b.Session.ChannelMessage(channelID, messageID)
+		return err
+	})
+	if err != nil || len(msg.Attachments) == 0 {
+		return ChunkMissing
+	}
+	if size > 0 && int64(msg.Attachments[0].Size) != size {
+		return ChunkMismatch
+	}
+	return ChunkOK
+}
+
+func (b *Bot) handleInfo(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	userID := interactionUser(i).ID
+	file, input := b.fileOption(i)
+	if file == nil {
+		b.respondEphemeral(i, b.t(i, "❌ No file **%s** in your vault.", input))
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: b.t(i, "🔍 Checking chunks..."), Flags: b.replyFlags(i)},
+	})
+
+	chunks, err := b.DB.GetChunks(file.ID)
+	if err != nil {
+		log.Printf("[BOT ERR] GetChunks failed: %v", err)
+		b.followup(i, b.t(i, "❌ Database error."))
+		return
+	}
+	tags, _ := b.DB.GetTags(file.ID)
+
+	healthy := 0
+	var sb strings.Builder
+	for idx, c := range chunks {
+		status := b.CheckChunk(c)
+		if status == ChunkOK || status == ChunkReplica {
+			healthy++
+		}
+		if idx < maxInfoChunks {
+			mark := "✅"
+			if status != ChunkOK {
+				mark = "⚠️ " + b.t(i, status)
+			}
+			sb.WriteString(fmt.Sprintf("`%d` %s %s\n", c.PartNum, c.MessageID, mark))
+		}
+	}
+	if len(chunks) > maxInfoChunks {
+		sb.WriteString(b.t(i, "… and %d more", len(chunks)-maxInfoChunks) + "\n")
+	}
+	if len(chunks) == 0 {
+		sb.WriteString(b.t(i, "*No chunks recorded*"))
+	}
+
+	color, health := 0x22c55e, b.t(i, "✅ %d/%d chunks present", healthy, len(chunks))
+	if healthy < len(chunks) || len(chunks) == 0 {
+		color, health = 0xef4444, b.t(i, "⚠️ %d/%d chunks intact", healthy, len(chunks))
+	}
+
+	uploader := file.OwnerID
+	if isSnowflake(uploader) {
+		uploader = "<@" + uploader + ">"
+	}
+	tagList := "—"
+	if len(tags) > 0 {
+		tagList = strings.Join(tags, ", ")
+	}
+	chunkSize := file.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = ChunkSize
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("📄 %s", file.Name),
+		Color: color,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "ID", Value: b.t(i, "#%d (version %d)", file.ID, file.Version), Inline: true},
+			{Name: b.t(i, "Size"), Value: formatBytes(file.Size), Inline: true},
+			{Name: b.t(i, "Uploaded"), Value: formatTime(file.CreatedAt, b.location(userID, i.GuildID)), Inline: true},
+			{Name: b.t(i, "Uploader"), Value: uploader, Inline: true},
+			{Name: b.t(i, "Tags"), Value: tagList, Inline: true},
+			{Name: b.t(i, "Chunk size"), Value: formatBytes(chunkSize), Inline: true},
+			{Name: "SHA-256", Value: "`" + file.Hash + "`"},
+			{Name: b.t(i, "Health"), Value: health},
+			{Name: b.t(i, "Chunks"), Value: sb.String()},
+		},
+	}
+	if file.SupersededAt != nil {
+		embed.Description = b.t(i, "This is an older version; a newer upload replaced it.")
+	}
+
+	content := ""
+	b.Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+		Embeds:  &[]*discordgo.MessageEmbed{embed},
+	})
+}
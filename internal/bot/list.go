@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	listPrefix  = "list:" // component custom ID: list:<page>:<page size>
+	maxPageSize = 25
+)
+
+func (b *Bot) handleList(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	pageSize := defaultPageSize
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "page_size" {
+			pageSize = min(max(int(opt.IntValue()), 1), maxPageSize)
+		}
+	}
+
+	embed, components, err := b.listPage(i, 0, pageSize)
+	if err != nil {
+		b.respondEphemeral(i, b.t(i, "❌ Database error."))
+		return
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func (b *Bot) handleListPage(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	pageStr, sizeStr, _ := strings.Cut(strings.TrimPrefix(i.MessageComponentData().CustomID, listPrefix), ":")
+	page, err1 := strconv.Atoi(pageStr)
+	pageSize, err2 := strconv.Atoi(sizeStr)
+	if err1 != nil || err2 != nil || page < 0 || pageSize < 1 || pageSize > maxPageSize {
+		return
+	}
+
+	embed, components, err := b.listPage(i, page, pageSize)
+	if err != nil {
+		return
+	}
+	b.updateBrowser(i, embed, components)
+}
+
+func (b *Bot) listPage(i *discordgo.InteractionCreate, page, pageSize int) (*discordgo.MessageEmbed, []discordgo.MessageComponent, error) {
+	files, total, err := b.DB.PageFiles(b.vaultOf(i), b.browserOwner(i), pageSize, page*pageSize)
+	if err != nil {
+		log.Printf("[BOT ERR] ListFiles failed: %v", err)
+		return nil, nil, err
+	}
+	embed, components := b.fileBrowser(i, b.t(i, "📂 Vault Assets"), files, total, page, pageSize, func(page int) string {
+		return fmt.Sprintf("%s%d:%d", listPrefix, page, pageSize)
+	})
+	return embed, components, nil
+}
@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"discordvault/internal/config"
+	"discordvault/internal/i18n"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// locale picks the language of replies to an interaction as set by
+// BOT_LOCALE: the user's client language, the server's community language
+// (the user's in DMs), or a fixed locale.
+func (b *Bot) locale(i *discordgo.InteractionCreate) string {
+	switch b.Config.Locale {
+	case config.LocaleUser:
+		return string(i.Locale)
+	case config.LocaleGuild:
+		if i.GuildLocale != nil {
+			return string(*i.GuildLocale)
+		}
+		return string(i.Locale)
+	}
+	return b.Config.Locale
+}
+
+// t translates a reply to an interaction; see i18n.T.
+func (b *Bot) t(i *discordgo.InteractionCreate, text string, args ...any) string {
+	return i18n.T(b.locale(i), text, args...)
+}
+
+// dmLocale is the language of replies to direct messages, which carry no
+// locale: the fixed BOT_LOCALE, or English.
+func (b *Bot) dmLocale() string {
+	if b.Config.Locale == config.LocaleUser || b.Config.Locale == config.LocaleGuild {
+		return "en"
+	}
+	return b.Config.Locale
+}
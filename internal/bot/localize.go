@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"discordvault/internal/i18n"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// localizeCommands fills in the localization fields of commands from the
+// i18n catalogs, so Discord clients in those languages show translated
+// descriptions and choices. Slash command and option names stay English,
+// matching the help text and docs; message commands have display names and
+// are translated too.
+func localizeCommands(commands []*discordgo.ApplicationCommand) {
+	var locales []discordgo.Locale
+	for _, l := range i18n.Locales() {
+		if _, ok := discordgo.Locales[discordgo.Locale(l)]; !ok {
+			log.Printf("[BOT WARN] Catalog %q is not a Discord locale; commands are not localized for it", l)
+			continue
+		}
+		locales = append(locales, discordgo.Locale(l))
+	}
+
+	for _, cmd := range commands {
+		if cmd.Type == discordgo.MessageApplicationCommand {
+			cmd.NameLocalizations = localizedPtr(locales, cmd.Name)
+		} else {
+			cmd.DescriptionLocalizations = localizedPtr(locales, cmd.Description)
+		}
+		localizeOptions(locales, cmd.Options)
+	}
+}
+
+func localizeOptions(locales []discordgo.Locale, options []*discordgo.ApplicationCommandOption) {
+	for _, opt := range options {
+		opt.DescriptionLocalizations = localized(locales, opt.Description)
+		for _, choice := range opt.Choices {
+			choice.NameLocalizations = localized(locales, choice.Name)
+		}
+		localizeOptions(locales, opt.Options)
+	}
+}
+
+// localized returns the translations of text, or nil when there are none.
+func localized(locales []discordgo.Locale, text string) map[discordgo.Locale]string {
+	var out map[discordgo.Locale]string
+	for _, l := range locales {
+		if t, ok := i18n.Translate(string(l), text); ok {
+			if out == nil {
+				out = make(map[discordgo.Locale]string)
+			}
+			out[l] = t
+		}
+	}
+	return out
+}
+
+func localizedPtr(locales []discordgo.Locale, text string) *map[discordgo.Locale]string {
+	if m := localized(locales, text); m != nil {
+		return &m
+	}
+	return nil
+}
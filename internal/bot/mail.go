@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"discordvault/internal/database"
+	"discordvault/internal/notify"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ErrEmailDisabled is returned when an email is requested but SMTP_HOST is
+// not configured.
+var ErrEmailDisabled = errors.New("email delivery is not configured")
+
+// emailNotice mails a rendered notification to NOTIFY_EMAIL_TO when its kind
+// is listed in NOTIFY_EMAIL_EVENTS. Without a title the first line of the
+// body becomes the subject.
+func (b *Bot) emailNotice(kind, title, body string) {
+	if b.Mailer == nil || !b.Config.EmailsEvent(kind) {
+		return
+	}
+	body = notify.PlainText(body)
+	subject := title
+	if subject == "" {
+		subject, body, _ = strings.Cut(body, "\n")
+	}
+	go func() {
+		if err := b.Mailer.Send(b.Config.EmailTo, "[Discord Vault] "+subject, body); err != nil {
+			log.Printf("[BOT ERR] Emailing %s notification failed: %v", kind, err)
+		}
+	}()
+}
+
+// EmailShare sends a share link to someone outside Discord.
+func (b *Bot) EmailShare(to string, file *database.FileMetadata, url string, expires *time.Time) error {
+	if b.Mailer == nil {
+		return ErrEmailDisabled
+	}
+	body := fmt.Sprintf("Someone sent you a file from the vault.\n\nFile: %s (%s)\nDownload: %s\n", file.Name, formatBytes(file.Size), url)
+	if expires != nil {
+		body += fmt.Sprintf("Link expires: %s\n", formatTime(*expires, b.channelLocation()))
+	}
+	return b.Mailer.Send([]string{to}, "Someone sent you a file: "+file.Name, body)
+}
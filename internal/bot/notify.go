@@ -0,0 +1,188 @@
+package bot
+
+import (
+	"discordvault/internal/database"
+	"discordvault/internal/notify"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func (b *Bot) NotifyUpload(file *database.FileMetadata, parts int, method string) {
+	b.notify(notify.KindUpload, b.Config.StorageChannel(file.GuildID), notify.Event{
+		Method:    method,
+		File:      file.Name,
+		Size:      formatBytes(file.Size),
+		SizeBytes: file.Size,
+		Parts:     parts,
+	})
+}
+
+func (b *Bot) NotifyDelete(file *database.FileMetadata, method, userID string) {
+	b.notify(notify.KindDelete, b.Config.StorageChannel(file.GuildID), notify.Event{
+		Method:    method,
+		File:      file.Name,
+		FileID:    file.ID,
+		Size:      formatBytes(file.Size),
+		SizeBytes: file.Size,
+		User:      userID,
+	})
+}
+
+func (b *Bot) NotifyError(method, filename string, err error) {
+	b.notify(notify.KindError, b.Config.ChannelID, notify.Event{
+		Method: method,
+		File:   filename,
+		Error:  err.Error(),
+	})
+}
+
+// maxDigestFailures caps the verification failures listed in a digest.
+const maxDigestFailures = 10
+
+// NotifyDigest posts a summary of vault activity to DIGEST_CHANNEL_ID.
+func (b *Bot) NotifyDigest(period string, d *database.Digest) {
+	var failures []string
+	for _, f := range d.VerifyFailures[:min(len(d.VerifyFailures), maxDigestFailures)] {
+		line := fmt.Sprintf("• %s", f.Detail)
+		if f.FileID != 0 {
+			line = fmt.Sprintf("• `%s` (#%d): %s", f.FileName, f.FileID, f.Detail)
+		}
+		failures = append(failures, line)
+	}
+	if len(d.VerifyFailures) > maxDigestFailures {
+		failures = append(failures, fmt.Sprintf("… and %d more", len(d.VerifyFailures)-maxDigestFailures))
+	}
+
+	b.notify(notify.KindDigest, b.Config.DigestChannelID, notify.Event{
+		Period:         period,
+		Since:          formatTime(d.Since, b.channelLocation()),
+		Uploads:        d.Uploads,
+		UploadedSize:   formatBytes(d.UploadedBytes),
+		Deletes:        d.Deletes,
+		DeletedSize:    formatBytes(d.DeletedBytes),
+		Files:          d.Files,
+		TotalSize:      formatBytes(d.TotalBytes),
+		VerifyFailures: len(d.VerifyFailures),
+		Failures:       strings.Join(failures, "\n"),
+	})
+}
+
+// notify posts an event to channelID: the storage channel of the file's vault
+// for uploads and deletes, the default one for everything else.
+func (b *Bot) notify(kind notify.Kind, channelID string, ev notify.Event) {
+	ev.Time = formatTime(time.Now(), b.channelLocation())
+
+	msg, err := b.Templates.Render(kind, ev)
+	if err != nil {
+		log.Printf("[BOT ERR] Rendering %s notification failed: %v", kind, err)
+		return
+	}
+
+	b.relay(string(kind), msg.Title, msg.Body)
+	if !b.postsNotice(kind) {
+		return
+	}
+
+	if msg.Title == "" {
+		b.send(channelID, msg.Body)
+		return
+	}
+	b.Session.ChannelMessageSendEmbed(channelID, &discordgo.MessageEmbed{
+		Title:       msg.Title,
+		Description: msg.Body,
+		Color:       msg.Color,
+	})
+}
+
+// NotifyShareDownload tells a file's owner that a share link was used. Owners
+// identified by a Discord user ID receive a DM when SHARE_NOTICE_TARGET=dm;
+// everything else goes to the storage channel of the file's vault.
+func (b *Bot) NotifyShareDownload(file *database.FileMetadata, at time.Time, ip, userAgent string) {
+	content := fmt.Sprintf("🔗 **Shared File Downloaded**\n**File:** `%s` (#%d)\n**Time:** `%s`\n**Network:** `%s`\n**Client:** `%s`",
+		noticeField(file.Name), file.ID, formatTime(at, b.location(file.OwnerID, "")), noticeField(ip), noticeField(userAgent))
+
+	if b.Config.ShareNoticeTarget == "dm" && isSnowflake(file.OwnerID) {
+		channel, err := b.Session.UserChannelCreate(file.OwnerID)
+		if err == nil {
+			if err = b.send(channel.ID, content); err == nil {
+				return
+			}
+		}
+		log.Printf("[BOT ERR] Share notice DM to %s failed, falling back to channel: %v", file.OwnerID, err)
+	}
+	b.send(b.Config.StorageChannel(file.GuildID), content)
+}
+
+// maxMessageLength is the most characters Discord accepts in a message.
+const maxMessageLength = 2000
+
+// maxNoticeField caps how much of a value a client sent, such as its user
+// agent, a notice repeats.
+const maxNoticeField = 200
+
+// send posts a plain text notice. File names and client details in notices
+// come from users, so the text is cut to Discord's limit and pings no one.
+func (b *Bot) send(channelID, content string) error {
+	_, err := b.Session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         truncate(content, maxMessageLength),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
+
+// noticeField prepares a client-supplied value for a code span in a notice.
+func noticeField(s string) string {
+	return truncate(strings.ReplaceAll(s, "`", "'"), maxNoticeField)
+}
+
+// truncate shortens s to at most n characters, cutting between runes.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+func isSnowflake(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// notifyHealth announces pauses and recoveries caused by Discord incidents.
+// The pause notice may itself fail to send while Discord is down, which is
+// when the email and bridge copies matter most.
+func (b *Bot) notifyHealth(degraded bool, reason string) {
+	content := "▶️ **Vault Resumed**\nDiscord has recovered; background jobs and queued writes continue."
+	if degraded {
+		content = fmt.Sprintf("⏸️ **Vault Paused**\n%s\nBackground jobs are on hold and new writes are queued.", reason)
+	}
+	b.relay("health", "", content)
+	b.send(b.Config.ChannelID, content)
+}
+
+// relay copies a notification to email and the Slack/Matrix bridges, each
+// filtered by its own event list.
+func (b *Bot) relay(kind, title, body string) {
+	b.emailNotice(kind, title, body)
+	if !b.Config.BridgesEvent(kind) {
+		return
+	}
+	for _, bridge := range b.Bridges {
+		go func(bridge notify.Bridge) {
+			if err := bridge.Post(title, body); err != nil {
+				log.Printf("[BOT ERR] Forwarding %s notification to %s failed: %v", kind, bridge.Name(), err)
+			}
+		}(bridge)
+	}
+}
@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// UntilOffPeak returns how long until the OFFPEAK_WINDOW opens, or 0 while
+// it is open or when there is none.
+func (b *Bot) UntilOffPeak() time.Duration {
+	if b.Config.OffPeak == nil {
+		return 0
+	}
+	return b.Config.OffPeak.Until(time.Now().In(b.Config.Location))
+}
+
+// WaitOffPeak blocks until the OFFPEAK_WINDOW is open or ctx ends. Background
+// uploads call it before every chunk, so they pause when the window closes
+// and pick up where they left off when it opens again.
+func (b *Bot) WaitOffPeak(ctx context.Context, what string) error {
+	for {
+		wait := b.UntilOffPeak()
+		if wait == 0 {
+			return nil
+		}
+		log.Printf("[BOT] Off-peak window %s closed, pausing %s for %s", b.Config.OffPeak, what, wait.Round(time.Minute))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+			log.Printf("[BOT] Off-peak window open, resuming %s", what)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// paceReserve is how many requests of a channel's message bucket uploads
+	// leave unused, so notices and replies in the same channel get through.
+	paceReserve = 1
+
+	// minPenalty and maxPenalty bound the extra delay after 429 responses.
+	minPenalty = 250 * time.Millisecond
+	maxPenalty = 5 * time.Second
+)
+
+// pacer spaces out chunk posts from the rate-limit headers Discord returns
+// on each channel's message route. An idle bot posts back to back; a bot
+// close to its limit waits for the bucket to reset, and one that was
+// answered with 429 slows down until posts succeed again.
+type pacer struct {
+	mu       sync.Mutex
+	channels map[string]*channelPace
+}
+
+type channelPace struct {
+	remaining int       // requests left in the current bucket window, -1 = unknown
+	reset     time.Time // when the bucket refills
+	penalty   time.Duration
+}
+
+// pace blocks until the storage token that may post to channelID soonest
+// is free, or ctx ends, and returns that token to post the chunk with.
+func (b *Bot) pace(ctx context.Context, channelID string) (*storageToken, error) {
+	t := b.storage.poster(channelID)
+	wait := t.pacer.wait(channelID)
+	if wait <= 0 {
+		return t, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return t, nil
+	}
+}
+
+func (p *pacer) wait(channelID string) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c := p.channels[channelID]
+	if c == nil {
+		return 0
+	}
+	wait := c.penalty
+	if c.remaining >= 0 && c.remaining <= paceReserve {
+		wait = max(wait, time.Until(c.reset))
+	}
+	return wait
+}
+
+// transport records the rate-limit state of message posts passing through.
+func (p *pacer) transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := base.RoundTrip(req)
+		if err == nil && req.Method == http.MethodPost {
+			if channelID, ok := messagesChannel(req.URL.Path); ok {
+				p.observe(channelID, resp)
+			}
+		}
+		return resp, err
+	})
+}
+
+func (p *pacer) observe(channelID string, resp *http.Response) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.channels == nil {
+		p.channels = make(map[string]*channelPace)
+	}
+	c := p.channels[channelID]
+	if c == nil {
+		c = &channelPace{remaining: -1}
+		p.channels[channelID] = c
+	}
+
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		c.remaining = remaining
+	}
+	if after, err := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Reset-After"), 64); err == nil {
+		c.reset = time.Now().Add(time.Duration(after * float64(time.Second)))
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		c.penalty = min(max(2*c.penalty, minPenalty), maxPenalty)
+	case resp.StatusCode < 300:
+		if c.penalty /= 2; c.penalty < minPenalty/8 {
+			c.penalty = 0
+		}
+	}
+}
+
+// messagesChannel extracts the channel ID from an API path of the form
+// /api/v*/channels/<id>/messages.
+func messagesChannel(path string) (string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 5 || parts[0] != "api" || parts[2] != "channels" || parts[4] != "messages" {
+		return "", false
+	}
+	return parts[3], true
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
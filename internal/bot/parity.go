@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"discordvault/internal/database"
+	"discordvault/internal/erasure"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// chunkReader reads the chunks of one file in order. A chunk that is gone or
+// damaged, even in its replicas, is rebuilt from the parity of its group if
+// the file has parity; the other chunks rebuilt with it are kept until read.
+// Chunks may be read from several goroutines at once.
+type chunkReader struct {
+	bot    *Bot
+	file   *database.FileMetadata
+	chunks []database.ChunkMetadata
+	code   *erasure.Code
+
+	mu      sync.Mutex
+	rebuilt map[int][]byte // chunk index -> ciphertext
+
+	// stored bypasses the chunk cache, to check what Discord holds.
+	stored bool
+}
+
+func (b *Bot) newChunkReader(file *database.FileMetadata, chunks []database.ChunkMetadata) *chunkReader {
+	r := &chunkReader{bot: b, file: file, chunks: chunks, rebuilt: make(map[int][]byte)}
+	if file.ParityData > 0 {
+		code, err := erasure.New(file.ParityData, file.ParityChunks)
+		if err != nil {
+			log.Printf("[BOT ERR] #%d has unusable erasure coding %d+%d: %v", file.ID, file.ParityData, file.ParityChunks, err)
+		}
+		r.code = code
+	}
+	return r
+}
+
+// read returns the ciphertext of the i-th chunk and whether it was rebuilt
+// from parity. Like fetchChunk it may return damaged data without an error
+// when nothing better is left.
+func (r *chunkReader) read(i int) ([]byte, bool, error) {
+	r.mu.Lock()
+	data, ok := r.rebuilt[i]
+	delete(r.rebuilt, i)
+	r.mu.Unlock()
+	if ok {
+		return data, true, nil
+	}
+	c := r.chunks[i]
+	data, err := r.fetch(c)
+	if (err == nil && chunkIntact(c, data)) || r.code == nil {
+		return data, false, err
+	}
+	rebuilt, rebuildErr := r.rebuild(i)
+	if rebuildErr != nil {
+		log.Printf("[BOT ERR] Chunk %d of #%d could not be rebuilt from parity: %v", c.PartNum, r.file.ID, rebuildErr)
+		return data, false, err
+	}
+	log.Printf("[BOT WARN] Chunk %d of #%d is damaged or gone, rebuilt it from parity", c.PartNum, r.file.ID)
+	return rebuilt, true, nil
+}
+
+func (r *chunkReader) fetch(c database.ChunkMetadata) ([]byte, error) {
+	if r.stored {
+		return r.bot.fetchStored(c)
+	}
+	return r.bot.fetchChunk(c)
+}
+
+// rebuild reconstructs the group of the i-th chunk from what is left of it
+// and returns that chunk.
+func (r *chunkReader) rebuild(i int) ([]byte, error) {
+	k := r.code.Data
+	group, first := i/k, i/k*k
+
+	shards := make([][]byte, k+r.code.Parity)
+	sizes := make([]int, k)
+	for j := range k {
+		idx := first + j
+		if idx >= len(r.chunks) {
+			shards[j] = []byte{}
+			continue
+		}
+		c := r.chunks[idx]
+		sizes[j] = int(c.Size)
+		if idx == i {
+			continue
+		}
+		if data, err := r.fetch(c); err == nil && chunkIntact(c, data) {
+			shards[j] = data
+		}
+	}
+
+	parity, err := r.bot.DB.ParityChunks(r.file.ID, group)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range parity {
+		if p.Shard >= r.code.Parity {
+			continue
+		}
+		if data, err := r.fetch(p.ChunkMetadata); err == nil && chunkIntact(p.ChunkMetadata, data) {
+			shards[k+p.Shard] = data
+		}
+	}
+
+	var missing []int
+	for j := range k {
+		if shards[j] == nil {
+			missing = append(missing, first+j)
+		}
+	}
+	if err := r.code.Reconstruct(shards, sizes); err != nil {
+		return nil, err
+	}
+	for _, idx := range missing {
+		if !chunkIntact(r.chunks[idx], shards[idx-first]) {
+			return nil, fmt.Errorf("chunk %d does not match its checksum after rebuilding", r.chunks[idx].PartNum)
+		}
+		if idx > i {
+			r.mu.Lock()
+			r.rebuilt[idx] = shards[idx-first]
+			r.mu.Unlock()
+		}
+	}
+	return shards[i-first], nil
+}
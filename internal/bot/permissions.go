@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"discordvault/internal/config"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// checkPermission reports whether the interaction's user may run command at
+// the level COMMAND_PERMISSIONS assigns it (members by default).
+func (b *Bot) checkPermission(i *discordgo.InteractionCreate, command string) bool {
+	switch b.Config.CommandLevel(command) {
+	case config.PermAnyone:
+		return true
+	case config.PermAdmin:
+		return b.isAdmin(i)
+	default:
+		return b.isMember(i) || b.isAdmin(i)
+	}
+}
+
+// dmPermitted is checkPermission for direct messages. DMs carry no member
+// roles, so only ALLOWED_USERS and ADMIN_USERS can grant access there.
+func (b *Bot) dmPermitted(userID, command string) bool {
+	admin := b.Config.IsAdmin(userID)
+	switch b.Config.CommandLevel(command) {
+	case config.PermAnyone:
+		return true
+	case config.PermAdmin:
+		return admin
+	default:
+		live := b.Config.Live()
+		open := len(live.AllowedUsers) == 0 && len(live.AllowedRoles) == 0
+		return open || admin || slices.Contains(live.AllowedUsers, userID)
+	}
+}
+
+// isMember reports whether the user is allowlisted by ID or by one of their
+// server roles. Without ALLOWED_USERS and ALLOWED_ROLES everyone is.
+func (b *Bot) isMember(i *discordgo.InteractionCreate) bool {
+	live := b.Config.Live()
+	if len(live.AllowedUsers) == 0 && len(live.AllowedRoles) == 0 {
+		return true
+	}
+	return slices.Contains(live.AllowedUsers, interactionUser(i).ID) || hasRole(i, live.AllowedRoles)
+}
+
+// isAdmin reports whether the user is listed in ADMIN_USERS or holds one of
+// the ADMIN_ROLES.
+func (b *Bot) isAdmin(i *discordgo.InteractionCreate) bool {
+	return b.Config.IsAdmin(interactionUser(i).ID) || hasRole(i, b.Config.Live().AdminRoles)
+}
+
+// hasRole reports whether the member holds any of roles. Roles only exist in
+// servers, so DMs never match.
+func hasRole(i *discordgo.InteractionCreate, roles []string) bool {
+	if i.Member == nil {
+		return false
+	}
+	for _, role := range i.Member.Roles {
+		if slices.Contains(roles, role) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package bot
+
+import (
+	"discordvault/internal/config"
+	"discordvault/internal/notify"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// replyFlags returns the flags for the reply to a command that is public by
+// default: ephemeral when BOT_RESPONSES or COMMAND_RESPONSES make it private.
+// Replies that always carry private data, such as share links, are ephemeral
+// regardless.
+func (b *Bot) replyFlags(i *discordgo.InteractionCreate) discordgo.MessageFlags {
+	if b.Config.PrivateReplies(i.ApplicationCommandData().Name) {
+		return discordgo.MessageFlagsEphemeral
+	}
+	return 0
+}
+
+// postsNotice reports whether a notification of kind is posted to Discord.
+// Private mode keeps file names out of the storage channels; email and the
+// bridges still receive the notice.
+func (b *Bot) postsNotice(kind notify.Kind) bool {
+	if b.Config.Responses != config.ResponsesPrivate {
+		return true
+	}
+	return kind != notify.KindUpload && kind != notify.KindDelete
+}
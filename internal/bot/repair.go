@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"context"
+	"discordvault/internal/database"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ErrUnrecoverable means a lost chunk has no intact replica and its group
+// too little parity left to rebuild it.
+var ErrUnrecoverable = errors.New("too little of the file is left to repair it")
+
+// markDegraded queues the files storing a lost or damaged chunk for repair.
+func (b *Bot) markDegraded(messageID, detail string) {
+	if err := b.DB.MarkDegraded(messageID, detail); err != nil {
+		log.Printf("[BOT ERR] Could not queue chunk %s for repair: %v", messageID, err)
+	}
+}
+
+// RepairFile re-uploads every chunk of file whose message is gone or
+// damaged, read from a replica or rebuilt from parity, and then every lost
+// parity chunk. It returns how many messages it replaced. Errors wrapping
+// ErrUnrecoverable will not go away by trying again.
+func (b *Bot) RepairFile(ctx context.Context, file *database.FileMetadata) (int, error) {
+	chunks, err := b.DB.GetChunks(file.ID)
+	if err != nil {
+		return 0, err
+	}
+	if err := b.Memory.Acquire(ctx); err != nil {
+		return 0, err
+	}
+	defer b.Memory.Release()
+	reader := b.newChunkReader(file, chunks)
+
+	repaired := 0
+	for idx, c := range chunks {
+		if ctx.Err() != nil {
+			return repaired, ctx.Err()
+		}
+		channelID := b.ChunkChannel(c)
+		data, err := b.fetchMessage(channelID, c.MessageID)
+		if err == nil && chunkIntact(c, data) {
+			continue
+		}
+		if err != nil && !errors.Is(err, errChunkMissing) {
+			return repaired, err
+		}
+
+		data, _, err = reader.read(idx)
+		if errors.Is(err, errChunkMissing) || (err == nil && !chunkIntact(c, data)) {
+			return repaired, fmt.Errorf("chunk %d: %w", c.PartNum, ErrUnrecoverable)
+		}
+		if err != nil {
+			return repaired, err
+		}
+		fresh, err := b.replaceMessage(ctx, channelID, c.MessageID, data)
+		if err != nil {
+			return repaired, fmt.Errorf("chunk %d: %w", c.PartNum, err)
+		}
+		fresh.PartNum = c.PartNum
+		if err := b.DB.ReplaceChunkMessage(c.MessageID, fresh); err != nil {
+			b.Session.ChannelMessageDelete(channelID, fresh.MessageID)
+			return repaired, err
+		}
+		b.Session.ChannelMessageDelete(channelID, c.MessageID)
+		chunks[idx] = fresh
+		repaired++
+	}
+
+	if reader.code == nil {
+		return repaired, nil
+	}
+	k := reader.code.Data
+	for group := 0; group*k < len(chunks); group++ {
+		n, err := b.repairParity(ctx, file, chunks[group*k:min((group+1)*k, len(chunks))], group, reader)
+		repaired += n
+		if err != nil {
+			return repaired, fmt.Errorf("parity of group %d: %w", group, err)
+		}
+	}
+	return repaired, nil
+}
+
+// repairParity encodes a group of intact chunks again and re-uploads the
+// parity chunks of it that are gone or damaged.
+func (b *Bot) repairParity(ctx context.Context, file *database.FileMetadata, chunks []database.ChunkMetadata, group int, reader *chunkReader) (int, error) {
+	parity, err := b.DB.ParityChunks(file.ID, group)
+	if err != nil {
+		return 0, err
+	}
+	var lost []database.ParityChunk
+	for _, p := range parity {
+		data, err := b.fetchMessage(p.ChannelID, p.MessageID)
+		if err != nil && !errors.Is(err, errChunkMissing) {
+			return 0, err
+		}
+		if err != nil || !chunkIntact(p.ChunkMetadata, data) {
+			lost = append(lost, p)
+		}
+	}
+	if len(lost) == 0 {
+		return 0, nil
+	}
+
+	encoder := reader.code.NewEncoder()
+	for _, c := range chunks {
+		data, err := b.fetchChunk(c)
+		if err != nil {
+			return 0, err
+		}
+		encoder.Add(data)
+	}
+	shards := encoder.Parity()
+
+	repaired := 0
+	for _, p := range lost {
+		fresh, err := b.replaceMessage(ctx, p.ChannelID, p.MessageID, shards[p.Shard])
+		if err != nil {
+			return repaired, err
+		}
+		old := p.MessageID
+		p.ChunkMetadata = fresh
+		if err := b.DB.ReplaceParityChunk(file.ID, p); err != nil {
+			b.Session.ChannelMessageDelete(p.ChannelID, fresh.MessageID)
+			return repaired, err
+		}
+		b.Session.ChannelMessageDelete(p.ChannelID, old)
+		repaired++
+	}
+	return repaired, nil
+}
+
+// replaceMessage posts a repaired chunk next to the message it replaces.
+func (b *Bot) replaceMessage(ctx context.Context, channelID, old string, data []byte) (database.ChunkMetadata, error) {
+	t, err := b.pace(ctx, channelID)
+	if err != nil {
+		return database.ChunkMetadata{}, err
+	}
+	fresh, err := b.postChunk(t, channelID, data)
+	if err != nil {
+		return fresh, err
+	}
+	log.Printf("[BOT] Re-uploaded chunk %s as %s", old, fresh.MessageID)
+	return fresh, nil
+}
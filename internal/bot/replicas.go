@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"discordvault/internal/database"
+	"discordvault/internal/retry"
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+// storeReplicas copies a stored chunk to every REPLICA_CHANNELS channel
+// other than its own and records the copies. If one copy cannot be stored,
+// the copies already posted are deleted again.
+func (b *Bot) storeReplicas(chunk database.ChunkMetadata, encrypted []byte) error {
+	var replicas []database.Replica
+	undo := func() {
+		for _, r := range replicas {
+			b.Session.ChannelMessageDelete(r.ChannelID, r.MessageID)
+		}
+	}
+	for _, channelID := range b.Config.ReplicaChannels {
+		if channelID == chunk.ChannelID {
+			continue
+		}
+		t, _ := b.pace(context.Background(), channelID)
+		replica, err := b.postChunk(t, channelID, encrypted)
+		if err != nil {
+			undo()
+			return fmt.Errorf("replica in %s: %w", channelID, err)
+		}
+		replicas = append(replicas, database.Replica{MessageID: replica.MessageID, ChannelID: channelID})
+	}
+	if len(replicas) == 0 {
+		return nil
+	}
+	if err := b.DB.AddReplicas(chunk.MessageID, replicas); err != nil {
+		undo()
+		return fmt.Errorf("recording replicas: %w", err)
+	}
+	return nil
+}
+
+// DeleteChunk deletes a chunk's message and its replicas. A message that is
+// already gone counts as deleted.
+func (b *Bot) DeleteChunk(c database.ChunkMetadata) error {
+	if err := b.Session.ChannelMessageDelete(b.ChunkChannel(c), c.MessageID); err != nil && !retry.IsNotFound(err) {
+		return err
+	}
+	b.DeleteReplicas([]string{c.MessageID})
+	return nil
+}
+
+// DeleteReplicas deletes and forgets the replicas of chunk messages that were
+// removed. Replicas that cannot be deleted are left to orphan GC.
+func (b *Bot) DeleteReplicas(messageIDs []string) {
+	replicas, err := b.DB.TakeReplicas(messageIDs)
+	if err != nil {
+		log.Printf("[BOT ERR] Replica lookup failed: %v", err)
+		return
+	}
+	for _, r := range replicas {
+		if err := b.Session.ChannelMessageDelete(r.ChannelID, r.MessageID); err != nil && !retry.IsNotFound(err) {
+			log.Printf("[BOT WARN] Could not remove replica %s, leaving it to orphan GC: %v", r.MessageID, err)
+		}
+	}
+}
+
+// chunkIntact reports whether data matches the chunk's recorded checksum.
+// Chunks stored before checksums were recorded always match.
+func chunkIntact(c database.ChunkMetadata, data []byte) bool {
+	if c.SHA256 == "" {
+		return true
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) == c.SHA256
+}
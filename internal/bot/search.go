@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	searchPrefix   = "search:" // component custom ID: search:<page>:<query>
+	maxSearchQuery = 80        // keeps the custom ID under Discord's 100 characters
+)
+
+func (b *Bot) handleSearch(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	query := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+	if query == "" || len(query) > maxSearchQuery {
+		b.respondEphemeral(i, b.t(i, "❌ Search terms must be 1-%d characters.", maxSearchQuery))
+		return
+	}
+
+	embed, components, err := b.searchPage(i, query, 0)
+	if err != nil {
+		b.respondEphemeral(i, b.t(i, "❌ Database error."))
+		return
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// handleSearchPage flips the result browser to the page named in the
+// button's custom ID.
+func (b *Bot) handleSearchPage(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	pageStr, query, ok := strings.Cut(strings.TrimPrefix(i.MessageComponentData().CustomID, searchPrefix), ":")
+	page, err := strconv.Atoi(pageStr)
+	if !ok || err != nil || page < 0 {
+		return
+	}
+
+	embed, components, err := b.searchPage(i, query, page)
+	if err != nil {
+		return
+	}
+	b.updateBrowser(i, embed, components)
+}
+
+func (b *Bot) searchPage(i *discordgo.InteractionCreate, query string, page int) (*discordgo.MessageEmbed, []discordgo.MessageComponent, error) {
+	files, total, err := b.DB.SearchFiles(b.vaultOf(i), query, b.browserOwner(i), defaultPageSize, page*defaultPageSize)
+	if err != nil {
+		log.Printf("[BOT ERR] Search failed: %v", err)
+		return nil, nil, err
+	}
+	embed, components := b.fileBrowser(i, b.t(i, "🔎 Search: %s", query), files, total, page, defaultPageSize, func(page int) string {
+		return fmt.Sprintf("%s%d:%s", searchPrefix, page, query)
+	})
+	if total == 0 {
+		embed.Description = b.t(i, "*No matching files*")
+	}
+	return embed, components, nil
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"bytes"
+	"discordvault/internal/qr"
+	"discordvault/internal/sharelink"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// qrScale is the pixel size of one QR module in attached images.
+const qrScale = 8
+
+func (b *Bot) handleShare(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var hours int
+	var withQR bool
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "expires_hours":
+			hours = int(opt.IntValue())
+		case "qr":
+			withQR = opt.BoolValue()
+		}
+	}
+	userID := interactionUser(i).ID
+
+	file, input := b.fileOption(i)
+	if file == nil {
+		b.respondEphemeral(i, b.t(i, "❌ No file **%s** in your vault.", input))
+		return
+	}
+
+	share, _, err := sharelink.Create(b.DB, b.Signer, file, userID, time.Duration(hours)*time.Hour, false)
+	if err != nil {
+		log.Printf("[BOT ERR] Share creation failed: %v", err)
+		b.respondEphemeral(i, b.t(i, "❌ Database error."))
+		return
+	}
+	url := sharelink.URL(b.Config.PublicURL, share.Token)
+	log.Printf("[BOT] Share link issued for File ID %d", file.ID)
+
+	content := fmt.Sprintf("🔗 **%s**\n%s", file.Name, url)
+	if share.ExpiresAt != nil {
+		content += "\n" + b.t(i, "Expires %s", formatTime(*share.ExpiresAt, b.location(userID, i.GuildID)))
+	}
+	data := &discordgo.InteractionResponseData{Content: content, Flags: discordgo.MessageFlagsEphemeral}
+
+	if withQR {
+		png, err := qrPNG(url)
+		if err != nil {
+			log.Printf("[BOT ERR] QR rendering failed: %v", err)
+			data.Content += "\n" + b.t(i, "*(QR code unavailable)*")
+		} else {
+			data.Files = []*discordgo.File{{Name: "share-qr.png", ContentType: "image/png", Reader: bytes.NewReader(png)}}
+		}
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+}
+
+func qrPNG(text string) ([]byte, error) {
+	code, err := qr.Encode(text)
+	if err != nil {
+		return nil, err
+	}
+	return code.PNG(qrScale)
+}
@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func (b *Bot) handleStats(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	stats, err := b.DB.Stats(b.vaultOf(i), 7, 3)
+	if err != nil {
+		log.Printf("[BOT ERR] Stats failed: %v", err)
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: b.t(i, "❌ Database error."), Flags: discordgo.MessageFlagsEphemeral},
+		})
+		return
+	}
+
+	uploads := 0
+	var uploaded int64
+	for _, d := range stats.UploadsPerDay {
+		uploads += d.Uploads
+		uploaded += d.Bytes
+	}
+
+	// Every chunk carries its own nonce and GCM tag.
+	overhead := int64(stats.Chunks) * (crypto.NonceSize + crypto.TagSize)
+	ciphertext := formatBytes(stats.TotalBytes + overhead)
+	if stats.TotalBytes > 0 {
+		ciphertext += fmt.Sprintf(" (+%.3f%%)", float64(overhead)*100/float64(stats.TotalBytes))
+	}
+	lastUpload := "—"
+	if stats.LastUpload != nil {
+		lastUpload = formatTime(*stats.LastUpload, b.location(interactionUser(i).ID, i.GuildID))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: b.t(i, "📊 Vault Statistics"),
+		Color: 0x3b82f6,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: b.t(i, "Files"), Value: b.t(i, "%d (%d versions)", stats.Files, stats.Versions), Inline: true},
+			{Name: b.t(i, "Stored"), Value: formatBytes(stats.TotalBytes), Inline: true},
+			{Name: b.t(i, "Chunks"), Value: fmt.Sprint(stats.Chunks), Inline: true},
+			{Name: b.t(i, "Ciphertext"), Value: ciphertext, Inline: true},
+			{Name: b.t(i, "Last Upload"), Value: lastUpload, Inline: true},
+			{Name: b.t(i, "Last 7 Days"), Value: b.t(i, "%d uploads, %s", uploads, formatBytes(uploaded)), Inline: true},
+			{Name: b.t(i, "Your Transfer This Month"), Value: b.transferSummary(i), Inline: true},
+			{Name: b.t(i, "Bot Health"), Value: b.healthSummary(i)},
+		},
+	}
+
+	// Other users' file names are only shown to admins
+	largestTitle := b.t(i, "Largest Files")
+	if !b.isAdmin(i) {
+		largestTitle = b.t(i, "Your Largest Files")
+		if stats.LargestFiles, err = b.DB.LargestFiles(b.vaultOf(i), interactionUser(i).ID, 3); err != nil {
+			log.Printf("[BOT ERR] Stats failed: %v", err)
+			stats.LargestFiles = nil
+		}
+	}
+	if len(stats.LargestFiles) > 0 {
+		var sb strings.Builder
+		for _, f := range stats.LargestFiles {
+			sb.WriteString(fmt.Sprintf("`#%d` **%s** (%s)\n", f.ID, f.Name, formatBytes(f.Size)))
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: largestTitle, Value: sb.String()})
+	}
+
+	if b.isAdmin(i) && len(stats.Owners) > 0 {
+		var sb strings.Builder
+		for idx, o := range stats.Owners {
+			if idx == 5 {
+				break
+			}
+			owner := o.OwnerID
+			if isSnowflake(owner) {
+				owner = "<@" + owner + ">"
+			}
+			sb.WriteString(b.t(i, "%s: %d files, %s", owner, o.Files, formatBytes(o.Bytes)) + "\n")
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: b.t(i, "Top Uploaders"), Value: sb.String()})
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed}, Flags: b.replyFlags(i)},
+	})
+}
+
+// transferSummary reports what the caller uploaded and downloaded this
+// month, against TRANSFER_CAP_MONTHLY if one is set.
+func (b *Bot) transferSummary(i *discordgo.InteractionCreate) string {
+	usage, err := b.DB.GetTransfer(database.UsagePeriod(time.Now()), database.UsageUser, interactionUser(i).ID)
+	if err != nil {
+		log.Printf("[BOT ERR] Transfer usage lookup failed: %v", err)
+		return "—"
+	}
+	summary := b.t(i, "↑ %s · ↓ %s", formatBytes(usage.Uploaded), formatBytes(usage.Downloaded))
+	if transferCap := b.Config.Live().TransferCap; transferCap > 0 {
+		summary += "\n" + b.t(i, "%s of %s cap", formatBytes(usage.Uploaded+usage.Downloaded), formatBytes(transferCap))
+	}
+	return summary
+}
+
+// healthSummary reports gateway latency, uptime, and the share of failed
+// Discord API calls since start.
+func (b *Bot) healthSummary(i *discordgo.InteractionCreate) string {
+	requests, failures := b.Health.Calls()
+	errorRate := b.t(i, "no calls yet")
+	if requests > 0 {
+		errorRate = b.t(i, "%.1f%% of %d calls failed", float64(failures)*100/float64(requests), requests)
+	}
+	status := b.t(i, "🟢 Operational")
+	if b.Health.Paused() {
+		status = b.t(i, "🟠 Paused: %s", b.Health.Reason())
+	}
+	return b.t(i, "%s\nLatency: %s · Uptime: %s\nAPI errors: %s",
+		status, b.Session.HeartbeatLatency().Round(time.Millisecond), time.Since(b.StartedAt).Round(time.Second), errorRate)
+}
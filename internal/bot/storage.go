@@ -0,0 +1,276 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"discordvault/internal/cdn"
+	"discordvault/internal/chunkname"
+	"discordvault/internal/config"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"discordvault/internal/retry"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// storeAttempts is how often StoreChunk posts a chunk that fails verification.
+const storeAttempts = 2
+
+// StoreChunk waits for a storage token to be free, posts an encrypted chunk
+// to a storage channel with it, and checks that it arrived intact as
+// configured by CHUNK_VERIFY. A chunk that fails the check is deleted and
+// posted again. With REPLICA_CHANNELS the chunk is also copied there, and it
+// is only stored once every copy is.
+func (b *Bot) StoreChunk(ctx context.Context, channelID string, encrypted []byte) (database.ChunkMetadata, error) {
+	t, err := b.pace(ctx, channelID)
+	if err != nil {
+		return database.ChunkMetadata{}, err
+	}
+	chunk, err := b.postChunk(t, channelID, encrypted)
+	if err != nil {
+		return chunk, err
+	}
+	if err := b.storeReplicas(chunk, encrypted); err != nil {
+		b.Session.ChannelMessageDelete(channelID, chunk.MessageID)
+		return database.ChunkMetadata{}, err
+	}
+	return chunk, nil
+}
+
+// postChunk posts a chunk with the token pace chose for it.
+func (b *Bot) postChunk(t *storageToken, channelID string, encrypted []byte) (database.ChunkMetadata, error) {
+	sum := sha256.Sum256(encrypted)
+	chunkSum := hex.EncodeToString(sum[:])
+
+	var err error
+	for attempt := 1; attempt <= storeAttempts; attempt++ {
+		var msg *discordgo.Message
+		sender := t.session
+		err = b.Retry.Do("chunk upload", func() error {
+			var sendErr error
+			started := time.Now()
+			msg, sendErr = sender.ChannelFileSend(channelID, chunkname.Format(b.Config.ChunkNaming, chunkSum), bytes.NewReader(encrypted))
+			b.observeChunk(len(encrypted), time.Since(started), sendErr)
+			return sendErr
+		})
+		if err != nil {
+			return database.ChunkMetadata{}, err
+		}
+		if err = b.verifyChunk(msg, int64(len(encrypted)), chunkSum); err == nil {
+			return database.ChunkMetadata{MessageID: msg.ID, Size: int64(len(encrypted)), SHA256: chunkSum, ChannelID: channelID}, nil
+		}
+		log.Printf("[BOT WARN] Chunk %s failed verification (attempt %d): %v", msg.ID, attempt, err)
+		sender.ChannelMessageDelete(channelID, msg.ID)
+	}
+	b.recordVerifyFailure(database.VaultEvent{Size: int64(len(encrypted)), Detail: "chunk rejected after upload: " + err.Error()})
+	return database.ChunkMetadata{}, fmt.Errorf("chunk verification failed: %w", err)
+}
+
+// verifyChunk compares the stored attachment's size with the ciphertext and,
+// for sampled chunks, downloads it again to compare the SHA-256.
+func (b *Bot) verifyChunk(msg *discordgo.Message, size int64, chunkSum string) error {
+	mode := b.Config.ChunkVerify
+	if mode == config.VerifyOff {
+		return nil
+	}
+	if len(msg.Attachments) != 1 {
+		return fmt.Errorf("message has %d attachments", len(msg.Attachments))
+	}
+	att := msg.Attachments[0]
+	if int64(att.Size) != size {
+		return fmt.Errorf("stored %d of %d bytes", att.Size, size)
+	}
+
+	if mode == config.VerifySize || (mode == config.VerifySample && rand.IntN(100) >= b.Config.ChunkVerifySample) {
+		return nil
+	}
+	resp, err := b.Retry.Get("chunk re-fetch", att.URL)
+	if err != nil {
+		return fmt.Errorf("re-fetch: %w", err)
+	}
+	defer resp.Body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return fmt.Errorf("re-fetch: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != chunkSum {
+		return fmt.Errorf("checksum mismatch: got %s", got)
+	}
+	return nil
+}
+
+// DiscardChunks deletes chunk messages of an upload that failed before its
+// metadata was committed, so no orphaned ciphertext is left in the channel.
+func (b *Bot) DiscardChunks(channelID string, messageIDs []string) {
+	if len(messageIDs) == 0 {
+		return
+	}
+	log.Printf("[BOT] Rolling back %d chunk(s) of an aborted upload", len(messageIDs))
+	for _, id := range messageIDs {
+		if err := b.Session.ChannelMessageDelete(channelID, id); err != nil {
+			log.Printf("[BOT WARN] Could not remove chunk %s, leaving it to orphan GC: %v", id, err)
+		}
+	}
+	b.DeleteReplicas(messageIDs)
+}
+
+// errChunkMissing means a chunk's message or attachment no longer exists.
+var errChunkMissing = errors.New("chunk message missing")
+
+// fetchChunk returns the ciphertext of one stored chunk from the chunk
+// cache, or downloads it with fetchStored and caches it.
+func (b *Bot) fetchChunk(c database.ChunkMetadata) ([]byte, error) {
+	if b.Cache == nil {
+		return b.fetchStored(c)
+	}
+	key := cacheKey(c)
+	if data, ok := b.Cache.Get(key); ok && chunkIntact(c, data) {
+		return data, nil
+	}
+	data, err := b.fetchStored(c)
+	if err == nil && chunkIntact(c, data) {
+		b.Cache.Put(key, data)
+	}
+	return data, err
+}
+
+// cacheKey names a chunk in the cache by its checksum, so chunks shared
+// between files are cached once, or by its message for older chunks.
+func cacheKey(c database.ChunkMetadata) string {
+	if c.SHA256 != "" {
+		return c.SHA256
+	}
+	return "m" + c.MessageID
+}
+
+// fetchStored downloads the ciphertext of one stored chunk, retrying lookups
+// and downloads that fail for a passing reason. A chunk that is gone or does
+// not match its checksum is queued for repair and read from an intact
+// replica instead, if any.
+func (b *Bot) fetchStored(c database.ChunkMetadata) ([]byte, error) {
+	data, err := b.fetchMessage(b.ChunkChannel(c), c.MessageID)
+	if err == nil && chunkIntact(c, data) {
+		return data, nil
+	}
+	switch {
+	case errors.Is(err, errChunkMissing):
+		b.markDegraded(c.MessageID, "chunk message gone")
+	case err == nil:
+		b.markDegraded(c.MessageID, "chunk fails its checksum")
+	}
+	replicas, lookupErr := b.DB.Replicas(c.MessageID)
+	if lookupErr != nil {
+		log.Printf("[BOT ERR] Replica lookup for chunk %s failed: %v", c.MessageID, lookupErr)
+	}
+	for _, r := range replicas {
+		copyData, copyErr := b.fetchMessage(r.ChannelID, r.MessageID)
+		if copyErr == nil && chunkIntact(c, copyData) {
+			log.Printf("[BOT WARN] Chunk %s is damaged or gone, read its replica in %s", c.MessageID, r.ChannelID)
+			return copyData, nil
+		}
+	}
+	return data, err
+}
+
+// fetchMessage downloads the attachment of a chunk message through a URL
+// looked up right before, refreshing it if the CDN rejects it. Lookups take
+// turns among the storage tokens.
+func (b *Bot) fetchMessage(channelID, messageID string) ([]byte, error) {
+	data, err := cdn.Fetch(b.storage.fetcher().session, b.Retry, channelID, messageID)
+	if retry.IsNotFound(err) || errors.Is(err, cdn.ErrNoAttachment) {
+		return nil, errChunkMissing
+	}
+	return data, err
+}
+
+// WriteFile decrypts the chunks of file into w in order, rebuilding lost
+// ones from parity where the file has it. Later chunks are fetched and
+// decrypted in parallel while earlier ones are written. Missing fragments
+// are logged and skipped; a decryption fault or the end of ctx stops the
+// stream.
+func (b *Bot) WriteFile(ctx context.Context, w io.Writer, file *database.FileMetadata) (int64, error) {
+	chunks, _ := b.DB.GetChunks(file.ID)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := b.streamChunks(ctx, b.newChunkReader(file, chunks))
+	defer stream.close()
+
+	var written int64
+	for _, chunk := range chunks {
+		c, err := stream.nextChunk(ctx)
+		if err != nil {
+			return written, err
+		}
+		if errors.Is(c.err, errChunkMissing) {
+			log.Printf("[BOT ERR] Fragment missing: %d", chunk.PartNum)
+			continue
+		}
+		if c.fault {
+			return written, fmt.Errorf("decryption fault at chunk %d: %w", chunk.PartNum, c.err)
+		}
+		if c.err != nil {
+			log.Printf("[BOT ERR] Fragment fetch failed: %v", c.err)
+			continue
+		}
+
+		n, err := w.Write(c.plain)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// WriteChunks decrypts chunks that belong to no file yet, such as those of
+// an upload session, into w in order. Unlike WriteFile it stops at the first
+// chunk it cannot read, since there is no parity to fall back on.
+func (b *Bot) WriteChunks(ctx context.Context, w io.Writer, chunks []database.ChunkMetadata) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := b.streamChunks(ctx, b.newChunkReader(&database.FileMetadata{}, chunks))
+	defer stream.close()
+
+	var written int64
+	for _, chunk := range chunks {
+		c, err := stream.nextChunk(ctx)
+		if err != nil {
+			return written, err
+		}
+		if c.err != nil {
+			return written, fmt.Errorf("chunk %d: %w", chunk.PartNum, c.err)
+		}
+		n, err := w.Write(c.plain)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ReadChunk decrypts the i-th of the chunks of file, rebuilding it from
+// parity like WriteFile when its message is gone or damaged.
+func (b *Bot) ReadChunk(ctx context.Context, file *database.FileMetadata, chunks []database.ChunkMetadata, i int) ([]byte, error) {
+	if err := b.Memory.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer b.Memory.Release()
+	encrypted, _, err := b.newChunkReader(file, chunks).read(i)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %d: %w", chunks[i].PartNum, err)
+	}
+	plain, err := crypto.Decrypt(encrypted, b.Config.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("decryption fault at chunk %d: %w", chunks[i].PartNum, err)
+	}
+	return plain, nil
+}
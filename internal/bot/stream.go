@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"context"
+	"discordvault/internal/crypto"
+	"runtime"
+	"sync"
+)
+
+// decryptedChunk is one chunk of a download, ready to be written.
+type decryptedChunk struct {
+	plain []byte
+	err   error // of the fetch, or of the decryption when fault is set
+	fault bool
+}
+
+// fetchedChunk is a chunk on its way from a fetch worker to a decrypt
+// worker.
+type fetchedChunk struct {
+	idx       int
+	encrypted []byte
+	err       error
+}
+
+// chunkStream fetches and decrypts the chunks of a file ahead of the writer:
+// DOWNLOAD_PARALLELISM fetch workers hand chunks to as many decrypt workers
+// as there are CPUs, at most that many, and next returns them in part order
+// however they finish. Chunks are started in order, at most twice
+// DOWNLOAD_PARALLELISM ahead of the writer, and each holds a buffer of the
+// memory budget from before its fetch until it is returned.
+type chunkStream struct {
+	bot     *Bot
+	results []chan decryptedChunk // by chunk index
+	window  chan struct{}
+	next    int
+
+	stopped    chan struct{} // closed once no more chunks are started
+	dispatched int           // chunks started, read after stopped
+}
+
+func (b *Bot) streamChunks(ctx context.Context, reader *chunkReader) *chunkStream {
+	fetchers := max(b.Config.DownloadParallelism, 1)
+	decrypters := min(fetchers, runtime.GOMAXPROCS(0))
+	s := &chunkStream{
+		bot:     b,
+		results: make([]chan decryptedChunk, len(reader.chunks)),
+		window:  make(chan struct{}, 2*fetchers),
+		stopped: make(chan struct{}),
+	}
+	for i := range s.results {
+		s.results[i] = make(chan decryptedChunk, 1)
+	}
+
+	fetchQueue := make(chan int)
+	go func() {
+		defer close(s.stopped)
+		defer close(fetchQueue)
+		for idx := range reader.chunks {
+			if !s.reserve(ctx) {
+				return
+			}
+			select {
+			case fetchQueue <- idx:
+				s.dispatched++
+			case <-ctx.Done():
+				s.release()
+				return
+			}
+		}
+	}()
+
+	decryptQueue := make(chan fetchedChunk)
+	var fetching sync.WaitGroup
+	for range fetchers {
+		fetching.Add(1)
+		go func() {
+			defer fetching.Done()
+			for idx := range fetchQueue {
+				encrypted, _, err := reader.read(idx)
+				decryptQueue <- fetchedChunk{idx, encrypted, err}
+			}
+		}()
+	}
+	go func() {
+		fetching.Wait()
+		close(decryptQueue)
+	}()
+
+	for range decrypters {
+		go func() {
+			for c := range decryptQueue {
+				if c.err != nil {
+					s.results[c.idx] <- decryptedChunk{err: c.err}
+					continue
+				}
+				plain, err := crypto.Decrypt(c.encrypted, b.Config.EncryptionKey)
+				s.results[c.idx] <- decryptedChunk{plain: plain, err: err, fault: err != nil}
+			}
+		}()
+	}
+	return s
+}
+
+// reserve waits for room in the window and the memory budget for one more
+// chunk.
+func (s *chunkStream) reserve(ctx context.Context) bool {
+	select {
+	case s.window <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	if err := s.bot.Memory.Acquire(ctx); err != nil {
+		<-s.window
+		return false
+	}
+	return true
+}
+
+func (s *chunkStream) release() {
+	s.bot.Memory.Release()
+	<-s.window
+}
+
+// nextChunk returns the next chunk in part order, or ctx's error.
+func (s *chunkStream) nextChunk(ctx context.Context) (decryptedChunk, error) {
+	select {
+	case c := <-s.results[s.next]:
+		s.next++
+		s.release()
+		return c, nil
+	case <-ctx.Done():
+		return decryptedChunk{}, ctx.Err()
+	}
+}
+
+// close stops starting chunks once ctx, which must be cancelled by the
+// caller, ends, and returns the buffers of chunks started but not read.
+func (s *chunkStream) close() {
+	go func() {
+		<-s.stopped
+		for ; s.next < s.dispatched; s.next++ {
+			<-s.results[s.next]
+			s.release()
+		}
+	}()
+}
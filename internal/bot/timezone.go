@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"discordvault/internal/database"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// location resolves the display zone for a user: their own preference, then
+// the guild's, then the configured TIMEZONE.
+func (b *Bot) location(userID, guildID string) *time.Location {
+	for _, pref := range []struct{ scope, id string }{
+		{database.ScopeUser, userID},
+		{database.ScopeGuild, guildID},
+	} {
+		if pref.id == "" {
+			continue
+		}
+		tz, err := b.DB.GetTimezone(pref.scope, pref.id)
+		if err != nil || tz == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+	return b.Config.Location
+}
+
+// channelLocation is the zone used for posts in the storage channel.
+func (b *Bot) channelLocation() *time.Location {
+	guildID := ""
+	if ch, err := b.Session.State.Channel(b.Config.ChannelID); err == nil {
+		guildID = ch.GuildID
+	}
+	return b.location("", guildID)
+}
+
+func formatTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02 15:04:05 MST")
+}
+
+func (b *Bot) handleTimezone(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	userID := interactionUser(i).ID
+	zone := ""
+	scope := database.ScopeUser
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "zone":
+			zone = opt.StringValue()
+		case "scope":
+			scope = opt.StringValue()
+		}
+	}
+
+	reply := func(content string) {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: content, Flags: discordgo.MessageFlagsEphemeral},
+		})
+	}
+
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		reply(b.t(i, "❌ Unknown timezone `%s`. Use an IANA name such as `Europe/Helsinki`.", zone))
+		return
+	}
+
+	subject := userID
+	if scope == database.ScopeGuild {
+		if i.GuildID == "" || !b.isAdmin(i) {
+			reply(b.t(i, "⛔ Only admins can set the server timezone."))
+			return
+		}
+		subject = i.GuildID
+	}
+
+	if err := b.DB.SetTimezone(scope, subject, loc.String()); err != nil {
+		log.Printf("[BOT ERR] Saving timezone failed: %v", err)
+		reply(b.t(i, "❌ Database error."))
+		return
+	}
+	reply(b.t(i, "🕒 %s timezone set to `%s` (now %s).", b.t(i, scope), loc, formatTime(time.Now(), loc)))
+}
@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"discordvault/internal/proxy"
+	"log"
+	"sync/atomic"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// storageToken is one bot chunk posts and downloads can be sent through.
+// The first is the bot's own session; STORAGE_BOT_TOKENS adds more, each
+// with rate limits of its own. Those only talk REST and never go online.
+type storageToken struct {
+	session *discordgo.Session
+	pacer   pacer
+	userID  string // "" until looked up
+}
+
+// tokenPool spreads chunk traffic over the storage tokens: each post goes
+// to the token that may post to its channel soonest, and each download to
+// the next token in turn.
+type tokenPool struct {
+	tokens []*storageToken
+	next   atomic.Uint64
+}
+
+// newStorageToken opens a REST session for token wrapped like the bot's
+// own, counting toward the health monitor and paced by its own headers.
+func (b *Bot) newStorageToken(token string) (*storageToken, error) {
+	s, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return nil, err
+	}
+	t := &storageToken{session: s}
+	s.Client.Transport = b.Health.Transport(proxy.Transport())
+	s.Client.Transport = t.pacer.transport(s.Client.Transport)
+	return t, nil
+}
+
+// lookupUsers records which bot user each token belongs to, the bot's own
+// from its gateway session, so orphan GC recognises chunks they posted.
+func (p *tokenPool) lookupUsers(self *discordgo.User) {
+	if self != nil {
+		p.tokens[0].userID = self.ID
+	}
+	for i, t := range p.tokens[1:] {
+		user, err := t.session.User("@me")
+		if err != nil {
+			log.Printf("[BOT WARN] Could not look up the bot of storage token %d: %v", i+1, err)
+			continue
+		}
+		t.userID = user.ID
+		log.Printf("[BOT] Storage token %d sends as %s", i+1, user.String())
+	}
+}
+
+// poster returns the token that may post to channelID soonest, taking turns
+// among tokens that are all free.
+func (p *tokenPool) poster(channelID string) *storageToken {
+	start := int(p.next.Add(1))
+	best := p.tokens[start%len(p.tokens)]
+	bestWait := best.pacer.wait(channelID)
+	for i := 1; i < len(p.tokens) && bestWait > 0; i++ {
+		t := p.tokens[(start+i)%len(p.tokens)]
+		if wait := t.pacer.wait(channelID); wait < bestWait {
+			best, bestWait = t, wait
+		}
+	}
+	return best
+}
+
+// fetcher returns the token for the next chunk download.
+func (p *tokenPool) fetcher() *storageToken {
+	return p.tokens[int(p.next.Add(1))%len(p.tokens)]
+}
+
+// PostedByVault reports whether msg was posted by the bot or one of its
+// storage tokens. Messages without an author, and those of tokens that
+// could not be looked up, do not count, so orphan GC leaves them alone.
+func (b *Bot) PostedByVault(msg *discordgo.Message) bool {
+	if msg.Author == nil {
+		return false
+	}
+	for _, t := range b.storage.tokens {
+		if t.userID != "" && t.userID == msg.Author.ID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,373 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"discordvault/internal/i18n"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// saveCommand is the message context-menu command that vaults a message's
+// attachments.
+const saveCommand = "Save to Vault"
+
+// maxUploadFiles is how many attachment options /upload has, and
+// uploadWorkers how many of them are stored at the same time.
+const (
+	maxUploadFiles = 10
+	uploadWorkers  = 3
+)
+
+// progressInterval is how often the response of a multi-chunk upload is
+// edited with its progress.
+const progressInterval = 3 * time.Second
+
+var messageLinkPattern = regexp.MustCompile(`channels/(?:\d+|@me)/(\d+)/(\d+)`)
+
+// uploadOptions returns the /upload options: file, file2 ... file10, and a
+// message whose attachments to take instead.
+func uploadOptions() []*discordgo.ApplicationCommandOption {
+	options := []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionAttachment, Name: "file", Description: "File to upload"},
+	}
+	for n := 2; n <= maxUploadFiles; n++ {
+		options = append(options, &discordgo.ApplicationCommandOption{
+			Type: discordgo.ApplicationCommandOptionAttachment, Name: fmt.Sprintf("file%d", n), Description: "Another file to upload",
+		})
+	}
+	return append(options, &discordgo.ApplicationCommandOption{
+		Type: discordgo.ApplicationCommandOptionString, Name: "message", Description: "Link or ID of a message whose attachments to upload",
+	})
+}
+
+func (b *Bot) handleUpload(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	var attachments []*discordgo.MessageAttachment
+	for _, opt := range data.Options {
+		switch opt.Type {
+		case discordgo.ApplicationCommandOptionAttachment:
+			attachments = append(attachments, data.Resolved.Attachments[opt.Value.(string)])
+		case discordgo.ApplicationCommandOptionString:
+			msg, err := b.referencedMessage(i.ChannelID, interactionUser(i).ID, opt.StringValue())
+			if err != nil {
+				b.respondEphemeral(i, b.t(i, "❌ Could not read that message. Paste its link or, for this channel, its ID."))
+				return
+			}
+			attachments = append(attachments, msg.Attachments...)
+		}
+	}
+	if len(attachments) == 0 {
+		b.respondEphemeral(i, b.t(i, "📭 Attach a file or point to a message with attachments."))
+		return
+	}
+
+	userID := interactionUser(i).ID
+	if !b.isAdmin(i) && b.TransferCapReached(userID) {
+		b.respondEphemeral(i, b.t(i, "📉 You have reached your monthly transfer cap."))
+		return
+	}
+
+	content := b.t(i, "⏳ Processing & Encrypting...")
+	if len(attachments) > 1 {
+		content = b.t(i, "⏳ Processing & Encrypting %d files...", len(attachments))
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: content, Flags: b.replyFlags(i)},
+	})
+	b.uploadResults(i, b.storeAttachments(attachments, b.vaultOf(i), userID, b.locale(i), func(status string) { b.followup(i, status) }))
+}
+
+// referencedMessage resolves a message link, or a bare message ID in
+// channelID, the channel the command was used in. A link to another channel
+// only resolves if userID may read that channel's history, so the bot cannot
+// be used to copy files out of channels the user cannot see.
+func (b *Bot) referencedMessage(channelID, userID, ref string) (*discordgo.Message, error) {
+	messageID := strings.TrimSpace(ref)
+	if m := messageLinkPattern.FindStringSubmatch(ref); m != nil && m[1] != channelID {
+		perms, err := b.Session.UserChannelPermissions(userID, m[1])
+		if err != nil {
+			return nil, err
+		}
+		const needed = discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory
+		if perms&needed != needed {
+			return nil, fmt.Errorf("%s may not read channel %s", userID, m[1])
+		}
+		channelID, messageID = m[1], m[2]
+	} else if m != nil {
+		messageID = m[2]
+	}
+	if !isSnowflake(messageID) {
+		return nil, fmt.Errorf("not a message link or ID: %q", ref)
+	}
+	return b.Session.ChannelMessage(channelID, messageID)
+}
+
+// handleSaveToVault stores every attachment of the message the context menu
+// was opened on and replies privately with the new file IDs.
+func (b *Bot) handleSaveToVault(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	msg := data.Resolved.Messages[data.TargetID]
+	if msg == nil || len(msg.Attachments) == 0 {
+		b.respondEphemeral(i, b.t(i, "📭 That message has no attachments."))
+		return
+	}
+
+	userID := interactionUser(i).ID
+	if !b.isAdmin(i) && b.TransferCapReached(userID) {
+		b.respondEphemeral(i, b.t(i, "📉 You have reached your monthly transfer cap."))
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	log.Printf("[BOT] Saving %d attachment(s) of message %s to the vault", len(msg.Attachments), msg.ID)
+	b.uploadResults(i, b.storeAttachments(msg.Attachments, b.vaultOf(i), userID, b.locale(i), func(status string) { b.followup(i, status) }))
+}
+
+// uploadResult is the outcome of storing one attachment.
+type uploadResult struct {
+	attachment *discordgo.MessageAttachment
+	file       *database.FileMetadata
+	failure    string
+}
+
+// storeAttachments stores attachments in a vault with up to uploadWorkers at
+// a time and returns the results in the original order. While uploads that
+// span more than one chunk run, their progress is passed to report. Progress
+// and failure messages are in locale.
+func (b *Bot) storeAttachments(attachments []*discordgo.MessageAttachment, guildID, userID, locale string, report func(status string)) []uploadResult {
+	progress := &uploadProgress{started: time.Now(), locale: locale}
+	for _, att := range attachments {
+		progress.total += int64(att.Size)
+	}
+	if progress.total > int64(b.MaxChunkSize()) {
+		stop := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			ticker := time.NewTicker(progressInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					report(progress.String())
+				}
+			}
+		}()
+		defer func() {
+			close(stop)
+			<-stopped
+		}()
+	}
+
+	results := make([]uploadResult, len(attachments))
+	sem := make(chan struct{}, uploadWorkers)
+	var wg sync.WaitGroup
+	for idx, att := range attachments {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			log.Printf("[BOT] Processing upload from Discord: %s", att.Filename)
+			file, failure := b.storeAttachment(att, guildID, userID, progress)
+			results[idx] = uploadResult{attachment: att, file: file, failure: failure}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// uploadProgress counts the plaintext bytes and chunks stored so far.
+type uploadProgress struct {
+	started time.Time
+	total   int64
+	locale  string
+
+	mu     sync.Mutex
+	done   int64
+	chunks int
+}
+
+func (p *uploadProgress) add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	p.chunks++
+}
+
+func (p *uploadProgress) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	percent := 0
+	if p.total > 0 {
+		percent = int(min(p.done*100/p.total, 100))
+	}
+	rate := float64(p.done) / time.Since(p.started).Seconds()
+	return i18n.T(p.locale, "⏳ Encrypting & uploading: **%d%%** (%s of %s)\n%d chunks stored · %s/s",
+		percent, formatBytes(p.done), formatBytes(p.total), p.chunks, formatBytes(int64(rate)))
+}
+
+// uploadResults replaces the interaction response with one line per file.
+func (b *Bot) uploadResults(i *discordgo.InteractionCreate, results []uploadResult) {
+	content := ""
+	b.Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+		Embeds:  &[]*discordgo.MessageEmbed{resultsEmbed(b.locale(i), results)},
+	})
+}
+
+// resultsEmbed summarises stored attachments with one line per file, in
+// locale.
+func resultsEmbed(locale string, results []uploadResult) *discordgo.MessageEmbed {
+	var lines []string
+	stored := 0
+	for _, r := range results {
+		if r.file == nil {
+			lines = append(lines, fmt.Sprintf("**%s**: %s", r.attachment.Filename, r.failure))
+			continue
+		}
+		stored++
+		line := fmt.Sprintf("✅ **#%d** %s (%s)", r.file.ID, r.file.Name, formatBytes(r.file.Size))
+		if r.file.Version > 1 {
+			line += ", " + i18n.T(locale, "version %d", r.file.Version)
+		}
+		lines = append(lines, line)
+	}
+
+	color := 0x22c55e
+	switch {
+	case stored == 0:
+		color = 0xef4444
+	case stored < len(results):
+		color = 0xf59e0b
+	}
+	return &discordgo.MessageEmbed{
+		Title:       i18n.T(locale, "📦 %d of %d secured", stored, len(results)),
+		Description: strings.Join(lines, "\n"),
+		Color:       color,
+	}
+}
+
+// storeAttachment downloads a Discord attachment, encrypts it into chunks in
+// the vault's storage channel, and records it for userID, adding every stored
+// chunk to progress. On failure it returns nil and a message for the user in
+// the progress locale.
+func (b *Bot) storeAttachment(attachment *discordgo.MessageAttachment, guildID, userID string, progress *uploadProgress) (*database.FileMetadata, string) {
+	channelID := b.Config.StorageChannel(guildID)
+	resp, err := b.Retry.Get("attachment fetch", attachment.URL)
+	if err != nil {
+		log.Printf("[BOT ERR] Failed to fetch attachment: %v", err)
+		return nil, i18n.T(progress.locale, "❌ Failed to fetch file.")
+	}
+	defer resp.Body.Close()
+
+	pool := b.NewChunkPool(channelID)
+	pool.ShardBy(attachment.Filename)
+	pool.DeltaFrom(guildID, attachment.Filename, userID)
+	var totalSize int64
+	hasher := sha256.New()
+
+	// Chunks already sent are removed again unless the metadata commits.
+	committed := false
+	defer func() {
+		if !committed {
+			go pool.Discard()
+		}
+	}()
+
+	chunkSize := b.NextChunkSize()
+	buffer := make([]byte, chunkSize)
+	for {
+		pool.Reserve(context.Background()) // waits for room in the memory budget
+		n, err := io.ReadFull(resp.Body, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			log.Printf("[BOT ERR] Failed to fetch attachment: %v", err)
+			return nil, i18n.T(progress.locale, "❌ Failed to fetch file.")
+		}
+		if n > 0 {
+			totalSize += int64(n)
+			hasher.Write(buffer[:n])
+
+			// Parts the stored version already holds are not sent again
+			if pool.Reuse(buffer[:n]) {
+				progress.add(int64(n))
+				continue
+			}
+
+			encrypted, err := crypto.Encrypt(buffer[:n], b.Config.EncryptionKey)
+			if err != nil {
+				log.Printf("[BOT ERR] Encryption failed: %v", err)
+				return nil, i18n.T(progress.locale, "❌ Encryption failed.")
+			}
+
+			waitCtx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			err = b.Health.Wait(waitCtx)
+			cancel()
+			if err != nil {
+				return nil, i18n.T(progress.locale, "⏸️ Discord is having an outage, upload aborted. Please retry later.")
+			}
+
+			log.Printf("[BOT] Saving encrypted payload to storage channel...")
+			err = pool.Submit(context.Background(), encrypted, func(database.ChunkMetadata) {
+				progress.add(int64(n))
+			})
+			if err != nil {
+				log.Printf("[BOT ERR] Discord storage failed: %v", err)
+				go b.NotifyError("Bot", attachment.Filename, err)
+				return nil, i18n.T(progress.locale, "❌ Could not save to storage channel.")
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+	chunks, err := pool.Wait()
+	if err != nil {
+		log.Printf("[BOT ERR] Discord storage failed: %v", err)
+		go b.NotifyError("Bot", attachment.Filename, err)
+		return nil, i18n.T(progress.locale, "❌ Could not save to storage channel.")
+	}
+	if len(chunks) == 0 {
+		return nil, i18n.T(progress.locale, "❌ File is empty.")
+	}
+
+	hashStr := hex.EncodeToString(hasher.Sum(nil))
+	file, err := b.DB.SaveUpload(guildID, attachment.Filename, totalSize, int64(chunkSize), hashStr, userID, chunks, b.Config.DuplicatePolicy)
+	if errors.Is(err, database.ErrNameTaken) {
+		return nil, i18n.T(progress.locale, "❌ A file named **%s** already exists.", attachment.Filename)
+	}
+	if err != nil {
+		log.Printf("[BOT ERR] DB Save failed: %v", err)
+		go b.NotifyError("Bot", attachment.Filename, err)
+		return nil, i18n.T(progress.locale, "❌ Database error.")
+	}
+	committed = true
+	pool.Commit(file)
+
+	log.Printf("[BOT] Success! Saved %s (ID: %d)", file.Name, file.ID)
+	if err := b.DB.RecordTransfer(database.UsageUser, userID, totalSize, 0); err != nil {
+		log.Printf("[BOT ERR] Transfer accounting failed: %v", err)
+	}
+
+	// Send notification log like web upload
+	go b.NotifyUpload(file, len(chunks), "Bot")
+	return file, ""
+}
@@ -0,0 +1,23 @@
+package bot
+
+import (
+	"discordvault/internal/database"
+	"log"
+	"time"
+)
+
+// TransferCapReached reports whether userID has used up TRANSFER_CAP_MONTHLY
+// for the current month. The cap is soft: a transfer that starts below it
+// runs to completion.
+func (b *Bot) TransferCapReached(userID string) bool {
+	transferCap := b.Config.Live().TransferCap
+	if transferCap <= 0 {
+		return false
+	}
+	usage, err := b.DB.GetTransfer(database.UsagePeriod(time.Now()), database.UsageUser, userID)
+	if err != nil {
+		log.Printf("[BOT ERR] Transfer usage lookup failed: %v", err)
+		return false
+	}
+	return usage.Uploaded+usage.Downloaded >= transferCap
+}
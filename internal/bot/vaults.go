@@ -0,0 +1,27 @@
+package bot
+
+import (
+	"discordvault/internal/database"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// vaultOf returns the vault an interaction works in: its guild's when
+// GUILD_CHANNELS gives that guild a storage channel, the default vault
+// otherwise (including DMs).
+func (b *Bot) vaultOf(i *discordgo.InteractionCreate) string {
+	if _, ok := b.Config.GuildChannels[i.GuildID]; ok && i.GuildID != "" {
+		return i.GuildID
+	}
+	return database.DefaultVault
+}
+
+// ChunkChannel returns the channel a chunk's message lives in. Chunks stored
+// before vaults had their own channels record none and live in the default
+// one.
+func (b *Bot) ChunkChannel(c database.ChunkMetadata) string {
+	if c.ChannelID != "" {
+		return c.ChannelID
+	}
+	return b.Config.ChannelID
+}
@@ -0,0 +1,249 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxVerifyParts caps the part numbers listed per problem in /verify.
+const maxVerifyParts = 20
+
+// ErrChunkUnreachable is returned by VerifyFile and ScrubFile when a chunk
+// could not be fetched for a reason other than its message being gone, such
+// as a network error. The check then says nothing about the file.
+var ErrChunkUnreachable = errors.New("chunk could not be fetched")
+
+// VerifyReport is the result of a full integrity check of one file.
+type VerifyReport struct {
+	Chunks    int
+	Missing   []int // part numbers whose message is gone or unreadable
+	Corrupted []int // part numbers that fail their checksum or do not decrypt
+	Rebuilt   []int // part numbers lost but restored from parity
+	Hash      string
+	Match     bool // Hash equals the recorded file hash
+}
+
+// VerifyFile downloads and decrypts every chunk of file and recomputes the
+// whole-file SHA-256, rebuilding lost chunks from parity where the file has
+// it. Unlike CheckChunk it reads the data itself, from Discord rather than
+// the chunk cache.
+func (b *Bot) VerifyFile(file *database.FileMetadata) (*VerifyReport, error) {
+	return b.ScrubFile(context.Background(), file, 0)
+}
+
+// ScrubFile verifies file like VerifyFile, waiting pace before each chunk
+// so a scrub of the whole vault does not crowd out uploads and downloads.
+func (b *Bot) ScrubFile(ctx context.Context, file *database.FileMetadata, pace time.Duration) (*VerifyReport, error) {
+	chunks, err := b.DB.GetChunks(file.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{Chunks: len(chunks)}
+	hasher := sha256.New()
+	// One chunk at a time is held in memory.
+	if err := b.Memory.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer b.Memory.Release()
+	reader := b.newChunkReader(file, chunks)
+	reader.stored = true
+	for idx, c := range chunks {
+		if pace > 0 {
+			select {
+			case <-time.After(pace):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		encrypted, rebuilt, err := reader.read(idx)
+		if err != nil {
+			if !errors.Is(err, errChunkMissing) {
+				return nil, fmt.Errorf("%w: chunk %d of #%d: %v", ErrChunkUnreachable, c.PartNum, file.ID, err)
+			}
+			report.Missing = append(report.Missing, c.PartNum)
+			continue
+		}
+		if c.SHA256 != "" {
+			if sum := sha256.Sum256(encrypted); hex.EncodeToString(sum[:]) != c.SHA256 {
+				report.Corrupted = append(report.Corrupted, c.PartNum)
+				continue
+			}
+		}
+		plain, err := crypto.Decrypt(encrypted, b.Config.EncryptionKey)
+		if err != nil {
+			report.Corrupted = append(report.Corrupted, c.PartNum)
+			continue
+		}
+		if rebuilt {
+			report.Rebuilt = append(report.Rebuilt, c.PartNum)
+		}
+		hasher.Write(plain)
+	}
+
+	report.Hash = hex.EncodeToString(hasher.Sum(nil))
+	report.Match = len(chunks) > 0 && len(report.Missing) == 0 && len(report.Corrupted) == 0 && report.Hash == file.Hash
+	return report, nil
+}
+
+// RecordHealth stores the file's health as found by report: corrupt when
+// part of it cannot be restored, degraded when lost chunks were or will be
+// restored from replicas or parity, and ok otherwise. It returns the health.
+// A file that turns degraded or corrupt is kept for the digest once, not on
+// every check that finds it unchanged.
+func (b *Bot) RecordHealth(file *database.FileMetadata, report *VerifyReport) (string, error) {
+	health, detail := database.HealthOK, strings.Join(report.Problems(file), ", ")
+	switch {
+	case report.Chunks == 0:
+		health, detail = database.HealthCorrupt, "no chunks recorded"
+	case len(report.Missing) > 0 || len(report.Corrupted) > 0 || (file.Hash != "" && !report.Match):
+		health = database.HealthCorrupt
+	case len(report.Rebuilt) > 0:
+		health = database.HealthDegraded
+	default:
+		status, err := b.DB.RepairStatus(file.ID)
+		if err != nil {
+			return "", err
+		}
+		if status != "" {
+			health, detail = database.HealthDegraded, "lost chunks waiting for repair"
+		}
+	}
+	if err := b.DB.SetFileHealth(file.ID, health, detail); err != nil {
+		return "", err
+	}
+	if health != database.HealthOK && health != file.Health {
+		b.recordVerifyFailure(database.VaultEvent{
+			GuildID:  file.GuildID,
+			FileID:   file.ID,
+			FileName: file.Name,
+			Size:     file.Size,
+			Detail:   detail,
+		})
+	}
+	return health, nil
+}
+
+// Problems lists what the check found wrong with file, empty if nothing.
+func (r *VerifyReport) Problems(file *database.FileMetadata) []string {
+	var problems []string
+	if len(r.Missing) > 0 {
+		problems = append(problems, fmt.Sprintf("%d missing", len(r.Missing)))
+	}
+	if len(r.Corrupted) > 0 {
+		problems = append(problems, fmt.Sprintf("%d corrupted", len(r.Corrupted)))
+	}
+	if len(r.Rebuilt) > 0 {
+		problems = append(problems, fmt.Sprintf("%d rebuilt from parity", len(r.Rebuilt)))
+	}
+	if len(problems) == 0 && r.Chunks > 0 && file.Hash != "" && !r.Match {
+		problems = append(problems, "SHA-256 mismatch")
+	}
+	return problems
+}
+
+// recordVerifyFailure keeps a failed integrity check for the digest.
+func (b *Bot) recordVerifyFailure(ev database.VaultEvent) {
+	ev.Kind = database.EventVerifyFailed
+	if err := b.DB.RecordEvent(ev); err != nil {
+		log.Printf("[BOT ERR] Recording verification failure failed: %v", err)
+	}
+}
+
+func (b *Bot) handleVerify(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	file, input := b.fileOption(i)
+	if file == nil {
+		b.respondEphemeral(i, b.t(i, "❌ No file **%s** in your vault.", input))
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: b.t(i, "🔬 Verifying **%s**...", file.Name), Flags: b.replyFlags(i)},
+	})
+
+	report, err := b.VerifyFile(file)
+	if errors.Is(err, ErrChunkUnreachable) {
+		log.Printf("[BOT WARN] Verify of #%d incomplete: %v", file.ID, err)
+		b.followup(i, b.t(i, "❌ Some chunks could not be fetched from Discord. Try again later."))
+		return
+	}
+	if err != nil {
+		log.Printf("[BOT ERR] Verify failed: %v", err)
+		b.followup(i, b.t(i, "❌ Database error."))
+		return
+	}
+	log.Printf("[BOT] Verified #%d: %d chunks, %d missing, %d corrupted, %d rebuilt, hash match %v",
+		file.ID, report.Chunks, len(report.Missing), len(report.Corrupted), len(report.Rebuilt), report.Match)
+	if _, err := b.RecordHealth(file, report); err != nil {
+		log.Printf("[BOT ERR] Recording health of #%d failed: %v", file.ID, err)
+	}
+
+	color, result := 0x22c55e, b.t(i, "✅ Intact: every chunk decrypts and the SHA-256 matches.")
+	switch {
+	case report.Chunks == 0:
+		color, result = 0xef4444, b.t(i, "⚠️ No chunks recorded.")
+	case len(report.Missing) > 0 || len(report.Corrupted) > 0:
+		color, result = 0xef4444, b.t(i, "❌ Damaged: the file cannot be restored completely.")
+	case file.Hash == "":
+		result = b.t(i, "✅ Every chunk decrypts. No SHA-256 was recorded at upload to compare with.")
+	case !report.Match:
+		color, result = 0xef4444, b.t(i, "❌ Every chunk decrypts, but the SHA-256 differs from the recorded one.")
+	case len(report.Rebuilt) > 0:
+		color, result = 0xf59e0b, b.t(i, "🩹 Restored: lost chunks were rebuilt from parity and the SHA-256 matches.")
+	}
+
+	recorded := "—"
+	if file.Hash != "" {
+		recorded = "`" + file.Hash + "`"
+	}
+	fields := []*discordgo.MessageEmbedField{
+		{Name: b.t(i, "Chunks"), Value: b.t(i, "%d checked", report.Chunks), Inline: true},
+		{Name: b.t(i, "Missing"), Value: b.partList(i, report.Missing), Inline: true},
+		{Name: b.t(i, "Corrupted"), Value: b.partList(i, report.Corrupted), Inline: true},
+	}
+	if file.ParityData > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: b.t(i, "Rebuilt from parity"), Value: b.partList(i, report.Rebuilt), Inline: true})
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{Name: b.t(i, "Recorded SHA-256"), Value: recorded})
+	if len(report.Missing) == 0 && len(report.Corrupted) == 0 && report.Chunks > 0 && !report.Match {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: b.t(i, "Computed SHA-256"), Value: "`" + report.Hash + "`"})
+	}
+
+	content := ""
+	b.Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+		Embeds: &[]*discordgo.MessageEmbed{{
+			Title:       fmt.Sprintf("🔬 %s", file.Name),
+			Description: result,
+			Color:       color,
+			Fields:      fields,
+		}},
+	})
+}
+
+// partList formats chunk part numbers for an embed field.
+func (b *Bot) partList(i *discordgo.InteractionCreate, parts []int) string {
+	if len(parts) == 0 {
+		return b.t(i, "none")
+	}
+	shown := make([]string, 0, min(len(parts), maxVerifyParts))
+	for _, p := range parts[:min(len(parts), maxVerifyParts)] {
+		shown = append(shown, fmt.Sprintf("`%d`", p))
+	}
+	list := strings.Join(shown, ", ")
+	if len(parts) > maxVerifyParts {
+		list += " " + b.t(i, "… and %d more", len(parts)-maxVerifyParts)
+	}
+	return list
+}
@@ -0,0 +1,80 @@
+// Package cdn downloads Discord message attachments. Discord signs
+// attachment URLs with an expiry, so a URL is never kept: the message is
+// looked up right before each download, and looked up again when the CDN
+// rejects the URL anyway.
+package cdn
+
+import (
+	"discordvault/internal/proxy"
+	"discordvault/internal/retry"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Refreshes is how often a download asks for a new URL after the CDN
+// rejected one.
+const Refreshes = 2
+
+// ErrNoAttachment means the message exists but carries no attachment.
+var ErrNoAttachment = errors.New("message has no attachment")
+
+// Expired reports whether err is the CDN refusing a URL, 403 for a stale
+// signature or 404 for an expired one. Either may also mean the attachment
+// is gone, which a fresh URL tells apart.
+func Expired(err error) bool {
+	var statusErr *retry.StatusError
+	return errors.As(err, &statusErr) && (statusErr.Code == http.StatusForbidden || statusErr.Code == http.StatusNotFound)
+}
+
+// URL looks up the current, freshly signed URL of a message's first
+// attachment.
+func URL(s *discordgo.Session, channelID, messageID string) (string, error) {
+	msg, err := s.ChannelMessage(channelID, messageID)
+	if err != nil {
+		return "", err
+	}
+	if len(msg.Attachments) == 0 {
+		return "", ErrNoAttachment
+	}
+	return msg.Attachments[0].URL, nil
+}
+
+// Fetch downloads the first attachment of a message with policy p, which
+// may be nil. A 404 for the message itself is returned as such; callers
+// tell it apart with retry.IsNotFound.
+func Fetch(s *discordgo.Session, p *retry.Policy, channelID, messageID string) ([]byte, error) {
+	var data []byte
+	err := p.Do("attachment "+messageID+" fetch", func() error {
+		url, err := URL(s, channelID, messageID)
+		if err != nil {
+			return err
+		}
+		data, err = get(url)
+		for refresh := 1; refresh <= Refreshes && Expired(err); refresh++ {
+			log.Printf("[RETRY WARN] CDN rejected the URL of %s (%v), refreshing it (%d/%d)", messageID, err, refresh, Refreshes)
+			if url, err = URL(s, channelID, messageID); err != nil {
+				return err
+			}
+			data, err = get(url)
+		}
+		return err
+	})
+	return data, err
+}
+
+func get(url string) ([]byte, error) {
+	resp, err := proxy.Client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := retry.CheckStatus(resp); err != nil {
+		return nil, fmt.Errorf("attachment fetch: %w", err)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,210 @@
+// Package chunkcache keeps recently downloaded chunks on local disk, so
+// files read again soon do not go back to Discord's CDN. Chunks are stored
+// exactly as fetched, still encrypted with the vault key, one file per
+// chunk. When the cache grows past its cap the least recently used chunks
+// are removed; file modification times carry the order across restarts.
+package chunkcache
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	extension  = ".chunk"
+	tempPrefix = "put-"
+)
+
+type entry struct {
+	key  string
+	size int64
+}
+
+// Cache is a size-capped LRU cache of chunks in a directory. It is safe for
+// concurrent use.
+type Cache struct {
+	dir string
+	max int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List // of *entry, most recently used first
+	entries map[string]*list.Element
+}
+
+// Open uses dir as a cache of at most max bytes, creating it if needed and
+// taking over the chunks already in it.
+func Open(dir string, max int64) (*Cache, error) {
+	if max <= 0 {
+		return nil, errors.New("chunk cache size must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	c := &Cache{dir: dir, max: max, order: list.New(), entries: make(map[string]*list.Element)}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type found struct {
+		entry
+		used time.Time
+	}
+	var existing []found
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), tempPrefix) {
+			os.Remove(filepath.Join(dir, f.Name())) // left by a crash during Put
+			continue
+		}
+		key, ok := strings.CutSuffix(f.Name(), extension)
+		if !ok || !validKey(key) {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		existing = append(existing, found{entry{key, info.Size()}, info.ModTime()})
+	}
+	sort.Slice(existing, func(i, j int) bool { return existing[i].used.After(existing[j].used) })
+	for _, f := range existing {
+		e := f.entry
+		c.entries[e.key] = c.order.PushBack(&e)
+		c.size += e.size
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	return c, nil
+}
+
+// Get returns the cached chunk stored under key.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if !validKey(key) {
+		return nil, false
+	}
+	c.mu.Lock()
+	el, ok := c.entries[key]
+	if ok {
+		c.order.MoveToFront(el)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.remove(key)
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+// Put stores a chunk under key, evicting the least recently used chunks to
+// stay under the cap. Chunks larger than the whole cache are not kept.
+func (c *Cache) Put(key string, data []byte) {
+	if !validKey(key) || int64(len(data)) > c.max {
+		return
+	}
+	c.mu.Lock()
+	_, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return
+	}
+
+	tmp, err := os.CreateTemp(c.dir, tempPrefix+"*")
+	if err != nil {
+		log.Printf("[CACHE ERR] Could not cache chunk: %v", err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("[CACHE ERR] Could not cache chunk: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.size -= el.Value.(*entry).size
+		c.order.Remove(el)
+	}
+	c.entries[key] = c.order.PushFront(&entry{key, int64(len(data))})
+	c.size += int64(len(data))
+	c.evict()
+}
+
+// Size returns the bytes held by the cache.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+func (c *Cache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.size -= el.Value.(*entry).size
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// evict removes least recently used chunks until the cache fits its cap.
+// c.mu must be held.
+func (c *Cache) evict() {
+	for c.size > c.max {
+		el := c.order.Back()
+		e := el.Value.(*entry)
+		c.order.Remove(el)
+		delete(c.entries, e.key)
+		c.size -= e.size
+		if err := os.Remove(c.path(e.key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[CACHE ERR] Could not evict chunk %s: %v", e.key, err)
+		}
+	}
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+extension)
+}
+
+// validKey keeps keys to letters and digits so they are safe file names.
+func validKey(key string) bool {
+	if key == "" || len(key) > 128 {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// String describes the cache for logs.
+func (c *Cache) String() string {
+	return fmt.Sprintf("%s (%d of %d bytes used)", c.dir, c.Size(), c.max)
+}
@@ -0,0 +1,85 @@
+// Package chunkname defines how chunk attachments are named in the storage
+// channel, so the bot, orphan GC, and the offline tools agree on what is a
+// vault chunk and what is other channel content.
+//
+// Two schemes exist:
+//
+//	v1      dv1-<sha256 of ciphertext>.vault
+//	legacy  <sha256 of ciphertext>.vault   (chunks stored before v1)
+//
+// Both carry the ciphertext hash, so a chunk can be checked against its own
+// name. Anything else ending in .vault, such as a user's "notes.vault", is
+// not a chunk.
+package chunkname
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Scheme versions.
+const (
+	Legacy  = 0
+	V1      = 1
+	Current = V1
+)
+
+// Extension is the suffix of every chunk attachment.
+const Extension = ".vault"
+
+const v1Prefix = "dv1-"
+
+// ErrNotChunk is returned by Parse for names that are not vault chunks.
+var ErrNotChunk = errors.New("not a vault chunk name")
+
+// Name is a parsed chunk attachment name.
+type Name struct {
+	Version int
+	SHA256  string // hex, lower case
+}
+
+// Format returns the attachment name of a chunk under the given scheme.
+func Format(version int, sum string) string {
+	if version == Legacy {
+		return sum + Extension
+	}
+	return v1Prefix + sum + Extension
+}
+
+// Parse recognises chunk names of every scheme.
+func Parse(filename string) (Name, error) {
+	base, ok := strings.CutSuffix(filename, Extension)
+	if !ok {
+		return Name{}, ErrNotChunk
+	}
+	version := Legacy
+	if sum, ok := strings.CutPrefix(base, v1Prefix); ok {
+		version, base = V1, sum
+	}
+	if !isHexSum(base) {
+		return Name{}, fmt.Errorf("%w: %q", ErrNotChunk, filename)
+	}
+	return Name{Version: version, SHA256: base}, nil
+}
+
+// ParseScheme reads a scheme from configuration: "v1" or "legacy".
+func ParseScheme(s string) (int, error) {
+	switch s {
+	case "v1":
+		return V1, nil
+	case "legacy":
+		return Legacy, nil
+	}
+	return 0, fmt.Errorf("unknown chunk naming scheme %q", s)
+}
+
+// isHexSum reports whether s is a lower-case hex SHA-256.
+func isHexSum(s string) bool {
+	if len(s) != 64 || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,66 @@
+package chunkname
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const sum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Name
+		wantErr bool
+	}{
+		{"dv1-" + sum + ".vault", Name{V1, sum}, false},
+		{sum + ".vault", Name{Legacy, sum}, false},
+		{"notes.vault", Name{}, true},
+		{"dv1-.vault", Name{}, true},
+		{sum + ".bin", Name{}, true},
+		{sum, Name{}, true},
+		{"dv1-" + strings.ToUpper(sum) + ".vault", Name{}, true},
+		{"dv2-" + sum + ".vault", Name{}, true},
+		{"dv1-" + sum[:63] + ".vault", Name{}, true},
+		{"dv1-" + sum + "0.vault", Name{}, true},
+		{"dv1-" + sum[:63] + "g.vault", Name{}, true},
+		{"x" + sum + ".vault", Name{}, true},
+		{sum + ".vault.vault", Name{}, true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.name)
+		if tt.wantErr {
+			if !errors.Is(err, ErrNotChunk) {
+				t.Errorf("Parse(%q) = %v, %v; want ErrNotChunk", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %v, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatParses(t *testing.T) {
+	for _, version := range []int{Legacy, V1} {
+		name := Format(version, sum)
+		got, err := Parse(name)
+		if err != nil || got != (Name{version, sum}) {
+			t.Errorf("Parse(Format(%d)) = %v, %v", version, got, err)
+		}
+	}
+}
+
+func TestParseScheme(t *testing.T) {
+	for s, want := range map[string]int{"v1": V1, "legacy": Legacy} {
+		if got, err := ParseScheme(s); err != nil || got != want {
+			t.Errorf("ParseScheme(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "V1", "v2"} {
+		if _, err := ParseScheme(s); err == nil {
+			t.Errorf("ParseScheme(%q) succeeded", s)
+		}
+	}
+}
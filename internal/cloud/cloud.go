@@ -0,0 +1,78 @@
+// Package cloud reads files out of other storage providers, so a vault can
+// take over from them: S3-compatible buckets, and web servers that publish
+// a directory listing.
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Object is one file of a source.
+type Object struct {
+	Key  string // slash-separated path below the source's URL
+	Size int64  // -1 if the source does not say
+	ETag string // changes with the contents, "" if the source has none
+}
+
+// Source lists and streams the files below a URL.
+type Source interface {
+	// String returns the URL the source was opened with.
+	String() string
+	List(ctx context.Context) ([]Object, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// S3Options configure access to an S3-compatible service. All fields are
+// optional; without keys, requests are sent unsigned, which works for
+// public buckets.
+type S3Options struct {
+	Endpoint  string // e.g. https://s3.eu-central-1.amazonaws.com, default AWS in Region
+	Region    string // default us-east-1
+	AccessKey string
+	SecretKey string
+}
+
+// Open returns the source for rawURL: s3://bucket/prefix for a bucket, or
+// an http(s) URL of a directory listing.
+func Open(rawURL string, opts S3Options, client *http.Client) (Source, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "s3":
+		if u.Host == "" {
+			return nil, errors.New("s3 URL has no bucket")
+		}
+		return newS3(u.Host, strings.TrimPrefix(u.Path, "/"), opts, client)
+	case "http", "https":
+		if u.Host == "" {
+			return nil, errors.New("URL has no host")
+		}
+		return newListing(u, client), nil
+	}
+	return nil, fmt.Errorf("unsupported source %q, use s3://bucket/prefix or an http(s) URL", rawURL)
+}
+
+// get sends req and returns the response body if it succeeded.
+func get(client *http.Client, req *http.Request) (io.ReadCloser, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GET %s: %s %s", req.URL.Redacted(), resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,129 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxListingDepth bounds how deep a listing is followed into subdirectories.
+const maxListingDepth = 32
+
+var hrefPattern = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'#]+)["']`)
+
+// listing reads a directory listing as web servers generate it (nginx
+// autoindex, Apache mod_autoindex, python -m http.server, ...): links ending
+// in a slash are subdirectories, other links below the listing's URL are
+// files. Links outside the listing, such as the parent directory or sort
+// orders, are ignored.
+type listing struct {
+	base   *url.URL
+	client *http.Client
+}
+
+func newListing(u *url.URL, client *http.Client) *listing {
+	base := *u
+	base.RawQuery, base.Fragment = "", ""
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+		base.RawPath = ""
+	}
+	return &listing{base: &base, client: client}
+}
+
+func (l *listing) String() string {
+	return l.base.Redacted()
+}
+
+func (l *listing) List(ctx context.Context) ([]Object, error) {
+	var objects []Object
+	seen := map[string]bool{}
+	var walk func(dir *url.URL, depth int) error
+	walk = func(dir *url.URL, depth int) error {
+		req, err := http.NewRequestWithContext(ctx, "GET", dir.String(), nil)
+		if err != nil {
+			return err
+		}
+		body, err := get(l.client, req)
+		if err != nil {
+			return err
+		}
+		page, err := io.ReadAll(io.LimitReader(body, 32<<20))
+		body.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, m := range hrefPattern.FindAllSubmatch(page, -1) {
+			ref, err := url.Parse(strings.ReplaceAll(string(m[1]), "&amp;", "&"))
+			if err != nil || ref.RawQuery != "" {
+				continue
+			}
+			link := dir.ResolveReference(ref)
+			key, ok := l.key(link)
+			if !ok || key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if strings.HasSuffix(key, "/") {
+				if depth < maxListingDepth {
+					if err := walk(link, depth+1); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			objects = append(objects, l.stat(ctx, link, key))
+		}
+		return nil
+	}
+	if err := walk(l.base, 0); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// stat asks the server for the size and version of a file with a HEAD
+// request. Servers without an ETag identify the version by Last-Modified;
+// what the server does not answer stays unknown.
+func (l *listing) stat(ctx context.Context, link *url.URL, key string) Object {
+	o := Object{Key: key, Size: -1}
+	req, err := http.NewRequestWithContext(ctx, "HEAD", link.String(), nil)
+	if err != nil {
+		return o
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return o
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return o
+	}
+	o.Size = resp.ContentLength
+	o.ETag = strings.Trim(resp.Header.Get("ETag"), `"`)
+	if o.ETag == "" {
+		o.ETag = resp.Header.Get("Last-Modified")
+	}
+	return o
+}
+
+// key returns the path of link below the listing's URL, if it is there.
+func (l *listing) key(link *url.URL) (string, bool) {
+	if link.Scheme != l.base.Scheme || link.Host != l.base.Host || !strings.HasPrefix(link.Path, l.base.Path) {
+		return "", false
+	}
+	return strings.TrimPrefix(link.Path, l.base.Path), true
+}
+
+func (l *listing) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	ref := &url.URL{Path: key}
+	req, err := http.NewRequestWithContext(ctx, "GET", l.base.ResolveReference(ref).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return get(l.client, req)
+}
@@ -1,46 +1,824 @@
 package config
 
 import (
+	"discordvault/internal/chunkname"
+	"discordvault/internal/erasure"
+	"discordvault/internal/i18n"
+	"discordvault/internal/logstream"
+	"discordvault/internal/retry"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 type Config struct {
-	DiscordToken  string
-	ChannelID     string
-	AllowedUsers  []string
-	EncryptionKey []byte
+	DiscordToken    string
+	StorageTokens   []string // extra bot tokens chunk traffic is spread over
+	ChannelID       string
+	GuildChannels   map[string]string // guild ID -> storage channel of that guild's vault
+	ReplicaChannels []string          // channels holding a copy of every chunk
+	ShardChannels   []string          // more channels the default vault's chunks are spread over
+	ShardStrategy   string            // ShardRoundRobin or ShardHash
+	Responses       string            // ResponsesPublic or ResponsesPrivate
+	Locale          string            // LocaleUser, LocaleGuild, or a fixed locale code
+	CommandReplies  map[string]string // bot command -> ResponsesPublic or ResponsesPrivate
+	EncryptionKey   []byte
+	DatabaseURL     string
+	SigningKeyPath  string
+	Proxy           *url.URL // HTTPS_PROXY or SOCKS_PROXY for traffic to Discord, nil = direct
+
+	NotifyTemplatesDir string
+	Location           *time.Location
+
+	PublicURL         string
+	ShareNoticeTarget string // "channel" or "dm"
+
+	CompactionInterval  time.Duration
+	CompactionRetention time.Duration
+	PurgePace           time.Duration
+	PurgeGrace          time.Duration // how long deleted files' messages are kept, 0 = delete at once
+
+	BackupInterval  time.Duration
+	BackupKeep      int
+	BackupChannelID string
+	BackupJobPaths  []string // server directories backup jobs may read, none = URLs only
+	ExportDir       string   // server directory /api/export-all writes to, "" = disabled
+
+	StatusPollInterval time.Duration
+
+	GCInterval time.Duration
+	GCMinAge   time.Duration
+
+	RepairInterval time.Duration // how often degraded files are repaired, 0 = never
+	ScrubInterval  time.Duration // how often every file is verified, 0 = never
+	ScrubPace      time.Duration // wait before each chunk the scrub downloads
+
+	IdempotencyTTL time.Duration
+	// UploadSessionTTL is how long a resumable upload may sit idle before
+	// its chunks are deleted; 0 keeps sessions until they finish.
+	UploadSessionTTL time.Duration
+
+	// SessionIdleTimeout ends a web login unused for this long, and
+	// SessionMaxAge ends it this long after login; 0 = no limit.
+	SessionIdleTimeout time.Duration
+	SessionMaxAge      time.Duration
+
+	DuplicatePolicy string // "suffix", "version", or "reject"
+
+	PipelinesFile string
+
+	ArtifactKeep []KeepRule
+
+	LifecycleInterval time.Duration
+
+	DownloadLinkTTL time.Duration
+
+	DownloadFilename string // template for downloaded file names, e.g. "{id}-{name}"
+
+	ChunkVerify       string // "off", "size", "sample", or "all"
+	ChunkVerifySample int    // percent of chunks re-fetched in "sample" mode
+	ChunkNaming       int    // chunkname scheme for new chunk attachments
+	ChunkSizing       string // "fixed" or "adaptive"
+	ChunkSize         int64  // largest plaintext chunk, 0 = from the storage guilds' boost tier
+
+	ChunkParallelism  int // chunks of one upload posted at the same time
+	MaxBufferedChunks int // chunks held in memory across all transfers, 0 = unlimited
+
+	DownloadParallelism int // chunks of one download fetched at the same time
+
+	ChunkCacheDir  string // directory of the local chunk cache, "" = off
+	ChunkCacheSize int64
+
+	// ParityData and ParityChunks are ERASURE_CODING: every ParityData chunks
+	// of a new upload get ParityChunks parity chunks. 0 = off.
+	ParityData   int
+	ParityChunks int
+
+	RetryAttempts   map[string]int // retry class -> attempts of a Discord storage call
+	RetryBackoff    time.Duration  // wait before the first retry, doubling after
+	RetryMaxBackoff time.Duration
+
+	SMTPHost     string // empty disables email
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	EmailTo      []string // recipients of digests and alerts
+	EmailEvents  []string // notification kinds also sent by email
+
+	SlackWebhookURL   string
+	MatrixHomeserver  string
+	MatrixRoomID      string
+	MatrixAccessToken string
+	BridgeEvents      []string // notification kinds forwarded to Slack and Matrix
+
+	DigestInterval  time.Duration // how often a summary report is posted, 0 = never
+	DigestChannelID string
+
+	OffPeak        *Window // nil = background uploads run at any time
+	OffPeakMinSize int64   // uploads at least this large wait for OffPeak, 0 = opt-in only
+
+	LogBuffer   int    // recent log entries kept for /api/admin/logs, 0 disables
+	LogFormat   string // LogText or LogJSON
+	LogFile     string // also write the log here, "" = stderr only
+	LogMaxSize  int64  // LogFile is rotated when it would grow past this
+	LogMaxFiles int    // rotated log files kept besides LogFile
+
+	LeaseTTL   time.Duration // primary lease for warm standby, 0 = single instance
+	InstanceID string        // identifies this instance in the lease
+
+	live atomic.Pointer[Live]
 }
 
+// Chunk verification modes.
+const (
+	VerifyOff    = "off"
+	VerifySize   = "size"
+	VerifySample = "sample"
+	VerifyAll    = "all"
+)
+
+// Visibility of bot command replies. Private replies are ephemeral, and
+// private mode also keeps upload and delete notices out of Discord.
+const (
+	ResponsesPublic  = "public"
+	ResponsesPrivate = "private"
+)
+
+// Where the bot takes the language of its replies from, unless BOT_LOCALE
+// names a fixed locale.
+const (
+	LocaleUser  = "user"  // the Discord client language of the user
+	LocaleGuild = "guild" // the community language of the server
+)
+
+// How the default vault spreads chunks over DISCORD_CHANNEL_ID and
+// SHARD_CHANNELS.
+const (
+	ShardRoundRobin = "round-robin" // every chunk goes to the next channel
+	ShardHash       = "hash"        // a file's chunks go to the channel its name hashes to
+)
+
+// LOG_FORMAT values.
+const (
+	LogText = "text"
+	LogJSON = "json"
+)
+
+// Chunk sizing modes.
+const (
+	SizingFixed    = "fixed"
+	SizingAdaptive = "adaptive"
+)
+
+// Bounds of CHUNK_SIZE. The largest leaves room for encryption overhead in
+// the 100MB attachment limit of a guild at boost tier 3.
+const (
+	MinChunkSize = 1024 * 1024
+	MaxChunkSize = 99 * 1024 * 1024
+)
+
+// Permission levels for bot commands. Members are the users and roles in
+// ALLOWED_USERS and ALLOWED_ROLES; admins always count as members.
+const (
+	PermAnyone = "anyone"
+	PermMember = "member"
+	PermAdmin  = "admin"
+)
+
+// FilenameFields are the placeholders DOWNLOAD_FILENAME may use.
+var FilenameFields = []string{"{name}", "{base}", "{ext}", "{id}", "{version}", "{date}"}
+
+var filenameField = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// KeepRule limits how many CI runs are kept for branches matching Pattern
+// (a path.Match glob). Keep 0 keeps every run.
+type KeepRule struct {
+	Pattern string
+	Keep    int
+}
+
+// Load reads the configuration. Every option is an environment variable,
+// and can also be set in a config file (see readSettings); the environment,
+// which includes .env, takes precedence over the file, and the file over
+// the defaults.
 func Load() (*Config, error) {
+	file, err := readSettings()
+	if err != nil {
+		return nil, err
+	}
+	settings.Store(file)
 	cfg := &Config{}
 
-	token := os.Getenv("DISCORD_TOKEN")
+	token, err := secret("DISCORD_TOKEN")
+	if err != nil {
+		return nil, err
+	}
 	if token == "" {
-		return nil, fmt.Errorf("DISCORD_TOKEN environment variable not set")
+		return nil, fmt.Errorf("DISCORD_TOKEN is not set in the environment or the config file")
 	}
 	cfg.DiscordToken = token
+	cfg.StorageTokens = splitList(env("STORAGE_BOT_TOKENS"))
 
-	channelID := os.Getenv("DISCORD_CHANNEL_ID")
+	channelID := env("DISCORD_CHANNEL_ID")
 	if channelID == "" {
-		return nil, fmt.Errorf("DISCORD_CHANNEL_ID environment variable not set")
+		return nil, fmt.Errorf("DISCORD_CHANNEL_ID is not set in the environment or the config file")
 	}
 	cfg.ChannelID = channelID
 
-	allowedUsersStr := os.Getenv("ALLOWED_USERS")
-	if allowedUsersStr != "" {
-		parts := strings.Split(allowedUsersStr, ",")
-		for _, part := range parts {
-			cfg.AllowedUsers = append(cfg.AllowedUsers, strings.TrimSpace(part))
+	cfg.GuildChannels = make(map[string]string)
+	for _, entry := range splitList(env("GUILD_CHANNELS")) {
+		guild, channel, ok := strings.Cut(entry, ":")
+		if !ok || guild == "" || channel == "" {
+			return nil, fmt.Errorf("GUILD_CHANNELS entry %q must be in the form guildID:channelID", entry)
+		}
+		cfg.GuildChannels[guild] = channel
+	}
+	cfg.ReplicaChannels = splitList(env("REPLICA_CHANNELS"))
+	cfg.ShardChannels = splitList(env("SHARD_CHANNELS"))
+	cfg.ShardStrategy = getEnv("SHARD_STRATEGY", ShardRoundRobin)
+	if cfg.ShardStrategy != ShardRoundRobin && cfg.ShardStrategy != ShardHash {
+		return nil, fmt.Errorf("SHARD_STRATEGY must be 'round-robin' or 'hash'")
+	}
+
+	live := &Live{}
+	cfg.live.Store(live)
+	live.AllowedUsers = splitList(env("ALLOWED_USERS"))
+	live.AdminUsers = splitList(env("ADMIN_USERS"))
+	live.AllowedRoles = splitList(env("ALLOWED_ROLES"))
+	live.AdminRoles = splitList(env("ADMIN_ROLES"))
+
+	live.CommandLevels = make(map[string]string)
+	for _, entry := range splitList(env("COMMAND_PERMISSIONS")) {
+		command, level, ok := strings.Cut(entry, "=")
+		command = strings.TrimPrefix(strings.TrimSpace(command), "/")
+		level = strings.TrimSpace(level)
+		if !ok || command == "" || (level != PermAnyone && level != PermMember && level != PermAdmin) {
+			return nil, fmt.Errorf("COMMAND_PERMISSIONS entry %q must be in the form command=anyone|member|admin", entry)
+		}
+		live.CommandLevels[command] = level
+	}
+
+	cfg.Responses = getEnv("BOT_RESPONSES", ResponsesPublic)
+	if cfg.Responses != ResponsesPublic && cfg.Responses != ResponsesPrivate {
+		return nil, fmt.Errorf("BOT_RESPONSES must be 'public' or 'private'")
+	}
+	cfg.Locale = getEnv("BOT_LOCALE", LocaleUser)
+	if cfg.Locale != LocaleUser && cfg.Locale != LocaleGuild && !i18n.Known(cfg.Locale) {
+		return nil, fmt.Errorf("BOT_LOCALE must be 'user', 'guild', or a locale with a catalog, one of en, %s", strings.Join(i18n.Locales(), ", "))
+	}
+	cfg.CommandReplies = make(map[string]string)
+	for _, entry := range splitList(env("COMMAND_RESPONSES")) {
+		command, visibility, ok := strings.Cut(entry, "=")
+		command = strings.TrimPrefix(strings.TrimSpace(command), "/")
+		visibility = strings.TrimSpace(visibility)
+		if !ok || command == "" || (visibility != ResponsesPublic && visibility != ResponsesPrivate) {
+			return nil, fmt.Errorf("COMMAND_RESPONSES entry %q must be in the form command=public|private", entry)
+		}
+		cfg.CommandReplies[command] = visibility
+	}
+
+	live.Cooldowns = make(map[string]time.Duration)
+	for _, entry := range splitList(getEnv("COMMAND_COOLDOWNS", "upload=10s,list=3s,search=3s")) {
+		command, value, ok := strings.Cut(entry, "=")
+		command = strings.TrimPrefix(strings.TrimSpace(command), "/")
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || command == "" || err != nil || d < 0 {
+			return nil, fmt.Errorf("COMMAND_COOLDOWNS entry %q must be in the form command=duration, e.g. upload=10s", entry)
 		}
+		live.Cooldowns[command] = d
 	}
 
-	key := os.Getenv("ENCRYPTION_KEY")
+	live.APIKeys = make(map[string]string)
+	for _, entry := range splitList(env("API_KEYS")) {
+		key, owner, ok := strings.Cut(entry, ":")
+		if !ok || key == "" || owner == "" {
+			return nil, fmt.Errorf("API_KEYS entry %q must be in the form key:owner", entry)
+		}
+		live.APIKeys[key] = owner
+	}
+
+	key, err := secret("ENCRYPTION_KEY")
+	if err != nil {
+		return nil, err
+	}
 	if len(key) != 32 {
 		return nil, fmt.Errorf("ENCRYPTION_KEY must be exactly 32 bytes (got %d)", len(key))
 	}
 	cfg.EncryptionKey = []byte(key)
 
+	cfg.DatabaseURL = DatabaseURL()
+	cfg.SigningKeyPath = getEnv("SIGNING_KEY_PATH", "./vault_signing.key")
+	cfg.NotifyTemplatesDir = env("NOTIFY_TEMPLATES_DIR")
+	cfg.PipelinesFile = env("PIPELINES_FILE")
+	if cfg.Location, err = time.LoadLocation(getEnv("TIMEZONE", "UTC")); err != nil {
+		return nil, fmt.Errorf("TIMEZONE is not a valid IANA zone: %w", err)
+	}
+
+	if cfg.Proxy, err = parseProxy(env("HTTPS_PROXY"), env("SOCKS_PROXY")); err != nil {
+		return nil, err
+	}
+
+	cfg.PublicURL = strings.TrimRight(getEnv("PUBLIC_URL", "http://localhost:8080"), "/")
+	cfg.ShareNoticeTarget = getEnv("SHARE_NOTICE_TARGET", "channel")
+	if cfg.ShareNoticeTarget != "channel" && cfg.ShareNoticeTarget != "dm" {
+		return nil, fmt.Errorf("SHARE_NOTICE_TARGET must be 'channel' or 'dm'")
+	}
+
+	if cfg.CompactionInterval, err = getDuration("COMPACTION_INTERVAL", 6*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.CompactionRetention, err = getDuration("COMPACTION_RETENTION", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.PurgePace, err = getDuration("PURGE_PACE", 2*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.PurgeGrace, err = getDuration("PURGE_GRACE", 0); err != nil {
+		return nil, err
+	}
+
+	if cfg.BackupInterval, err = getDuration("METADATA_BACKUP_INTERVAL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.BackupKeep, err = getInt("METADATA_BACKUP_KEEP", 7); err != nil {
+		return nil, err
+	}
+	if cfg.BackupKeep < 1 {
+		return nil, fmt.Errorf("METADATA_BACKUP_KEEP must be at least 1")
+	}
+	cfg.BackupChannelID = getEnv("METADATA_BACKUP_CHANNEL_ID", cfg.ChannelID)
+	for _, dir := range splitList(env("BACKUP_JOB_PATHS")) {
+		if !filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("BACKUP_JOB_PATHS: %q is not an absolute path", dir)
+		}
+		cfg.BackupJobPaths = append(cfg.BackupJobPaths, filepath.Clean(dir))
+	}
+	if cfg.ExportDir = env("EXPORT_DIR"); cfg.ExportDir != "" {
+		if !filepath.IsAbs(cfg.ExportDir) {
+			return nil, fmt.Errorf("EXPORT_DIR: %q is not an absolute path", cfg.ExportDir)
+		}
+		cfg.ExportDir = filepath.Clean(cfg.ExportDir)
+	}
+
+	if cfg.StatusPollInterval, err = getDuration("DISCORD_STATUS_POLL_INTERVAL", 2*time.Minute); err != nil {
+		return nil, err
+	}
+
+	if cfg.GCInterval, err = getDuration("GC_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+	if cfg.GCMinAge, err = getDuration("GC_MIN_AGE", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.RepairInterval, err = getDuration("REPAIR_INTERVAL", 15*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.ScrubInterval, err = getDuration("SCRUB_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+	if cfg.ScrubPace, err = getDuration("SCRUB_PACE", time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.IdempotencyTTL, err = getDuration("IDEMPOTENCY_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.UploadSessionTTL, err = getDuration("UPLOAD_SESSION_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.SessionIdleTimeout, err = getDuration("SESSION_IDLE_TIMEOUT", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.SessionMaxAge, err = getDuration("SESSION_MAX_AGE", 12*time.Hour); err != nil {
+		return nil, err
+	}
+
+	cfg.DuplicatePolicy = getEnv("DUPLICATE_POLICY", "suffix")
+	if !ValidDuplicatePolicy(cfg.DuplicatePolicy) {
+		return nil, fmt.Errorf("DUPLICATE_POLICY must be 'suffix', 'version', or 'reject'")
+	}
+
+	if live.TransferCap, err = getBytes("TRANSFER_CAP_MONTHLY", 0); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range splitList(env("ARTIFACT_KEEP")) {
+		pattern, keep, ok := strings.Cut(entry, "=")
+		n, err := strconv.Atoi(keep)
+		if !ok || pattern == "" || err != nil || n < 0 {
+			return nil, fmt.Errorf("ARTIFACT_KEEP entry %q must be in the form branch-glob=runs", entry)
+		}
+		cfg.ArtifactKeep = append(cfg.ArtifactKeep, KeepRule{Pattern: pattern, Keep: n})
+	}
+
+	if cfg.LifecycleInterval, err = getDuration("LIFECYCLE_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+
+	if cfg.DownloadLinkTTL, err = getDuration("DOWNLOAD_LINK_TTL", 15*time.Minute); err != nil {
+		return nil, err
+	}
+
+	cfg.DownloadFilename = getEnv("DOWNLOAD_FILENAME", "{name}")
+	for _, field := range filenameField.FindAllString(cfg.DownloadFilename, -1) {
+		if !slices.Contains(FilenameFields, field) {
+			return nil, fmt.Errorf("DOWNLOAD_FILENAME: unknown field %s (use %s)", field, strings.Join(FilenameFields, ", "))
+		}
+	}
+
+	cfg.ChunkVerify = getEnv("CHUNK_VERIFY", VerifySize)
+	switch cfg.ChunkVerify {
+	case VerifyOff, VerifySize, VerifySample, VerifyAll:
+	default:
+		return nil, fmt.Errorf("CHUNK_VERIFY must be 'off', 'size', 'sample', or 'all'")
+	}
+	if cfg.ChunkVerifySample, err = getInt("CHUNK_VERIFY_SAMPLE", 10); err != nil {
+		return nil, err
+	}
+	if cfg.ChunkVerifySample < 0 || cfg.ChunkVerifySample > 100 {
+		return nil, fmt.Errorf("CHUNK_VERIFY_SAMPLE must be a percentage between 0 and 100")
+	}
+	if cfg.ChunkNaming, err = chunkname.ParseScheme(getEnv("CHUNK_NAMING", "v1")); err != nil {
+		return nil, fmt.Errorf("CHUNK_NAMING must be 'v1' or 'legacy'")
+	}
+	cfg.ChunkSizing = getEnv("CHUNK_SIZING", SizingFixed)
+	if cfg.ChunkSizing != SizingFixed && cfg.ChunkSizing != SizingAdaptive {
+		return nil, fmt.Errorf("CHUNK_SIZING must be 'fixed' or 'adaptive'")
+	}
+	if strings.EqualFold(strings.TrimSpace(env("CHUNK_SIZE")), "auto") {
+		cfg.ChunkSize = 0
+	} else if cfg.ChunkSize, err = getBytes("CHUNK_SIZE", 7*1024*1024); err != nil {
+		return nil, err
+	} else if cfg.ChunkSize < MinChunkSize || cfg.ChunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("CHUNK_SIZE must be 'auto' or a size between 1MB and 99MB")
+	}
+
+	if cfg.ChunkParallelism, err = getInt("CHUNK_PARALLELISM", 3); err != nil {
+		return nil, err
+	}
+	if cfg.ChunkParallelism < 1 {
+		return nil, fmt.Errorf("CHUNK_PARALLELISM must be at least 1")
+	}
+	if cfg.MaxBufferedChunks, err = getInt("MAX_BUFFERED_CHUNKS", 32); err != nil {
+		return nil, err
+	}
+	if cfg.DownloadParallelism, err = getInt("DOWNLOAD_PARALLELISM", 4); err != nil {
+		return nil, err
+	}
+	if cfg.DownloadParallelism < 1 {
+		return nil, fmt.Errorf("DOWNLOAD_PARALLELISM must be at least 1")
+	}
+	cfg.ChunkCacheDir = env("CHUNK_CACHE_DIR")
+	if cfg.ChunkCacheSize, err = getBytes("CHUNK_CACHE_SIZE", 2<<30); err != nil {
+		return nil, err
+	}
+	if cfg.ChunkCacheDir != "" && cfg.ChunkCacheSize <= 0 {
+		return nil, fmt.Errorf("CHUNK_CACHE_SIZE must be positive")
+	}
+	if coding := env("ERASURE_CODING"); coding != "" {
+		data, parity, ok := strings.Cut(coding, "+")
+		cfg.ParityData, err = strconv.Atoi(strings.TrimSpace(data))
+		if err == nil {
+			cfg.ParityChunks, err = strconv.Atoi(strings.TrimSpace(parity))
+		}
+		if !ok || err != nil {
+			return nil, fmt.Errorf("ERASURE_CODING must be in the form data+parity, e.g. 10+2")
+		}
+		if cfg.ParityData < 1 || cfg.ParityChunks < 1 || cfg.ParityData+cfg.ParityChunks > erasure.MaxShards {
+			return nil, fmt.Errorf("ERASURE_CODING needs at least 1 data and 1 parity chunk and at most %d in total", erasure.MaxShards)
+		}
+	}
+
+	cfg.RetryAttempts = map[string]int{retry.Network: 4, retry.Server: 4, retry.RateLimit: 3}
+	for _, entry := range splitList(env("RETRY_ATTEMPTS")) {
+		class, value, ok := strings.Cut(entry, "=")
+		class = strings.TrimSpace(class)
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || !slices.Contains(retry.Classes, class) || err != nil || n < 1 {
+			return nil, fmt.Errorf("RETRY_ATTEMPTS entry %q must be in the form class=attempts with class one of %s", entry, strings.Join(retry.Classes, ", "))
+		}
+		cfg.RetryAttempts[class] = n
+	}
+	if cfg.RetryBackoff, err = getDuration("RETRY_BACKOFF", 500*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.RetryMaxBackoff, err = getDuration("RETRY_MAX_BACKOFF", 30*time.Second); err != nil {
+		return nil, err
+	}
+
+	cfg.SMTPHost = env("SMTP_HOST")
+	if cfg.SMTPPort, err = getInt("SMTP_PORT", 587); err != nil {
+		return nil, err
+	}
+	cfg.SMTPUsername = env("SMTP_USERNAME")
+	cfg.SMTPPassword = env("SMTP_PASSWORD")
+	cfg.SMTPFrom = getEnv("SMTP_FROM", cfg.SMTPUsername)
+	cfg.EmailTo = splitList(env("NOTIFY_EMAIL_TO"))
+	cfg.EmailEvents = splitList(getEnv("NOTIFY_EMAIL_EVENTS", "digest,error,health"))
+	if cfg.SMTPHost != "" && cfg.SMTPFrom == "" {
+		return nil, fmt.Errorf("SMTP_FROM is required when SMTP_HOST is set")
+	}
+
+	cfg.SlackWebhookURL = env("SLACK_WEBHOOK_URL")
+	cfg.MatrixHomeserver = env("MATRIX_HOMESERVER")
+	cfg.MatrixRoomID = env("MATRIX_ROOM_ID")
+	cfg.MatrixAccessToken = env("MATRIX_ACCESS_TOKEN")
+	if cfg.MatrixHomeserver != "" && (cfg.MatrixRoomID == "" || cfg.MatrixAccessToken == "") {
+		return nil, fmt.Errorf("MATRIX_ROOM_ID and MATRIX_ACCESS_TOKEN are required when MATRIX_HOMESERVER is set")
+	}
+	cfg.BridgeEvents = splitList(getEnv("NOTIFY_BRIDGE_EVENTS", "upload,delete,error,digest,health"))
+
+	if cfg.DigestInterval, err = getDuration("DIGEST_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+	cfg.DigestChannelID = getEnv("DIGEST_CHANNEL_ID", cfg.ChannelID)
+
+	if spec := env("OFFPEAK_WINDOW"); spec != "" {
+		if cfg.OffPeak, err = ParseWindow(spec); err != nil {
+			return nil, fmt.Errorf("OFFPEAK_WINDOW: %w", err)
+		}
+	}
+	if cfg.OffPeakMinSize, err = getBytes("OFFPEAK_MIN_SIZE", 0); err != nil {
+		return nil, err
+	}
+
+	if cfg.LogBuffer, err = getInt("LOG_BUFFER", 1000); err != nil {
+		return nil, err
+	}
+	live.LogLevel = strings.ToLower(getEnv("LOG_LEVEL", logstream.LevelInfo))
+	if !logstream.ValidLevel(live.LogLevel) {
+		return nil, fmt.Errorf("LOG_LEVEL must be 'debug', 'info', 'warn', 'error', or 'critical'")
+	}
+	cfg.LogFormat = getEnv("LOG_FORMAT", LogText)
+	if cfg.LogFormat != LogText && cfg.LogFormat != LogJSON {
+		return nil, fmt.Errorf("LOG_FORMAT must be 'text' or 'json'")
+	}
+	cfg.LogFile = env("LOG_FILE")
+	if cfg.LogMaxSize, err = getBytes("LOG_MAX_SIZE", 100*1024*1024); err != nil {
+		return nil, err
+	}
+	if cfg.LogMaxSize <= 0 {
+		return nil, fmt.Errorf("LOG_MAX_SIZE must be positive")
+	}
+	if cfg.LogMaxFiles, err = getInt("LOG_MAX_FILES", 5); err != nil {
+		return nil, err
+	}
+	if cfg.LogMaxFiles < 0 {
+		return nil, fmt.Errorf("LOG_MAX_FILES must not be negative")
+	}
+
+	if cfg.LeaseTTL, err = getDuration("HA_LEASE_TTL", 0); err != nil {
+		return nil, err
+	}
+	if cfg.LeaseTTL > 0 && cfg.LeaseTTL < 3*time.Second {
+		return nil, fmt.Errorf("HA_LEASE_TTL must be at least 3s")
+	}
+	cfg.InstanceID = getEnv("INSTANCE_ID", defaultInstanceID())
+
+	if err := file.checkUnused(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
+
+// defaultInstanceID names this process by host and PID, which is unique
+// among instances sharing a database unless two hosts share a name.
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "vault"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// EmailsEvent reports whether notifications of kind are also emailed to
+// NOTIFY_EMAIL_TO.
+func (c *Config) EmailsEvent(kind string) bool {
+	if c.SMTPHost == "" || len(c.EmailTo) == 0 {
+		return false
+	}
+	for _, k := range c.EmailEvents {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// BridgesEvent reports whether notifications of kind are forwarded to the
+// Slack and Matrix bridges.
+func (c *Config) BridgesEvent(kind string) bool {
+	for _, k := range c.BridgeEvents {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// DatabaseURL returns the metadata store location: a SQLite file path or a
+// postgres:// connection URL.
+func DatabaseURL() string {
+	return getEnv("DATABASE_URL", "./metadata.db")
+}
+
+// IsAdmin reports whether the given owner ID may see and manage every file.
+func (c *Config) IsAdmin(id string) bool {
+	for _, admin := range c.Live().AdminUsers {
+		if admin == id {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProxy reads HTTPS_PROXY, an http:// URL, and SOCKS_PROXY, a
+// socks5:// URL; either may be given as just host:port. The Discord gateway
+// can only tunnel through these two kinds, so https:// proxies are refused.
+func parseProxy(httpsProxy, socksProxy string) (*url.URL, error) {
+	name, raw, scheme := "HTTPS_PROXY", strings.TrimSpace(httpsProxy), "http"
+	if socks := strings.TrimSpace(socksProxy); socks != "" {
+		if raw != "" {
+			return nil, fmt.Errorf("HTTPS_PROXY and SOCKS_PROXY cannot both be set")
+		}
+		name, raw, scheme = "SOCKS_PROXY", socks, "socks5"
+	}
+	if raw == "" {
+		return nil, nil
+	}
+	if !strings.Contains(raw, "://") {
+		raw = scheme + "://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != scheme || u.Host == "" {
+		return nil, fmt.Errorf("%s must be a %s://host:port URL", name, scheme)
+	}
+	return u, nil
+}
+
+// StorageChannel returns the channel holding the chunks of a vault:
+// DISCORD_CHANNEL_ID for the default vault.
+func (c *Config) StorageChannel(guildID string) string {
+	if channel, ok := c.GuildChannels[guildID]; ok {
+		return channel
+	}
+	return c.ChannelID
+}
+
+// Shards returns the channels new chunks of the vault stored in channelID
+// are spread over: the default channel and SHARD_CHANNELS for the default
+// vault, channelID alone for the others.
+func (c *Config) Shards(channelID string) []string {
+	if channelID != c.ChannelID || len(c.ShardChannels) == 0 {
+		return []string{channelID}
+	}
+	shards := []string{c.ChannelID}
+	for _, channel := range c.ShardChannels {
+		if !slices.Contains(shards, channel) {
+			shards = append(shards, channel)
+		}
+	}
+	return shards
+}
+
+// StorageChannels lists every configured storage channel, default and its
+// shards first, followed by the replica channels.
+func (c *Config) StorageChannels() []string {
+	channels := c.Shards(c.ChannelID)
+	for _, channel := range c.GuildChannels {
+		if !slices.Contains(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	for _, channel := range c.ReplicaChannels {
+		if !slices.Contains(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// CommandLevel returns the permission level required to run a bot command.
+func (c *Config) CommandLevel(command string) string {
+	if level, ok := c.Live().CommandLevels[command]; ok {
+		return level
+	}
+	return PermMember
+}
+
+// PrivateReplies reports whether replies to a bot command are only shown to
+// the user who ran it.
+func (c *Config) PrivateReplies(command string) bool {
+	if visibility, ok := c.CommandReplies[command]; ok {
+		return visibility == ResponsesPrivate
+	}
+	return c.Responses == ResponsesPrivate
+}
+
+// ValidDuplicatePolicy reports whether p names a duplicate filename policy.
+func ValidDuplicatePolicy(p string) bool {
+	return p == "suffix" || p == "version" || p == "reject"
+}
+
+func getEnv(name, fallback string) string {
+	if v := env(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// getDuration parses a Go duration such as "30m" or "6h". "0" disables
+// interval based features.
+func getDuration(name string, fallback time.Duration) (time.Duration, error) {
+	v := env(name)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration like 30m or 6h: %w", name, err)
+	}
+	return d, nil
+}
+
+func getInt(name string, fallback int) (int, error) {
+	v := env(name)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number: %w", name, err)
+	}
+	return n, nil
+}
+
+// secret returns the option name, or the contents of the file named by
+// name_FILE, such as a mounted Docker or Kubernetes secret, so the secret
+// need not be put in the environment. A trailing newline in the file is
+// dropped.
+func secret(name string) (string, error) {
+	path := env(name + "_FILE")
+	if path == "" {
+		return env(name), nil
+	}
+	if env(name) != "" {
+		return "", fmt.Errorf("%s and %s_FILE cannot both be set", name, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// getBytes parses a size with ParseSize.
+func getBytes(name string, fallback int64) (int64, error) {
+	v := env(name)
+	if strings.TrimSpace(v) == "" {
+		return fallback, nil
+	}
+	n, err := ParseSize(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a size like 500MB or 2GB", name)
+	}
+	return n, nil
+}
+
+// ParseSize parses a size such as "500MB" or "2GB" (binary units); a bare
+// number is taken as bytes.
+func ParseSize(v string) (int64, error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	multiplier := int64(1)
+	for i, unit := range []string{"KB", "MB", "GB", "TB"} {
+		if strings.HasSuffix(v, unit) {
+			multiplier = 1 << (10 * (i + 1))
+			v = strings.TrimSpace(strings.TrimSuffix(v, unit))
+			break
+		}
+	}
+	v = strings.TrimSuffix(v, "B")
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a size like 500MB or 2GB", v)
+	}
+	return n * multiplier, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFiles are looked for in the working directory, in this
+// order, when CONFIG_FILE is not set.
+var DefaultConfigFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// mapOptions are the list options whose entries are key-value pairs, with
+// the separator an entry uses. In a config file they may be written as a
+// mapping.
+var mapOptions = map[string]string{
+	"GUILD_CHANNELS":      ":",
+	"API_KEYS":            ":",
+	"COMMAND_PERMISSIONS": "=",
+	"COMMAND_RESPONSES":   "=",
+	"COMMAND_COOLDOWNS":   "=",
+	"ARTIFACT_KEEP":       "=",
+	"RETRY_ATTEMPTS":      "=",
+}
+
+// fileSettings are the options of a config file, by environment variable
+// name. Load records which ones it reads, so misspelled keys are reported.
+type fileSettings struct {
+	path   string
+	values map[string]string
+
+	mu   sync.Mutex
+	used map[string]bool
+}
+
+// settings is the config file in effect; nil until one is read.
+var settings atomic.Pointer[fileSettings]
+
+// env returns the environment variable name, or else the value the config
+// file gives it. An empty variable counts as unset, so placeholders left
+// empty in .env do not hide the file.
+func env(name string) string {
+	s := settings.Load()
+	if s == nil {
+		// Callers outside Load, like DatabaseURL for the migrate command.
+		// Errors in the file are reported by Load.
+		if s, _ = readSettings(); s != nil {
+			settings.CompareAndSwap(nil, s)
+		}
+	}
+	var fromFile string
+	if s != nil {
+		s.mu.Lock()
+		s.used[name] = true
+		fromFile = s.values[name]
+		s.mu.Unlock()
+	}
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fromFile
+}
+
+// readSettings reads CONFIG_FILE, or the first of DefaultConfigFiles that
+// exists. With no file it returns empty settings.
+func readSettings() (*fileSettings, error) {
+	s := &fileSettings{values: make(map[string]string), used: make(map[string]bool)}
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		for _, name := range DefaultConfigFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+		if path == "" {
+			return s, nil
+		}
+	}
+	s.path = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	var doc map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	if err := flatten(s.values, "", doc); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// flatten stores the options of a config file section under their
+// environment variable names. Nested sections join their keys with
+// underscores, so discord: {channel_id: 1} is DISCORD_CHANNEL_ID, and lists
+// become comma-separated.
+func flatten(values map[string]string, prefix string, section map[string]any) error {
+	for key, v := range section {
+		name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+		if sep, ok := mapOptions[name]; ok {
+			if m, ok := asSection(v); ok {
+				var entries []string
+				for k, entry := range m {
+					s, err := scalar(name, entry)
+					if err != nil {
+						return err
+					}
+					entries = append(entries, k+sep+s)
+				}
+				slices.Sort(entries)
+				values[name] = strings.Join(entries, ",")
+				continue
+			}
+		}
+		if m, ok := asSection(v); ok {
+			if err := flatten(values, name, m); err != nil {
+				return err
+			}
+			continue
+		}
+		if list, ok := v.([]any); ok {
+			entries := make([]string, len(list))
+			for i, entry := range list {
+				s, err := scalar(name, entry)
+				if err != nil {
+					return err
+				}
+				entries[i] = s
+			}
+			values[name] = strings.Join(entries, ",")
+			continue
+		}
+		s, err := scalar(name, v)
+		if err != nil {
+			return err
+		}
+		values[name] = s
+	}
+	return nil
+}
+
+// asSection returns v as a mapping with string keys. YAML decodes mappings
+// with non-string keys, such as guild IDs, as map[any]any.
+func asSection(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case map[any]any:
+		section := make(map[string]any, len(m))
+		for k, child := range m {
+			section[fmt.Sprint(k)] = child
+		}
+		return section, true
+	}
+	return nil, false
+}
+
+func scalar(name string, v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	}
+	return "", fmt.Errorf("%s must be a value or a list of values", name)
+}
+
+// checkUnused reports options of the config file Load did not read, which
+// are most likely misspelled.
+func (s *fileSettings) checkUnused() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var unknown []string
+	for name := range s.values {
+		if !s.used[name] {
+			unknown = append(unknown, strings.ToLower(name))
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	slices.Sort(unknown)
+	return errors.New("config file " + s.path + ": unknown options " + strings.Join(unknown, ", "))
+}
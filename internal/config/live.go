@@ -0,0 +1,32 @@
+package config
+
+import "time"
+
+// Live holds the options that can change while the vault runs: who may use
+// it, transfer quotas, command rate limits, and the log level. Reload swaps them as a
+// whole, so a reader sees either the old or the new set, never a mix, and
+// uploads in flight are not interrupted.
+type Live struct {
+	AllowedUsers  []string
+	AdminUsers    []string
+	AllowedRoles  []string                 // Discord role IDs treated like ALLOWED_USERS
+	AdminRoles    []string                 // Discord role IDs treated like ADMIN_USERS
+	CommandLevels map[string]string        // bot command -> PermAnyone, PermMember, or PermAdmin
+	Cooldowns     map[string]time.Duration // bot command -> minimum time between uses per user
+	APIKeys       map[string]string        // API key -> owner ID
+	TransferCap   int64                    // monthly upload+download bytes per user, 0 = unlimited
+	LogLevel      string                   // lowest logstream level written to the log
+}
+
+// Live returns the reloadable options in effect. Callers should hold on to
+// the result only for the request or command at hand.
+func (c *Config) Live() *Live {
+	return c.live.Load()
+}
+
+// Reload takes over the reloadable options of fresh, a configuration just
+// read with Load. Every other option keeps the value it started with until
+// the next restart.
+func (c *Config) Reload(fresh *Config) {
+	c.live.Store(fresh.Live())
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a daily time range such as 22:00-06:00, in the vault's TIMEZONE.
+// A window whose end is before its start wraps past midnight.
+type Window struct {
+	Start, End time.Duration // offsets from midnight
+}
+
+// ParseWindow parses "HH:MM-HH:MM".
+func ParseWindow(s string) (*Window, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("window %q must be in the form HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, fmt.Errorf("window %q is empty", s)
+	}
+	return &Window{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Until returns how long after t the window next opens, or 0 while it is
+// open. t should already be in the vault's location.
+func (w *Window) Until(t time.Time) time.Duration {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	now := t.Sub(midnight)
+	if w.Start < w.End {
+		if now >= w.Start && now < w.End {
+			return 0
+		}
+	} else if now >= w.Start || now < w.End {
+		return 0
+	}
+
+	open := midnight.Add(w.Start)
+	if !open.After(t) {
+		open = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()).Add(w.Start)
+	}
+	return open.Sub(t)
+}
+
+func (w *Window) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(w.Start) + "-" + clock(w.End)
+}
@@ -3,11 +3,22 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io"
 )
 
+// Format describes the on-Discord ciphertext layout. Every chunk attachment is
+// nonce || AES-256-GCM(plaintext) || tag, with no additional framing.
+const (
+	Algorithm = "AES-256-GCM"
+	NonceSize = 12
+	TagSize   = 16
+)
+
 func Encrypt(data []byte, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -46,3 +57,15 @@ func Decrypt(data []byte, key []byte) ([]byte, error) {
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
 	return gcm.Open(nil, nonce, ciphertext, nil)
 }
+
+// Digest returns a keyed SHA-256 of a chunk's plaintext. Equal chunks have
+// equal digests, so unchanged chunks of a new version can be recognized,
+// while the digests alone reveal nothing about the contents to someone
+// without the key.
+func Digest(data []byte, key []byte) string {
+	derive := hmac.New(sha256.New, key)
+	derive.Write([]byte("chunk digest"))
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
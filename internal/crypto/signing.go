@@ -0,0 +1,132 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Signer holds the vault's Ed25519 identity key, used to sign artifacts
+// (metadata backups, export manifests, share payloads) so recipients can
+// verify they came from this vault.
+type Signer struct {
+	private ed25519.PrivateKey
+}
+
+// LoadOrCreateSigner reads the identity key seed from path, generating and
+// persisting a new one on first run. The seed is stored encrypted with the
+// vault encryption key.
+func LoadOrCreateSigner(path string, key []byte) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		seed := make([]byte, ed25519.SeedSize)
+		if _, err := rand.Read(seed); err != nil {
+			return nil, err
+		}
+		encrypted, err := Encrypt(seed, key)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, encrypted, 0600); err != nil {
+			return nil, fmt.Errorf("failed to persist signing key: %w", err)
+		}
+		return &Signer{private: ed25519.NewKeyFromSeed(seed)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return unlockSigner(data, key)
+}
+
+// LoadSigner reads the identity key seed from path like LoadOrCreateSigner,
+// but fails instead of creating one when there is none.
+func LoadSigner(path string, key []byte) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return unlockSigner(data, key)
+}
+
+func unlockSigner(data, key []byte) (*Signer, error) {
+	seed, err := Decrypt(data, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock signing key (wrong ENCRYPTION_KEY?): %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, errors.New("signing key file is corrupt")
+	}
+	return &Signer{private: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.private.Public().(ed25519.PublicKey)
+}
+
+// PublicKeyString returns the base64 encoded public key.
+func (s *Signer) PublicKeyString() string {
+	return base64.StdEncoding.EncodeToString(s.PublicKey())
+}
+
+// Fingerprint is a short, human comparable identifier of the public key.
+func (s *Signer) Fingerprint() string {
+	sum := sha256.Sum256(s.PublicKey())
+	return hex.EncodeToString(sum[:8])
+}
+
+// Sign returns a base64 encoded signature over data.
+func (s *Signer) Sign(data []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.private, data))
+}
+
+// Verify checks a base64 signature produced by Sign against a base64 public key.
+func Verify(publicKey string, data []byte, signature string) bool {
+	pub, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(pub, data, sig)
+}
+
+// Envelope wraps a JSON payload with a detached signature by the vault
+// identity key. The signature covers the exact payload bytes.
+type Envelope struct {
+	Payload   json.RawMessage `json:"payload"`
+	PublicKey string          `json:"public_key"`
+	Signature string          `json:"signature"`
+}
+
+// Seal marshals v and signs the result.
+func (s *Signer) Seal(v any) (*Envelope, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{Payload: payload, PublicKey: s.PublicKeyString(), Signature: s.Sign(payload)}, nil
+}
+
+// Open verifies the envelope and unmarshals its payload into v. When
+// trustedKey is empty the embedded public key is used, which proves integrity
+// but not origin.
+func (e *Envelope) Open(trustedKey string, v any) error {
+	key := trustedKey
+	if key == "" {
+		key = e.PublicKey
+	} else if e.PublicKey != "" && e.PublicKey != trustedKey {
+		return errors.New("envelope was signed by a different vault")
+	}
+	if !Verify(key, e.Payload, e.Signature) {
+		return errors.New("envelope signature is invalid")
+	}
+	return json.Unmarshal(e.Payload, v)
+}
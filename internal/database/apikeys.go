@@ -0,0 +1,107 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// API key scopes. A read key may only make GET requests; an admin key has
+// the admin override as long as its owner is an admin.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// APIKey is a key a user created. The key itself is only known when it is
+// created; the database keeps its SHA-256.
+type APIKey struct {
+	ID         string     `json:"id"`
+	OwnerID    string     `json:"owner_id"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+const apiKeyColumns = `id, owner_id, name, scope, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var k APIKey
+	var used, revoked sql.NullTime
+	if err := row.Scan(&k.ID, &k.OwnerID, &k.Name, &k.Scope, &k.CreatedAt, &used, &revoked); err != nil {
+		return nil, err
+	}
+	if used.Valid {
+		k.LastUsedAt = &used.Time
+	}
+	if revoked.Valid {
+		k.RevokedAt = &revoked.Time
+	}
+	return &k, nil
+}
+
+// CreateAPIKey stores k under the SHA-256 of its key.
+func (db *Database) CreateAPIKey(k *APIKey, keyHash string) error {
+	now := time.Now().UTC()
+	_, err := db.exec(`INSERT INTO api_keys (id, owner_id, name, scope, key_hash, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		k.ID, k.OwnerID, k.Name, k.Scope, keyHash, now.Format(timeLayout))
+	if err == nil {
+		k.CreatedAt = now
+	}
+	return err
+}
+
+// APIKeyByHash returns the unrevoked key with that SHA-256, or
+// sql.ErrNoRows.
+func (db *Database) APIKeyByHash(keyHash string) (*APIKey, error) {
+	return scanAPIKey(db.queryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL`, keyHash))
+}
+
+// GetAPIKey returns sql.ErrNoRows for unknown keys.
+func (db *Database) GetAPIKey(id string) (*APIKey, error) {
+	return scanAPIKey(db.queryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id))
+}
+
+// ListAPIKeys returns the keys of ownerID, or every key when it is empty,
+// revoked ones included, newest first.
+func (db *Database) ListAPIKeys(ownerID string) ([]APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys`
+	var args []any
+	if ownerID != "" {
+		query += ` WHERE owner_id = ?`
+		args = append(args, ownerID)
+	}
+	rows, err := db.query(query+` ORDER BY created_at DESC, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+// TouchAPIKey records that a key was used now, unless it already was since
+// the given time, so busy keys are not written on every request.
+func (db *Database) TouchAPIKey(id string, since time.Time) error {
+	_, err := db.exec(`UPDATE api_keys SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)`,
+		time.Now().UTC().Format(timeLayout), id, since.UTC().Format(timeLayout))
+	return err
+}
+
+// RevokeAPIKey stops a key from working. Revoking it again changes
+// nothing.
+func (db *Database) RevokeAPIKey(id string) error {
+	_, err := db.exec(`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`,
+		time.Now().UTC().Format(timeLayout), id)
+	return err
+}
@@ -0,0 +1,81 @@
+package database
+
+import "time"
+
+// Artifact is the immutable build metadata recorded for a CI upload.
+type Artifact struct {
+	FileID    int       `json:"file_id"`
+	Project   string    `json:"project"`
+	Branch    string    `json:"branch"`
+	Commit    string    `json:"commit"`
+	RunID     string    `json:"run_id"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const artifactColumns = `a.file_id, a.project, a.branch, a.commit_sha, a.run_id, a.name, f.size, a.created_at`
+
+func (db *Database) SaveArtifact(a *Artifact) error {
+	_, err := db.exec(`INSERT INTO artifacts (file_id, project, branch, commit_sha, run_id, name) VALUES (?, ?, ?, ?, ?, ?)`,
+		a.FileID, a.Project, a.Branch, a.Commit, a.RunID, a.Name)
+	return err
+}
+
+// LatestArtifact returns the newest artifact for project and branch, limited
+// to one artifact name when name is set and to one uploader when ownerID is.
+func (db *Database) LatestArtifact(project, branch, name, ownerID string) (*Artifact, error) {
+	query := `SELECT ` + artifactColumns + ` FROM artifacts a JOIN files f ON f.id = a.file_id
+		WHERE a.project = ? AND a.branch = ?`
+	args := []any{project, branch}
+	if ownerID != "" {
+		query += ` AND f.owner_id = ?`
+		args = append(args, ownerID)
+	}
+	if name != "" {
+		query += ` AND a.name = ?`
+		args = append(args, name)
+	}
+	query += ` ORDER BY a.created_at DESC, a.file_id DESC LIMIT 1`
+
+	var a Artifact
+	err := db.queryRow(query, args...).Scan(&a.FileID, &a.Project, &a.Branch, &a.Commit, &a.RunID, &a.Name, &a.Size, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ListArtifacts returns artifacts newest first, optionally filtered by
+// project, branch, and uploader.
+func (db *Database) ListArtifacts(project, branch, ownerID string) ([]Artifact, error) {
+	query := `SELECT ` + artifactColumns + ` FROM artifacts a JOIN files f ON f.id = a.file_id WHERE 1 = 1`
+	var args []any
+	if ownerID != "" {
+		query += ` AND f.owner_id = ?`
+		args = append(args, ownerID)
+	}
+	if project != "" {
+		query += ` AND a.project = ?`
+		args = append(args, project)
+	}
+	if branch != "" {
+		query += ` AND a.branch = ?`
+		args = append(args, branch)
+	}
+	rows, err := db.query(query+` ORDER BY a.project, a.branch, a.created_at DESC, a.file_id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	artifacts := []Artifact{}
+	for rows.Next() {
+		var a Artifact
+		if err := rows.Scan(&a.FileID, &a.Project, &a.Branch, &a.Commit, &a.RunID, &a.Name, &a.Size, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, a)
+	}
+	return artifacts, rows.Err()
+}
@@ -0,0 +1,257 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrJobNameTaken is returned when another backup job has the name.
+var ErrJobNameTaken = errors.New("a backup job with that name already exists")
+
+// Backup run states.
+const (
+	RunRunning = "running"
+	RunOK      = "ok"
+	RunFailed  = "failed"
+)
+
+// backupRunHistory is how many runs are kept per backup job.
+const backupRunHistory = 50
+
+// BackupJob backs up Source, a server path or an http(s) URL, whenever the
+// cron expression Schedule fires. Every run stores a new version of the same
+// vault file; Keep limits how many versions are kept (0 keeps all).
+type BackupJob struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	Source    string     `json:"source"`
+	FolderID  int        `json:"folder_id"`
+	Keep      int        `json:"keep"`
+	Enabled   bool       `json:"enabled"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	NextRunAt time.Time  `json:"next_run_at"`
+	LastRun   *BackupRun `json:"last_run,omitempty"`
+}
+
+// BackupRun is one run of a backup job.
+type BackupRun struct {
+	ID         int        `json:"id"`
+	JobID      int        `json:"job_id"`
+	Status     string     `json:"status"`
+	FileID     int        `json:"file_id,omitempty"`
+	Size       int64      `json:"size"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+const backupJobColumns = `id, name, schedule, source, folder_id, keep, enabled, created_by, created_at, next_run_at`
+
+func scanBackupJob(row rowScanner) (*BackupJob, error) {
+	var j BackupJob
+	if err := row.Scan(&j.ID, &j.Name, &j.Schedule, &j.Source, &j.FolderID, &j.Keep, &j.Enabled, &j.CreatedBy, &j.CreatedAt, &j.NextRunAt); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+const backupRunColumns = `id, job_id, status, file_id, size, error, started_at, finished_at`
+
+func scanBackupRun(row rowScanner) (*BackupRun, error) {
+	var r BackupRun
+	var finished sql.NullTime
+	if err := row.Scan(&r.ID, &r.JobID, &r.Status, &r.FileID, &r.Size, &r.Error, &r.StartedAt, &finished); err != nil {
+		return nil, err
+	}
+	if finished.Valid {
+		r.FinishedAt = &finished.Time
+	}
+	return &r, nil
+}
+
+// CreateBackupJob stores a new job, or returns ErrJobNameTaken.
+func (db *Database) CreateBackupJob(j *BackupJob) (int, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM backup_jobs WHERE name = ?`, j.Name).Scan(&n); err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		return 0, ErrJobNameTaken
+	}
+	var id int
+	if err := tx.QueryRow(`INSERT INTO backup_jobs (name, schedule, source, folder_id, keep, enabled, created_by, next_run_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		j.Name, j.Schedule, j.Source, j.FolderID, j.Keep, j.Enabled, j.CreatedBy, j.NextRunAt.UTC().Format(timeLayout)).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (db *Database) GetBackupJob(id int) (*BackupJob, error) {
+	return scanBackupJob(db.queryRow(`SELECT `+backupJobColumns+` FROM backup_jobs WHERE id = ?`, id))
+}
+
+func (db *Database) ListBackupJobs() ([]BackupJob, error) {
+	return db.queryBackupJobs(`SELECT ` + backupJobColumns + ` FROM backup_jobs ORDER BY id`)
+}
+
+// DueBackupJobs returns the enabled jobs whose next run is at or before now.
+func (db *Database) DueBackupJobs(now time.Time) ([]BackupJob, error) {
+	return db.queryBackupJobs(`SELECT `+backupJobColumns+` FROM backup_jobs WHERE enabled = ? AND next_run_at <= ? ORDER BY next_run_at`,
+		true, now.UTC().Format(timeLayout))
+}
+
+func (db *Database) queryBackupJobs(query string, args ...any) ([]BackupJob, error) {
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []BackupJob{}
+	for rows.Next() {
+		j, err := scanBackupJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *j)
+	}
+	return jobs, rows.Err()
+}
+
+// UpdateBackupJob replaces the settings and next run of an existing job, or
+// returns ErrJobNameTaken.
+func (db *Database) UpdateBackupJob(j *BackupJob) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM backup_jobs WHERE name = ? AND id <> ?`, j.Name, j.ID).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrJobNameTaken
+	}
+	res, err := tx.Exec(`UPDATE backup_jobs SET name = ?, schedule = ?, source = ?, folder_id = ?, keep = ?, enabled = ?, next_run_at = ? WHERE id = ?`,
+		j.Name, j.Schedule, j.Source, j.FolderID, j.Keep, j.Enabled, j.NextRunAt.UTC().Format(timeLayout), j.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// SetBackupJobNextRun schedules the next run of a job.
+func (db *Database) SetBackupJobNextRun(id int, next time.Time) error {
+	_, err := db.exec(`UPDATE backup_jobs SET next_run_at = ? WHERE id = ?`, next.UTC().Format(timeLayout), id)
+	return err
+}
+
+// DeleteBackupJob removes a job and its run history. Files it stored stay.
+func (db *Database) DeleteBackupJob(id int) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM backup_runs WHERE job_id = ?`, id); err != nil {
+		return err
+	}
+	res, err := tx.Exec(`DELETE FROM backup_jobs WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// StartBackupRun records that a run of the job began.
+func (db *Database) StartBackupRun(jobID int) (int, error) {
+	var id int
+	err := db.queryRow(`INSERT INTO backup_runs (job_id, status, started_at) VALUES (?, ?, ?) RETURNING id`,
+		jobID, RunRunning, time.Now().UTC().Format(timeLayout)).Scan(&id)
+	return id, err
+}
+
+// FinishBackupRun records the outcome of a run and drops the oldest runs of
+// the job beyond the kept history.
+func (db *Database) FinishBackupRun(id int, status string, fileID int, size int64, errText string) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var jobID int
+	if err := tx.QueryRow(`UPDATE backup_runs SET status = ?, file_id = ?, size = ?, error = ?, finished_at = ? WHERE id = ? RETURNING job_id`,
+		status, fileID, size, errText, time.Now().UTC().Format(timeLayout), id).Scan(&jobID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM backup_runs WHERE job_id = ? AND id NOT IN
+		(SELECT id FROM backup_runs WHERE job_id = ? ORDER BY id DESC LIMIT ?)`, jobID, jobID, backupRunHistory); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AbandonBackupRuns marks runs left running by a stopped server as failed.
+func (db *Database) AbandonBackupRuns() error {
+	_, err := db.exec(`UPDATE backup_runs SET status = ?, error = ?, finished_at = ? WHERE status = ?`,
+		RunFailed, "interrupted by a server restart", time.Now().UTC().Format(timeLayout), RunRunning)
+	return err
+}
+
+// ListBackupRuns returns the recorded runs of a job, newest first.
+func (db *Database) ListBackupRuns(jobID int) ([]BackupRun, error) {
+	rows, err := db.query(`SELECT `+backupRunColumns+` FROM backup_runs WHERE job_id = ? ORDER BY id DESC`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []BackupRun{}
+	for rows.Next() {
+		r, err := scanBackupRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *r)
+	}
+	return runs, rows.Err()
+}
+
+// LastBackupRuns returns the newest run of every job that has run, by job ID.
+func (db *Database) LastBackupRuns() (map[int]*BackupRun, error) {
+	rows, err := db.query(`SELECT ` + backupRunColumns + ` FROM backup_runs
+		WHERE id IN (SELECT MAX(id) FROM backup_runs GROUP BY job_id)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make(map[int]*BackupRun)
+	for rows.Next() {
+		r, err := scanBackupRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs[r.JobID] = r
+	}
+	return runs, rows.Err()
+}
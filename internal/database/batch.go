@@ -0,0 +1,163 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Batch operation names.
+const (
+	BatchRename = "rename"
+	BatchMove   = "move"
+	BatchTag    = "tag"
+	BatchDelete = "delete"
+	BatchRetain = "retain"
+)
+
+var (
+	ErrNameTaken      = errors.New("a file with that name already exists")
+	ErrFolderNotFound = errors.New("folder not found")
+)
+
+// BatchOp is one step of an ApplyBatch call.
+type BatchOp struct {
+	Op         string
+	FileID     int
+	Name       string    // rename
+	FolderID   int       // move; 0 = vault root
+	AddTags    []string  // tag
+	RemoveTags []string  // tag
+	ExpiresAt  time.Time // retain; zero keeps the file indefinitely
+	PurgedBy   string    // delete; set with PURGE_GRACE to keep the file restorable
+}
+
+// BatchError reports which operation made a batch roll back.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error { return e.Err }
+
+// ApplyBatch runs ops in a single transaction: either all of them take effect
+// or none do. Deletes go through releaseFile, or softDeleteFile when they
+// name who purged the file, keeping the batch itself free of Discord calls.
+func (db *Database) ApplyBatch(ops []BatchOp) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, op := range ops {
+		if err := applyBatchOp(tx, op); err != nil {
+			return &BatchError{Index: i, Err: err}
+		}
+	}
+	return tx.Commit()
+}
+
+func applyBatchOp(tx *Tx, op BatchOp) error {
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM files WHERE id = ?`, op.FileID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return fmt.Errorf("file %d not found", op.FileID)
+	}
+
+	switch op.Op {
+	case BatchRename:
+		if op.Name == "" {
+			return errors.New("name is required")
+		}
+		var current, guildID string
+		if err := tx.QueryRow(`SELECT name, guild_id FROM files WHERE id = ?`, op.FileID).Scan(&current, &guildID); err != nil {
+			return err
+		}
+		var taken int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM files WHERE guild_id = ? AND name = ? AND name != ? AND superseded_at IS NULL`, guildID, op.Name, current).Scan(&taken); err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrNameTaken
+		}
+		// Older versions follow the rename so they stay linked to the file.
+		_, err := tx.Exec(`UPDATE files SET name = ? WHERE guild_id = ? AND name = ?`, op.Name, guildID, current)
+		return err
+
+	case BatchMove:
+		var folder any
+		if op.FolderID != 0 {
+			var found int
+			if err := tx.QueryRow(`SELECT COUNT(*) FROM folders WHERE id = ?`, op.FolderID).Scan(&found); err != nil {
+				return err
+			}
+			if found == 0 {
+				return ErrFolderNotFound
+			}
+			folder = op.FolderID
+		}
+		_, err := tx.Exec(`UPDATE files SET folder_id = ? WHERE id = ?`, folder, op.FileID)
+		return err
+
+	case BatchTag:
+		for _, tag := range op.AddTags {
+			if _, err := tx.Exec(`INSERT INTO file_tags (file_id, tag) VALUES (?, ?) ON CONFLICT (file_id, tag) DO NOTHING`, op.FileID, tag); err != nil {
+				return err
+			}
+		}
+		for _, tag := range op.RemoveTags {
+			if _, err := tx.Exec(`DELETE FROM file_tags WHERE file_id = ? AND tag = ?`, op.FileID, tag); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case BatchDelete:
+		if op.PurgedBy != "" {
+			return softDeleteFile(tx, op.FileID, op.PurgedBy)
+		}
+		return releaseFile(tx, op.FileID)
+
+	case BatchRetain:
+		if op.ExpiresAt.IsZero() {
+			_, err := tx.Exec(`DELETE FROM file_retention WHERE file_id = ?`, op.FileID)
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO file_retention (file_id, expires_at) VALUES (?, ?)
+			ON CONFLICT (file_id) DO UPDATE SET expires_at = excluded.expires_at`, op.FileID, op.ExpiresAt.UTC().Format(timeLayout))
+		return err
+	}
+	return fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// ReleaseFile deletes a file's metadata and hands all of its chunk messages
+// to released_chunks, so the compaction job removes them from Discord after
+// the retention window instead of the caller deleting them right away.
+func (db *Database) ReleaseFile(id int) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := releaseFile(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func releaseFile(tx *Tx, id int) error {
+	if _, err := tx.Exec(`INSERT INTO released_chunks (message_id, channel_id)
+		SELECT DISTINCT message_id, channel_id FROM chunks WHERE file_id = ?
+		ON CONFLICT (message_id) DO NOTHING`, id); err != nil {
+		return err
+	}
+	return deleteFileRows(tx, id)
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// ExclusiveChunks returns the chunks of fileID whose Discord messages are not
+// referenced by any other file, i.e. the ones safe to delete right away.
+func (db *Database) ExclusiveChunks(fileID int) ([]ChunkMetadata, error) {
+	query := `SELECT ` + chunkColumns + ` FROM chunks c
+		WHERE file_id = ? AND NOT EXISTS (
+			SELECT 1 FROM chunks o WHERE o.message_id = c.message_id AND o.file_id != c.file_id
+		) ORDER BY part_num ASC`
+	rows, err := db.query(query, fileID)
+	if err != nil {
+		return nil, err
+	}
+	return scanChunks(rows)
+}
+
+// CompactionCandidates returns released messages older than cutoff that no
+// chunk row, live or purged, references anymore. Only MessageID and ChannelID
+// are set.
+func (db *Database) CompactionCandidates(cutoff time.Time) ([]ChunkMetadata, error) {
+	query := `SELECT message_id, channel_id FROM released_chunks r
+		WHERE released_at <= ?
+			AND NOT EXISTS (SELECT 1 FROM chunks c WHERE c.message_id = r.message_id)
+			AND NOT EXISTS (SELECT 1 FROM purged_chunks p WHERE p.message_id = r.message_id)
+		ORDER BY released_at ASC`
+	rows, err := db.query(query, cutoff.UTC().Format(timeLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []ChunkMetadata
+	for rows.Next() {
+		var c ChunkMetadata
+		if err := rows.Scan(&c.MessageID, &c.ChannelID); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, c)
+	}
+	return msgs, rows.Err()
+}
+
+// ForgetReleased drops message IDs from the release log once they have been
+// deleted from Discord.
+func (db *Database) ForgetReleased(messageIDs []string) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range messageIDs {
+		if _, err := tx.Exec(`DELETE FROM released_chunks WHERE message_id = ?`, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// KnownMessageIDs returns which of ids are referenced by a chunk row, still
+// waiting in the release log, held for a purged file, stored by an
+// unfinished upload session, recorded as a replica, or a parity chunk.
+func (db *Database) KnownMessageIDs(ids []string) (map[string]bool, error) {
+	known := make(map[string]bool)
+	if len(ids) == 0 {
+		return known, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, 0, len(ids)*6)
+	for range 6 {
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	rows, err := db.query(`SELECT message_id FROM chunks WHERE message_id IN (`+placeholders+`)
+		UNION SELECT message_id FROM released_chunks WHERE message_id IN (`+placeholders+`)
+		UNION SELECT message_id FROM purged_chunks WHERE message_id IN (`+placeholders+`)
+		UNION SELECT message_id FROM upload_session_chunks WHERE message_id IN (`+placeholders+`)
+		UNION SELECT replica_message_id FROM chunk_replicas WHERE replica_message_id IN (`+placeholders+`)
+		UNION SELECT message_id FROM parity_chunks WHERE message_id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		known[id] = true
+	}
+	return known, rows.Err()
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// StorageSnapshot is the size of the whole vault at the end of a day, or
+// now for the current day.
+type StorageSnapshot struct {
+	Day         string    `json:"day"` // YYYY-MM-DD, UTC
+	Files       int       `json:"files"`
+	Versions    int       `json:"versions"`
+	Bytes       int64     `json:"bytes"`
+	Chunks      int       `json:"chunks"`
+	StoredBytes int64     `json:"stored_bytes"` // recorded ciphertext size
+	RecordedAt  time.Time `json:"recorded_at"`
+}
+
+// RecordStorageSnapshot stores the current totals of every vault as the
+// snapshot of today.
+func (db *Database) RecordStorageSnapshot() error {
+	now := time.Now().UTC()
+	s := StorageSnapshot{Day: now.Format("2006-01-02")}
+	if err := db.queryRow(`SELECT COUNT(*), COALESCE(SUM(size), 0) FROM files`).Scan(&s.Versions, &s.Bytes); err != nil {
+		return err
+	}
+	if err := db.queryRow(`SELECT COUNT(*) FROM files WHERE superseded_at IS NULL`).Scan(&s.Files); err != nil {
+		return err
+	}
+	if err := db.queryRow(`SELECT COUNT(*), COALESCE(SUM(size), 0) FROM chunks`).Scan(&s.Chunks, &s.StoredBytes); err != nil {
+		return err
+	}
+	_, err := db.exec(`INSERT INTO storage_snapshots (day, files, versions, bytes, chunks, stored_bytes, recorded_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (day) DO UPDATE SET files = excluded.files, versions = excluded.versions, bytes = excluded.bytes,
+			chunks = excluded.chunks, stored_bytes = excluded.stored_bytes, recorded_at = excluded.recorded_at`,
+		s.Day, s.Files, s.Versions, s.Bytes, s.Chunks, s.StoredBytes, now.Format(timeLayout))
+	return err
+}
+
+// StorageGrowth returns the snapshots of the last days, oldest first. Days
+// without a snapshot, before the first one or while the vault was down,
+// are missing.
+func (db *Database) StorageGrowth(days int) ([]StorageSnapshot, error) {
+	since := time.Now().UTC().AddDate(0, 0, -days+1).Format("2006-01-02")
+	rows, err := db.query(`SELECT day, files, versions, bytes, chunks, stored_bytes, recorded_at FROM storage_snapshots
+		WHERE day >= ? ORDER BY day`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []StorageSnapshot{}
+	for rows.Next() {
+		var s StorageSnapshot
+		if err := rows.Scan(&s.Day, &s.Files, &s.Versions, &s.Bytes, &s.Chunks, &s.StoredBytes, &s.RecordedAt); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// StorageByOwner returns the versions and bytes each owner stores across
+// every vault, most first.
+func (db *Database) StorageByOwner() ([]OwnerUsage, error) {
+	return db.ownerUsage("1 = 1", nil)
+}
+
+// Activity is one entry of the recent activity feed: an upload, or an
+// event of the operational history.
+type Activity struct {
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"kind"` // "upload" or a vault_events kind
+	GuildID  string    `json:"guild_id"`
+	FileID   int       `json:"file_id,omitempty"`
+	FileName string    `json:"file_name,omitempty"`
+	OwnerID  string    `json:"owner_id,omitempty"` // uploads only
+	Size     int64     `json:"size"`
+	Detail   string    `json:"detail,omitempty"`
+}
+
+// RecentActivity returns the latest limit uploads, deletions, and
+// verification failures across every vault, newest first.
+func (db *Database) RecentActivity(limit int) ([]Activity, error) {
+	files, err := db.queryFiles(`SELECT `+fileColumns+` FROM files ORDER BY created_at DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	activity := []Activity{}
+	for _, f := range files {
+		activity = append(activity, Activity{Time: f.CreatedAt, Kind: "upload", GuildID: f.GuildID, FileID: f.ID, FileName: f.Name, OwnerID: f.OwnerID, Size: f.Size})
+	}
+
+	events, err := db.recentEvents(limit, EventDelete, EventVerifyFailed)
+	if err != nil {
+		return nil, err
+	}
+	for _, ev := range events {
+		activity = append(activity, Activity{Time: ev.CreatedAt, Kind: ev.Kind, GuildID: ev.GuildID, FileID: ev.FileID, FileName: ev.FileName, Size: ev.Size, Detail: ev.Detail})
+	}
+
+	slices.SortStableFunc(activity, func(a, b Activity) int { return b.Time.Compare(a.Time) })
+	return activity[:min(limit, len(activity))], nil
+}
+
+// RecentEvents returns the latest limit events of kind, newest first.
+func (db *Database) RecentEvents(kind string, limit int) ([]VaultEvent, error) {
+	return db.recentEvents(limit, kind)
+}
+
+func (db *Database) recentEvents(limit int, kinds ...string) ([]VaultEvent, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(kinds)), ",")
+	args := make([]any, 0, len(kinds)+1)
+	for _, kind := range kinds {
+		args = append(args, kind)
+	}
+	rows, err := db.query(`SELECT id, kind, guild_id, COALESCE(file_id, 0), file_name, size, detail, created_at FROM vault_events
+		WHERE kind IN (`+placeholders+`) ORDER BY created_at DESC, id DESC LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []VaultEvent{}
+	for rows.Next() {
+		var ev VaultEvent
+		if err := rows.Scan(&ev.ID, &ev.Kind, &ev.GuildID, &ev.FileID, &ev.FileName, &ev.Size, &ev.Detail, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+// UnhealthyFiles returns every file, in any vault, whose last scrub found it
+// degraded or corrupt or that waits for or failed repair, with its repair
+// state.
+func (db *Database) UnhealthyFiles() ([]FileMetadata, error) {
+	files, err := db.queryFiles(`SELECT `+fileColumns+` FROM files
+		WHERE health IN (?, ?) OR id IN (SELECT file_id FROM file_repairs)
+		ORDER BY created_at DESC`, HealthDegraded, HealthCorrupt)
+	if err != nil {
+		return nil, err
+	}
+	repairs, err := db.FileRepairs()
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		files[i].Repair = repairs[files[i].ID]
+	}
+	if files == nil {
+		files = []FileMetadata{}
+	}
+	return files, nil
+}
@@ -2,14 +2,30 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"path"
+	"strings"
 	"time"
 
 	_ "github.com/glebarez/go-sqlite"
+	_ "github.com/lib/pq"
 )
 
+// timeLayout is how timestamps are passed as query parameters so they compare
+// correctly against SQLite's CURRENT_TIMESTAMP text and Postgres TIMESTAMP.
+const timeLayout = "2006-01-02 15:04:05"
+
+// DefaultVault is the guild ID of the vault that lives in the default storage
+// channel; guilds without a channel of their own share it.
+const DefaultVault = ""
+
+// AllVaults makes Stats cover every vault at once.
+const AllVaults = "*"
+
 type Database struct {
-	Conn *sql.DB
+	Conn    *sql.DB
+	dialect dialect
 }
 
 type FileMetadata struct {
@@ -17,7 +33,52 @@ type FileMetadata struct {
 	Name      string
 	Size      int64
 	Hash      string
+	OwnerID   string
+	FolderID  int    // 0 = vault root
+	GuildID   string // vault the file belongs to, DefaultVault or a guild ID
+	ChunkSize int64  // plaintext bytes per chunk but the last, 0 for files stored before sizes were recorded (7MB)
+	Version   int
 	CreatedAt time.Time
+
+	// Every ParityData chunks share ParityChunks Reed-Solomon parity chunks;
+	// both are 0 for files stored without parity.
+	ParityData   int `json:",omitempty"`
+	ParityChunks int `json:",omitempty"`
+
+	// Health is the result of the last scrub, one of the Health constants, or
+	// "" if the file was never scrubbed.
+	Health       string     `json:",omitempty"`
+	HealthDetail string     `json:",omitempty"`
+	CheckedAt    *time.Time `json:",omitempty"`
+
+	// Repair is set by listings for files with a lost or damaged chunk.
+	Repair *FileRepair `json:",omitempty"`
+
+	// SupersededAt is set once a newer version with the same name exists.
+	SupersededAt *time.Time `json:",omitempty"`
+}
+
+// fileColumns is the column list scanned by scanFile.
+const fileColumns = `id, name, size, hash, owner_id, COALESCE(folder_id, 0), version, created_at, superseded_at, guild_id, chunk_size, parity_data, parity_chunks, health, health_detail, checked_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFile(row rowScanner) (*FileMetadata, error) {
+	var f FileMetadata
+	var superseded, checked sql.NullTime
+	if err := row.Scan(&f.ID, &f.Name, &f.Size, &f.Hash, &f.OwnerID, &f.FolderID, &f.Version, &f.CreatedAt, &superseded, &f.GuildID, &f.ChunkSize, &f.ParityData, &f.ParityChunks,
+		&f.Health, &f.HealthDetail, &checked); err != nil {
+		return nil, err
+	}
+	if superseded.Valid {
+		f.SupersededAt = &superseded.Time
+	}
+	if checked.Valid {
+		f.CheckedAt = &checked.Time
+	}
+	return &f, nil
 }
 
 type ChunkMetadata struct {
@@ -25,75 +86,98 @@ type ChunkMetadata struct {
 	FileID    int
 	MessageID string
 	PartNum   int
+	Size      int64  // ciphertext bytes, 0 for chunks stored before sizes were recorded
+	SHA256    string // hex sha256 of the ciphertext, "" when unknown
+	ChannelID string // storage channel, "" for chunks stored before vaults had their own
+	Digest    string // keyed hash of the plaintext (crypto.Digest), "" when unknown
 }
 
-func Initialize(path string) (*Database, error) {
-	db, err := sql.Open("sqlite", path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+// chunkColumns is the column list scanned by scanChunks.
+const chunkColumns = `id, file_id, message_id, part_num, size, sha256, channel_id, digest`
+
+func scanChunks(rows *sql.Rows) ([]ChunkMetadata, error) {
+	defer rows.Close()
+
+	var chunks []ChunkMetadata
+	for rows.Next() {
+		var c ChunkMetadata
+		if err := rows.Scan(&c.ID, &c.FileID, &c.MessageID, &c.PartNum, &c.Size, &c.SHA256, &c.ChannelID, &c.Digest); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
 	}
+	return chunks, rows.Err()
+}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+// Open connects to the metadata store named by url (a SQLite file path or a
+// postgres:// URL) and applies pending migrations.
+func Open(url string) (*Database, error) {
+	db, err := Connect(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.migrate(); err != nil {
+		db.Conn.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
 	}
+	return db, nil
+}
 
-	// Enable Foreign Keys
-	if _, err := db.Exec("PRAGMA foreign_keys = ON;"); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+// Connect connects to the metadata store like Open but leaves the schema as
+// it is, for commands that move it to a version of their own.
+func Connect(url string) (*Database, error) {
+	d, dsn := dialectFor(url)
+	conn, err := sql.Open(d.Driver(), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := createTables(db); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &Database{Conn: db}, nil
+	return &Database{Conn: conn, dialect: d}, nil
 }
 
-func createTables(db *sql.DB) error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS files (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL UNIQUE,
-			size INTEGER NOT NULL,
-			hash TEXT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);`,
-		`CREATE TABLE IF NOT EXISTS chunks (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			file_id INTEGER NOT NULL,
-			message_id TEXT NOT NULL,
-			part_num INTEGER NOT NULL,
-			FOREIGN KEY(file_id) REFERENCES files(id) ON DELETE CASCADE
-		);`,
-	}
+// Backend returns the name of the active SQL backend ("sqlite" or "postgres").
+func (db *Database) Backend() string {
+	return db.dialect.Name()
+}
 
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return err
-		}
-	}
-	return nil
+// ListFiles returns the current version of every file in a vault.
+func (db *Database) ListFiles(guildID string) ([]FileMetadata, error) {
+	query := `SELECT ` + fileColumns + ` FROM files WHERE guild_id = ? AND superseded_at IS NULL ORDER BY created_at DESC`
+	return db.queryFiles(query, guildID)
 }
 
-func (db *Database) SaveFile(name string, size int64, hash string) (int, error) {
-	query := `INSERT INTO files (name, size, hash) VALUES (?, ?, ?) RETURNING id`
-	var id int
-	err := db.Conn.QueryRow(query, name, size, hash).Scan(&id)
-	if err != nil {
-		return 0, err
-	}
-	return id, nil
+// ListFilesByOwner returns only the files in a vault uploaded by the given
+// owner ID.
+func (db *Database) ListFilesByOwner(guildID, ownerID string) ([]FileMetadata, error) {
+	query := `SELECT ` + fileColumns + ` FROM files WHERE guild_id = ? AND owner_id = ? AND superseded_at IS NULL ORDER BY created_at DESC`
+	return db.queryFiles(query, guildID, ownerID)
 }
 
-func (db *Database) SaveChunk(fileID int, messageID string, partNum int) error {
-	query := `INSERT INTO chunks (file_id, message_id, part_num) VALUES (?, ?, ?)`
-	_, err := db.Conn.Exec(query, fileID, messageID, partNum)
-	return err
+// PageFiles returns one page of a vault's current files, newest first, and
+// the total count. ownerID limits the listing to one owner when set.
+func (db *Database) PageFiles(guildID, ownerID string, limit, offset int) ([]FileMetadata, int, error) {
+	where := ` FROM files WHERE guild_id = ? AND superseded_at IS NULL`
+	args := []any{guildID}
+	if ownerID != "" {
+		where += ` AND owner_id = ?`
+		args = append(args, ownerID)
+	}
+
+	var total int
+	if err := db.queryRow(`SELECT COUNT(*)`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	files, err := db.queryFiles(`SELECT `+fileColumns+where+` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	return files, total, err
 }
 
-func (db *Database) ListFiles() ([]FileMetadata, error) {
-	query := `SELECT id, name, size, hash, created_at FROM files ORDER BY created_at DESC`
-	rows, err := db.Conn.Query(query)
+func (db *Database) queryFiles(query string, args ...any) ([]FileMetadata, error) {
+	rows, err := db.query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -101,53 +185,224 @@ func (db *Database) ListFiles() ([]FileMetadata, error) {
 
 	var files []FileMetadata
 	for rows.Next() {
-		var f FileMetadata
-		if err := rows.Scan(&f.ID, &f.Name, &f.Size, &f.Hash, &f.CreatedAt); err != nil {
+		f, err := scanFile(rows)
+		if err != nil {
 			return nil, err
 		}
-		files = append(files, f)
+		files = append(files, *f)
 	}
 	return files, nil
 }
 
 func (db *Database) GetFile(id int) (*FileMetadata, error) {
-	query := `SELECT id, name, size, hash, created_at FROM files WHERE id = ?`
-	var f FileMetadata
-	err := db.Conn.QueryRow(query, id).Scan(&f.ID, &f.Name, &f.Size, &f.Hash, &f.CreatedAt)
+	query := `SELECT ` + fileColumns + ` FROM files WHERE id = ?`
+	return scanFile(db.queryRow(query, id))
+}
+
+// GetFileByName returns the current version of the named file in a vault.
+func (db *Database) GetFileByName(guildID, name string) (*FileMetadata, error) {
+	query := `SELECT ` + fileColumns + ` FROM files WHERE guild_id = ? AND name = ? AND superseded_at IS NULL`
+	return scanFile(db.queryRow(query, guildID, name))
+}
+
+// DeleteFile removes the file row and its chunk rows. Chunk messages that are
+// still referenced by another file are recorded in released_chunks so the
+// compaction job can reclaim them once nothing points at them anymore.
+// Deleting the current version of a file makes the previous one current.
+func (db *Database) DeleteFile(id int) error {
+	tx, err := db.begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &f, nil
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO released_chunks (message_id, channel_id)
+		SELECT DISTINCT message_id, channel_id FROM chunks
+		WHERE file_id = ? AND message_id IN (SELECT message_id FROM chunks WHERE file_id != ?)
+		ON CONFLICT (message_id) DO NOTHING`, id, id)
+	if err != nil {
+		return err
+	}
+
+	if err := deleteFileRows(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (db *Database) DeleteFile(id int) error {
-	// Cascading delete should handle chunks due to foreign key constraint,
-	// but we must ensure foreign keys are enabled or delete manually.
-	// SQLite supports FKs but they might need to be enabled.
-	// Safest is to rely on ON DELETE CASCADE if enabled, or just delete chunks first?
-	// The table definition HAS "ON DELETE CASCADE".
-	// We just need to ensure PRAGMA foreign_keys = ON; is set.
-	// I'll add that to Initialize or just trust manual deletion for now to be safe.
-
-	_, err := db.Conn.Exec("DELETE FROM files WHERE id = ?", id)
+// deleteFileRows removes a file with its chunks, parity, tags, and artifact record, records the
+// deletion for the digest and, if it was the current version, promotes the newest older
+// version in its place.
+func deleteFileRows(tx *Tx, id int) error {
+	var name, guildID string
+	var size int64
+	var superseded sql.NullTime
+	if err := tx.QueryRow(`SELECT name, guild_id, size, superseded_at FROM files WHERE id = ?`, id).Scan(&name, &guildID, &size, &superseded); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO vault_events (kind, guild_id, file_id, file_name, size) VALUES (?, ?, ?, ?, ?)`,
+		EventDelete, guildID, id, name, size); err != nil {
+		return err
+	}
+
+	// Parity chunks belong to this file alone; compaction deletes them.
+	if err := dropParity(tx, id); err != nil {
+		return err
+	}
+
+	for _, stmt := range []string{
+		`DELETE FROM chunks WHERE file_id = ?`,
+		`DELETE FROM file_tags WHERE file_id = ?`,
+		`DELETE FROM artifacts WHERE file_id = ?`,
+		`UPDATE release_channels SET file_id = NULL WHERE file_id = ?`,
+		`DELETE FROM files WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+
+	if superseded.Valid {
+		return nil
+	}
+	_, err := tx.Exec(`UPDATE files SET superseded_at = NULL WHERE id = (
+		SELECT id FROM files WHERE guild_id = ? AND name = ? ORDER BY version DESC LIMIT 1
+	)`, guildID, name)
 	return err
 }
 
 func (db *Database) GetChunks(fileID int) ([]ChunkMetadata, error) {
-	query := `SELECT id, file_id, message_id, part_num FROM chunks WHERE file_id = ? ORDER BY part_num ASC`
-	rows, err := db.Conn.Query(query, fileID)
+	query := `SELECT ` + chunkColumns + ` FROM chunks WHERE file_id = ? ORDER BY part_num ASC`
+	rows, err := db.query(query, fileID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanChunks(rows)
+}
 
-	var chunks []ChunkMetadata
-	for rows.Next() {
-		var c ChunkMetadata
-		if err := rows.Scan(&c.ID, &c.FileID, &c.MessageID, &c.PartNum); err != nil {
+// ListFilesOlderThan returns files created before cutoff, oldest first.
+func (db *Database) ListFilesOlderThan(cutoff time.Time) ([]FileMetadata, error) {
+	query := `SELECT ` + fileColumns + ` FROM files WHERE created_at < ? ORDER BY created_at ASC`
+	return db.queryFiles(query, cutoff.UTC().Format(timeLayout))
+}
+
+// Duplicate filename policies for SaveUpload.
+const (
+	DuplicateReject  = "reject"
+	DuplicateSuffix  = "suffix"
+	DuplicateVersion = "version"
+)
+
+// ErrChunkGone is returned by SaveUpload when a chunk reused from another
+// file was deleted with that file while the upload ran.
+var ErrChunkGone = errors.New("a reused chunk was deleted during the upload")
+
+// SaveUpload records a file stored in chunks of chunkSize plaintext bytes and
+// all of its chunks (numbered in slice order) in one transaction, so a
+// failure never leaves a file row with missing chunks behind. When the name
+// is already taken, policy decides whether the upload is renamed to
+// "name (2).ext", stored as a new version of the existing file, or rejected
+// with ErrNameTaken. Only the owner of a file can add versions to it.
+// Chunks with an ID are rows of another file whose messages the upload
+// shares.
+func (db *Database) SaveUpload(guildID, name string, size, chunkSize int64, hash string, ownerID string, chunks []ChunkMetadata, policy string) (*FileMetadata, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	version, folderID := 1, 0
+	var previous int
+	var previousOwner string
+	err = tx.QueryRow(`SELECT id, owner_id, version, COALESCE(folder_id, 0) FROM files WHERE guild_id = ? AND name = ? AND superseded_at IS NULL`, guildID, name).
+		Scan(&previous, &previousOwner, &version, &folderID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		version, folderID = 1, 0
+	case err != nil:
+		return nil, err
+	case policy == DuplicateVersion && previousOwner == ownerID:
+		version++
+		if _, err := tx.Exec(`UPDATE files SET superseded_at = ? WHERE id = ?`, time.Now().UTC().Format(timeLayout), previous); err != nil {
+			return nil, err
+		}
+	case policy == DuplicateSuffix:
+		if name, err = freeName(tx, guildID, name); err != nil {
+			return nil, err
+		}
+		previous, version, folderID = 0, 1, 0
+	default:
+		return nil, ErrNameTaken
+	}
+
+	var folder any
+	if folderID != 0 {
+		folder = folderID
+	}
+	var id int
+	if err := tx.QueryRow(`INSERT INTO files (name, size, hash, owner_id, folder_id, version, guild_id, chunk_size) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		name, size, hash, ownerID, folder, version, guildID, chunkSize).Scan(&id); err != nil {
+		return nil, err
+	}
+	for idx, c := range chunks {
+		// A chunk reused from an earlier version is only safe to share
+		// while that version still references it.
+		if c.ID != 0 {
+			var exists int
+			err := tx.QueryRow(`SELECT COUNT(*) FROM chunks WHERE id = ? AND message_id = ?`, c.ID, c.MessageID).Scan(&exists)
+			if err != nil {
+				return nil, err
+			}
+			if exists == 0 {
+				return nil, ErrChunkGone
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO chunks (file_id, message_id, part_num, size, sha256, channel_id, digest) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, c.MessageID, idx+1, c.Size, c.SHA256, c.ChannelID, c.Digest); err != nil {
+			return nil, err
+		}
+	}
+	if version > 1 {
+		if _, err := tx.Exec(`INSERT INTO file_tags (file_id, tag) SELECT ?, tag FROM file_tags WHERE file_id = ?`, id, previous); err != nil {
 			return nil, err
 		}
-		chunks = append(chunks, c)
 	}
-	return chunks, nil
+
+	file, err := scanFile(tx.QueryRow(`SELECT `+fileColumns+` FROM files WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+	return file, tx.Commit()
+}
+
+// freeName returns name with the lowest " (n)" suffix not used by a current
+// file in the vault, keeping compound extensions like .tar.gz intact.
+func freeName(tx *Tx, guildID, name string) (string, error) {
+	base, ext := name, path.Ext(name)
+	if ext == name {
+		ext = "" // dotfile such as ".env"
+	}
+	base = strings.TrimSuffix(name, ext)
+	if inner := path.Ext(base); strings.EqualFold(inner, ".tar") && inner != base {
+		base, ext = strings.TrimSuffix(base, inner), inner+ext
+	}
+
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, n, ext)
+		var taken int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM files WHERE guild_id = ? AND name = ? AND superseded_at IS NULL`, guildID, candidate).Scan(&taken); err != nil {
+			return "", err
+		}
+		if taken == 0 {
+			return candidate, nil
+		}
+	}
+}
+
+// ListVersions returns every stored version of the named file in a vault,
+// newest first.
+func (db *Database) ListVersions(guildID, name string) ([]FileMetadata, error) {
+	query := `SELECT ` + fileColumns + ` FROM files WHERE guild_id = ? AND name = ? ORDER BY version DESC`
+	return db.queryFiles(query, guildID, name)
 }
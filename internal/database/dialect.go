@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// dialect captures everything that differs between the supported SQL
+// backends. Queries are written once with "?" placeholders and rebound per
+// backend; schema changes live in per-dialect migration directories.
+type dialect interface {
+	Name() string
+	Driver() string
+	// MigrationsDir is the directory under migrations/ holding this backend's scripts.
+	MigrationsDir() string
+	Rebind(query string) string
+	TableExistsQuery() string
+	ColumnExistsQuery() string
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string               { return "sqlite" }
+func (sqliteDialect) Driver() string             { return "sqlite" }
+func (sqliteDialect) MigrationsDir() string      { return "sqlite" }
+func (sqliteDialect) Rebind(query string) string { return query }
+func (sqliteDialect) TableExistsQuery() string {
+	return `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+}
+func (sqliteDialect) ColumnExistsQuery() string {
+	return `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string          { return "postgres" }
+func (postgresDialect) Driver() string        { return "postgres" }
+func (postgresDialect) MigrationsDir() string { return "postgres" }
+
+// Rebind turns "?" placeholders into "$1", "$2", ... outside of string literals.
+func (postgresDialect) Rebind(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query) + 8)
+	n := 0
+	inString := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+		case r == '?' && !inString:
+			n++
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func (postgresDialect) TableExistsQuery() string {
+	return `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+}
+
+func (postgresDialect) ColumnExistsQuery() string {
+	return `SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`
+}
+
+// dialectFor picks the backend from a DATABASE_URL value. Anything that is not
+// a postgres:// URL is treated as a SQLite file path.
+func dialectFor(url string) (dialect, string) {
+	switch {
+	case strings.HasPrefix(url, "postgres://"), strings.HasPrefix(url, "postgresql://"):
+		return postgresDialect{}, url
+	case strings.HasPrefix(url, "sqlite://"):
+		return sqliteDialect{}, sqliteDSN(strings.TrimPrefix(url, "sqlite://"))
+	default:
+		return sqliteDialect{}, sqliteDSN(url)
+	}
+}
+
+// sqlitePragmas are applied to every pooled connection. WAL lets the web
+// server and bot read while an upload writes, and the busy timeout makes
+// concurrent writers wait instead of failing with "database is locked".
+var sqlitePragmas = []struct{ name, value string }{
+	{"journal_mode", "WAL"},
+	{"busy_timeout", "5000"},
+	{"foreign_keys", "1"},
+	{"synchronous", "NORMAL"},
+}
+
+// sqliteDSN appends the default pragmas to path unless the caller already
+// set them in the query string (e.g. "?_pragma=busy_timeout(15000)").
+func sqliteDSN(path string) string {
+	base, query, _ := strings.Cut(path, "?")
+	params := []string{}
+	if query != "" {
+		params = append(params, query)
+	}
+	for _, p := range sqlitePragmas {
+		if strings.Contains(query, p.name) {
+			continue
+		}
+		params = append(params, "_pragma="+p.name+"("+p.value+")")
+	}
+	return base + "?" + strings.Join(params, "&")
+}
+
+func (db *Database) exec(query string, args ...any) (sql.Result, error) {
+	return db.Conn.Exec(db.dialect.Rebind(query), args...)
+}
+
+func (db *Database) query(query string, args ...any) (*sql.Rows, error) {
+	return db.Conn.Query(db.dialect.Rebind(query), args...)
+}
+
+func (db *Database) queryRow(query string, args ...any) *sql.Row {
+	return db.Conn.QueryRow(db.dialect.Rebind(query), args...)
+}
+
+// Tx is a transaction that rebinds placeholders for the active dialect.
+type Tx struct {
+	tx      *sql.Tx
+	dialect dialect
+}
+
+func (db *Database) begin() (*Tx, error) {
+	tx, err := db.Conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx: tx, dialect: db.dialect}, nil
+}
+
+func (t *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return t.tx.Exec(t.dialect.Rebind(query), args...)
+}
+
+func (t *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.tx.Query(t.dialect.Rebind(query), args...)
+}
+
+func (t *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return t.tx.QueryRow(t.dialect.Rebind(query), args...)
+}
+
+func (t *Tx) Commit() error   { return t.tx.Commit() }
+func (t *Tx) Rollback() error { return t.tx.Rollback() }
+
+// SQLitePath returns the database file for a SQLite DATABASE_URL, or false
+// for other backends.
+func SQLitePath(url string) (string, bool) {
+	d, dsn := dialectFor(url)
+	if d.Name() != "sqlite" {
+		return "", false
+	}
+	path, _, _ := strings.Cut(dsn, "?")
+	return path, true
+}
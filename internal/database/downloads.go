@@ -0,0 +1,56 @@
+package database
+
+import "time"
+
+// DownloadToken is a single-use link handed out by the bot's /download
+// command for files too large to attach.
+type DownloadToken struct {
+	Token     string
+	FileID    int
+	UserID    string
+	ExpiresAt time.Time
+}
+
+func (db *Database) CreateDownloadToken(t *DownloadToken) error {
+	_, err := db.exec(`INSERT INTO download_tokens (token, file_id, user_id, expires_at) VALUES (?, ?, ?, ?)`,
+		t.Token, t.FileID, t.UserID, t.ExpiresAt.UTC().Format(timeLayout))
+	return err
+}
+
+// GetDownloadToken returns an unexpired, unused token without using it up,
+// or sql.ErrNoRows.
+func (db *Database) GetDownloadToken(token string) (*DownloadToken, error) {
+	var t DownloadToken
+	err := db.queryRow(`SELECT token, file_id, user_id, expires_at FROM download_tokens
+		WHERE token = ? AND used_at IS NULL AND expires_at > ?`, token, time.Now().UTC().Format(timeLayout)).
+		Scan(&t.Token, &t.FileID, &t.UserID, &t.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ConsumeDownloadToken marks an unexpired token as used and returns it. A
+// token that is unknown, expired, or already used yields sql.ErrNoRows.
+func (db *Database) ConsumeDownloadToken(token string) (*DownloadToken, error) {
+	now := time.Now().UTC().Format(timeLayout)
+	var t DownloadToken
+	err := db.queryRow(`UPDATE download_tokens SET used_at = ?
+		WHERE token = ? AND used_at IS NULL AND expires_at > ?
+		RETURNING token, file_id, user_id, expires_at`, now, token, now).
+		Scan(&t.Token, &t.FileID, &t.UserID, &t.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ExpireDownloadTokens removes used and expired tokens.
+func (db *Database) ExpireDownloadTokens() (int64, error) {
+	res, err := db.exec(`DELETE FROM download_tokens WHERE used_at IS NOT NULL OR expires_at <= ?`,
+		time.Now().UTC().Format(timeLayout))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// DuplicateGroup is a set of stored files in one vault with identical
+// content. Files are ordered oldest first; consolidation keeps the first
+// one's chunks.
+type DuplicateGroup struct {
+	GuildID string         `json:"guild_id,omitempty"`
+	Hash    string         `json:"hash"`
+	Size    int64          `json:"size"`
+	Files   []FileMetadata `json:"files"`
+	// Messages counts the distinct chunk messages the group occupies, and
+	// Reclaimable how many of them consolidation would free.
+	Messages    int `json:"messages"`
+	Reclaimable int `json:"reclaimable"`
+}
+
+// DuplicateGroups returns every group of files sharing a content hash whose
+// copies are still stored separately. Vaults never share chunks, so files in
+// different vaults are never grouped.
+func (db *Database) DuplicateGroups() ([]DuplicateGroup, error) {
+	files, err := db.queryFiles(`SELECT ` + fileColumns + ` FROM files f
+		WHERE hash != '' AND EXISTS (
+			SELECT 1 FROM files o WHERE o.guild_id = f.guild_id AND o.hash = f.hash AND o.size = f.size AND o.id != f.id
+		) ORDER BY guild_id, hash, size, id`)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []DuplicateGroup
+	for _, f := range files {
+		if n := len(groups); n > 0 && groups[n-1].GuildID == f.GuildID && groups[n-1].Hash == f.Hash && groups[n-1].Size == f.Size {
+			groups[n-1].Files = append(groups[n-1].Files, f)
+			continue
+		}
+		groups = append(groups, DuplicateGroup{GuildID: f.GuildID, Hash: f.Hash, Size: f.Size, Files: []FileMetadata{f}})
+	}
+
+	kept := groups[:0]
+	for _, g := range groups {
+		ids := make([]any, len(g.Files))
+		for i, f := range g.Files {
+			ids[i] = f.ID
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+		if err := db.queryRow(`SELECT COUNT(DISTINCT message_id) FROM chunks WHERE file_id IN (`+placeholders+`)`, ids...).Scan(&g.Messages); err != nil {
+			return nil, err
+		}
+		var canonical int
+		if err := db.queryRow(`SELECT COUNT(DISTINCT message_id) FROM chunks WHERE file_id = ?`, g.Files[0].ID).Scan(&canonical); err != nil {
+			return nil, err
+		}
+		if g.Reclaimable = g.Messages - canonical; g.Reclaimable > 0 {
+			kept = append(kept, g)
+		}
+	}
+	return kept, nil
+}
+
+// ConsolidateDuplicates turns every file in a vault with the given hash into
+// an alias of the oldest one: their chunk rows are replaced by the oldest file's, and the
+// messages they used alone are released for compaction. Names, owners,
+// versions, and tags are unchanged. It returns how many messages were
+// released, or sql.ErrNoRows if the hash has no duplicates.
+func (db *Database) ConsolidateDuplicates(guildID, hash string) (int, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var keep int
+	var size int64
+	if err := tx.QueryRow(`SELECT id, size FROM files WHERE guild_id = ? AND hash = ? ORDER BY id ASC LIMIT 1`, guildID, hash).Scan(&keep, &size); err != nil {
+		return 0, err
+	}
+	rows, err := tx.Query(`SELECT id FROM files WHERE guild_id = ? AND hash = ? AND size = ? AND id != ?`, guildID, hash, size, keep)
+	if err != nil {
+		return 0, err
+	}
+	var aliases []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		aliases = append(aliases, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(aliases) == 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	before, err := groupMessages(tx, guildID, hash, size)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range aliases {
+		if _, err := tx.Exec(`INSERT INTO released_chunks (message_id, channel_id)
+			SELECT DISTINCT message_id, channel_id FROM chunks WHERE file_id = ?
+				AND message_id NOT IN (SELECT message_id FROM chunks WHERE file_id = ?)
+			ON CONFLICT (message_id) DO NOTHING`, id, keep); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM chunks WHERE file_id = ?`, id); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`INSERT INTO chunks (file_id, message_id, part_num, size, sha256, channel_id, digest)
+			SELECT ?, message_id, part_num, size, sha256, channel_id, digest FROM chunks WHERE file_id = ?`, id, keep); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`UPDATE files SET chunk_size = (SELECT chunk_size FROM files WHERE id = ?) WHERE id = ?`, keep, id); err != nil {
+			return 0, err
+		}
+		if err := dropParity(tx, id); err != nil {
+			return 0, err
+		}
+	}
+	after, err := groupMessages(tx, guildID, hash, size)
+	if err != nil {
+		return 0, err
+	}
+	return before - after, tx.Commit()
+}
+
+// groupMessages counts the distinct chunk messages used by files in a vault
+// with the given content.
+func groupMessages(tx *Tx, guildID, hash string, size int64) (int, error) {
+	var n int
+	err := tx.QueryRow(`SELECT COUNT(DISTINCT message_id) FROM chunks
+		WHERE file_id IN (SELECT id FROM files WHERE guild_id = ? AND hash = ? AND size = ?)`, guildID, hash, size).Scan(&n)
+	return n, err
+}
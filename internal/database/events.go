@@ -0,0 +1,110 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Kinds of vault_events rows.
+const (
+	EventDelete       = "delete"
+	EventVerifyFailed = "verify_failed"
+	EventDigest       = "digest"
+)
+
+// VaultEvent is one entry of the operational history the digest reports on.
+type VaultEvent struct {
+	ID        int       `json:"id"`
+	Kind      string    `json:"kind"`
+	GuildID   string    `json:"guild_id"`
+	FileID    int       `json:"file_id,omitempty"` // 0 when no file was saved, e.g. a rejected chunk
+	FileName  string    `json:"file_name,omitempty"`
+	Size      int64     `json:"size"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecordEvent appends an event to the history.
+func (db *Database) RecordEvent(ev VaultEvent) error {
+	_, err := db.exec(`INSERT INTO vault_events (kind, guild_id, file_id, file_name, size, detail) VALUES (?, ?, ?, ?, ?, ?)`,
+		ev.Kind, ev.GuildID, nullableID(ev.FileID), ev.FileName, ev.Size, ev.Detail)
+	return err
+}
+
+func nullableID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// LastEvent returns when the newest event of kind was recorded, or nil if
+// there is none.
+func (db *Database) LastEvent(kind string) (*time.Time, error) {
+	var at time.Time
+	err := db.queryRow(`SELECT created_at FROM vault_events WHERE kind = ? ORDER BY created_at DESC LIMIT 1`, kind).Scan(&at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &at, nil
+}
+
+// PruneEvents removes events recorded before cutoff.
+func (db *Database) PruneEvents(cutoff time.Time) (int64, error) {
+	res, err := db.exec(`DELETE FROM vault_events WHERE created_at < ?`, cutoff.UTC().Format(timeLayout))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Digest summarizes vault activity since a point in time, across every vault.
+type Digest struct {
+	Since          time.Time
+	Uploads        int
+	UploadedBytes  int64
+	Deletes        int
+	DeletedBytes   int64
+	Files          int   // current files now
+	TotalBytes     int64 // all versions now
+	VerifyFailures []VaultEvent
+}
+
+// DigestSince collects the numbers for a scheduled summary report.
+func (db *Database) DigestSince(since time.Time) (*Digest, error) {
+	d := &Digest{Since: since, VerifyFailures: []VaultEvent{}}
+	from := since.UTC().Format(timeLayout)
+
+	if err := db.queryRow(`SELECT COUNT(*), COALESCE(SUM(size), 0) FROM files WHERE created_at >= ?`, from).Scan(&d.Uploads, &d.UploadedBytes); err != nil {
+		return nil, err
+	}
+	if err := db.queryRow(`SELECT COUNT(*), COALESCE(SUM(size), 0) FROM vault_events WHERE kind = ? AND created_at >= ?`,
+		EventDelete, from).Scan(&d.Deletes, &d.DeletedBytes); err != nil {
+		return nil, err
+	}
+	if err := db.queryRow(`SELECT COUNT(*) FROM files WHERE superseded_at IS NULL`).Scan(&d.Files); err != nil {
+		return nil, err
+	}
+	if err := db.queryRow(`SELECT COALESCE(SUM(size), 0) FROM files`).Scan(&d.TotalBytes); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.query(`SELECT id, kind, guild_id, COALESCE(file_id, 0), file_name, size, detail, created_at
+		FROM vault_events WHERE kind = ? AND created_at >= ? ORDER BY created_at ASC`, EventVerifyFailed, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ev VaultEvent
+		if err := rows.Scan(&ev.ID, &ev.Kind, &ev.GuildID, &ev.FileID, &ev.FileName, &ev.Size, &ev.Detail, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		d.VerifyFailures = append(d.VerifyFailures, ev)
+	}
+	return d, rows.Err()
+}
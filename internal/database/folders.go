@@ -0,0 +1,105 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+type Folder struct {
+	ID        int
+	Name      string
+	ParentID  int // 0 = vault root
+	OwnerID   string
+	CreatedAt time.Time
+}
+
+const folderColumns = `id, name, COALESCE(parent_id, 0), owner_id, created_at`
+
+func (db *Database) CreateFolder(name string, parentID int, ownerID string) (int, error) {
+	var parent any
+	if parentID != 0 {
+		parent = parentID
+	}
+	var id int
+	err := db.queryRow(`INSERT INTO folders (name, parent_id, owner_id) VALUES (?, ?, ?) RETURNING id`, name, parent, ownerID).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (db *Database) GetFolder(id int) (*Folder, error) {
+	var f Folder
+	err := db.queryRow(`SELECT `+folderColumns+` FROM folders WHERE id = ?`, id).Scan(&f.ID, &f.Name, &f.ParentID, &f.OwnerID, &f.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// ListFolders returns every folder, or only those of ownerID when it is set.
+func (db *Database) ListFolders(ownerID string) ([]Folder, error) {
+	query := `SELECT ` + folderColumns + ` FROM folders`
+	var args []any
+	if ownerID != "" {
+		query += ` WHERE owner_id = ?`
+		args = append(args, ownerID)
+	}
+	rows, err := db.query(query+` ORDER BY name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var folders []Folder
+	for rows.Next() {
+		var f Folder
+		if err := rows.Scan(&f.ID, &f.Name, &f.ParentID, &f.OwnerID, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
+
+// FolderFiles returns the current files of every vault filed in one of
+// folderIDs, by name.
+func (db *Database) FolderFiles(folderIDs []int) ([]FileMetadata, error) {
+	if len(folderIDs) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(folderIDs))
+	for i, id := range folderIDs {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(folderIDs)), ",")
+	return db.queryFiles(`SELECT `+fileColumns+` FROM files WHERE folder_id IN (`+placeholders+`) AND superseded_at IS NULL ORDER BY name`, args...)
+}
+
+// GetTags returns the tags of a file in alphabetical order.
+func (db *Database) GetTags(fileID int) ([]string, error) {
+	rows, err := db.query(`SELECT tag FROM file_tags WHERE file_id = ? ORDER BY tag`, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// NormalizeTag lowercases and trims a tag; it returns "" for unusable input.
+func NormalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if len(tag) > 64 {
+		return ""
+	}
+	return tag
+}
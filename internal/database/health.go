@@ -0,0 +1,42 @@
+package database
+
+import "time"
+
+// Health states recorded by the integrity scrub.
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // chunks were lost but the file can be restored
+	HealthCorrupt  = "corrupt"
+
+	// HealthUnchecked stands for files never checked, which record no
+	// health, in stats and searches.
+	HealthUnchecked = "unchecked"
+)
+
+// SetFileHealth records the result of checking a file now.
+func (db *Database) SetFileHealth(fileID int, health, detail string) error {
+	_, err := db.exec(`UPDATE files SET health = ?, health_detail = ?, checked_at = ? WHERE id = ?`,
+		health, detail, time.Now().UTC().Format(timeLayout), fileID)
+	return err
+}
+
+// ScrubCandidates returns the IDs of files, every version in every vault,
+// not checked since cutoff, never checked ones first.
+func (db *Database) ScrubCandidates(cutoff time.Time) ([]int, error) {
+	rows, err := db.query(`SELECT id FROM files WHERE checked_at IS NULL OR checked_at < ?
+		ORDER BY checked_at IS NOT NULL, checked_at, id`, cutoff.UTC().Format(timeLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
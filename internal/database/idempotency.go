@@ -0,0 +1,74 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// IdempotentResult is the stored outcome of a request made with an
+// Idempotency-Key. Status is 0 while the original request is still running.
+type IdempotentResult struct {
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// ReserveIdempotencyKey claims key for principal. It returns nil when the key
+// was free (or its previous use is older than cutoff) and the caller should
+// execute the request, or the stored result of the earlier request otherwise.
+func (db *Database) ReserveIdempotencyKey(principal, key, fingerprint string, cutoff time.Time) (*IdempotentResult, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM idempotency_keys WHERE principal = ? AND idem_key = ? AND created_at < ?`,
+		principal, key, cutoff.UTC().Format(timeLayout)); err != nil {
+		return nil, err
+	}
+
+	res, err := tx.Exec(`INSERT INTO idempotency_keys (principal, idem_key, fingerprint, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (principal, idem_key) DO NOTHING`, principal, key, fingerprint, time.Now().UTC().Format(timeLayout))
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil, tx.Commit()
+	}
+
+	var r IdempotentResult
+	err = tx.QueryRow(`SELECT fingerprint, status, content_type, body FROM idempotency_keys WHERE principal = ? AND idem_key = ?`,
+		principal, key).Scan(&r.Fingerprint, &r.Status, &r.ContentType, &r.Body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("idempotency key vanished during reservation")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, tx.Commit()
+}
+
+// CompleteIdempotencyKey stores the response so retries can replay it.
+func (db *Database) CompleteIdempotencyKey(principal, key string, status int, contentType string, body []byte) error {
+	_, err := db.exec(`UPDATE idempotency_keys SET status = ?, content_type = ?, body = ? WHERE principal = ? AND idem_key = ?`,
+		status, contentType, body, principal, key)
+	return err
+}
+
+// ReleaseIdempotencyKey forgets a reservation so the request can be retried.
+func (db *Database) ReleaseIdempotencyKey(principal, key string) error {
+	_, err := db.exec(`DELETE FROM idempotency_keys WHERE principal = ? AND idem_key = ?`, principal, key)
+	return err
+}
+
+// ExpireIdempotencyKeys drops stored results created before cutoff.
+func (db *Database) ExpireIdempotencyKeys(cutoff time.Time) (int64, error) {
+	res, err := db.exec(`DELETE FROM idempotency_keys WHERE created_at < ?`, cutoff.UTC().Format(timeLayout))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
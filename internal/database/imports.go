@@ -0,0 +1,80 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// ImportedAttachments reports which of the given attachment IDs a channel
+// import already copied into the vault.
+func (db *Database) ImportedAttachments(ids []string) (map[string]bool, error) {
+	imported := make(map[string]bool)
+	if len(ids) == 0 {
+		return imported, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := db.query(`SELECT attachment_id FROM imported_attachments WHERE attachment_id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		imported[id] = true
+	}
+	return imported, rows.Err()
+}
+
+// RecordImport remembers that an attachment was stored as fileID.
+func (db *Database) RecordImport(attachmentID, channelID, messageID string, fileID int) error {
+	_, err := db.exec(`INSERT INTO imported_attachments (attachment_id, channel_id, message_id, file_id, imported_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (attachment_id) DO NOTHING`,
+		attachmentID, channelID, messageID, fileID, time.Now().UTC().Format(timeLayout))
+	return err
+}
+
+// MigratedObject is an object a migration copied into the vault.
+type MigratedObject struct {
+	ETag   string
+	Size   int64
+	FileID int
+}
+
+// MigratedObjects returns the objects of source a migration copied into the
+// vault so far, by key.
+func (db *Database) MigratedObjects(source string) (map[string]MigratedObject, error) {
+	rows, err := db.query(`SELECT object_key, etag, size, file_id FROM migrated_objects WHERE source = ?`, source)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := make(map[string]MigratedObject)
+	for rows.Next() {
+		var key string
+		var o MigratedObject
+		if err := rows.Scan(&key, &o.ETag, &o.Size, &o.FileID); err != nil {
+			return nil, err
+		}
+		objects[key] = o
+	}
+	return objects, rows.Err()
+}
+
+// RecordMigration remembers that an object of source was stored as fileID,
+// replacing the record of an earlier copy.
+func (db *Database) RecordMigration(source, key, etag string, size int64, fileID int) error {
+	_, err := db.exec(`INSERT INTO migrated_objects (source, object_key, etag, size, file_id, migrated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (source, object_key) DO UPDATE SET etag = excluded.etag, size = excluded.size, file_id = excluded.file_id, migrated_at = excluded.migrated_at`,
+		source, key, etag, size, fileID, time.Now().UTC().Format(timeLayout))
+	return err
+}
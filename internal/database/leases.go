@@ -0,0 +1,43 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// AcquireLease takes or renews the named lease for holder until now+ttl. It
+// reports false when another holder's lease has not expired yet. Acquiring
+// and renewing are one statement, so two instances racing for a lapsed lease
+// cannot both win.
+func (db *Database) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	res, err := db.exec(`INSERT INTO leases (name, holder, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE leases.holder = excluded.holder OR leases.expires_at < ?`,
+		name, holder, now.Add(ttl).Format(timeLayout), now.Format(timeLayout))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReleaseLease gives up the named lease if holder still has it, so a standby
+// can take over without waiting for it to expire.
+func (db *Database) ReleaseLease(name, holder string) error {
+	_, err := db.exec(`DELETE FROM leases WHERE name = ? AND holder = ?`, name, holder)
+	return err
+}
+
+// LeaseHolder returns the current holder of the named lease and when it
+// expires, or "" when nobody holds it.
+func (db *Database) LeaseHolder(name string) (string, time.Time, error) {
+	var holder string
+	var expires time.Time
+	err := db.queryRow(`SELECT holder, expires_at FROM leases WHERE name = ?`, name).Scan(&holder, &expires)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", time.Time{}, nil
+	}
+	return holder, expires, err
+}
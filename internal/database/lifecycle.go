@@ -0,0 +1,110 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// LifecyclePolicy expires files automatically. Tag limits the policy to files
+// carrying that tag ("" matches every file). MaxAgeDays deletes matching
+// files older than that many days; MaxVersions keeps only the newest versions
+// of each name. Zero disables either rule.
+type LifecyclePolicy struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Tag         string    `json:"tag"`
+	MaxAgeDays  int       `json:"max_age_days"`
+	MaxVersions int       `json:"max_versions"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+const policyColumns = `id, name, tag, max_age_days, max_versions, created_by, created_at`
+
+func scanPolicy(row rowScanner) (*LifecyclePolicy, error) {
+	var p LifecyclePolicy
+	if err := row.Scan(&p.ID, &p.Name, &p.Tag, &p.MaxAgeDays, &p.MaxVersions, &p.CreatedBy, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (db *Database) CreatePolicy(p *LifecyclePolicy) (int, error) {
+	var id int
+	err := db.queryRow(`INSERT INTO lifecycle_policies (name, tag, max_age_days, max_versions, created_by) VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		p.Name, p.Tag, p.MaxAgeDays, p.MaxVersions, p.CreatedBy).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (db *Database) GetPolicy(id int) (*LifecyclePolicy, error) {
+	return scanPolicy(db.queryRow(`SELECT `+policyColumns+` FROM lifecycle_policies WHERE id = ?`, id))
+}
+
+func (db *Database) ListPolicies() ([]LifecyclePolicy, error) {
+	rows, err := db.query(`SELECT ` + policyColumns + ` FROM lifecycle_policies ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []LifecyclePolicy{}
+	for rows.Next() {
+		p, err := scanPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, *p)
+	}
+	return policies, rows.Err()
+}
+
+// UpdatePolicy replaces the rules of an existing policy.
+func (db *Database) UpdatePolicy(p *LifecyclePolicy) error {
+	res, err := db.exec(`UPDATE lifecycle_policies SET name = ?, tag = ?, max_age_days = ?, max_versions = ? WHERE id = ?`,
+		p.Name, p.Tag, p.MaxAgeDays, p.MaxVersions, p.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (db *Database) DeletePolicy(id int) error {
+	res, err := db.exec(`DELETE FROM lifecycle_policies WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// PolicyMatches returns every file version the policy applies to, grouped by
+// name with the newest version first. Files published on a release channel
+// are left out so a policy never breaks a public download URL.
+func (db *Database) PolicyMatches(p *LifecyclePolicy) ([]FileMetadata, error) {
+	query := `SELECT ` + fileColumns + ` FROM files
+		WHERE id NOT IN (SELECT file_id FROM release_channels WHERE file_id IS NOT NULL)`
+	var args []any
+	if p.Tag != "" {
+		query += ` AND id IN (SELECT file_id FROM file_tags WHERE tag = ?)`
+		args = append(args, p.Tag)
+	}
+	return db.queryFiles(query+` ORDER BY name, version DESC`, args...)
+}
+
+// RetentionExpired returns the files whose retention date has passed by
+// now. Like PolicyMatches it leaves out files published on a release
+// channel.
+func (db *Database) RetentionExpired(now time.Time) ([]FileMetadata, error) {
+	return db.queryFiles(`SELECT `+fileColumns+` FROM files
+		WHERE id IN (SELECT file_id FROM file_retention WHERE expires_at <= ?)
+		AND id NOT IN (SELECT file_id FROM release_channels WHERE file_id IS NOT NULL)
+		ORDER BY id`, now.UTC().Format(timeLayout))
+}
@@ -0,0 +1,117 @@
+package database
+
+import "time"
+
+// ManifestFormat identifies the export manifest schema.
+const ManifestFormat = "discordvault-manifest/1"
+
+// Manifest is a portable description of every stored file and the Discord
+// messages holding its chunks — enough to rebuild the metadata store.
+type Manifest struct {
+	Format    string         `json:"format"`
+	CreatedAt time.Time      `json:"created_at"`
+	ChannelID string         `json:"channel_id"`
+	Files     []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	ID        int             `json:"id"`
+	Name      string          `json:"name"`
+	Size      int64           `json:"size"`
+	Hash      string          `json:"hash"`
+	OwnerID   string          `json:"owner_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Chunks    []ManifestChunk `json:"chunks"`
+
+	GuildID      string     `json:"guild_id,omitempty"`
+	ChunkSize    int64      `json:"chunk_size,omitempty"`
+	Version      int        `json:"version,omitempty"`
+	SupersededAt *time.Time `json:"superseded_at,omitempty"`
+}
+
+type ManifestChunk struct {
+	PartNum   int    `json:"part_num"`
+	MessageID string `json:"message_id"`
+	Size      int64  `json:"size,omitempty"`
+	SHA256    string `json:"sha256,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"` // "" = the default storage channel
+}
+
+// ExportManifest snapshots all file and chunk metadata, including older
+// versions.
+func (db *Database) ExportManifest(channelID string) (*Manifest, error) {
+	files, err := db.queryFiles(`SELECT ` + fileColumns + ` FROM files ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Format: ManifestFormat, CreatedAt: time.Now().UTC(), ChannelID: channelID, Files: []ManifestFile{}}
+	for _, f := range files {
+		chunks, err := db.GetChunks(f.ID)
+		if err != nil {
+			return nil, err
+		}
+		mf := ManifestFile{ID: f.ID, Name: f.Name, Size: f.Size, Hash: f.Hash, OwnerID: f.OwnerID, CreatedAt: f.CreatedAt.UTC(), Version: f.Version, GuildID: f.GuildID, ChunkSize: f.ChunkSize}
+		if f.SupersededAt != nil {
+			superseded := f.SupersededAt.UTC()
+			mf.SupersededAt = &superseded
+		}
+		for _, c := range chunks {
+			mf.Chunks = append(mf.Chunks, ManifestChunk{PartNum: c.PartNum, MessageID: c.MessageID, Size: c.Size, SHA256: c.SHA256, ChannelID: c.ChannelID})
+		}
+		m.Files = append(m.Files, mf)
+	}
+	return m, nil
+}
+
+// ImportManifest inserts every file from m, keeping the original IDs so
+// existing share links and bot references stay valid. Files whose ID already
+// exists are skipped. It returns the number of files imported.
+func (db *Database) ImportManifest(m *Manifest) (int, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	imported := 0
+	for _, f := range m.Files {
+		var exists int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM files WHERE id = ?`, f.ID).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if exists > 0 {
+			continue
+		}
+		version := f.Version
+		if version == 0 {
+			version = 1
+		}
+		var superseded any
+		if f.SupersededAt != nil {
+			superseded = f.SupersededAt.UTC().Format(timeLayout)
+		}
+		if _, err := tx.Exec(`INSERT INTO files (id, name, size, hash, owner_id, created_at, version, superseded_at, guild_id, chunk_size) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			f.ID, f.Name, f.Size, f.Hash, f.OwnerID, f.CreatedAt.UTC().Format(timeLayout), version, superseded, f.GuildID, f.ChunkSize); err != nil {
+			return 0, err
+		}
+		for _, c := range f.Chunks {
+			if _, err := tx.Exec(`INSERT INTO chunks (file_id, message_id, part_num, size, sha256, channel_id) VALUES (?, ?, ?, ?, ?, ?)`,
+				f.ID, c.MessageID, c.PartNum, c.Size, c.SHA256, c.ChannelID); err != nil {
+				return 0, err
+			}
+		}
+		imported++
+	}
+
+	if db.dialect.Name() == "postgres" {
+		// Explicit IDs bypass the serial sequences; move them past the imported rows.
+		for _, table := range []string{"files", "chunks"} {
+			if _, err := tx.Exec(`SELECT setval(pg_get_serial_sequence('` + table + `', 'id'), COALESCE((SELECT MAX(id) FROM ` + table + `), 1))`); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return imported, tx.Commit()
+}
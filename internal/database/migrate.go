@@ -0,0 +1,253 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+// Migration is one versioned schema change loaded from migrations/NNNN_name.{up,down}.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func loadMigrations(d dialect) ([]Migration, error) {
+	dir := "migrations/" + d.MigrationsDir()
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		name := e.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("unexpected migration file %q", name)
+		}
+		num, label, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if err != nil {
+			return nil, fmt.Errorf("migration %q has no numeric prefix", name)
+		}
+		data, err := migrationFiles.ReadFile(dir + "/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// SchemaVersion returns the highest applied migration version.
+func (db *Database) SchemaVersion() (int, error) {
+	return db.currentVersion()
+}
+
+// LatestSchemaVersion returns the highest migration version shipped in this build.
+func (db *Database) LatestSchemaVersion() (int, error) {
+	migrations, err := loadMigrations(db.dialect)
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Inspect connects to the database at url and reports its backend, the
+// applied schema version, 0 for an empty database, and the latest version
+// this build ships, without applying any migration.
+func Inspect(url string) (backend string, current, latest int, err error) {
+	db, err := Connect(url)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	defer db.Conn.Close()
+
+	exists, err := db.tableExists("schema_version")
+	if err == nil && exists {
+		current, err = db.currentVersion()
+	}
+	if err == nil {
+		latest, err = db.LatestSchemaVersion()
+	}
+	return db.Backend(), current, latest, err
+}
+
+func (db *Database) migrate() error {
+	latest, err := db.LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+	return db.MigrateTo(latest)
+}
+
+// MigrateTo moves the schema up or down until target is the applied version.
+func (db *Database) MigrateTo(target int) error {
+	if _, err := db.exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`); err != nil {
+		return err
+	}
+	if err := db.adoptLegacySchema(); err != nil {
+		return fmt.Errorf("failed to adopt existing schema: %w", err)
+	}
+
+	migrations, err := loadMigrations(db.dialect)
+	if err != nil {
+		return err
+	}
+	current, err := db.currentVersion()
+	if err != nil {
+		return err
+	}
+
+	if target >= current {
+		for _, m := range migrations {
+			if m.Version <= current || m.Version > target {
+				continue
+			}
+			log.Printf("[DB] Applying migration %04d_%s", m.Version, m.Name)
+			if err := db.applyMigration(m.Up, `INSERT INTO schema_version (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+			}
+		}
+		return nil
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+		if m.Down == "" {
+			return fmt.Errorf("migration %04d_%s cannot be reverted", m.Version, m.Name)
+		}
+		log.Printf("[DB] Reverting migration %04d_%s", m.Version, m.Name)
+		if err := db.applyMigration(m.Down, `DELETE FROM schema_version WHERE version = ?`, m.Version); err != nil {
+			return fmt.Errorf("revert of %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// noForeignKeys marks SQLite migrations that rebuild a referenced table. They
+// run with foreign key enforcement off, otherwise dropping the old table
+// would cascade into its children.
+const noForeignKeys = "-- migrate:no-foreign-keys"
+
+func (db *Database) applyMigration(script string, bookkeeping string, args ...any) error {
+	ctx := context.Background()
+	conn, err := db.Conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if db.dialect.Name() == "sqlite" && strings.Contains(script, noForeignKeys) {
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+	}
+
+	sqlTx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	tx := &Tx{tx: sqlTx, dialect: db.dialect}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(bookkeeping, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *Database) currentVersion() (int, error) {
+	var version sql.NullInt64
+	if err := db.queryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// adoptLegacySchema records the versions already present in databases created
+// before schema_version existed, so their non-idempotent migrations are skipped.
+func (db *Database) adoptLegacySchema() error {
+	current, err := db.currentVersion()
+	if err != nil || current > 0 {
+		return err
+	}
+
+	hasFiles, err := db.tableExists("files")
+	if err != nil || !hasFiles {
+		return err
+	}
+
+	if _, err := db.exec(`INSERT INTO schema_version (version, name) VALUES (1, 'initial')`); err != nil {
+		return err
+	}
+	adopted := 1
+
+	hasOwner, err := db.columnExists("files", "owner_id")
+	if err != nil {
+		return err
+	}
+	if hasOwner {
+		if _, err := db.exec(`INSERT INTO schema_version (version, name) VALUES (2, 'file_owner')`); err != nil {
+			return err
+		}
+		adopted = 2
+	}
+
+	log.Printf("[DB] Adopted pre-migration schema at version %d", adopted)
+	return nil
+}
+
+func (db *Database) tableExists(table string) (bool, error) {
+	var n int
+	err := db.queryRow(db.dialect.TableExistsQuery(), table).Scan(&n)
+	return n > 0, err
+}
+
+func (db *Database) columnExists(table, column string) (bool, error) {
+	var n int
+	err := db.queryRow(db.dialect.ColumnExistsQuery(), table, column).Scan(&n)
+	return n > 0, err
+}
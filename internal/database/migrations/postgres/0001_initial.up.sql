@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS files (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	size BIGINT NOT NULL,
+	hash TEXT,
+	created_at TIMESTAMP DEFAULT (NOW() AT TIME ZONE 'UTC')
+);
+
+CREATE TABLE IF NOT EXISTS chunks (
+	id SERIAL PRIMARY KEY,
+	file_id INTEGER NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+	message_id TEXT NOT NULL,
+	part_num INTEGER NOT NULL
+);
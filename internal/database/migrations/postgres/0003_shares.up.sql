@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS shares (
+	id SERIAL PRIMARY KEY,
+	token TEXT NOT NULL UNIQUE,
+	file_id INTEGER NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+	created_by TEXT NOT NULL,
+	notify_owner BOOLEAN NOT NULL DEFAULT FALSE,
+	signature TEXT NOT NULL,
+	downloads INTEGER NOT NULL DEFAULT 0,
+	expires_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT (NOW() AT TIME ZONE 'UTC')
+);
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS released_chunks (
+	message_id TEXT PRIMARY KEY,
+	released_at TIMESTAMP DEFAULT (NOW() AT TIME ZONE 'UTC')
+);
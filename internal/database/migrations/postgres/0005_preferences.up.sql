@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS preferences (
+	scope TEXT NOT NULL,
+	subject_id TEXT NOT NULL,
+	timezone TEXT NOT NULL DEFAULT '',
+	updated_at TIMESTAMP DEFAULT (NOW() AT TIME ZONE 'UTC'),
+	PRIMARY KEY (scope, subject_id)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_files_name;
+DROP INDEX IF EXISTS idx_files_created_at;
+DROP INDEX IF EXISTS idx_chunks_message_id;
+DROP INDEX IF EXISTS idx_chunks_file_id;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_file_tags_tag;
+DROP TABLE IF EXISTS file_tags;
+DROP INDEX IF EXISTS idx_files_folder_id;
+ALTER TABLE files DROP COLUMN folder_id;
+DROP TABLE IF EXISTS folders;
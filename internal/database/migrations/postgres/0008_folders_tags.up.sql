@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS folders (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	parent_id INTEGER REFERENCES folders(id) ON DELETE CASCADE,
+	owner_id TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT (NOW() AT TIME ZONE 'UTC')
+);
+
+ALTER TABLE files ADD COLUMN folder_id INTEGER REFERENCES folders(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_files_folder_id ON files(folder_id);
+
+CREATE TABLE IF NOT EXISTS file_tags (
+	file_id INTEGER NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (file_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_file_tags_tag ON file_tags(tag);
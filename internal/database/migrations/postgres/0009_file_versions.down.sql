@@ -0,0 +1,6 @@
+-- Older versions cannot be represented without versioning and are dropped.
+DELETE FROM files WHERE superseded_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_files_current_name;
+ALTER TABLE files DROP COLUMN superseded_at;
+ALTER TABLE files DROP COLUMN version;
+ALTER TABLE files ADD CONSTRAINT files_name_key UNIQUE (name);
@@ -0,0 +1,4 @@
+ALTER TABLE files DROP CONSTRAINT IF EXISTS files_name_key;
+ALTER TABLE files ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE files ADD COLUMN superseded_at TIMESTAMP;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_files_current_name ON files(name) WHERE superseded_at IS NULL;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS artifacts (
+	file_id INTEGER PRIMARY KEY REFERENCES files(id) ON DELETE CASCADE,
+	project TEXT NOT NULL,
+	branch TEXT NOT NULL,
+	commit_sha TEXT NOT NULL,
+	run_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT (NOW() AT TIME ZONE 'UTC')
+);
+CREATE INDEX IF NOT EXISTS idx_artifacts_branch ON artifacts(project, branch, created_at);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS release_channels (
+	name TEXT PRIMARY KEY,
+	file_id INTEGER REFERENCES files(id) ON DELETE SET NULL,
+	owner_id TEXT NOT NULL,
+	published_at TIMESTAMP DEFAULT (NOW() AT TIME ZONE 'UTC')
+);
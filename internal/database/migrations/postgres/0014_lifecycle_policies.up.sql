@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS lifecycle_policies (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	tag TEXT NOT NULL DEFAULT '',
+	max_age_days INTEGER NOT NULL DEFAULT 0,
+	max_versions INTEGER NOT NULL DEFAULT 0,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT (NOW() AT TIME ZONE 'UTC')
+);
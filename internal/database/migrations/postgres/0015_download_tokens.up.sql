@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS download_tokens (
+	token TEXT PRIMARY KEY,
+	file_id INTEGER NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP
+);
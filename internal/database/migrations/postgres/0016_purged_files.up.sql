@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS purged_files (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	hash TEXT NOT NULL DEFAULT '',
+	owner_id TEXT NOT NULL DEFAULT '',
+	folder_id INTEGER,
+	version INTEGER NOT NULL DEFAULT 1,
+	tags TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	purged_by TEXT NOT NULL DEFAULT '',
+	purged_at TIMESTAMP DEFAULT (NOW() AT TIME ZONE 'UTC')
+);
+CREATE INDEX IF NOT EXISTS idx_purged_files_purged_at ON purged_files(purged_at);
+
+CREATE TABLE IF NOT EXISTS purged_chunks (
+	file_id INTEGER NOT NULL REFERENCES purged_files(id) ON DELETE CASCADE,
+	message_id TEXT NOT NULL,
+	part_num INTEGER NOT NULL,
+	size BIGINT NOT NULL DEFAULT 0,
+	sha256 TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_purged_chunks_file_id ON purged_chunks(file_id);
+CREATE INDEX IF NOT EXISTS idx_purged_chunks_message_id ON purged_chunks(message_id);
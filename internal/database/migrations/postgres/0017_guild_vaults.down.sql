@@ -0,0 +1,9 @@
+-- Fails while two vaults hold current files with the same name; rename one first.
+DROP INDEX IF EXISTS idx_files_current_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_files_current_name ON files(name) WHERE superseded_at IS NULL;
+
+ALTER TABLE purged_chunks DROP COLUMN channel_id;
+ALTER TABLE purged_files DROP COLUMN guild_id;
+ALTER TABLE released_chunks DROP COLUMN channel_id;
+ALTER TABLE chunks DROP COLUMN channel_id;
+ALTER TABLE files DROP COLUMN guild_id;
@@ -0,0 +1,2 @@
+ALTER TABLE purged_files DROP COLUMN chunk_size;
+ALTER TABLE files DROP COLUMN chunk_size;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS upload_session_chunks;
+DROP TABLE IF EXISTS upload_sessions;
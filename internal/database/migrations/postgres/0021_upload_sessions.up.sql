@@ -0,0 +1,27 @@
+-- Uploads that can be resumed after the connection or the server dropped.
+-- Every stored chunk is recorded with the SHA-256 state of the file up to
+-- its end, so a resumed upload continues hashing where the chunk ended.
+CREATE TABLE IF NOT EXISTS upload_sessions (
+	id TEXT PRIMARY KEY,
+	principal TEXT NOT NULL,
+	name TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	chunk_size BIGINT NOT NULL,
+	policy TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_updated_at ON upload_sessions(updated_at);
+
+CREATE TABLE IF NOT EXISTS upload_session_chunks (
+	session_id TEXT NOT NULL REFERENCES upload_sessions(id) ON DELETE CASCADE,
+	part_num INTEGER NOT NULL,
+	message_id TEXT NOT NULL,
+	channel_id TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	sha256 TEXT NOT NULL,
+	plain_size BIGINT NOT NULL,
+	hash_state BYTEA NOT NULL,
+	PRIMARY KEY (session_id, part_num)
+);
+CREATE INDEX IF NOT EXISTS idx_upload_session_chunks_message_id ON upload_session_chunks(message_id);
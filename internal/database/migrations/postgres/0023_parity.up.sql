@@ -0,0 +1,16 @@
+-- Erasure coding parameters of each file: every parity_data chunks share
+-- parity_chunks parity chunks. 0 = stored without parity.
+ALTER TABLE files ADD COLUMN parity_data INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE files ADD COLUMN parity_chunks INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS parity_chunks (
+	file_id INTEGER NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+	group_num INTEGER NOT NULL,
+	shard INTEGER NOT NULL,
+	message_id TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	sha256 TEXT NOT NULL,
+	channel_id TEXT NOT NULL,
+	PRIMARY KEY (file_id, group_num, shard)
+);
+CREATE INDEX IF NOT EXISTS idx_parity_chunks_message_id ON parity_chunks(message_id);
@@ -0,0 +1,10 @@
+-- Files with a chunk found gone or damaged, waiting for the repair job.
+-- status is 'degraded' until repaired, or 'unrecoverable' when too little
+-- of the file is left.
+CREATE TABLE IF NOT EXISTS file_repairs (
+	file_id INTEGER PRIMARY KEY REFERENCES files(id) ON DELETE CASCADE,
+	status TEXT NOT NULL,
+	detail TEXT NOT NULL DEFAULT '',
+	detected_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_files_checked_at;
+ALTER TABLE files DROP COLUMN checked_at;
+ALTER TABLE files DROP COLUMN health_detail;
+ALTER TABLE files DROP COLUMN health;
@@ -0,0 +1,6 @@
+-- Result of the last integrity scrub of each file: 'ok', 'degraded' (lost
+-- chunks can still be restored), or 'corrupt'. '' = never scrubbed.
+ALTER TABLE files ADD COLUMN health TEXT NOT NULL DEFAULT '';
+ALTER TABLE files ADD COLUMN health_detail TEXT NOT NULL DEFAULT '';
+ALTER TABLE files ADD COLUMN checked_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_files_checked_at ON files(checked_at);
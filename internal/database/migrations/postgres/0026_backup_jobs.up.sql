@@ -0,0 +1,27 @@
+-- Backups of server paths and URLs that run on a cron schedule. Each run is
+-- recorded in backup_runs; status is 'running', 'ok', or 'failed'.
+CREATE TABLE IF NOT EXISTS backup_jobs (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	schedule TEXT NOT NULL,
+	source TEXT NOT NULL,
+	folder_id INTEGER NOT NULL DEFAULT 0,
+	keep INTEGER NOT NULL DEFAULT 0,
+	enabled BOOLEAN NOT NULL DEFAULT TRUE,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT (NOW() AT TIME ZONE 'UTC'),
+	next_run_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_backup_jobs_next_run_at ON backup_jobs(next_run_at);
+
+CREATE TABLE IF NOT EXISTS backup_runs (
+	id SERIAL PRIMARY KEY,
+	job_id INTEGER NOT NULL REFERENCES backup_jobs(id) ON DELETE CASCADE,
+	status TEXT NOT NULL,
+	file_id INTEGER NOT NULL DEFAULT 0,
+	size BIGINT NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	started_at TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_backup_runs_job_id ON backup_runs(job_id);
@@ -0,0 +1,9 @@
+-- Discord attachments copied into the vault by a channel import, so running
+-- the import again skips them.
+CREATE TABLE IF NOT EXISTS imported_attachments (
+	attachment_id TEXT PRIMARY KEY,
+	channel_id TEXT NOT NULL,
+	message_id TEXT NOT NULL,
+	file_id INTEGER NOT NULL,
+	imported_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,11 @@
+-- Objects copied into the vault from another storage provider, so running
+-- the migration again skips them unless they changed.
+CREATE TABLE IF NOT EXISTS migrated_objects (
+	source TEXT NOT NULL,
+	object_key TEXT NOT NULL,
+	etag TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	file_id INTEGER NOT NULL,
+	migrated_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (source, object_key)
+);
@@ -0,0 +1,11 @@
+-- Daily totals of the whole vault, for the storage growth chart of the
+-- admin dashboard. The row of the current day is overwritten until it ends.
+CREATE TABLE IF NOT EXISTS storage_snapshots (
+	day TEXT PRIMARY KEY,
+	files INTEGER NOT NULL DEFAULT 0,
+	versions INTEGER NOT NULL DEFAULT 0,
+	bytes BIGINT NOT NULL DEFAULT 0,
+	chunks INTEGER NOT NULL DEFAULT 0,
+	stored_bytes BIGINT NOT NULL DEFAULT 0,
+	recorded_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,16 @@
+-- Progress of each upload, so a client can follow it by operation ID,
+-- including after the connection or the server dropped.
+CREATE TABLE IF NOT EXISTS operations (
+	id TEXT PRIMARY KEY,
+	kind TEXT NOT NULL,
+	principal TEXT NOT NULL,
+	name TEXT NOT NULL DEFAULT '',
+	state TEXT NOT NULL,
+	bytes_done BIGINT NOT NULL DEFAULT 0,
+	bytes_expected BIGINT NOT NULL DEFAULT 0,
+	file_id INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_operations_principal ON operations (principal, created_at);
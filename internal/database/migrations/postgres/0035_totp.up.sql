@@ -0,0 +1,17 @@
+-- Two-factor authentication for web logins. A secret stays disabled until
+-- its first code is confirmed; last_step is the newest step a code was
+-- accepted for, so codes cannot be replayed. Recovery codes are stored as
+-- their SHA-256.
+CREATE TABLE IF NOT EXISTS user_totp (
+	user_id TEXT PRIMARY KEY,
+	secret TEXT NOT NULL,
+	enabled BOOLEAN NOT NULL DEFAULT FALSE,
+	last_step BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+	user_id TEXT NOT NULL,
+	code_hash TEXT NOT NULL,
+	used_at TIMESTAMP,
+	PRIMARY KEY (user_id, code_hash)
+);
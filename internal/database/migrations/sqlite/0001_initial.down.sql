@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS chunks;
+DROP TABLE IF EXISTS files;
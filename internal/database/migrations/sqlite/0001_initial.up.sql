@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS files (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	size INTEGER NOT NULL,
+	hash TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS chunks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	file_id INTEGER NOT NULL,
+	message_id TEXT NOT NULL,
+	part_num INTEGER NOT NULL,
+	FOREIGN KEY(file_id) REFERENCES files(id) ON DELETE CASCADE
+);
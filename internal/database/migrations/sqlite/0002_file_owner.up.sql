@@ -0,0 +1 @@
+ALTER TABLE files ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
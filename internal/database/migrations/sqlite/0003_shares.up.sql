@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS shares (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	token TEXT NOT NULL UNIQUE,
+	file_id INTEGER NOT NULL,
+	created_by TEXT NOT NULL,
+	notify_owner INTEGER NOT NULL DEFAULT 0,
+	signature TEXT NOT NULL,
+	downloads INTEGER NOT NULL DEFAULT 0,
+	expires_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(file_id) REFERENCES files(id) ON DELETE CASCADE
+);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS folders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	parent_id INTEGER REFERENCES folders(id) ON DELETE CASCADE,
+	owner_id TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE files ADD COLUMN folder_id INTEGER;
+CREATE INDEX IF NOT EXISTS idx_files_folder_id ON files(folder_id);
+
+CREATE TABLE IF NOT EXISTS file_tags (
+	file_id INTEGER NOT NULL,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (file_id, tag),
+	FOREIGN KEY(file_id) REFERENCES files(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_file_tags_tag ON file_tags(tag);
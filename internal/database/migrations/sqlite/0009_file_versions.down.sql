@@ -0,0 +1,25 @@
+-- migrate:no-foreign-keys
+-- Older versions cannot be represented without versioning and are dropped.
+DELETE FROM chunks WHERE file_id IN (SELECT id FROM files WHERE superseded_at IS NOT NULL);
+DELETE FROM file_tags WHERE file_id IN (SELECT id FROM files WHERE superseded_at IS NOT NULL);
+DELETE FROM shares WHERE file_id IN (SELECT id FROM files WHERE superseded_at IS NOT NULL);
+DELETE FROM files WHERE superseded_at IS NOT NULL;
+
+CREATE TABLE files_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	size INTEGER NOT NULL,
+	hash TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	owner_id TEXT NOT NULL DEFAULT '',
+	folder_id INTEGER
+);
+INSERT INTO files_old (id, name, size, hash, created_at, owner_id, folder_id)
+	SELECT id, name, size, hash, created_at, owner_id, folder_id FROM files;
+UPDATE sqlite_sequence SET seq = (SELECT seq FROM sqlite_sequence WHERE name = 'files') WHERE name = 'files_old';
+DROP TABLE files;
+ALTER TABLE files_old RENAME TO files;
+
+CREATE INDEX IF NOT EXISTS idx_files_created_at ON files(created_at);
+CREATE INDEX IF NOT EXISTS idx_files_name ON files(name);
+CREATE INDEX IF NOT EXISTS idx_files_folder_id ON files(folder_id);
@@ -0,0 +1,24 @@
+-- migrate:no-foreign-keys
+-- Rebuilds files without UNIQUE(name) so a name can have several versions;
+-- only the current version (superseded_at IS NULL) must be unique.
+CREATE TABLE files_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	hash TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	owner_id TEXT NOT NULL DEFAULT '',
+	folder_id INTEGER,
+	version INTEGER NOT NULL DEFAULT 1,
+	superseded_at DATETIME
+);
+INSERT INTO files_new (id, name, size, hash, created_at, owner_id, folder_id)
+	SELECT id, name, size, hash, created_at, owner_id, folder_id FROM files;
+UPDATE sqlite_sequence SET seq = (SELECT seq FROM sqlite_sequence WHERE name = 'files') WHERE name = 'files_new';
+DROP TABLE files;
+ALTER TABLE files_new RENAME TO files;
+
+CREATE INDEX IF NOT EXISTS idx_files_created_at ON files(created_at);
+CREATE INDEX IF NOT EXISTS idx_files_name ON files(name);
+CREATE INDEX IF NOT EXISTS idx_files_folder_id ON files(folder_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_files_current_name ON files(name) WHERE superseded_at IS NULL;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS transfer_usage (
+	period TEXT NOT NULL,
+	subject_type TEXT NOT NULL,
+	subject_id TEXT NOT NULL,
+	uploaded INTEGER NOT NULL DEFAULT 0,
+	downloaded INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (period, subject_type, subject_id)
+);
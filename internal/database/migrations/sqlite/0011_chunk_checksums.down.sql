@@ -0,0 +1,2 @@
+ALTER TABLE chunks DROP COLUMN sha256;
+ALTER TABLE chunks DROP COLUMN size;
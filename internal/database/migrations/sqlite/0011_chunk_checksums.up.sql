@@ -0,0 +1,2 @@
+ALTER TABLE chunks ADD COLUMN size INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE chunks ADD COLUMN sha256 TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_artifacts_branch;
+DROP TABLE IF EXISTS artifacts;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS artifacts (
+	file_id INTEGER PRIMARY KEY,
+	project TEXT NOT NULL,
+	branch TEXT NOT NULL,
+	commit_sha TEXT NOT NULL,
+	run_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(file_id) REFERENCES files(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_artifacts_branch ON artifacts(project, branch, created_at);
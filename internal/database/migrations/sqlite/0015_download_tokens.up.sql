@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS download_tokens (
+	token TEXT PRIMARY KEY,
+	file_id INTEGER NOT NULL,
+	user_id TEXT NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME,
+	FOREIGN KEY(file_id) REFERENCES files(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS purged_chunks;
+DROP TABLE IF EXISTS purged_files;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS purged_files (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	hash TEXT NOT NULL DEFAULT '',
+	owner_id TEXT NOT NULL DEFAULT '',
+	folder_id INTEGER,
+	version INTEGER NOT NULL DEFAULT 1,
+	tags TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	purged_by TEXT NOT NULL DEFAULT '',
+	purged_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_purged_files_purged_at ON purged_files(purged_at);
+
+CREATE TABLE IF NOT EXISTS purged_chunks (
+	file_id INTEGER NOT NULL,
+	message_id TEXT NOT NULL,
+	part_num INTEGER NOT NULL,
+	size INTEGER NOT NULL DEFAULT 0,
+	sha256 TEXT NOT NULL DEFAULT '',
+	FOREIGN KEY(file_id) REFERENCES purged_files(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_purged_chunks_file_id ON purged_chunks(file_id);
+CREATE INDEX IF NOT EXISTS idx_purged_chunks_message_id ON purged_chunks(message_id);
@@ -0,0 +1,11 @@
+-- Files belong to a vault: '' is the default vault, otherwise the ID of a
+-- guild with its own storage channel. Chunks record the channel they live in
+-- ('' = DISCORD_CHANNEL_ID, for rows written before this migration).
+ALTER TABLE files ADD COLUMN guild_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE chunks ADD COLUMN channel_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE released_chunks ADD COLUMN channel_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE purged_files ADD COLUMN guild_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE purged_chunks ADD COLUMN channel_id TEXT NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS idx_files_current_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_files_current_name ON files(guild_id, name) WHERE superseded_at IS NULL;
@@ -0,0 +1,14 @@
+-- Operational history for the scheduled digest: deletions, verification
+-- failures, and the digests themselves (so a restart does not skip or repeat
+-- one).
+CREATE TABLE IF NOT EXISTS vault_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	kind TEXT NOT NULL,
+	guild_id TEXT NOT NULL DEFAULT '',
+	file_id INTEGER,
+	file_name TEXT NOT NULL DEFAULT '',
+	size INTEGER NOT NULL DEFAULT 0,
+	detail TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_vault_events_kind_created ON vault_events(kind, created_at);
@@ -0,0 +1,4 @@
+-- Plaintext bytes per chunk of each file; every chunk but the last holds
+-- exactly this many. 0 = the fixed 7MB used before chunk sizes could vary.
+ALTER TABLE files ADD COLUMN chunk_size INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE purged_files ADD COLUMN chunk_size INTEGER NOT NULL DEFAULT 0;
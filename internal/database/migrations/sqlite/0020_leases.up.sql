@@ -0,0 +1,8 @@
+-- Named leases for leader election between instances sharing a database.
+-- The holder keeps renewing expires_at; once it lapses another instance may
+-- take over.
+CREATE TABLE IF NOT EXISTS leases (
+	name TEXT PRIMARY KEY,
+	holder TEXT NOT NULL,
+	expires_at DATETIME NOT NULL
+);
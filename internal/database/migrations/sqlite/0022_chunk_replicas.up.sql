@@ -0,0 +1,9 @@
+-- Extra copies of chunk messages in REPLICA_CHANNELS, keyed by the message
+-- of the original chunk so every file sharing that chunk shares its copies.
+CREATE TABLE IF NOT EXISTS chunk_replicas (
+	message_id TEXT NOT NULL,
+	replica_message_id TEXT NOT NULL,
+	replica_channel_id TEXT NOT NULL,
+	PRIMARY KEY (message_id, replica_channel_id)
+);
+CREATE INDEX IF NOT EXISTS idx_chunk_replicas_replica_message_id ON chunk_replicas(replica_message_id);
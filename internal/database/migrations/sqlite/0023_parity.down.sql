@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS parity_chunks;
+ALTER TABLE files DROP COLUMN parity_chunks;
+ALTER TABLE files DROP COLUMN parity_data;
@@ -0,0 +1,27 @@
+-- Backups of server paths and URLs that run on a cron schedule. Each run is
+-- recorded in backup_runs; status is 'running', 'ok', or 'failed'.
+CREATE TABLE IF NOT EXISTS backup_jobs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	schedule TEXT NOT NULL,
+	source TEXT NOT NULL,
+	folder_id INTEGER NOT NULL DEFAULT 0,
+	keep INTEGER NOT NULL DEFAULT 0,
+	enabled INTEGER NOT NULL DEFAULT 1,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	next_run_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_backup_jobs_next_run_at ON backup_jobs(next_run_at);
+
+CREATE TABLE IF NOT EXISTS backup_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	job_id INTEGER NOT NULL REFERENCES backup_jobs(id) ON DELETE CASCADE,
+	status TEXT NOT NULL,
+	file_id INTEGER NOT NULL DEFAULT 0,
+	size INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	started_at DATETIME NOT NULL,
+	finished_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_backup_runs_job_id ON backup_runs(job_id);
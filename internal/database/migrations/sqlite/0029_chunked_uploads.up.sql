@@ -0,0 +1,3 @@
+-- Upload sessions whose chunks are sent one request each, in any order and
+-- in parallel, instead of as a stream from an offset.
+ALTER TABLE upload_sessions ADD COLUMN chunked INTEGER NOT NULL DEFAULT 0;
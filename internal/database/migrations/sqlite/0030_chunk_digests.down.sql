@@ -0,0 +1,2 @@
+ALTER TABLE purged_chunks DROP COLUMN digest;
+ALTER TABLE chunks DROP COLUMN digest;
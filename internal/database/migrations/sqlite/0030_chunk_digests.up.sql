@@ -0,0 +1,4 @@
+-- Keyed SHA-256 of each chunk's plaintext, so a new version of a file can
+-- reuse the chunks it shares with the stored one. '' = unknown.
+ALTER TABLE chunks ADD COLUMN digest TEXT NOT NULL DEFAULT '';
+ALTER TABLE purged_chunks ADD COLUMN digest TEXT NOT NULL DEFAULT '';
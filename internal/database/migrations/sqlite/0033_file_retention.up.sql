@@ -0,0 +1,6 @@
+-- Files set to expire on a date, by a bulk retention action. The lifecycle
+-- job deletes them once it has passed.
+CREATE TABLE IF NOT EXISTS file_retention (
+	file_id INTEGER PRIMARY KEY REFERENCES files(id) ON DELETE CASCADE,
+	expires_at DATETIME NOT NULL
+);
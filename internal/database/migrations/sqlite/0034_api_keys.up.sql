@@ -0,0 +1,13 @@
+-- API keys users create for themselves, next to the API_KEYS of the
+-- configuration. Only the SHA-256 of a key is stored.
+CREATE TABLE IF NOT EXISTS api_keys (
+	id TEXT PRIMARY KEY,
+	owner_id TEXT NOT NULL,
+	name TEXT NOT NULL DEFAULT '',
+	scope TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL,
+	last_used_at DATETIME,
+	revoked_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_owner ON api_keys (owner_id);
@@ -0,0 +1,15 @@
+-- Web logins. The cookie is only stored as its SHA-256; id names a session
+-- in listings and revocations.
+CREATE TABLE IF NOT EXISTS web_sessions (
+	id TEXT PRIMARY KEY,
+	token_hash TEXT NOT NULL UNIQUE,
+	user_id TEXT NOT NULL,
+	admin INTEGER NOT NULL DEFAULT 0,
+	key_id TEXT NOT NULL DEFAULT '',
+	scope TEXT NOT NULL DEFAULT '',
+	remote_addr TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	last_seen_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_web_sessions_user ON web_sessions (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE user_totp DROP COLUMN locked_until;
+ALTER TABLE user_totp DROP COLUMN failed_attempts;
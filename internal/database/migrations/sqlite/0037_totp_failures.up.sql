@@ -0,0 +1,3 @@
+-- Wrong two-factor codes in a row, and until when they lock a user out.
+ALTER TABLE user_totp ADD COLUMN failed_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE user_totp ADD COLUMN locked_until DATETIME;
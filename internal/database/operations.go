@@ -0,0 +1,118 @@
+package database
+
+import "time"
+
+// Operation states. An operation is queued until its first bytes are sent
+// to Discord, and ends complete or failed.
+const (
+	OpQueued    = "queued"
+	OpUploading = "uploading"
+	OpComplete  = "complete"
+	OpFailed    = "failed"
+)
+
+// Operation is the progress of one upload. BytesExpected is the length of
+// the request, a little more than the file, or 0 if the client did not
+// send it.
+type Operation struct {
+	ID            string    `json:"id"`
+	Kind          string    `json:"kind"` // "upload"
+	Principal     string    `json:"-"`
+	Name          string    `json:"name"`
+	State         string    `json:"state"`
+	BytesDone     int64     `json:"bytes_done"`
+	BytesExpected int64     `json:"bytes_expected,omitempty"`
+	FileID        int       `json:"file_id,omitempty"` // once complete
+	Error         string    `json:"error,omitempty"`   // once failed
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+const operationColumns = `id, kind, principal, name, state, bytes_done, bytes_expected, file_id, error, created_at, updated_at`
+
+func scanOperation(row rowScanner) (*Operation, error) {
+	var op Operation
+	if err := row.Scan(&op.ID, &op.Kind, &op.Principal, &op.Name, &op.State, &op.BytesDone, &op.BytesExpected, &op.FileID, &op.Error, &op.CreatedAt, &op.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// CreateOperation records op as queued.
+func (db *Database) CreateOperation(op *Operation) error {
+	now := time.Now().UTC()
+	op.State = OpQueued
+	_, err := db.exec(`INSERT INTO operations (`+operationColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		op.ID, op.Kind, op.Principal, op.Name, op.State, op.BytesDone, op.BytesExpected, op.FileID, op.Error, now.Format(timeLayout), now.Format(timeLayout))
+	if err == nil {
+		op.CreatedAt, op.UpdatedAt = now, now
+	}
+	return err
+}
+
+// UpdateOperation records the name, state, and progress of a running
+// operation.
+func (db *Database) UpdateOperation(id, name, state string, done int64) error {
+	_, err := db.exec(`UPDATE operations SET name = ?, state = ?, bytes_done = ?, updated_at = ? WHERE id = ?`,
+		name, state, done, time.Now().UTC().Format(timeLayout), id)
+	return err
+}
+
+// FinishOperation marks an operation complete with the file it stored, or
+// failed with errText if file is nil.
+func (db *Database) FinishOperation(id string, file *FileMetadata, errText string) error {
+	now := time.Now().UTC().Format(timeLayout)
+	if file == nil {
+		_, err := db.exec(`UPDATE operations SET state = ?, error = ?, updated_at = ? WHERE id = ?`, OpFailed, errText, now, id)
+		return err
+	}
+	_, err := db.exec(`UPDATE operations SET state = ?, name = ?, bytes_done = ?, file_id = ?, updated_at = ? WHERE id = ?`,
+		OpComplete, file.Name, file.Size, file.ID, now, id)
+	return err
+}
+
+// GetOperation returns sql.ErrNoRows for unknown or expired operations.
+func (db *Database) GetOperation(id string) (*Operation, error) {
+	return scanOperation(db.queryRow(`SELECT `+operationColumns+` FROM operations WHERE id = ?`, id))
+}
+
+// ListOperations returns the latest limit operations of principal, newest
+// first.
+func (db *Database) ListOperations(principal string, limit int) ([]Operation, error) {
+	rows, err := db.query(`SELECT `+operationColumns+` FROM operations WHERE principal = ? ORDER BY created_at DESC LIMIT ?`, principal, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ops := []Operation{}
+	for rows.Next() {
+		op, err := scanOperation(rows)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, *op)
+	}
+	return ops, rows.Err()
+}
+
+// AbandonOperations marks operations a stopped server left queued or
+// uploading, those created before since, as failed.
+func (db *Database) AbandonOperations(since time.Time) (int64, error) {
+	res, err := db.exec(`UPDATE operations SET state = ?, error = ?, updated_at = ? WHERE state IN (?, ?) AND created_at < ?`,
+		OpFailed, "interrupted by a server restart", time.Now().UTC().Format(timeLayout), OpQueued, OpUploading, since.UTC().Format(timeLayout))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ExpireOperations drops finished operations last updated before cutoff.
+func (db *Database) ExpireOperations(cutoff time.Time) (int64, error) {
+	res, err := db.exec(`DELETE FROM operations WHERE state IN (?, ?) AND updated_at < ?`,
+		OpComplete, OpFailed, cutoff.UTC().Format(timeLayout))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
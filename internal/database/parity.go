@@ -0,0 +1,65 @@
+package database
+
+// ParityChunk is a Reed-Solomon parity chunk of a file. Shard numbers the
+// parity chunks of group Group, the Group-th run of ParityData chunks,
+// from 0.
+type ParityChunk struct {
+	ChunkMetadata
+	Group int
+	Shard int
+}
+
+// SaveParity records a file's erasure coding and its parity chunks.
+func (db *Database) SaveParity(fileID, data, parity int, chunks []ParityChunk) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE files SET parity_data = ?, parity_chunks = ? WHERE id = ?`, data, parity, fileID); err != nil {
+		return err
+	}
+	for _, c := range chunks {
+		if _, err := tx.Exec(`INSERT INTO parity_chunks (file_id, group_num, shard, message_id, size, sha256, channel_id) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			fileID, c.Group, c.Shard, c.MessageID, c.Size, c.SHA256, c.ChannelID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ParityChunks returns the parity chunks of one group of a file by shard.
+func (db *Database) ParityChunks(fileID, group int) ([]ParityChunk, error) {
+	rows, err := db.query(`SELECT group_num, shard, message_id, size, sha256, channel_id
+		FROM parity_chunks WHERE file_id = ? AND group_num = ? ORDER BY shard`, fileID, group)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []ParityChunk
+	for rows.Next() {
+		var c ParityChunk
+		if err := rows.Scan(&c.Group, &c.Shard, &c.MessageID, &c.Size, &c.SHA256, &c.ChannelID); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, rows.Err()
+}
+
+// dropParity releases a file's parity chunks and records it as stored
+// without parity, for when its chunks are replaced.
+func dropParity(tx *Tx, fileID int) error {
+	if _, err := tx.Exec(`INSERT INTO released_chunks (message_id, channel_id)
+		SELECT message_id, channel_id FROM parity_chunks WHERE file_id = ?
+		ON CONFLICT (message_id) DO NOTHING`, fileID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM parity_chunks WHERE file_id = ?`, fileID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE files SET parity_data = 0, parity_chunks = 0 WHERE id = ?`, fileID)
+	return err
+}
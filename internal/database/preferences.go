@@ -0,0 +1,31 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Preference scopes.
+const (
+	ScopeUser  = "user"
+	ScopeGuild = "guild"
+)
+
+// GetTimezone returns the stored IANA zone name for a user or guild, or ""
+// when none has been set.
+func (db *Database) GetTimezone(scope, subjectID string) (string, error) {
+	var tz string
+	err := db.queryRow(`SELECT timezone FROM preferences WHERE scope = ? AND subject_id = ?`, scope, subjectID).Scan(&tz)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return tz, err
+}
+
+func (db *Database) SetTimezone(scope, subjectID, tz string) error {
+	_, err := db.exec(`INSERT INTO preferences (scope, subject_id, timezone, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(scope, subject_id) DO UPDATE SET timezone = excluded.timezone, updated_at = excluded.updated_at`,
+		scope, subjectID, tz, time.Now().UTC())
+	return err
+}
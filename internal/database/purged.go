@@ -0,0 +1,244 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// PurgedFile is a deleted file whose chunk messages are kept on Discord until
+// the purge grace period ends, so it can still be restored.
+type PurgedFile struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Hash      string    `json:"hash"`
+	OwnerID   string    `json:"owner_id"`
+	GuildID   string    `json:"guild_id,omitempty"`
+	Version   int       `json:"version"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	PurgedBy  string    `json:"purged_by"`
+	PurgedAt  time.Time `json:"purged_at"`
+}
+
+// SoftDeleteFile removes a file from the vault like DeleteFile but moves its
+// metadata and chunk list to purged_files instead of forgetting them. Its
+// chunk messages stay on Discord until ForgetPurged.
+func (db *Database) SoftDeleteFile(id int, purgedBy string) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := softDeleteFile(tx, id, purgedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func softDeleteFile(tx *Tx, id int, purgedBy string) error {
+	tags, err := fileTags(tx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO purged_files (id, name, size, hash, owner_id, folder_id, version, tags, created_at, purged_by, guild_id, chunk_size)
+		SELECT id, name, size, COALESCE(hash, ''), owner_id, folder_id, version, ?, created_at, ?, guild_id, chunk_size FROM files WHERE id = ?`,
+		strings.Join(tags, ","), purgedBy, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO purged_chunks (file_id, message_id, part_num, size, sha256, channel_id, digest)
+		SELECT file_id, message_id, part_num, size, sha256, channel_id, digest FROM chunks WHERE file_id = ?`, id); err != nil {
+		return err
+	}
+	return deleteFileRows(tx, id)
+}
+
+func fileTags(tx *Tx, id int) ([]string, error) {
+	rows, err := tx.Query(`SELECT tag FROM file_tags WHERE file_id = ? ORDER BY tag`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+const purgedColumns = `id, name, size, hash, owner_id, guild_id, version, tags, created_at, purged_by, purged_at`
+
+func scanPurged(row rowScanner) (*PurgedFile, error) {
+	var p PurgedFile
+	var tags string
+	if err := row.Scan(&p.ID, &p.Name, &p.Size, &p.Hash, &p.OwnerID, &p.GuildID, &p.Version, &tags, &p.CreatedAt, &p.PurgedBy, &p.PurgedAt); err != nil {
+		return nil, err
+	}
+	p.Tags = []string{}
+	if tags != "" {
+		p.Tags = strings.Split(tags, ",")
+	}
+	return &p, nil
+}
+
+// ListPurged returns files waiting out the purge grace period, newest purge
+// first. An empty ownerID lists everyone's.
+func (db *Database) ListPurged(ownerID string) ([]PurgedFile, error) {
+	query := `SELECT ` + purgedColumns + ` FROM purged_files`
+	var args []any
+	if ownerID != "" {
+		query += ` WHERE owner_id = ?`
+		args = append(args, ownerID)
+	}
+	rows, err := db.query(query+` ORDER BY purged_at DESC, id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	purged := []PurgedFile{}
+	for rows.Next() {
+		p, err := scanPurged(rows)
+		if err != nil {
+			return nil, err
+		}
+		purged = append(purged, *p)
+	}
+	return purged, rows.Err()
+}
+
+// GetPurged returns one purged file, or sql.ErrNoRows.
+func (db *Database) GetPurged(id int) (*PurgedFile, error) {
+	return scanPurged(db.queryRow(`SELECT `+purgedColumns+` FROM purged_files WHERE id = ?`, id))
+}
+
+// RestorePurged puts a purged file back under its original ID. It becomes the
+// current version again unless a newer version of the name exists in its
+// vault; if another owner has taken the name meanwhile it gets a " (n)" suffix.
+func (db *Database) RestorePurged(id int) (*FileMetadata, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	p, err := scanPurged(tx.QueryRow(`SELECT `+purgedColumns+` FROM purged_files WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	name, version := p.Name, p.Version
+	var superseded any
+	var current, currentVersion int
+	var currentOwner string
+	err = tx.QueryRow(`SELECT id, owner_id, version FROM files WHERE guild_id = ? AND name = ? AND superseded_at IS NULL`, p.GuildID, name).
+		Scan(&current, &currentOwner, &currentVersion)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return nil, err
+	case currentOwner != p.OwnerID:
+		if name, err = freeName(tx, p.GuildID, name); err != nil {
+			return nil, err
+		}
+		version = 1
+	case currentVersion > version:
+		superseded = time.Now().UTC().Format(timeLayout)
+	default:
+		if _, err := tx.Exec(`UPDATE files SET superseded_at = ? WHERE id = ?`, time.Now().UTC().Format(timeLayout), current); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(`INSERT INTO files (id, name, size, hash, owner_id, folder_id, version, created_at, superseded_at, guild_id, chunk_size)
+		SELECT id, ?, size, hash, owner_id, (SELECT id FROM folders WHERE id = purged_files.folder_id), ?, created_at, ?, guild_id, chunk_size
+		FROM purged_files WHERE id = ?`, name, version, superseded, id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`INSERT INTO chunks (file_id, message_id, part_num, size, sha256, channel_id, digest)
+		SELECT file_id, message_id, part_num, size, sha256, channel_id, digest FROM purged_chunks WHERE file_id = ?`, id); err != nil {
+		return nil, err
+	}
+	for _, tag := range p.Tags {
+		if _, err := tx.Exec(`INSERT INTO file_tags (file_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM purged_files WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+
+	file, err := scanFile(tx.QueryRow(`SELECT `+fileColumns+` FROM files WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+	return file, tx.Commit()
+}
+
+// ExpiredPurges returns the IDs of files purged before cutoff.
+func (db *Database) ExpiredPurges(cutoff time.Time) ([]int, error) {
+	rows, err := db.query(`SELECT id FROM purged_files WHERE purged_at <= ? ORDER BY purged_at ASC`, cutoff.UTC().Format(timeLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// PurgedMessages returns the chunk messages of a purged file that nothing
+// else references, i.e. the ones to delete from Discord when it expires. Only
+// MessageID and ChannelID are set.
+func (db *Database) PurgedMessages(id int) ([]ChunkMetadata, error) {
+	rows, err := db.query(`SELECT DISTINCT message_id, channel_id FROM purged_chunks p
+		WHERE file_id = ?
+			AND NOT EXISTS (SELECT 1 FROM chunks c WHERE c.message_id = p.message_id)
+			AND NOT EXISTS (SELECT 1 FROM purged_chunks o WHERE o.message_id = p.message_id AND o.file_id != p.file_id)`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []ChunkMetadata
+	for rows.Next() {
+		var c ChunkMetadata
+		if err := rows.Scan(&c.MessageID, &c.ChannelID); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, c)
+	}
+	return msgs, rows.Err()
+}
+
+// ForgetPurged drops a purged file for good once its messages are deleted.
+func (db *Database) ForgetPurged(id int) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM purged_chunks WHERE file_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM purged_files WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ReleaseChannel points a public, stable name (e.g. "myapp-stable") at the
+// file version currently published on it. FileID is 0 when the published
+// file was deleted.
+type ReleaseChannel struct {
+	Name        string    `json:"name"`
+	FileID      int       `json:"file_id"`
+	OwnerID     string    `json:"owner_id"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+const releaseColumns = `name, COALESCE(file_id, 0), owner_id, published_at`
+
+// PublishRelease points channel at fileID, creating the channel if needed.
+func (db *Database) PublishRelease(channel string, fileID int, ownerID string) error {
+	_, err := db.exec(`INSERT INTO release_channels (name, file_id, owner_id, published_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET file_id = excluded.file_id, published_at = excluded.published_at`,
+		channel, fileID, ownerID, time.Now().UTC().Format(timeLayout))
+	return err
+}
+
+func (db *Database) GetRelease(channel string) (*ReleaseChannel, error) {
+	var rc ReleaseChannel
+	err := db.queryRow(`SELECT `+releaseColumns+` FROM release_channels WHERE name = ?`, channel).
+		Scan(&rc.Name, &rc.FileID, &rc.OwnerID, &rc.PublishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &rc, nil
+}
+
+// ListReleases returns every channel, or only those of ownerID when it is set.
+func (db *Database) ListReleases(ownerID string) ([]ReleaseChannel, error) {
+	query := `SELECT ` + releaseColumns + ` FROM release_channels`
+	var args []any
+	if ownerID != "" {
+		query += ` WHERE owner_id = ?`
+		args = append(args, ownerID)
+	}
+	rows, err := db.query(query+` ORDER BY name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	releases := []ReleaseChannel{}
+	for rows.Next() {
+		var rc ReleaseChannel
+		if err := rows.Scan(&rc.Name, &rc.FileID, &rc.OwnerID, &rc.PublishedAt); err != nil {
+			return nil, err
+		}
+		releases = append(releases, rc)
+	}
+	return releases, rows.Err()
+}
+
+func (db *Database) DeleteRelease(channel string) error {
+	res, err := db.exec(`DELETE FROM release_channels WHERE name = ?`, channel)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// IsPublished reports whether fileID is live on any release channel.
+func (db *Database) IsPublished(fileID int) (bool, error) {
+	var n int
+	err := db.queryRow(`SELECT COUNT(*) FROM release_channels WHERE file_id = ?`, fileID).Scan(&n)
+	return n > 0, err
+}
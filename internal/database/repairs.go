@@ -0,0 +1,125 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Repair states of a file with a chunk found gone or damaged.
+const (
+	RepairDegraded      = "degraded"
+	RepairUnrecoverable = "unrecoverable"
+)
+
+// FileRepair is the repair state of a degraded file.
+type FileRepair struct {
+	Status     string    `json:"status"`
+	Detail     string    `json:"detail,omitempty"`
+	DetectedAt time.Time `json:"detected_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// MarkDegraded queues every file that stores the chunk or parity chunk
+// messageID for repair. Files already known to be degraded keep their state.
+func (db *Database) MarkDegraded(messageID, detail string) error {
+	now := time.Now().UTC().Format(timeLayout)
+	_, err := db.exec(`INSERT INTO file_repairs (file_id, status, detail, detected_at, updated_at)
+		SELECT file_id, ?, ?, ?, ? FROM chunks WHERE message_id = ?
+		UNION SELECT file_id, ?, ?, ?, ? FROM parity_chunks WHERE message_id = ?
+		ON CONFLICT (file_id) DO NOTHING`,
+		RepairDegraded, detail, now, now, messageID, RepairDegraded, detail, now, now, messageID)
+	return err
+}
+
+// DegradedFiles returns the IDs of files waiting for repair, oldest first.
+func (db *Database) DegradedFiles() ([]int, error) {
+	rows, err := db.query(`SELECT file_id FROM file_repairs WHERE status = ? ORDER BY detected_at`, RepairDegraded)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// FileRepairs returns the repair state of every degraded file by file ID.
+func (db *Database) FileRepairs() (map[int]*FileRepair, error) {
+	rows, err := db.query(`SELECT file_id, status, detail, detected_at, updated_at FROM file_repairs`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	repairs := make(map[int]*FileRepair)
+	for rows.Next() {
+		var id int
+		var r FileRepair
+		if err := rows.Scan(&id, &r.Status, &r.Detail, &r.DetectedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		repairs[id] = &r
+	}
+	return repairs, rows.Err()
+}
+
+// RepairStatus returns the repair state of a file, "" if it is not queued.
+func (db *Database) RepairStatus(fileID int) (string, error) {
+	var status string
+	err := db.queryRow(`SELECT status FROM file_repairs WHERE file_id = ?`, fileID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return status, err
+}
+
+// SetRepairStatus updates the repair state of a degraded file.
+func (db *Database) SetRepairStatus(fileID int, status, detail string) error {
+	_, err := db.exec(`UPDATE file_repairs SET status = ?, detail = ?, updated_at = ? WHERE file_id = ?`,
+		status, detail, time.Now().UTC().Format(timeLayout), fileID)
+	return err
+}
+
+// ClearRepair marks a file as healthy again.
+func (db *Database) ClearRepair(fileID int) error {
+	_, err := db.exec(`DELETE FROM file_repairs WHERE file_id = ?`, fileID)
+	return err
+}
+
+// ReplaceChunkMessage points every file, live or purged, that stores a chunk
+// as old to the re-uploaded copy c, and moves the chunk's replicas along.
+func (db *Database) ReplaceChunkMessage(old string, c ChunkMetadata) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []string{
+		`UPDATE chunks SET message_id = ?, channel_id = ? WHERE message_id = ?`,
+		`UPDATE purged_chunks SET message_id = ?, channel_id = ? WHERE message_id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, c.MessageID, c.ChannelID, old); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE chunk_replicas SET message_id = ? WHERE message_id = ?`, c.MessageID, old); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceParityChunk records a re-uploaded parity chunk.
+func (db *Database) ReplaceParityChunk(fileID int, p ParityChunk) error {
+	_, err := db.exec(`UPDATE parity_chunks SET message_id = ?, size = ?, sha256 = ?, channel_id = ? WHERE file_id = ? AND group_num = ? AND shard = ?`,
+		p.MessageID, p.Size, p.SHA256, p.ChannelID, fileID, p.Group, p.Shard)
+	return err
+}
@@ -0,0 +1,90 @@
+package database
+
+import "strings"
+
+// Replica is a copy of a chunk message in another channel.
+type Replica struct {
+	MessageID string
+	ChannelID string
+}
+
+// AddReplicas records copies of the chunk stored as messageID.
+func (db *Database) AddReplicas(messageID string, replicas []Replica) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, r := range replicas {
+		if _, err := tx.Exec(`INSERT INTO chunk_replicas (message_id, replica_message_id, replica_channel_id) VALUES (?, ?, ?)`,
+			messageID, r.MessageID, r.ChannelID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Replicas returns the recorded copies of the chunk stored as messageID.
+func (db *Database) Replicas(messageID string) ([]Replica, error) {
+	rows, err := db.query(`SELECT replica_message_id, replica_channel_id FROM chunk_replicas WHERE message_id = ?`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var replicas []Replica
+	for rows.Next() {
+		var r Replica
+		if err := rows.Scan(&r.MessageID, &r.ChannelID); err != nil {
+			return nil, err
+		}
+		replicas = append(replicas, r)
+	}
+	return replicas, rows.Err()
+}
+
+// TakeReplicas forgets the copies of the given chunk messages and returns
+// them, so the caller can delete them from Discord.
+func (db *Database) TakeReplicas(messageIDs []string) ([]Replica, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(messageIDs)), ",")
+	args := make([]any, len(messageIDs))
+	for i, id := range messageIDs {
+		args[i] = id
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT replica_message_id, replica_channel_id FROM chunk_replicas WHERE message_id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	var replicas []Replica
+	for rows.Next() {
+		var r Replica
+		if err := rows.Scan(&r.MessageID, &r.ChannelID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		replicas = append(replicas, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(replicas) == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.Exec(`DELETE FROM chunk_replicas WHERE message_id IN (`+placeholders+`)`, args...); err != nil {
+		return nil, err
+	}
+	return replicas, tx.Commit()
+}
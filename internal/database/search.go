@@ -0,0 +1,118 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// FileFilter selects current files of a vault. Every set field narrows the
+// result; zero values match everything.
+type FileFilter struct {
+	GuildID    string
+	Query      string // name contains it (case-insensitive), or tag equals it
+	OwnerID    string
+	MinSize    int64     // bytes, inclusive
+	MaxSize    int64     // bytes, inclusive
+	After      time.Time // uploaded at or after
+	Before     time.Time // uploaded before
+	Extensions []string  // name ends with one of them, e.g. ".png"
+	AllTags    []string  // carries every one
+	AnyTags    []string  // carries at least one
+	NoTags     []string  // carries none
+	Health     []string  // scrub result is one of them, HealthUnchecked included
+	Limit      int       // <= 0 returns every match
+	Offset     int
+}
+
+// SearchFiles returns a vault's current files whose name contains query
+// (case-insensitive) or that carry query as a tag, newest first. ownerID
+// limits the search to one owner when set; limit <= 0 returns every match.
+// The second result is the total number of matches.
+func (db *Database) SearchFiles(guildID, query, ownerID string, limit, offset int) ([]FileMetadata, int, error) {
+	return db.FilterFiles(FileFilter{GuildID: guildID, Query: query, OwnerID: ownerID, Limit: limit, Offset: offset})
+}
+
+// FilterFiles returns the files f selects, newest first, and the total
+// number of matches.
+func (db *Database) FilterFiles(f FileFilter) ([]FileMetadata, int, error) {
+	where := ` FROM files WHERE guild_id = ? AND superseded_at IS NULL`
+	args := []any{f.GuildID}
+	add := func(clause string, values ...any) {
+		where += ` AND ` + clause
+		args = append(args, values...)
+	}
+	// in adds a condition on a list of values, written as column IN (?, ...).
+	in := func(clause string, values []string) {
+		list := make([]any, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		add(strings.Replace(clause, "?", strings.TrimSuffix(strings.Repeat("?,", len(values)), ","), 1), list...)
+	}
+
+	if f.Query != "" {
+		add(`(LOWER(name) LIKE ? ESCAPE '\' OR id IN (SELECT file_id FROM file_tags WHERE tag = ?))`,
+			"%"+escapeLike(strings.ToLower(f.Query))+"%", NormalizeTag(f.Query))
+	}
+	if f.OwnerID != "" {
+		add(`owner_id = ?`, f.OwnerID)
+	}
+	if f.MinSize > 0 {
+		add(`size >= ?`, f.MinSize)
+	}
+	if f.MaxSize > 0 {
+		add(`size <= ?`, f.MaxSize)
+	}
+	if !f.After.IsZero() {
+		add(`created_at >= ?`, f.After.UTC().Format(timeLayout))
+	}
+	if !f.Before.IsZero() {
+		add(`created_at < ?`, f.Before.UTC().Format(timeLayout))
+	}
+	if len(f.Extensions) > 0 {
+		var ors []string
+		var patterns []any
+		for _, ext := range f.Extensions {
+			ors = append(ors, `LOWER(name) LIKE ? ESCAPE '\'`)
+			patterns = append(patterns, "%"+escapeLike(strings.ToLower(ext)))
+		}
+		add(`(`+strings.Join(ors, ` OR `)+`)`, patterns...)
+	}
+	for _, tag := range f.AllTags {
+		add(`id IN (SELECT file_id FROM file_tags WHERE tag = ?)`, tag)
+	}
+	if len(f.AnyTags) > 0 {
+		in(`id IN (SELECT file_id FROM file_tags WHERE tag IN (?))`, f.AnyTags)
+	}
+	if len(f.NoTags) > 0 {
+		in(`id NOT IN (SELECT file_id FROM file_tags WHERE tag IN (?))`, f.NoTags)
+	}
+	if len(f.Health) > 0 {
+		health := make([]string, len(f.Health))
+		for i, h := range f.Health {
+			if h != HealthUnchecked {
+				health[i] = h
+			}
+		}
+		in(`health IN (?)`, health)
+	}
+
+	var total int
+	if err := db.queryRow(`SELECT COUNT(*)`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	page := `SELECT ` + fileColumns + where + ` ORDER BY created_at DESC, id DESC`
+	if f.Limit > 0 {
+		page += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+	files, err := db.queryFiles(page, args...)
+	return files, total, err
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
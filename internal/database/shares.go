@@ -0,0 +1,47 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Share struct {
+	ID          int
+	Token       string
+	FileID      int
+	CreatedBy   string
+	NotifyOwner bool
+	Signature   string
+	Downloads   int
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time
+}
+
+func (db *Database) CreateShare(sh *Share) (int, error) {
+	query := `INSERT INTO shares (token, file_id, created_by, notify_owner, signature, expires_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING id`
+	var id int
+	err := db.queryRow(query, sh.Token, sh.FileID, sh.CreatedBy, sh.NotifyOwner, sh.Signature, sh.ExpiresAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (db *Database) GetShareByToken(token string) (*Share, error) {
+	query := `SELECT id, token, file_id, created_by, notify_owner, signature, downloads, expires_at, created_at FROM shares WHERE token = ?`
+	var sh Share
+	var expires sql.NullTime
+	err := db.queryRow(query, token).Scan(&sh.ID, &sh.Token, &sh.FileID, &sh.CreatedBy, &sh.NotifyOwner, &sh.Signature, &sh.Downloads, &expires, &sh.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if expires.Valid {
+		sh.ExpiresAt = &expires.Time
+	}
+	return &sh, nil
+}
+
+func (db *Database) RecordShareDownload(id int) error {
+	_, err := db.exec(`UPDATE shares SET downloads = downloads + 1 WHERE id = ?`, id)
+	return err
+}
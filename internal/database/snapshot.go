@@ -0,0 +1,20 @@
+package database
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrSnapshotUnsupported is returned by Snapshot on non-SQLite backends,
+// which have their own dump tooling.
+var ErrSnapshotUnsupported = errors.New("snapshots are only supported for SQLite")
+
+// Snapshot writes a consistent copy of the whole database to path.
+func (db *Database) Snapshot(path string) error {
+	if db.dialect.Name() != "sqlite" {
+		return ErrSnapshotUnsupported
+	}
+	os.Remove(path)
+	_, err := db.exec(`VACUUM INTO ?`, path)
+	return err
+}
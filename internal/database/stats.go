@@ -0,0 +1,159 @@
+package database
+
+import "time"
+
+type DayCount struct {
+	Day     string `json:"day"` // YYYY-MM-DD, UTC
+	Uploads int    `json:"uploads"`
+	Bytes   int64  `json:"bytes"`
+}
+
+type OwnerUsage struct {
+	OwnerID string `json:"owner_id"`
+	Files   int    `json:"files"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// VaultStats aggregates vault growth. Files and LargestFiles cover current
+// versions only; Versions and TotalBytes include superseded versions too.
+type VaultStats struct {
+	Files         int            `json:"files"`
+	Versions      int            `json:"versions"`
+	TotalBytes    int64          `json:"total_bytes"`
+	Chunks        int            `json:"chunks"`
+	StoredBytes   int64          `json:"stored_bytes"` // recorded ciphertext size
+	Health        map[string]int `json:"health"`       // versions per scrub result, "unchecked" if never scrubbed
+	LastUpload    *time.Time     `json:"last_upload,omitempty"`
+	UploadsPerDay []DayCount     `json:"uploads_per_day"`
+	LargestFiles  []FileMetadata `json:"largest_files"`
+	Owners        []OwnerUsage   `json:"owners"`
+}
+
+// Stats computes vault totals, daily uploads for the last days, and the top
+// largest current files of one vault, or of all of them for AllVaults.
+func (db *Database) Stats(guildID string, days, top int) (*VaultStats, error) {
+	st := &VaultStats{Health: map[string]int{}, UploadsPerDay: []DayCount{}, LargestFiles: []FileMetadata{}, Owners: []OwnerUsage{}}
+
+	// Every query below filters files with "WHERE <vault> ...".
+	vault, args := "1 = 1", []any{}
+	if guildID != AllVaults {
+		vault, args = "guild_id = ?", []any{guildID}
+	}
+
+	if err := db.queryRow(`SELECT COUNT(*), COALESCE(SUM(size), 0) FROM files WHERE `+vault, args...).Scan(&st.Versions, &st.TotalBytes); err != nil {
+		return nil, err
+	}
+	if err := db.queryRow(`SELECT COUNT(*) FROM files WHERE `+vault+` AND superseded_at IS NULL`, args...).Scan(&st.Files); err != nil {
+		return nil, err
+	}
+	if err := db.queryRow(`SELECT COUNT(*), COALESCE(SUM(size), 0) FROM chunks
+		WHERE file_id IN (SELECT id FROM files WHERE `+vault+`)`, args...).Scan(&st.Chunks, &st.StoredBytes); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.query(`SELECT health, COUNT(*) FROM files WHERE `+vault+` GROUP BY health`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var health string
+		var n int
+		if err := rows.Scan(&health, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if health == "" {
+			health = HealthUnchecked
+		}
+		st.Health[health] = n
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if st.Versions > 0 {
+		var last time.Time
+		if err := db.queryRow(`SELECT created_at FROM files WHERE `+vault+` ORDER BY created_at DESC LIMIT 1`, args...).Scan(&last); err != nil {
+			return nil, err
+		}
+		st.LastUpload = &last
+	}
+
+	// Bucket in Go so the same query works on every backend.
+	since := time.Now().UTC().AddDate(0, 0, -days+1).Truncate(24 * time.Hour)
+	rows, err = db.query(`SELECT size, created_at FROM files WHERE `+vault+` AND created_at >= ?`, append(args, since.Format(timeLayout))...)
+	if err != nil {
+		return nil, err
+	}
+	for d := 0; d < days; d++ {
+		st.UploadsPerDay = append(st.UploadsPerDay, DayCount{Day: since.AddDate(0, 0, d).Format("2006-01-02")})
+	}
+	buckets := make(map[string]*DayCount)
+	for i := range st.UploadsPerDay {
+		buckets[st.UploadsPerDay[i].Day] = &st.UploadsPerDay[i]
+	}
+	for rows.Next() {
+		var size int64
+		var created time.Time
+		if err := rows.Scan(&size, &created); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if b := buckets[created.UTC().Format("2006-01-02")]; b != nil {
+			b.Uploads++
+			b.Bytes += size
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if st.LargestFiles, err = db.queryFiles(`SELECT `+fileColumns+` FROM files WHERE `+vault+` AND superseded_at IS NULL ORDER BY size DESC LIMIT ?`, append(args, top)...); err != nil {
+		return nil, err
+	}
+	if st.LargestFiles == nil {
+		st.LargestFiles = []FileMetadata{}
+	}
+
+	if st.Owners, err = db.ownerUsage(vault, args); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// LargestFiles returns the top largest current files of ownerID in one
+// vault, or in all of them for AllVaults.
+func (db *Database) LargestFiles(guildID, ownerID string, top int) ([]FileMetadata, error) {
+	query, args := `SELECT `+fileColumns+` FROM files WHERE owner_id = ? AND superseded_at IS NULL`, []any{ownerID}
+	if guildID != AllVaults {
+		query += ` AND guild_id = ?`
+		args = append(args, guildID)
+	}
+	files, err := db.queryFiles(query+` ORDER BY size DESC LIMIT ?`, append(args, top)...)
+	if files == nil {
+		files = []FileMetadata{}
+	}
+	return files, err
+}
+
+// ownerUsage returns the versions and bytes stored per owner in the files
+// matching vault, most first.
+func (db *Database) ownerUsage(vault string, args []any) ([]OwnerUsage, error) {
+	rows, err := db.query(`SELECT owner_id, COUNT(*), COALESCE(SUM(size), 0) FROM files WHERE `+vault+` GROUP BY owner_id ORDER BY SUM(size) DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	owners := []OwnerUsage{}
+	for rows.Next() {
+		var o OwnerUsage
+		if err := rows.Scan(&o.OwnerID, &o.Files, &o.Bytes); err != nil {
+			return nil, err
+		}
+		owners = append(owners, o)
+	}
+	return owners, rows.Err()
+}
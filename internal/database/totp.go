@@ -0,0 +1,155 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TOTP is a user's two-factor secret. It only guards logins once Enabled.
+type TOTP struct {
+	UserID    string
+	Secret    string
+	Enabled   bool
+	LastStep  int64 // newest step a code was accepted for
+	CreatedAt time.Time
+
+	FailedAttempts int        // wrong codes in a row
+	LockedUntil    *time.Time // no codes are checked before then
+}
+
+// GetTOTP returns sql.ErrNoRows for users without two-factor
+// authentication.
+func (db *Database) GetTOTP(userID string) (*TOTP, error) {
+	var t TOTP
+	var locked sql.NullTime
+	err := db.queryRow(`SELECT user_id, secret, enabled, last_step, created_at, failed_attempts, locked_until FROM user_totp WHERE user_id = ?`, userID).
+		Scan(&t.UserID, &t.Secret, &t.Enabled, &t.LastStep, &t.CreatedAt, &t.FailedAttempts, &locked)
+	if err != nil {
+		return nil, err
+	}
+	if locked.Valid {
+		t.LockedUntil = &locked.Time
+	}
+	return &t, nil
+}
+
+// SetPendingTOTP stores a new secret for a user, not yet enabled. It
+// replaces an earlier secret that was never confirmed.
+func (db *Database) SetPendingTOTP(userID, secret string) error {
+	_, err := db.exec(`INSERT INTO user_totp (user_id, secret, enabled, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, enabled = excluded.enabled, last_step = 0, created_at = excluded.created_at`,
+		userID, secret, false, time.Now().UTC().Format(timeLayout))
+	return err
+}
+
+// EnableTOTP turns two-factor authentication on after the code for step
+// was confirmed, and replaces the user's recovery codes.
+func (db *Database) EnableTOTP(userID string, step int64, recoveryHashes []string) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE user_totp SET enabled = ?, last_step = ? WHERE user_id = ?`, true, step, userID); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes drops a user's recovery codes, used or not, for new
+// ones.
+func (db *Database) ReplaceRecoveryCodes(userID string, recoveryHashes []string) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *Tx, userID string, recoveryHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryHashes {
+		if _, err := tx.Exec(`INSERT INTO totp_recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseTOTPStep records that a code for step was accepted. It reports false
+// when a code for that step or a later one already was, so each code works
+// once even for concurrent logins.
+func (db *Database) UseTOTPStep(userID string, step int64) (bool, error) {
+	res, err := db.exec(`UPDATE user_totp SET last_step = ? WHERE user_id = ? AND last_step < ?`, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It reports false
+// for codes that are unknown or were used before.
+func (db *Database) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	res, err := db.exec(`UPDATE totp_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+		time.Now().UTC().Format(timeLayout), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// RecordTOTPFailure counts a wrong code and returns how many came in a row.
+func (db *Database) RecordTOTPFailure(userID string) (int, error) {
+	var n int
+	err := db.queryRow(`UPDATE user_totp SET failed_attempts = failed_attempts + 1 WHERE user_id = ? RETURNING failed_attempts`, userID).Scan(&n)
+	return n, err
+}
+
+// LockTOTP refuses a user's codes until the given time.
+func (db *Database) LockTOTP(userID string, until time.Time) error {
+	_, err := db.exec(`UPDATE user_totp SET locked_until = ? WHERE user_id = ?`, until.UTC().Format(timeLayout), userID)
+	return err
+}
+
+// ResetTOTPFailures clears the count of wrong codes after a right one.
+func (db *Database) ResetTOTPFailures(userID string) error {
+	_, err := db.exec(`UPDATE user_totp SET failed_attempts = 0, locked_until = NULL WHERE user_id = ? AND failed_attempts > 0`, userID)
+	return err
+}
+
+// RecoveryCodesLeft counts a user's unused recovery codes.
+func (db *Database) RecoveryCodesLeft(userID string) (int, error) {
+	var n int
+	err := db.queryRow(`SELECT COUNT(*) FROM totp_recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+// DisableTOTP removes a user's secret and recovery codes.
+func (db *Database) DisableTOTP(userID string) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
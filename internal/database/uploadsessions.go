@@ -0,0 +1,135 @@
+package database
+
+import "time"
+
+// UploadSession is an upload that can be resumed after the connection or
+// the server dropped. Size is the length of the whole file, announced when
+// the session is created.
+type UploadSession struct {
+	ID        string
+	Principal string
+	Name      string
+	Size      int64
+	ChunkSize int64
+	Policy    string // duplicate policy applied when the upload completes
+	Chunked   bool   // chunks are sent by number rather than streamed
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SessionChunk is a chunk an upload session has stored so far. HashState is
+// the marshaled SHA-256 of the file up to the end of this chunk, or empty for
+// a chunk of a chunked session that arrived before the one preceding it.
+type SessionChunk struct {
+	ChunkMetadata
+	PlainSize int64
+	HashState []byte
+}
+
+func (db *Database) CreateUploadSession(s *UploadSession) error {
+	now := time.Now().UTC()
+	_, err := db.exec(`INSERT INTO upload_sessions (id, principal, name, size, chunk_size, policy, chunked, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, s.Principal, s.Name, s.Size, s.ChunkSize, s.Policy, s.Chunked, now.Format(timeLayout), now.Format(timeLayout))
+	if err == nil {
+		s.CreatedAt, s.UpdatedAt = now, now
+	}
+	return err
+}
+
+// GetUploadSession returns sql.ErrNoRows for unknown or expired sessions.
+func (db *Database) GetUploadSession(id string) (*UploadSession, error) {
+	var s UploadSession
+	err := db.queryRow(`SELECT id, principal, name, size, chunk_size, policy, chunked, created_at, updated_at FROM upload_sessions WHERE id = ?`, id).
+		Scan(&s.ID, &s.Principal, &s.Name, &s.Size, &s.ChunkSize, &s.Policy, &s.Chunked, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UploadSessionChunks returns the chunks stored for a session by part.
+func (db *Database) UploadSessionChunks(id string) ([]SessionChunk, error) {
+	rows, err := db.query(`SELECT part_num, message_id, channel_id, size, sha256, plain_size, hash_state
+		FROM upload_session_chunks WHERE session_id = ? ORDER BY part_num`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []SessionChunk
+	for rows.Next() {
+		var c SessionChunk
+		if err := rows.Scan(&c.PartNum, &c.MessageID, &c.ChannelID, &c.Size, &c.SHA256, &c.PlainSize, &c.HashState); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, rows.Err()
+}
+
+// AddUploadSessionChunk records a stored chunk and keeps the session from
+// expiring.
+func (db *Database) AddUploadSessionChunk(id string, c SessionChunk) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO upload_session_chunks (session_id, part_num, message_id, channel_id, size, sha256, plain_size, hash_state) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, c.PartNum, c.MessageID, c.ChannelID, c.Size, c.SHA256, c.PlainSize, c.HashState); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE upload_sessions SET updated_at = ? WHERE id = ?`, time.Now().UTC().Format(timeLayout), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TruncateUploadSession forgets the session's chunks after part parts.
+func (db *Database) TruncateUploadSession(id string, parts int) error {
+	_, err := db.exec(`DELETE FROM upload_session_chunks WHERE session_id = ? AND part_num > ?`, id, parts)
+	return err
+}
+
+// DeleteUploadSession forgets a session and its chunks. The chunk messages
+// are left to the caller.
+func (db *Database) DeleteUploadSession(id string) error {
+	_, err := db.exec(`DELETE FROM upload_sessions WHERE id = ?`, id)
+	return err
+}
+
+// ExpireUploadSessions deletes sessions not updated since cutoff and returns
+// the chunks they had stored.
+func (db *Database) ExpireUploadSessions(cutoff time.Time) ([]ChunkMetadata, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before := cutoff.UTC().Format(timeLayout)
+	rows, err := tx.Query(`SELECT c.message_id, c.channel_id FROM upload_session_chunks c
+		JOIN upload_sessions s ON s.id = c.session_id WHERE s.updated_at < ?`, before)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []ChunkMetadata
+	for rows.Next() {
+		var c ChunkMetadata
+		if err := rows.Scan(&c.MessageID, &c.ChannelID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM upload_sessions WHERE updated_at < ?`, before); err != nil {
+		return nil, err
+	}
+	return chunks, tx.Commit()
+}
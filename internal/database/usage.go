@@ -0,0 +1,69 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Transfer usage subjects.
+const (
+	UsageUser = "user"
+	UsageKey  = "key"
+)
+
+// TransferUsage is the number of plaintext bytes a user or API key moved
+// through the vault during one calendar month (UTC).
+type TransferUsage struct {
+	Period      string `json:"period"`
+	SubjectType string `json:"subject_type"`
+	SubjectID   string `json:"subject_id"`
+	Uploaded    int64  `json:"uploaded"`
+	Downloaded  int64  `json:"downloaded"`
+}
+
+// UsagePeriod returns the accounting period containing t, e.g. "2024-05".
+func UsagePeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// RecordTransfer adds bytes to the subject's usage for the current month.
+func (db *Database) RecordTransfer(subjectType, subjectID string, uploaded, downloaded int64) error {
+	_, err := db.exec(`INSERT INTO transfer_usage (period, subject_type, subject_id, uploaded, downloaded) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (period, subject_type, subject_id) DO UPDATE SET
+			uploaded = transfer_usage.uploaded + excluded.uploaded,
+			downloaded = transfer_usage.downloaded + excluded.downloaded`,
+		UsagePeriod(time.Now()), subjectType, subjectID, uploaded, downloaded)
+	return err
+}
+
+// GetTransfer returns a subject's usage for period; unknown subjects have zero usage.
+func (db *Database) GetTransfer(period, subjectType, subjectID string) (*TransferUsage, error) {
+	u := TransferUsage{Period: period, SubjectType: subjectType, SubjectID: subjectID}
+	err := db.queryRow(`SELECT uploaded, downloaded FROM transfer_usage WHERE period = ? AND subject_type = ? AND subject_id = ?`,
+		period, subjectType, subjectID).Scan(&u.Uploaded, &u.Downloaded)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// ListTransfer returns every subject's usage for period, heaviest first.
+func (db *Database) ListTransfer(period string) ([]TransferUsage, error) {
+	rows, err := db.query(`SELECT period, subject_type, subject_id, uploaded, downloaded FROM transfer_usage
+		WHERE period = ? ORDER BY uploaded + downloaded DESC`, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := []TransferUsage{}
+	for rows.Next() {
+		var u TransferUsage
+		if err := rows.Scan(&u.Period, &u.SubjectType, &u.SubjectID, &u.Uploaded, &u.Downloaded); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
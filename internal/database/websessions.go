@@ -0,0 +1,121 @@
+package database
+
+import "time"
+
+// WebSession is a browser logged in through /api/login. The cookie itself
+// is only known to the browser; the database keeps its SHA-256.
+type WebSession struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Admin      bool      `json:"admin"`
+	KeyID      string    `json:"key_id,omitempty"` // API key the login used
+	Scope      string    `json:"scope,omitempty"`
+	RemoteAddr string    `json:"remote_addr"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+const webSessionColumns = `id, user_id, admin, key_id, scope, remote_addr, user_agent, created_at, last_seen_at`
+
+func scanWebSession(row rowScanner) (*WebSession, error) {
+	var ws WebSession
+	if err := row.Scan(&ws.ID, &ws.UserID, &ws.Admin, &ws.KeyID, &ws.Scope, &ws.RemoteAddr, &ws.UserAgent, &ws.CreatedAt, &ws.LastSeenAt); err != nil {
+		return nil, err
+	}
+	return &ws, nil
+}
+
+// CreateWebSession stores ws under the SHA-256 of its cookie.
+func (db *Database) CreateWebSession(ws *WebSession, tokenHash string) error {
+	now := time.Now().UTC()
+	_, err := db.exec(`INSERT INTO web_sessions (id, token_hash, user_id, admin, key_id, scope, remote_addr, user_agent, created_at, last_seen_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ws.ID, tokenHash, ws.UserID, ws.Admin, ws.KeyID, ws.Scope, ws.RemoteAddr, ws.UserAgent, now.Format(timeLayout), now.Format(timeLayout))
+	if err == nil {
+		ws.CreatedAt, ws.LastSeenAt = now, now
+	}
+	return err
+}
+
+// WebSessionByHash returns the session with that cookie hash, or
+// sql.ErrNoRows. It may have expired; callers check.
+func (db *Database) WebSessionByHash(tokenHash string) (*WebSession, error) {
+	return scanWebSession(db.queryRow(`SELECT `+webSessionColumns+` FROM web_sessions WHERE token_hash = ?`, tokenHash))
+}
+
+// GetWebSession returns sql.ErrNoRows for unknown sessions.
+func (db *Database) GetWebSession(id string) (*WebSession, error) {
+	return scanWebSession(db.queryRow(`SELECT `+webSessionColumns+` FROM web_sessions WHERE id = ?`, id))
+}
+
+// ListWebSessions returns the sessions of userID, or every user's when it
+// is empty, most recently used first. Sessions last seen before idleCutoff
+// or created before ageCutoff have expired and are left out; zero cutoffs
+// leave out nothing.
+func (db *Database) ListWebSessions(userID string, idleCutoff, ageCutoff time.Time) ([]WebSession, error) {
+	query := `SELECT ` + webSessionColumns + ` FROM web_sessions WHERE last_seen_at >= ? AND created_at >= ?`
+	args := []any{idleCutoff.UTC().Format(timeLayout), ageCutoff.UTC().Format(timeLayout)}
+	if userID != "" {
+		query += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+	rows, err := db.query(query+` ORDER BY last_seen_at DESC, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []WebSession{}
+	for rows.Next() {
+		ws, err := scanWebSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *ws)
+	}
+	return sessions, rows.Err()
+}
+
+// TouchWebSession records that a session was used now, unless it already
+// was since the given time.
+func (db *Database) TouchWebSession(id string, since time.Time) error {
+	_, err := db.exec(`UPDATE web_sessions SET last_seen_at = ? WHERE id = ? AND last_seen_at < ?`,
+		time.Now().UTC().Format(timeLayout), id, since.UTC().Format(timeLayout))
+	return err
+}
+
+// DeleteWebSession ends one session.
+func (db *Database) DeleteWebSession(id string) error {
+	_, err := db.exec(`DELETE FROM web_sessions WHERE id = ?`, id)
+	return err
+}
+
+// DeleteUserWebSessions ends every session of a user and returns how many
+// there were.
+func (db *Database) DeleteUserWebSessions(userID string) (int64, error) {
+	res, err := db.exec(`DELETE FROM web_sessions WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteKeyWebSessions ends every session logged in with an API key.
+func (db *Database) DeleteKeyWebSessions(keyID string) (int64, error) {
+	res, err := db.exec(`DELETE FROM web_sessions WHERE key_id = ?`, keyID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ExpireWebSessions deletes the sessions ListWebSessions would leave out
+// for the same cutoffs.
+func (db *Database) ExpireWebSessions(idleCutoff, ageCutoff time.Time) (int64, error) {
+	res, err := db.exec(`DELETE FROM web_sessions WHERE last_seen_at < ? OR created_at < ?`,
+		idleCutoff.UTC().Format(timeLayout), ageCutoff.UTC().Format(timeLayout))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,52 @@
+// Package demo runs the vault without a Discord bot or a database on disk,
+// so the web UI and API can be tried out: Discord is replaced by an
+// in-memory fake and metadata lives in an in-memory SQLite database.
+// Everything is gone when the process exits.
+package demo
+
+import (
+	"crypto/rand"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ChannelID is the storage channel of the demo vault.
+const ChannelID = "100000000000000001"
+
+// DatabaseURL is a SQLite database held in memory and shared by every
+// pooled connection.
+const DatabaseURL = "file:demo?mode=memory&cache=shared"
+
+// Setup overrides the settings that would otherwise point at real Discord
+// channels, keys, and files, and routes Discord traffic of the process to a
+// fresh in-memory Discord. It must run before the configuration is loaded
+// and before any Discord session is created. The returned function removes
+// the demo's temporary files.
+func Setup() (func(), error) {
+	dir, err := os.MkdirTemp("", "discordvault-demo-")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	env := map[string]string{
+		"DISCORD_TOKEN":      "demo",
+		"DISCORD_CHANNEL_ID": ChannelID,
+		"GUILD_CHANNELS":     "",
+		"ENCRYPTION_KEY":     (rand.Text() + rand.Text())[:32],
+		"DATABASE_URL":       DatabaseURL,
+		"SIGNING_KEY_PATH":   filepath.Join(dir, "vault_signing.key"),
+		"HA_LEASE_TTL":       "0",
+	}
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	http.DefaultTransport = NewDiscord(http.DefaultTransport)
+	log.Printf("[DEMO] Using in-memory Discord and metadata; nothing is kept after exit")
+	return cleanup, nil
+}
@@ -0,0 +1,311 @@
+package demo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	apiHost = "discord.com"
+	cdnHost = "cdn.discordapp.com"
+
+	// discordEpoch is the first millisecond of 2015, where snowflakes start.
+	discordEpoch = 1420070400000
+)
+
+// Discord is an in-memory stand-in for the parts of the Discord REST API and
+// attachment CDN the vault uses: posting, fetching, listing, editing, and
+// deleting channel messages with attachments, and opening DM channels.
+// Requests to any other host go to Fallback.
+type Discord struct {
+	Fallback http.RoundTripper
+
+	mu       sync.Mutex
+	nextID   uint64
+	messages map[string][]*discordgo.Message // by channel, oldest first
+	files    map[string][]byte               // attachment ID -> content
+}
+
+// NewDiscord returns an empty in-memory Discord passing other requests to
+// fallback.
+func NewDiscord(fallback http.RoundTripper) *Discord {
+	return &Discord{
+		Fallback: fallback,
+		messages: make(map[string][]*discordgo.Message),
+		files:    make(map[string][]byte),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (d *Discord) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.URL.Hostname() {
+	case apiHost:
+		return d.api(req)
+	case cdnHost:
+		return d.cdn(req)
+	}
+	return d.Fallback.RoundTrip(req)
+}
+
+// api serves /api/v*/... requests.
+func (d *Discord) api(req *http.Request) (*http.Response, error) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "api" {
+		return respond(req, http.StatusNotFound, apiError("Unknown endpoint"))
+	}
+	parts = parts[2:]
+
+	switch {
+	case len(parts) == 3 && parts[0] == "users" && parts[2] == "channels" && req.Method == http.MethodPost:
+		return respond(req, http.StatusOK, &discordgo.Channel{ID: d.id(), Type: discordgo.ChannelTypeDM})
+	case len(parts) < 3 || parts[0] != "channels" || parts[2] != "messages":
+		return respond(req, http.StatusNotFound, apiError("Unknown endpoint (not available in demo mode)"))
+	}
+
+	channelID := parts[1]
+	switch {
+	case len(parts) == 3 && req.Method == http.MethodGet:
+		return respond(req, http.StatusOK, d.list(channelID, req.URL.Query()))
+	case len(parts) == 3 && req.Method == http.MethodPost:
+		msg, err := d.post(channelID, req)
+		if err != nil {
+			return respond(req, http.StatusBadRequest, apiError(err.Error()))
+		}
+		return respond(req, http.StatusOK, msg)
+	case len(parts) == 4 && parts[3] == "bulk-delete" && req.Method == http.MethodPost:
+		var body struct {
+			Messages []string `json:"messages"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return respond(req, http.StatusBadRequest, apiError(err.Error()))
+		}
+		for _, id := range body.Messages {
+			d.delete(channelID, id)
+		}
+		return respond(req, http.StatusNoContent, nil)
+	case len(parts) == 4:
+		return d.message(channelID, parts[3], req)
+	}
+	return respond(req, http.StatusNotFound, apiError("Unknown endpoint"))
+}
+
+// message serves GET, PATCH, and DELETE on a single message.
+func (d *Discord) message(channelID, messageID string, req *http.Request) (*http.Response, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	idx := slices.IndexFunc(d.messages[channelID], func(m *discordgo.Message) bool { return m.ID == messageID })
+	if idx < 0 {
+		return respond(req, http.StatusNotFound, apiError("Unknown Message"))
+	}
+	msg := d.messages[channelID][idx]
+
+	switch req.Method {
+	case http.MethodGet:
+		return respond(req, http.StatusOK, msg)
+	case http.MethodPatch:
+		var edit struct {
+			Content *string                    `json:"content"`
+			Embeds  *[]*discordgo.MessageEmbed `json:"embeds"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&edit); err != nil {
+			return respond(req, http.StatusBadRequest, apiError(err.Error()))
+		}
+		if edit.Content != nil {
+			msg.Content = *edit.Content
+		}
+		if edit.Embeds != nil {
+			msg.Embeds = *edit.Embeds
+		}
+		now := time.Now()
+		msg.EditedTimestamp = &now
+		return respond(req, http.StatusOK, msg)
+	case http.MethodDelete:
+		d.removeLocked(channelID, idx)
+		return respond(req, http.StatusNoContent, nil)
+	}
+	return respond(req, http.StatusMethodNotAllowed, apiError("Method not allowed"))
+}
+
+// post stores a message sent as JSON or as multipart with attachments.
+func (d *Discord) post(channelID string, req *http.Request) (*discordgo.Message, error) {
+	var send discordgo.MessageSend
+	type upload struct {
+		name string
+		data []byte
+	}
+	var uploads []upload
+
+	mediaType, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		mr := multipart.NewReader(req.Body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return nil, err
+			}
+			if part.FormName() == "payload_json" {
+				if err := json.Unmarshal(data, &send); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			uploads = append(uploads, upload{name: part.FileName(), data: data})
+		}
+	} else if err := json.NewDecoder(req.Body).Decode(&send); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	msg := &discordgo.Message{
+		ID:        d.idLocked(),
+		ChannelID: channelID,
+		Content:   send.Content,
+		Embeds:    send.Embeds,
+		Timestamp: time.Now(),
+		Author:    &discordgo.User{ID: "0", Username: "Discord Vault (demo)", Bot: true},
+	}
+	for _, u := range uploads {
+		id := d.idLocked()
+		d.files[id] = u.data
+		msg.Attachments = append(msg.Attachments, &discordgo.MessageAttachment{
+			ID:       id,
+			Filename: u.name,
+			Size:     len(u.data),
+			URL:      fmt.Sprintf("https://%s/attachments/%s/%s/%s", cdnHost, channelID, id, u.name),
+		})
+	}
+	d.messages[channelID] = append(d.messages[channelID], msg)
+	return msg, nil
+}
+
+// list returns up to limit messages, newest first, honouring before and
+// after like Discord does.
+func (d *Discord) list(channelID string, q map[string][]string) []*discordgo.Message {
+	get := func(key string) string {
+		if v := q[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	limit, err := strconv.Atoi(get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	before, after := snowflake(get("before")), snowflake(get("after"))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := []*discordgo.Message{}
+	msgs := d.messages[channelID]
+	for idx := len(msgs) - 1; idx >= 0 && len(out) < limit; idx-- {
+		id := snowflake(msgs[idx].ID)
+		if (before != 0 && id >= before) || (after != 0 && id <= after) {
+			continue
+		}
+		out = append(out, msgs[idx])
+	}
+	return out
+}
+
+func (d *Discord) delete(channelID, messageID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if idx := slices.IndexFunc(d.messages[channelID], func(m *discordgo.Message) bool { return m.ID == messageID }); idx >= 0 {
+		d.removeLocked(channelID, idx)
+	}
+}
+
+func (d *Discord) removeLocked(channelID string, idx int) {
+	for _, att := range d.messages[channelID][idx].Attachments {
+		delete(d.files, att.ID)
+	}
+	d.messages[channelID] = slices.Delete(d.messages[channelID], idx, idx+1)
+}
+
+// cdn serves /attachments/<channel>/<attachment>/<name>.
+func (d *Discord) cdn(req *http.Request) (*http.Response, error) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "attachments" {
+		return respond(req, http.StatusNotFound, nil)
+	}
+	d.mu.Lock()
+	data, ok := d.files[parts[2]]
+	d.mu.Unlock()
+	if !ok {
+		return respond(req, http.StatusNotFound, nil)
+	}
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Status:        "200 OK",
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/octet-stream"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+func (d *Discord) id() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.idLocked()
+}
+
+// idLocked returns a new snowflake; IDs grow with time like Discord's.
+func (d *Discord) idLocked() string {
+	d.nextID = max(d.nextID+1, uint64(time.Now().UnixMilli()-discordEpoch)<<22)
+	return strconv.FormatUint(d.nextID, 10)
+}
+
+func snowflake(s string) uint64 {
+	n, _ := strconv.ParseUint(s, 10, 64)
+	return n
+}
+
+func apiError(message string) *discordgo.APIErrorMessage {
+	return &discordgo.APIErrorMessage{Message: message}
+}
+
+// respond builds a JSON response; a nil body sends none.
+func respond(req *http.Request, status int, body any) (*http.Response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	return &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
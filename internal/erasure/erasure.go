@@ -0,0 +1,249 @@
+// Package erasure implements the systematic Reed-Solomon code used for
+// parity chunks. A group of Data chunks gets Parity extra chunks, and any
+// Data chunks of the group, data or parity, are enough to rebuild the rest.
+//
+// The code works over GF(2^8) with the polynomial x^8+x^4+x^3+x^2+1 (0x11d).
+// Its matrix is a Vandermonde matrix normalised so its top rows are the
+// identity, which keeps data chunks unchanged and makes every square
+// submatrix invertible.
+package erasure
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxShards is the largest Data+Parity the field allows.
+const MaxShards = 256
+
+// ErrTooFewShards means fewer than Data chunks of a group are left.
+var ErrTooFewShards = errors.New("too few chunks left to rebuild the group")
+
+var (
+	expTable [510]byte
+	logTable [256]byte
+	mulTable [256][256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		expTable[i] = byte(x)
+		logTable[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < len(expTable); i++ {
+		expTable[i] = expTable[i-255]
+	}
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			mulTable[a][b] = expTable[int(logTable[a])+int(logTable[b])]
+		}
+	}
+}
+
+func inverse(a byte) byte { return expTable[255-int(logTable[a])] }
+
+func power(a byte, n int) byte {
+	if n == 0 {
+		return 1
+	}
+	if a == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])*n%255]
+}
+
+// mulAdd adds c*src to dst; dst must be at least as long as src.
+func mulAdd(dst, src []byte, c byte) {
+	if c == 0 {
+		return
+	}
+	row := &mulTable[c]
+	for i, v := range src {
+		dst[i] ^= row[v]
+	}
+}
+
+type matrix [][]byte
+
+func newMatrix(rows, cols int) matrix {
+	m := make(matrix, rows)
+	for r := range m {
+		m[r] = make([]byte, cols)
+	}
+	return m
+}
+
+func (m matrix) mul(o matrix) matrix {
+	out := newMatrix(len(m), len(o[0]))
+	for r := range m {
+		for c := range o[0] {
+			var v byte
+			for k := range o {
+				v ^= mulTable[m[r][k]][o[k][c]]
+			}
+			out[r][c] = v
+		}
+	}
+	return out
+}
+
+// invert returns the inverse of a square matrix by Gauss-Jordan elimination.
+func (m matrix) invert() (matrix, error) {
+	n := len(m)
+	work := newMatrix(n, 2*n)
+	for r := range m {
+		copy(work[r], m[r])
+		work[r][n+r] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && work[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil, errors.New("erasure: singular matrix")
+		}
+		work[col], work[pivot] = work[pivot], work[col]
+		if v := work[col][col]; v != 1 {
+			scale := inverse(v)
+			for c := range work[col] {
+				work[col][c] = mulTable[work[col][c]][scale]
+			}
+		}
+		for r := 0; r < n; r++ {
+			if r != col && work[r][col] != 0 {
+				f := work[r][col]
+				for c := range work[r] {
+					work[r][c] ^= mulTable[f][work[col][c]]
+				}
+			}
+		}
+	}
+	inv := newMatrix(n, n)
+	for r := range inv {
+		copy(inv[r], work[r][n:])
+	}
+	return inv, nil
+}
+
+// Code is a Reed-Solomon code with Data data chunks and Parity parity chunks
+// per group.
+type Code struct {
+	Data   int
+	Parity int
+	matrix matrix // (Data+Parity) x Data, identity on top
+}
+
+// New builds the code for data+parity chunks per group.
+func New(data, parity int) (*Code, error) {
+	if data < 1 || parity < 1 || data+parity > MaxShards {
+		return nil, fmt.Errorf("erasure: need at least 1 data and 1 parity chunk and at most %d in total, got %d+%d", MaxShards, data, parity)
+	}
+	total := data + parity
+	vandermonde := newMatrix(total, data)
+	for r := range vandermonde {
+		for c := range vandermonde[r] {
+			vandermonde[r][c] = power(byte(r), c)
+		}
+	}
+	top, err := vandermonde[:data].invert()
+	if err != nil {
+		return nil, err
+	}
+	return &Code{Data: data, Parity: parity, matrix: vandermonde.mul(top)}, nil
+}
+
+// Encoder computes the parity chunks of one group as its data chunks arrive,
+// so the group never has to be held in memory. Chunks may differ in length;
+// shorter ones count as padded with zeros.
+type Encoder struct {
+	code   *Code
+	parity [][]byte
+	count  int
+}
+
+func (c *Code) NewEncoder() *Encoder {
+	return &Encoder{code: c, parity: make([][]byte, c.Parity)}
+}
+
+// Add folds the next data chunk of the group into the parity.
+func (e *Encoder) Add(chunk []byte) {
+	if e.count == e.code.Data {
+		panic("erasure: group is full")
+	}
+	for j := range e.parity {
+		if len(e.parity[j]) < len(chunk) {
+			e.parity[j] = append(e.parity[j], make([]byte, len(chunk)-len(e.parity[j]))...)
+		}
+		mulAdd(e.parity[j], chunk, e.code.matrix[e.code.Data+j][e.count])
+	}
+	e.count++
+}
+
+// Count is the number of data chunks added since the last Parity.
+func (e *Encoder) Count() int { return e.count }
+
+// Parity returns the parity chunks of the group and starts a new one. A
+// group with fewer than Data chunks is encoded as if the rest were empty.
+func (e *Encoder) Parity() [][]byte {
+	parity := e.parity
+	e.parity, e.count = make([][]byte, e.code.Parity), 0
+	return parity
+}
+
+// Reconstruct fills in the missing (nil) data chunks of a group. chunks
+// holds the Data data chunks followed by the Parity parity chunks; sizes
+// holds the length of each data chunk, to which rebuilt chunks are cut.
+// Slots past the end of a short final group must be passed as empty, not
+// nil, chunks.
+func (c *Code) Reconstruct(chunks [][]byte, sizes []int) error {
+	if len(chunks) != c.Data+c.Parity || len(sizes) != c.Data {
+		return fmt.Errorf("erasure: want %d chunks and %d sizes, got %d and %d", c.Data+c.Parity, c.Data, len(chunks), len(sizes))
+	}
+	var missing []int
+	for i := 0; i < c.Data; i++ {
+		if chunks[i] == nil {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var present []int
+	length := 0
+	for i, chunk := range chunks {
+		if chunk == nil {
+			continue
+		}
+		if len(present) < c.Data {
+			present = append(present, i)
+		}
+		length = max(length, len(chunk))
+	}
+	if len(present) < c.Data {
+		return ErrTooFewShards
+	}
+
+	sub := newMatrix(c.Data, c.Data)
+	for r, i := range present {
+		copy(sub[r], c.matrix[i])
+	}
+	decode, err := sub.invert()
+	if err != nil {
+		return err
+	}
+	for _, i := range missing {
+		out := make([]byte, max(length, sizes[i]))
+		for j, p := range present {
+			mulAdd(out, chunks[p], decode[i][j])
+		}
+		chunks[i] = out[:sizes[i]]
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package erasure
+
+import (
+	"bytes"
+	"errors"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// group returns data chunks of uneven lengths, some of them empty.
+func group(rng *rand.Rand, n int) [][]byte {
+	chunks := make([][]byte, n)
+	for i := range chunks {
+		chunks[i] = make([]byte, rng.Intn(64))
+		rng.Read(chunks[i])
+	}
+	return chunks
+}
+
+func encode(c *Code, data [][]byte) [][]byte {
+	enc := c.NewEncoder()
+	for _, chunk := range data {
+		enc.Add(chunk)
+	}
+	return append(append([][]byte{}, data...), enc.Parity()...)
+}
+
+func sizes(data [][]byte) []int {
+	s := make([]int, len(data))
+	for i, chunk := range data {
+		s[i] = len(chunk)
+	}
+	return s
+}
+
+func TestReconstructEveryErasure(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, shape := range [][2]int{{1, 1}, {1, 3}, {2, 2}, {3, 1}, {4, 2}, {5, 3}, {3, 5}} {
+		c, err := New(shape[0], shape[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := group(rng, c.Data)
+		full := encode(c, data)
+		total := c.Data + c.Parity
+		for lost := 0; lost < 1<<total; lost++ {
+			if bits.OnesCount(uint(lost)) > c.Parity {
+				continue
+			}
+			chunks := append([][]byte{}, full...)
+			for i := range chunks {
+				if lost>>i&1 == 1 {
+					chunks[i] = nil
+				}
+			}
+			if err := c.Reconstruct(chunks, sizes(data)); err != nil {
+				t.Fatalf("%d+%d, lost %b: %v", c.Data, c.Parity, lost, err)
+			}
+			for i, want := range data {
+				if !bytes.Equal(chunks[i], want) {
+					t.Fatalf("%d+%d, lost %b: chunk %d = %x, want %x", c.Data, c.Parity, lost, i, chunks[i], want)
+				}
+			}
+		}
+	}
+}
+
+func TestReconstructShortGroup(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	c, err := New(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := group(rng, 2)
+	full := encode(c, data)
+	// The two slots past the end of the group are empty, not missing.
+	chunks := [][]byte{nil, nil, {}, {}, full[2], full[3]}
+	if err := c.Reconstruct(chunks, append(sizes(data), 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range data {
+		if !bytes.Equal(chunks[i], want) {
+			t.Errorf("chunk %d = %x, want %x", i, chunks[i], want)
+		}
+	}
+}
+
+func TestReconstructTooFew(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	c, err := New(3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := group(rng, c.Data)
+	chunks := encode(c, data)
+	chunks[0], chunks[1], chunks[4] = nil, nil, nil
+	if err := c.Reconstruct(chunks, sizes(data)); !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("Reconstruct = %v, want ErrTooFewShards", err)
+	}
+}
+
+func TestNewRejectsBadShapes(t *testing.T) {
+	for _, shape := range [][2]int{{0, 1}, {1, 0}, {200, 57}} {
+		if _, err := New(shape[0], shape[1]); err == nil {
+			t.Errorf("New(%d, %d) succeeded", shape[0], shape[1])
+		}
+	}
+	if _, err := New(200, 56); err != nil {
+		t.Errorf("New(200, 56): %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package health
+
+import (
+	"context"
+	"discordvault/internal/proxy"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const statusURL = "https://discordstatus.com/api/v2/status.json"
+
+// errorWindow and errorThreshold define "widespread 5xx": this many server
+// errors from Discord within the window pauses background work.
+const (
+	errorWindow    = time.Minute
+	errorThreshold = 5
+)
+
+// Monitor tracks whether Discord is currently usable, combining the public
+// status page with the 5xx rate observed on our own API calls.
+type Monitor struct {
+	// OnChange is called whenever the vault pauses or resumes.
+	OnChange func(degraded bool, reason string)
+
+	client *http.Client
+
+	mu          sync.Mutex
+	incident    string
+	serverErrs  []time.Time
+	recheck     *time.Timer // evaluates again once the oldest 5xx expires
+	requests    int64
+	failures    int64
+	degraded    bool
+	reason      string
+	recoveredCh chan struct{}
+}
+
+func NewMonitor() *Monitor {
+	return &Monitor{
+		client:      &http.Client{Timeout: 10 * time.Second, Transport: proxy.Transport()},
+		recoveredCh: make(chan struct{}),
+	}
+}
+
+// Paused reports whether background jobs and writes should hold off.
+func (m *Monitor) Paused() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.degraded
+}
+
+// Reason describes the current incident, if any.
+func (m *Monitor) Reason() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reason
+}
+
+// Calls returns how many Discord API calls were made since start and how many
+// of them failed with a server error, a rate limit, or a network error.
+func (m *Monitor) Calls() (requests, failures int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests, m.failures
+}
+
+// Wait blocks until Discord is healthy again or ctx ends. Writes call it
+// before sending chunks so they queue during incidents instead of failing.
+func (m *Monitor) Wait(ctx context.Context) error {
+	for {
+		m.mu.Lock()
+		if !m.degraded {
+			m.mu.Unlock()
+			return nil
+		}
+		ch := m.recoveredCh
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+// Poll checks the Discord status page every interval until ctx ends.
+func (m *Monitor) Poll(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.checkStatusPage(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) checkStatusPage(ctx context.Context) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		log.Printf("[HEALTH] Status page unreachable: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status struct {
+			Indicator   string `json:"indicator"`
+			Description string `json:"description"`
+		} `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	switch body.Status.Indicator {
+	case "major", "critical":
+		m.incident = body.Status.Description
+	default:
+		m.incident = ""
+	}
+	m.mu.Unlock()
+	m.evaluate()
+}
+
+// observe records the outcome of one Discord API call.
+func (m *Monitor) observe(status int) {
+	now := time.Now()
+	m.mu.Lock()
+	m.requests++
+	if status >= 500 || status == http.StatusTooManyRequests {
+		m.failures++
+	}
+	if status >= 500 {
+		m.serverErrs = append(m.serverErrs, now)
+	}
+	m.mu.Unlock()
+	m.evaluate()
+}
+
+// evaluate decides whether Discord is degraded. Errors older than
+// errorWindow are dropped first, so a 5xx burst ends once it is a minute
+// old even when no new call comes in.
+func (m *Monitor) evaluate() {
+	now := time.Now()
+	m.mu.Lock()
+	kept := m.serverErrs[:0]
+	for _, t := range m.serverErrs {
+		if now.Sub(t) < errorWindow {
+			kept = append(kept, t)
+		}
+	}
+	m.serverErrs = kept
+	var reasons []string
+	if m.incident != "" {
+		reasons = append(reasons, "Discord status: "+m.incident)
+	}
+	if len(m.serverErrs) >= errorThreshold {
+		reasons = append(reasons, fmt.Sprintf("%d Discord 5xx errors in the last minute", len(m.serverErrs)))
+		// Writes wait while degraded, so no new call may come to end it
+		if m.recheck == nil {
+			m.recheck = time.AfterFunc(errorWindow-now.Sub(m.serverErrs[0]), func() {
+				m.mu.Lock()
+				m.recheck = nil
+				m.mu.Unlock()
+				m.evaluate()
+			})
+		}
+	}
+	degraded := len(reasons) > 0
+	reason := strings.Join(reasons, "; ")
+
+	changed := degraded != m.degraded
+	m.degraded = degraded
+	m.reason = reason
+	if changed && !degraded {
+		close(m.recoveredCh)
+		m.recoveredCh = make(chan struct{})
+	}
+	onChange := m.OnChange
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if degraded {
+		log.Printf("[HEALTH] Discord degraded, pausing background work: %s", reason)
+	} else {
+		log.Printf("[HEALTH] Discord recovered, resuming background work")
+	}
+	if onChange != nil {
+		go onChange(degraded, reason)
+	}
+}
+
+// Transport wraps base so every Discord API response feeds the 5xx detector.
+func (m *Monitor) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := base.RoundTrip(req)
+		if strings.HasSuffix(req.URL.Hostname(), "discord.com") {
+			switch {
+			case err != nil:
+				m.observe(599)
+			default:
+				m.observe(resp.StatusCode)
+			}
+		}
+		return resp, err
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -0,0 +1,87 @@
+// Package i18n translates the bot's user-facing text. Catalogs are keyed by
+// the English source text, so English needs no catalog and any string
+// missing from a catalog falls back to English.
+//
+// Catalogs live in locales/<locale>.json, named by Discord locale code
+// ("fi", "de", "pt-BR"), as a flat object of English text to translation.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var catalogs = mustLoad()
+
+func mustLoad() map[string]map[string]string {
+	entries, err := fs.ReadDir(localeFiles, "locales")
+	if err != nil {
+		panic(err)
+	}
+	out := make(map[string]map[string]string)
+	for _, e := range entries {
+		data, err := localeFiles.ReadFile("locales/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: locales/%s: %v", e.Name(), err))
+		}
+		out[strings.TrimSuffix(e.Name(), ".json")] = catalog
+	}
+	return out
+}
+
+// Locales lists the locales with a catalog, sorted.
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// T translates text into locale, falling back to the English text, and
+// formats it with args like fmt.Sprintf when any are given.
+func T(locale, text string, args ...any) string {
+	if translated, ok := Translate(locale, text); ok {
+		text = translated
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// Known reports whether locale is English or has a catalog, directly or
+// through its language.
+func Known(locale string) bool {
+	lang, _, _ := strings.Cut(locale, "-")
+	if lang == "en" {
+		return true
+	}
+	_, exact := catalogs[locale]
+	_, base := catalogs[lang]
+	return exact || base
+}
+
+// Translate returns text in locale. A regional locale such as "de-AT" falls
+// back to its language's catalog. ok is false when no translation exists.
+func Translate(locale, text string) (string, bool) {
+	catalog, found := catalogs[locale]
+	if !found {
+		lang, _, _ := strings.Cut(locale, "-")
+		catalog = catalogs[lang]
+	}
+	translated, ok := catalog[text]
+	return translated, ok && translated != ""
+}
@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"context"
+	"discordvault/internal/config"
+	"discordvault/internal/database"
+	"log"
+	"path"
+)
+
+// ArtifactRetention keeps only the newest CI runs per branch as configured by
+// ARTIFACT_KEEP. Expired artifacts are released to the compaction job, which
+// deletes their chunk messages from Discord.
+type ArtifactRetention struct {
+	DB    *database.Database
+	Rules []config.KeepRule
+}
+
+func (a *ArtifactRetention) Run(ctx context.Context) error {
+	artifacts, err := a.DB.ListArtifacts("", "", "")
+	if err != nil {
+		return err
+	}
+
+	// Artifacts arrive grouped by project and branch, newest first.
+	expired := 0
+	runs := make(map[string]map[string]bool)
+	for _, art := range artifacts {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		keep, ok := a.keepFor(art.Branch)
+		if !ok || keep == 0 {
+			continue
+		}
+		group := art.Project + "\x00" + art.Branch
+		if runs[group] == nil {
+			runs[group] = make(map[string]bool)
+		}
+		if runs[group][art.RunID] || len(runs[group]) < keep {
+			runs[group][art.RunID] = true
+			continue
+		}
+		if err := a.DB.ReleaseFile(art.FileID); err != nil {
+			log.Printf("[JOBS ERR] Could not expire artifact #%d: %v", art.FileID, err)
+			continue
+		}
+		expired++
+	}
+
+	if expired > 0 {
+		log.Printf("[JOBS] Artifact retention expired %d files", expired)
+	}
+	return nil
+}
+
+// keepFor returns the run limit of the first rule matching branch.
+func (a *ArtifactRetention) keepFor(branch string) (int, bool) {
+	for _, rule := range a.Rules {
+		if ok, _ := path.Match(rule.Pattern, branch); ok || rule.Pattern == "*" {
+			return rule.Keep, true
+		}
+	}
+	return 0, false
+}
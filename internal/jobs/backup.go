@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"discordvault/internal/bot"
+	"discordvault/internal/cdn"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	backupPrefix    = "metadata-"
+	backupExtension = ".vaultdb"
+	signaturePrefix = "sig:"
+)
+
+// MetadataBackup uploads encrypted, signed snapshots of the metadata database
+// to Discord so the vault can rebuild its own index after losing the host.
+type MetadataBackup struct {
+	Bot       *bot.Bot
+	DB        *database.Database
+	Signer    *crypto.Signer
+	ChannelID string
+	Keep      int
+}
+
+func (m *MetadataBackup) Run(ctx context.Context) error {
+	tmp := filepath.Join(os.TempDir(), fmt.Sprintf("discordvault-snapshot-%d.db", time.Now().UnixNano()))
+	defer os.Remove(tmp)
+
+	if err := m.DB.Snapshot(tmp); err != nil {
+		if errors.Is(err, database.ErrSnapshotUnsupported) {
+			log.Printf("[JOBS] Metadata backup skipped: %v", err)
+			return nil
+		}
+		return err
+	}
+	plain, err := os.ReadFile(tmp)
+	if err != nil {
+		return err
+	}
+	encrypted, err := crypto.Encrypt(plain, m.Bot.Config.EncryptionKey)
+	if err != nil {
+		return err
+	}
+
+	name := backupPrefix + time.Now().UTC().Format("20060102-150405") + backupExtension
+	content := fmt.Sprintf("🗄️ **Metadata Backup** `%s` (%d bytes)\n%s%s", name, len(plain), signaturePrefix, m.Signer.Sign(encrypted))
+	err = m.Bot.Retry.Do("metadata backup upload", func() error {
+		_, err := m.Bot.Session.ChannelFileSendWithMessage(m.ChannelID, content, name, bytes.NewReader(encrypted))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("uploading snapshot: %w", err)
+	}
+	log.Printf("[JOBS] Metadata backup %s uploaded", name)
+
+	return m.prune()
+}
+
+// prune deletes all but the newest Keep backups.
+func (m *MetadataBackup) prune() error {
+	backups, err := findBackups(m.Bot.Session, m.ChannelID)
+	if err != nil {
+		return err
+	}
+	for idx, msg := range backups {
+		if idx < m.Keep {
+			continue
+		}
+		if err := m.Bot.Session.ChannelMessageDelete(m.ChannelID, msg.ID); err != nil {
+			log.Printf("[JOBS ERR] Pruning backup %s failed: %v", msg.ID, err)
+		}
+	}
+	return nil
+}
+
+// findBackups returns the bot's backup messages in the channel, newest first.
+func findBackups(s *discordgo.Session, channelID string) ([]*discordgo.Message, error) {
+	var backups []*discordgo.Message
+	before := ""
+	// Backups are posted by the bot into a busy channel; scan a bounded window.
+	for page := 0; page < 50; page++ {
+		msgs, err := s.ChannelMessages(channelID, 100, before, "", "")
+		if err != nil {
+			return nil, err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		for _, msg := range msgs {
+			if isBackup(msg) && (s.State.User == nil || msg.Author == nil || msg.Author.ID == s.State.User.ID) {
+				backups = append(backups, msg)
+			}
+		}
+		before = msgs[len(msgs)-1].ID
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Timestamp.After(backups[j].Timestamp) })
+	return backups, nil
+}
+
+func isBackup(msg *discordgo.Message) bool {
+	if len(msg.Attachments) != 1 {
+		return false
+	}
+	name := msg.Attachments[0].Filename
+	return strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupExtension)
+}
+
+// RestoreLatestBackup downloads the newest metadata snapshot, verifies its
+// signature, decrypts it, and writes it to dbPath. An existing database file
+// is moved aside rather than overwritten. Without a signer, as on a host
+// that lost its identity key, the snapshot is only authenticated by
+// decrypting it with key.
+func RestoreLatestBackup(s *discordgo.Session, channelID string, key []byte, signer *crypto.Signer, dbPath string) error {
+	backups, err := findBackups(s, channelID)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		return errors.New("no metadata backups found in channel")
+	}
+	latest := backups[0]
+
+	encrypted, err := cdn.Fetch(s, nil, channelID, latest.ID)
+	if err != nil {
+		return err
+	}
+
+	if signer != nil {
+		_, signature, ok := strings.Cut(latest.Content, signaturePrefix)
+		if !ok || !crypto.Verify(signer.PublicKeyString(), encrypted, strings.TrimSpace(signature)) {
+			return fmt.Errorf("backup %s is not signed by this vault's identity key", latest.Attachments[0].Filename)
+		}
+	}
+
+	plain, err := crypto.Decrypt(encrypted, key)
+	if err != nil {
+		return fmt.Errorf("decrypting backup: %w", err)
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		aside := fmt.Sprintf("%s.bak-%s", dbPath, time.Now().UTC().Format("20060102-150405"))
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			if err := os.Rename(dbPath+suffix, aside+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		log.Printf("[RESTORE] Existing database moved to %s", aside)
+	}
+	if err := os.WriteFile(dbPath, plain, 0600); err != nil {
+		return err
+	}
+	log.Printf("[RESTORE] Restored %s from backup %s", dbPath, latest.Attachments[0].Filename)
+	return nil
+}
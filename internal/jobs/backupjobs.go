@@ -0,0 +1,339 @@
+package jobs
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"discordvault/internal/bot"
+	"discordvault/internal/database"
+	"discordvault/internal/vault"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// BackupJobCheckInterval is how often the scheduler asks BackupJobs for due
+// jobs, which bounds how late a job starts after its cron time.
+const BackupJobCheckInterval = time.Minute
+
+// ErrJobRunning is returned when a backup job is started while it runs.
+var ErrJobRunning = errors.New("backup job is already running")
+
+// BackupJobs runs the scheduled backup jobs stored in the database. Each run
+// uploads its source as a new version of one vault file and drops versions
+// beyond the job's Keep limit.
+type BackupJobs struct {
+	Bot   *bot.Bot
+	DB    *database.Database
+	Paths []string // directories path sources must lie in, from BACKUP_JOB_PATHS
+	HTTP  *http.Client
+
+	mu        sync.Mutex
+	running   map[int]bool
+	recovered bool
+}
+
+// ParseSchedule parses a standard five-field cron expression or a descriptor
+// such as @daily.
+func ParseSchedule(expr string) (cron.Schedule, error) {
+	return cron.ParseStandard(expr)
+}
+
+// NextRun returns when a job with the given schedule runs next after now, in
+// the server's time zone.
+func (b *BackupJobs) NextRun(schedule string, now time.Time) (time.Time, error) {
+	sched, err := ParseSchedule(schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return sched.Next(now.In(b.Bot.Config.Location)), nil
+}
+
+// CheckSource reports why source cannot be backed up: it must be an http(s)
+// URL or an absolute path inside one of Paths.
+func (b *BackupJobs) CheckSource(source string) error {
+	if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		if u.Host == "" {
+			return errors.New("URL has no host")
+		}
+		return nil
+	}
+	if !filepath.IsAbs(source) {
+		return errors.New("source must be an http(s) URL or an absolute path")
+	}
+	_, err := b.resolve(source)
+	return err
+}
+
+// resolve returns the path source points to after following symlinks, if
+// it is inside one of Paths.
+func (b *BackupJobs) resolve(source string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(source))
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range b.Paths {
+		root, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		if resolved == root || strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", errors.New("path is not inside BACKUP_JOB_PATHS")
+}
+
+// Run starts every job that is due, one after another.
+func (b *BackupJobs) Run(ctx context.Context) error {
+	b.mu.Lock()
+	recovered := b.recovered
+	b.recovered = true
+	b.mu.Unlock()
+	if !recovered {
+		if err := b.DB.AbandonBackupRuns(); err != nil {
+			return err
+		}
+	}
+
+	due, err := b.DB.DueBackupJobs(time.Now())
+	if err != nil {
+		return err
+	}
+	for i := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		job := &due[i]
+		runID, err := b.begin(job)
+		if errors.Is(err, ErrJobRunning) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[JOBS ERR] Backup job %q could not start: %v", job.Name, err)
+			continue
+		}
+		b.execute(ctx, job, runID)
+	}
+	return nil
+}
+
+// Start runs job now in the background and returns its run ID.
+func (b *BackupJobs) Start(job *database.BackupJob) (int, error) {
+	runID, err := b.begin(job)
+	if err != nil {
+		return 0, err
+	}
+	go b.execute(context.Background(), job, runID)
+	return runID, nil
+}
+
+// begin marks job as running, schedules its next run, and records the run.
+func (b *BackupJobs) begin(job *database.BackupJob) (int, error) {
+	b.mu.Lock()
+	if b.running == nil {
+		b.running = make(map[int]bool)
+	}
+	if b.running[job.ID] {
+		b.mu.Unlock()
+		return 0, ErrJobRunning
+	}
+	b.running[job.ID] = true
+	b.mu.Unlock()
+
+	next, err := b.NextRun(job.Schedule, time.Now())
+	if err == nil {
+		err = b.DB.SetBackupJobNextRun(job.ID, next)
+	}
+	var runID int
+	if err == nil {
+		runID, err = b.DB.StartBackupRun(job.ID)
+	}
+	if err != nil {
+		b.done(job)
+		return 0, err
+	}
+	return runID, nil
+}
+
+func (b *BackupJobs) done(job *database.BackupJob) {
+	b.mu.Lock()
+	delete(b.running, job.ID)
+	b.mu.Unlock()
+}
+
+// execute backs up job's source, records the outcome of the run, and posts
+// failures to the vault channel.
+func (b *BackupJobs) execute(ctx context.Context, job *database.BackupJob, runID int) {
+	defer b.done(job)
+	log.Printf("[JOBS] Backup job %q started", job.Name)
+
+	file, err := b.backup(ctx, job)
+	status, fileID, size, errText := database.RunOK, 0, int64(0), ""
+	if file != nil {
+		fileID, size = file.ID, file.Size
+	}
+	if err != nil {
+		status, errText = database.RunFailed, err.Error()
+		log.Printf("[JOBS ERR] Backup job %q failed: %v", job.Name, err)
+		go b.Bot.NotifyError("Backup", job.Name, err)
+	} else {
+		log.Printf("[JOBS] Backup job %q stored %s version %d (#%d)", job.Name, file.Name, file.Version, file.ID)
+	}
+	if err := b.DB.FinishBackupRun(runID, status, fileID, size, errText); err != nil {
+		log.Printf("[JOBS ERR] Recording backup run of %q failed: %v", job.Name, err)
+	}
+	if err == nil && job.Keep > 0 {
+		if err := b.prune(job, file); err != nil {
+			log.Printf("[JOBS ERR] Backup job %q retention failed: %v", job.Name, err)
+		}
+	}
+}
+
+// backup uploads the job's source as a new version of its vault file.
+func (b *BackupJobs) backup(ctx context.Context, job *database.BackupJob) (*database.FileMetadata, error) {
+	r, ext, err := b.open(ctx, job.Source)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	s := &vault.Service{Bot: b.Bot, DB: b.DB, Config: b.Bot.Config, Owner: job.CreatedBy, Method: "Backup", OffPeak: true}
+	return s.Upload(ctx, job.Name+ext, r, job.FolderID, database.DuplicateVersion)
+}
+
+// open returns the contents of source and the extension of the file they
+// are stored as. Directories are streamed as a gzipped tar archive.
+func (b *BackupJobs) open(ctx context.Context, source string) (io.ReadCloser, string, error) {
+	if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		req, err := http.NewRequestWithContext(ctx, "GET", source, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		client := b.HTTP
+		if client == nil {
+			client = http.DefaultClient
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, "", fmt.Errorf("GET %s: %s", u.Redacted(), resp.Status)
+		}
+		return resp.Body, path.Ext(u.Path), nil
+	}
+
+	resolved, err := b.resolve(source)
+	if err != nil {
+		return nil, "", err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() {
+		f, err := os.Open(resolved)
+		return f, filepath.Ext(resolved), err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTarGz(pw, resolved))
+	}()
+	return pr, ".tar.gz", nil
+}
+
+// writeTarGz archives the regular files and directories below root into w.
+// Symlinks and special files are skipped.
+func writeTarGz(w io.Writer, root string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.CopyN(tw, f, hdr.Size)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// prune releases the versions of the job's file beyond its Keep limit to
+// the compaction job. Versions published on a release channel are kept.
+func (b *BackupJobs) prune(job *database.BackupJob, latest *database.FileMetadata) error {
+	versions, err := b.DB.ListVersions(latest.GuildID, latest.Name)
+	if err != nil {
+		return err
+	}
+	kept := 0
+	for _, v := range versions {
+		if v.OwnerID != job.CreatedBy {
+			continue
+		}
+		if kept < job.Keep {
+			kept++
+			continue
+		}
+		if published, err := b.DB.IsPublished(v.ID); err != nil || published {
+			continue
+		}
+		if err := b.DB.ReleaseFile(v.ID); err != nil {
+			return err
+		}
+		log.Printf("[JOBS] Backup job %q expired version %d (#%d)", job.Name, v.Version, v.ID)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"discordvault/internal/database"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Bulk actions.
+const (
+	BulkMove   = "move"
+	BulkTag    = "tag"
+	BulkDelete = "delete"
+	BulkRetain = "retain"
+)
+
+// MaxBulkFiles is the most files one bulk action may name.
+const MaxBulkFiles = 10000
+
+// BulkRequest applies one action to many files. Owner is the user the
+// action runs for; unless Admin, files of other owners are skipped as not
+// found.
+type BulkRequest struct {
+	Action     string
+	FileIDs    []int
+	FolderID   int       // move; 0 = vault root
+	AddTags    []string  // tag
+	RemoveTags []string  // tag
+	ExpiresAt  time.Time // retain; zero clears the retention date
+	Owner      string
+	Admin      bool
+}
+
+// BulkFailure is a file a bulk action could not be applied to.
+type BulkFailure struct {
+	FileID int    `json:"file_id"`
+	Error  string `json:"error"`
+}
+
+// BulkStatus reports the progress of a bulk action.
+type BulkStatus struct {
+	ID         string        `json:"id"`
+	Action     string        `json:"action"`
+	State      string        `json:"state"` // running, done
+	Files      int           `json:"files"`
+	Done       int           `json:"done"`
+	Failed     int           `json:"failed"`
+	Failures   []BulkFailure `json:"failures"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Owner      string        `json:"-"`
+}
+
+// BulkJobs applies bulk actions in the background, one file at a time, so
+// a failure affects only its file. Deletions keep chunk messages for Grace
+// like a web delete, or else release them to the compaction job.
+type BulkJobs struct {
+	DB    *database.Database
+	Grace time.Duration
+
+	mu   sync.Mutex
+	jobs map[string]*BulkStatus
+}
+
+// Start launches a bulk action and returns its job ID.
+func (b *BulkJobs) Start(req BulkRequest) string {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	status := &BulkStatus{
+		ID:        hex.EncodeToString(idBytes),
+		Action:    req.Action,
+		State:     "running",
+		Files:     len(req.FileIDs),
+		Failures:  []BulkFailure{},
+		StartedAt: time.Now().UTC(),
+		Owner:     req.Owner,
+	}
+
+	b.mu.Lock()
+	if b.jobs == nil {
+		b.jobs = make(map[string]*BulkStatus)
+	}
+	b.jobs[status.ID] = status
+	b.mu.Unlock()
+
+	go b.run(status, req)
+	return status.ID
+}
+
+// Status returns a snapshot of a bulk job.
+func (b *BulkJobs) Status(id string) (BulkStatus, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status, ok := b.jobs[id]
+	if !ok {
+		return BulkStatus{}, false
+	}
+	snapshot := *status
+	snapshot.Failures = append([]BulkFailure(nil), status.Failures...)
+	return snapshot, true
+}
+
+func (b *BulkJobs) run(status *BulkStatus, req BulkRequest) {
+	log.Printf("[JOBS] Bulk %s %s started for %d files", req.Action, status.ID, len(req.FileIDs))
+	for _, id := range req.FileIDs {
+		err := b.apply(req, id)
+		b.mu.Lock()
+		if err != nil {
+			status.Failed++
+			status.Failures = append(status.Failures, BulkFailure{FileID: id, Error: err.Error()})
+		} else {
+			status.Done++
+		}
+		b.mu.Unlock()
+	}
+
+	b.mu.Lock()
+	now := time.Now().UTC()
+	status.State, status.FinishedAt = "done", &now
+	b.mu.Unlock()
+	log.Printf("[JOBS] Bulk %s %s finished: %d done, %d failed", req.Action, status.ID, status.Done, status.Failed)
+}
+
+func (b *BulkJobs) apply(req BulkRequest, id int) error {
+	file, err := b.DB.GetFile(id)
+	if err != nil || (!req.Admin && file.OwnerID != req.Owner) {
+		return errors.New("file not found")
+	}
+	if req.Action == BulkDelete && b.Grace > 0 {
+		return b.DB.SoftDeleteFile(id, req.Owner)
+	}
+
+	op := database.BatchOp{FileID: id}
+	switch req.Action {
+	case BulkMove:
+		op.Op, op.FolderID = database.BatchMove, req.FolderID
+	case BulkTag:
+		op.Op, op.AddTags, op.RemoveTags = database.BatchTag, req.AddTags, req.RemoveTags
+	case BulkDelete:
+		op.Op = database.BatchDelete
+	case BulkRetain:
+		op.Op, op.ExpiresAt = database.BatchRetain, req.ExpiresAt
+	}
+	err = b.DB.ApplyBatch([]database.BatchOp{op})
+	var batchErr *database.BatchError
+	if errors.As(err, &batchErr) {
+		return batchErr.Err
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"context"
+	"discordvault/internal/bot"
+	"discordvault/internal/database"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// bulkDeleteMaxAge is the age limit Discord enforces for bulk message deletes.
+const bulkDeleteMaxAge = 14 * 24 * time.Hour
+
+// CompactionReport summarises one compaction pass.
+type CompactionReport struct {
+	Candidates     int   `json:"candidates"`
+	Deleted        int   `json:"deleted"`
+	ReclaimedBytes int64 `json:"reclaimed_bytes"`
+	Failed         int   `json:"failed"`
+}
+
+// Compactor deletes chunk messages that no live file references anymore once
+// they have been released for longer than the retention period.
+type Compactor struct {
+	Bot       *bot.Bot
+	DB        *database.Database
+	Retention time.Duration
+}
+
+func (c *Compactor) Run(ctx context.Context) error {
+	_, err := c.Compact(ctx)
+	return err
+}
+
+func (c *Compactor) Compact(ctx context.Context) (*CompactionReport, error) {
+	msgs, err := c.DB.CompactionCandidates(time.Now().Add(-c.Retention))
+	if err != nil {
+		return nil, err
+	}
+	report := &CompactionReport{Candidates: len(msgs)}
+	if len(msgs) == 0 {
+		return report, nil
+	}
+
+	// Bulk deletes work per channel, so recent messages are grouped by the
+	// storage channel they live in.
+	bulk := make(map[string][]string)
+	var single []database.ChunkMetadata
+	var done []string
+
+	for _, m := range msgs {
+		if ctx.Err() != nil {
+			break
+		}
+		channelID := c.Bot.ChunkChannel(m)
+		var msg *discordgo.Message
+		err := c.Bot.Retry.Do("chunk "+m.MessageID+" lookup", func() (err error) {
+			msg, err = c.Bot.Session.ChannelMessage(channelID, m.MessageID)
+			return err
+		})
+		if err != nil {
+			if isNotFound(err) {
+				done = append(done, m.MessageID)
+				continue
+			}
+			report.Failed++
+			continue
+		}
+		for _, a := range msg.Attachments {
+			report.ReclaimedBytes += int64(a.Size)
+		}
+		if ts, err := discordgo.SnowflakeTimestamp(m.MessageID); err == nil && time.Since(ts) < bulkDeleteMaxAge-time.Hour {
+			bulk[channelID] = append(bulk[channelID], m.MessageID)
+		} else {
+			single = append(single, m)
+		}
+	}
+
+	for channelID, ids := range bulk {
+		for start := 0; start < len(ids); start += 100 {
+			end := min(start+100, len(ids))
+			batch := ids[start:end]
+			var err error
+			if len(batch) == 1 {
+				err = c.Bot.Session.ChannelMessageDelete(channelID, batch[0])
+			} else {
+				err = c.Bot.Session.ChannelMessagesBulkDelete(channelID, batch)
+			}
+			if err != nil {
+				log.Printf("[JOBS ERR] Bulk delete of %d messages failed: %v", len(batch), err)
+				for _, id := range batch {
+					single = append(single, database.ChunkMetadata{MessageID: id, ChannelID: channelID})
+				}
+				continue
+			}
+			done = append(done, batch...)
+			report.Deleted += len(batch)
+		}
+	}
+
+	for _, m := range single {
+		if err := c.Bot.Session.ChannelMessageDelete(c.Bot.ChunkChannel(m), m.MessageID); err != nil && !isNotFound(err) {
+			report.Failed++
+			continue
+		}
+		done = append(done, m.MessageID)
+		report.Deleted++
+	}
+
+	c.Bot.DeleteReplicas(done)
+	if err := c.DB.ForgetReleased(done); err != nil {
+		return report, err
+	}
+
+	log.Printf("[JOBS] Compaction removed %d/%d released chunks, reclaimed %d bytes", report.Deleted, report.Candidates, report.ReclaimedBytes)
+	return report, nil
+}
+
+func isNotFound(err error) bool {
+	if restErr, ok := err.(*discordgo.RESTError); ok && restErr.Response != nil {
+		return restErr.Response.StatusCode == 404
+	}
+	return false
+}
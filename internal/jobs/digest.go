@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"discordvault/internal/bot"
+	"discordvault/internal/database"
+	"log"
+	"time"
+)
+
+// eventRetention is how long vault_events rows are kept.
+const eventRetention = 90 * 24 * time.Hour
+
+// DigestCheckInterval is how often the scheduler asks Digest whether a
+// report is due. Due-ness comes from the last digest in the database, so
+// restarts neither skip nor repeat a report.
+const DigestCheckInterval = time.Hour
+
+// Digest posts a summary of uploads, deletions, storage, and verification
+// failures every Interval.
+type Digest struct {
+	Bot      *bot.Bot
+	DB       *database.Database
+	Interval time.Duration
+}
+
+func (d *Digest) Run(ctx context.Context) error {
+	now := time.Now()
+	since := now.Add(-d.Interval)
+	last, err := d.DB.LastEvent(database.EventDigest)
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		if now.Sub(*last) < d.Interval {
+			return nil
+		}
+		since = *last
+	}
+
+	report, err := d.DB.DigestSince(since)
+	if err != nil {
+		return err
+	}
+	d.Bot.NotifyDigest(d.period(), report)
+	if err := d.DB.RecordEvent(database.VaultEvent{Kind: database.EventDigest}); err != nil {
+		return err
+	}
+	log.Printf("[JOBS] Digest posted: %d uploads, %d deletes, %d verification failures",
+		report.Uploads, report.Deletes, len(report.VerifyFailures))
+
+	if _, err := d.DB.PruneEvents(now.Add(-max(eventRetention, 2*d.Interval))); err != nil {
+		log.Printf("[JOBS ERR] Pruning vault events failed: %v", err)
+	}
+	return nil
+}
+
+// period names the interval for the digest title.
+func (d *Digest) period() string {
+	switch d.Interval {
+	case 24 * time.Hour:
+		return "daily"
+	case 7 * 24 * time.Hour:
+		return "weekly"
+	}
+	return "every " + d.Interval.String()
+}
@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"discordvault/internal/bot"
+	"discordvault/internal/database"
+	"discordvault/internal/vault"
+	"encoding/hex"
+	"errors"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrExportDisabled is returned when no export directory is configured.
+var ErrExportDisabled = errors.New("EXPORT_DIR is not set")
+
+// ExportStatus reports the progress of a vault export.
+type ExportStatus struct {
+	ID    string `json:"id"`
+	State string `json:"state"` // exporting, done, cancelled, failed
+	Name  string `json:"name"`  // directory below EXPORT_DIR
+	vault.ExportProgress
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Exporter writes the whole default vault to directories below Dir on the
+// server, with the layout and resume support of vault.Export.
+type Exporter struct {
+	Bot *bot.Bot
+	DB  *database.Database
+	Dir string // EXPORT_DIR
+
+	mu      sync.Mutex
+	jobs    map[string]*ExportStatus
+	running map[string]bool // names being exported to
+}
+
+// Start launches a background export into the directory name below Dir and
+// returns its job ID. An empty name picks a new directory named after the
+// current time; the name of an earlier export resumes it.
+func (e *Exporter) Start(ctx context.Context, owner, name string) (string, error) {
+	if e.Dir == "" {
+		return "", ErrExportDisabled
+	}
+	if name == "" {
+		name = "vault-export-" + time.Now().UTC().Format("20060102-150405")
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", errors.New("name must be a single directory name")
+	}
+
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	status := &ExportStatus{
+		ID:        hex.EncodeToString(idBytes),
+		State:     "exporting",
+		Name:      name,
+		StartedAt: time.Now().UTC(),
+	}
+
+	e.mu.Lock()
+	if e.running[name] {
+		e.mu.Unlock()
+		return "", errors.New("an export into " + name + " is already running")
+	}
+	if e.jobs == nil {
+		e.jobs = make(map[string]*ExportStatus)
+		e.running = make(map[string]bool)
+	}
+	e.jobs[status.ID] = status
+	e.running[name] = true
+	e.mu.Unlock()
+
+	go e.run(ctx, status, owner)
+	return status.ID, nil
+}
+
+// Status returns a snapshot of an export job.
+func (e *Exporter) Status(id string) (ExportStatus, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	status, ok := e.jobs[id]
+	if !ok {
+		return ExportStatus{}, false
+	}
+	return *status, true
+}
+
+func (e *Exporter) run(ctx context.Context, status *ExportStatus, owner string) {
+	dir := filepath.Join(e.Dir, status.Name)
+	log.Printf("[JOBS] Vault export %s started into %s", status.ID, dir)
+
+	s := &vault.Service{Bot: e.Bot, DB: e.DB, Config: e.Bot.Config, Owner: owner, Method: "Export"}
+	progress, err := vault.Export(ctx, s, dir, func(p vault.ExportProgress) {
+		e.mu.Lock()
+		status.ExportProgress = p
+		e.mu.Unlock()
+	})
+
+	e.mu.Lock()
+	now := time.Now().UTC()
+	status.ExportProgress = progress
+	status.FinishedAt = &now
+	switch {
+	case ctx.Err() != nil:
+		status.State = "cancelled"
+	case err != nil:
+		status.State, status.Error = "failed", err.Error()
+	default:
+		status.State = "done"
+	}
+	delete(e.running, status.Name)
+	e.mu.Unlock()
+	log.Printf("[JOBS] Vault export %s %s: %d exported, %d skipped, %d failed", status.ID, status.State, progress.Exported, progress.Skipped, progress.Failed)
+}
@@ -0,0 +1,125 @@
+package jobs
+
+import (
+	"context"
+	"discordvault/internal/bot"
+	"discordvault/internal/chunkname"
+	"discordvault/internal/database"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Orphan is a chunk message in a storage channel that no file references.
+type Orphan struct {
+	MessageID string    `json:"message_id"`
+	ChannelID string    `json:"channel_id"`
+	Filename  string    `json:"filename"`
+	Size      int       `json:"size"`
+	PostedAt  time.Time `json:"posted_at"`
+}
+
+type GCReport struct {
+	DryRun         bool     `json:"dry_run"`
+	Scanned        int      `json:"scanned"`
+	Orphans        []Orphan `json:"orphans"`
+	Deleted        int      `json:"deleted"`
+	ReclaimedBytes int64    `json:"reclaimed_bytes"`
+}
+
+// OrphanCollector removes chunk messages left behind by uploads that failed
+// after sending chunks but before their metadata was saved. Messages younger
+// than MinAge are ignored so in-flight uploads are never touched.
+type OrphanCollector struct {
+	Bot    *bot.Bot
+	DB     *database.Database
+	MinAge time.Duration
+}
+
+func (g *OrphanCollector) Run(ctx context.Context) error {
+	_, err := g.Collect(ctx, false)
+	return err
+}
+
+// Collect scans every storage channel, the default one and each guild
+// vault's, for orphaned chunk messages.
+func (g *OrphanCollector) Collect(ctx context.Context, dryRun bool) (*GCReport, error) {
+	report := &GCReport{DryRun: dryRun, Orphans: []Orphan{}}
+	for _, channelID := range g.Bot.Config.StorageChannels() {
+		if err := g.collect(ctx, channelID, dryRun, report); err != nil {
+			return report, err
+		}
+	}
+
+	log.Printf("[JOBS] Orphan GC scanned %d messages, found %d orphans, deleted %d (dry run: %v)", report.Scanned, len(report.Orphans), report.Deleted, dryRun)
+	return report, ctx.Err()
+}
+
+func (g *OrphanCollector) collect(ctx context.Context, channelID string, dryRun bool, report *GCReport) error {
+	cutoff := time.Now().Add(-g.MinAge)
+
+	before := ""
+	for ctx.Err() == nil {
+		msgs, err := g.Bot.Session.ChannelMessages(channelID, 100, before, "", "")
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		before = msgs[len(msgs)-1].ID
+
+		var candidates []*discordgo.Message
+		for _, msg := range msgs {
+			report.Scanned++
+			if g.isChunkMessage(msg) && msg.Timestamp.Before(cutoff) {
+				candidates = append(candidates, msg)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		ids := make([]string, len(candidates))
+		for idx, msg := range candidates {
+			ids[idx] = msg.ID
+		}
+		known, err := g.DB.KnownMessageIDs(ids)
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range candidates {
+			if known[msg.ID] {
+				continue
+			}
+			att := msg.Attachments[0]
+			report.Orphans = append(report.Orphans, Orphan{MessageID: msg.ID, ChannelID: channelID, Filename: att.Filename, Size: att.Size, PostedAt: msg.Timestamp})
+			if dryRun {
+				continue
+			}
+			if err := g.Bot.Session.ChannelMessageDelete(channelID, msg.ID); err != nil && !isNotFound(err) {
+				log.Printf("[JOBS ERR] GC could not delete %s: %v", msg.ID, err)
+				continue
+			}
+			g.Bot.DeleteReplicas([]string{msg.ID})
+			report.Deleted++
+			report.ReclaimedBytes += int64(att.Size)
+		}
+	}
+	return nil
+}
+
+// isChunkMessage matches single-attachment messages posted by the vault's
+// bots whose attachment carries a chunk name of any scheme. Other .vault
+// files in the channel are left alone.
+func (g *OrphanCollector) isChunkMessage(msg *discordgo.Message) bool {
+	if len(msg.Attachments) != 1 {
+		return false
+	}
+	if _, err := chunkname.Parse(msg.Attachments[0].Filename); err != nil {
+		return false
+	}
+	return g.Bot.PostedByVault(msg)
+}
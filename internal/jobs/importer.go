@@ -0,0 +1,223 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"discordvault/internal/bot"
+	"discordvault/internal/chunkname"
+	"discordvault/internal/database"
+	"discordvault/internal/vault"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ImportStatus reports the progress of a channel import.
+type ImportStatus struct {
+	ID          string     `json:"id"`
+	State       string     `json:"state"` // scanning, importing, done, cancelled, failed
+	ChannelID   string     `json:"channel_id"`
+	Messages    int        `json:"messages"`    // messages scanned
+	Attachments int        `json:"attachments"` // attachments found that were not imported before
+	Skipped     int        `json:"skipped"`     // attachments imported by an earlier run
+	Imported    int        `json:"imported"`
+	Failed      int        `json:"failed"`
+	Bytes       int64      `json:"bytes"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// Importer copies attachments that were posted to a Discord channel by hand
+// into the vault. Each attachment becomes a file owned by the author of its
+// message; the original messages are left alone. Attachments are recorded as
+// imported, so running an import of the same channel again only picks up
+// new ones.
+type Importer struct {
+	Bot *bot.Bot
+	DB  *database.Database
+
+	mu   sync.Mutex
+	jobs map[string]*ImportStatus
+}
+
+// importItem is one attachment waiting to be imported.
+type importItem struct {
+	msg *discordgo.Message
+	att *discordgo.MessageAttachment
+}
+
+// Start launches a background import of channelID into folder, 0 for the
+// vault root, and returns its job ID.
+func (im *Importer) Start(ctx context.Context, channelID string, folder int) (string, error) {
+	if channelID == "" || strings.Trim(channelID, "0123456789") != "" {
+		return "", errors.New("not a Discord channel ID")
+	}
+	if slices.Contains(im.Bot.Config.StorageChannels(), channelID) {
+		return "", errors.New("that channel already stores the vault")
+	}
+
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	status := &ImportStatus{
+		ID:        hex.EncodeToString(idBytes),
+		State:     "scanning",
+		ChannelID: channelID,
+		StartedAt: time.Now().UTC(),
+	}
+
+	im.mu.Lock()
+	if im.jobs == nil {
+		im.jobs = make(map[string]*ImportStatus)
+	}
+	im.jobs[status.ID] = status
+	im.mu.Unlock()
+
+	go im.run(ctx, status, folder)
+	return status.ID, nil
+}
+
+// Status returns a snapshot of an import job.
+func (im *Importer) Status(id string) (ImportStatus, bool) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	status, ok := im.jobs[id]
+	if !ok {
+		return ImportStatus{}, false
+	}
+	return *status, true
+}
+
+func (im *Importer) update(status *ImportStatus, fn func(*ImportStatus)) {
+	im.mu.Lock()
+	fn(status)
+	im.mu.Unlock()
+}
+
+func (im *Importer) run(ctx context.Context, status *ImportStatus, folder int) {
+	log.Printf("[JOBS] Channel import %s started for channel %s", status.ID, status.ChannelID)
+
+	items, err := im.scan(ctx, status)
+	if err != nil {
+		im.update(status, func(s *ImportStatus) { s.Error = err.Error() })
+		im.finish(status, "failed")
+		return
+	}
+
+	im.update(status, func(s *ImportStatus) { s.State = "importing" })
+	for _, item := range items {
+		if ctx.Err() != nil {
+			im.finish(status, "cancelled")
+			return
+		}
+		file, err := im.importAttachment(ctx, status.ChannelID, item, folder)
+		if err != nil {
+			log.Printf("[JOBS ERR] Channel import %s: %s (message %s): %v", status.ID, item.att.Filename, item.msg.ID, err)
+			im.update(status, func(s *ImportStatus) { s.Failed++ })
+			continue
+		}
+		im.update(status, func(s *ImportStatus) {
+			s.Imported++
+			s.Bytes += file.Size
+		})
+	}
+
+	im.finish(status, "done")
+	im.Bot.Session.ChannelMessageSend(im.Bot.Config.ChannelID, fmt.Sprintf("📥 **Channel Import Complete**\n**Channel:** <#%s>\n**Files:** %d\n**Already Imported:** %d\n**Failures:** %d",
+		status.ChannelID, status.Imported, status.Skipped, status.Failed))
+}
+
+// scan pages through the channel's history and returns the attachments not
+// imported before, oldest first, so files keep their order and names taken
+// by several uploads get suffixes in the order they were posted. Chunks a
+// bot posted under a vault chunk name are ignored.
+func (im *Importer) scan(ctx context.Context, status *ImportStatus) ([]importItem, error) {
+	var items []importItem
+	before := ""
+	for ctx.Err() == nil {
+		msgs, err := im.Bot.Session.ChannelMessages(status.ChannelID, 100, before, "", "")
+		if err != nil {
+			return nil, err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		before = msgs[len(msgs)-1].ID
+
+		var page []importItem
+		var ids []string
+		for _, msg := range msgs {
+			for _, att := range msg.Attachments {
+				if msg.Author != nil && msg.Author.Bot {
+					if _, err := chunkname.Parse(att.Filename); err == nil {
+						continue
+					}
+				}
+				page = append(page, importItem{msg: msg, att: att})
+				ids = append(ids, att.ID)
+			}
+		}
+		imported, err := im.DB.ImportedAttachments(ids)
+		if err != nil {
+			return nil, err
+		}
+		skipped := 0
+		for _, item := range page {
+			if imported[item.att.ID] {
+				skipped++
+				continue
+			}
+			items = append(items, item)
+		}
+		im.update(status, func(s *ImportStatus) {
+			s.Messages += len(msgs)
+			s.Attachments = len(items)
+			s.Skipped += skipped
+		})
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	slices.Reverse(items)
+	return items, nil
+}
+
+// importAttachment downloads one attachment and stores it like an upload by
+// the message author.
+func (im *Importer) importAttachment(ctx context.Context, channelID string, item importItem, folder int) (*database.FileMetadata, error) {
+	resp, err := im.Bot.Retry.Get("attachment fetch", item.att.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	owner := ""
+	if item.msg.Author != nil {
+		owner = item.msg.Author.ID
+	}
+	s := &vault.Service{Bot: im.Bot, DB: im.DB, Config: im.Bot.Config, Owner: owner, Method: "Import", Quiet: true}
+	file, err := s.Upload(ctx, item.att.Filename, resp.Body, folder, database.DuplicateSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if err := im.DB.RecordImport(item.att.ID, channelID, item.msg.ID, file.ID); err != nil {
+		log.Printf("[JOBS ERR] Recording import of %s failed: %v", item.att.ID, err)
+	}
+	return file, nil
+}
+
+func (im *Importer) finish(status *ImportStatus, state string) {
+	im.update(status, func(s *ImportStatus) {
+		now := time.Now().UTC()
+		s.State = state
+		s.FinishedAt = &now
+	})
+	log.Printf("[JOBS] Channel import %s %s: %d imported, %d skipped, %d failed", status.ID, state, status.Imported, status.Skipped, status.Failed)
+}
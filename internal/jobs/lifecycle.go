@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"discordvault/internal/database"
+	"log"
+	"time"
+)
+
+// Lifecycle applies the lifecycle policies stored in the database, and
+// expires files whose retention date has passed. Expired files are released
+// to the compaction job, which deletes their chunk messages from Discord.
+type Lifecycle struct {
+	DB *database.Database
+}
+
+func (l *Lifecycle) Run(ctx context.Context) error {
+	policies, err := l.DB.ListPolicies()
+	if err != nil {
+		return err
+	}
+	for i := range policies {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		n, err := l.Apply(&policies[i])
+		if err != nil {
+			log.Printf("[JOBS ERR] Lifecycle policy %q failed: %v", policies[i].Name, err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("[JOBS] Lifecycle policy %q expired %d files", policies[i].Name, n)
+		}
+	}
+
+	// Files given a retention date by a bulk action
+	files, err := l.DB.RetentionExpired(time.Now())
+	if err != nil {
+		return err
+	}
+	expired := 0
+	for _, f := range files {
+		if err := l.DB.ReleaseFile(f.ID); err != nil {
+			log.Printf("[JOBS ERR] Could not expire file #%d: %v", f.ID, err)
+			continue
+		}
+		expired++
+	}
+	if expired > 0 {
+		log.Printf("[JOBS] Retention expired %d files", expired)
+	}
+	return nil
+}
+
+// Apply runs a single policy and returns how many files it expired.
+func (l *Lifecycle) Apply(p *database.LifecyclePolicy) (int, error) {
+	files, err := l.DB.PolicyMatches(p)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -p.MaxAgeDays)
+	expired := 0
+	seen := make(map[string]int)
+	for _, f := range files {
+		// Files arrive grouped by name, newest version first.
+		seen[f.Name]++
+		tooOld := p.MaxAgeDays > 0 && f.CreatedAt.Before(cutoff)
+		tooMany := p.MaxVersions > 0 && seen[f.Name] > p.MaxVersions
+		if !tooOld && !tooMany {
+			continue
+		}
+		if err := l.DB.ReleaseFile(f.ID); err != nil {
+			log.Printf("[JOBS ERR] Could not expire file #%d: %v", f.ID, err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
@@ -0,0 +1,286 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"discordvault/internal/bot"
+	"discordvault/internal/cloud"
+	"discordvault/internal/database"
+	"discordvault/internal/vault"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Concurrency limits of a migration.
+const (
+	DefaultMigrationConcurrency = 4
+	MaxMigrationConcurrency     = 16
+)
+
+// MigrationStatus reports the progress of a migration from another storage
+// provider.
+type MigrationStatus struct {
+	ID         string     `json:"id"`
+	State      string     `json:"state"` // listing, migrating, done, cancelled, failed
+	Source     string     `json:"source"`
+	Objects    int        `json:"objects"` // objects found at the source
+	Skipped    int        `json:"skipped"` // migrated by an earlier run and unchanged
+	Migrated   int        `json:"migrated"`
+	Failed     int        `json:"failed"`
+	Bytes      int64      `json:"bytes"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Migrator copies every object of a cloud.Source into the vault, streaming
+// each one from the source through encryption to Discord without staging
+// it on disk. Directories in object keys become folders. Copied objects are
+// recorded, so migrating the same source again only copies objects that are
+// new or changed; changed ones become new versions of their vault file.
+type Migrator struct {
+	Bot *bot.Bot
+	DB  *database.Database
+
+	mu   sync.Mutex
+	jobs map[string]*MigrationStatus
+}
+
+// Start launches a background migration of src into folder, 0 for the vault
+// root, copying up to concurrency objects at once, and returns its job ID.
+// The files and folders it creates belong to owner.
+func (m *Migrator) Start(ctx context.Context, src cloud.Source, owner string, folder, concurrency int) (string, error) {
+	if concurrency <= 0 {
+		concurrency = DefaultMigrationConcurrency
+	}
+	if concurrency > MaxMigrationConcurrency {
+		return "", fmt.Errorf("concurrency must be at most %d", MaxMigrationConcurrency)
+	}
+
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	status := &MigrationStatus{
+		ID:        hex.EncodeToString(idBytes),
+		State:     "listing",
+		Source:    src.String(),
+		StartedAt: time.Now().UTC(),
+	}
+
+	m.mu.Lock()
+	if m.jobs == nil {
+		m.jobs = make(map[string]*MigrationStatus)
+	}
+	m.jobs[status.ID] = status
+	m.mu.Unlock()
+
+	go m.run(ctx, status, src, owner, folder, concurrency)
+	return status.ID, nil
+}
+
+// Status returns a snapshot of a migration job.
+func (m *Migrator) Status(id string) (MigrationStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status, ok := m.jobs[id]
+	if !ok {
+		return MigrationStatus{}, false
+	}
+	return *status, true
+}
+
+func (m *Migrator) update(status *MigrationStatus, fn func(*MigrationStatus)) {
+	m.mu.Lock()
+	fn(status)
+	m.mu.Unlock()
+}
+
+func (m *Migrator) run(ctx context.Context, status *MigrationStatus, src cloud.Source, owner string, folder, concurrency int) {
+	log.Printf("[JOBS] Migration %s started from %s", status.ID, status.Source)
+
+	objects, err := src.List(ctx)
+	var done map[string]database.MigratedObject
+	if err == nil {
+		done, err = m.DB.MigratedObjects(status.Source)
+	}
+	var folders *migrationFolders
+	if err == nil {
+		folders, err = m.newFolders(owner, folder)
+	}
+	if err != nil {
+		m.update(status, func(s *MigrationStatus) { s.Error = err.Error() })
+		m.finish(status, "failed")
+		return
+	}
+
+	var pending []cloud.Object
+	for _, o := range objects {
+		if prev, ok := done[o.Key]; ok && unchanged(prev, o) {
+			continue
+		}
+		pending = append(pending, o)
+	}
+	m.update(status, func(s *MigrationStatus) {
+		s.State = "migrating"
+		s.Objects = len(objects)
+		s.Skipped = len(objects) - len(pending)
+	})
+
+	work := make(chan cloud.Object)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for o := range work {
+				var prev *database.MigratedObject
+				if p, ok := done[o.Key]; ok {
+					prev = &p
+				}
+				file, err := m.migrateObject(ctx, src, o, prev, folders, owner)
+				if err != nil {
+					log.Printf("[JOBS ERR] Migration %s: %s: %v", status.ID, o.Key, err)
+					m.update(status, func(s *MigrationStatus) { s.Failed++ })
+					continue
+				}
+				m.update(status, func(s *MigrationStatus) {
+					s.Migrated++
+					s.Bytes += file.Size
+				})
+			}
+		}()
+	}
+feed:
+	for _, o := range pending {
+		select {
+		case work <- o:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		m.finish(status, "cancelled")
+		return
+	}
+	final := m.finish(status, "done")
+	m.Bot.Session.ChannelMessageSend(m.Bot.Config.ChannelID, fmt.Sprintf("🚚 **Migration Complete**\n**Source:** %s\n**Files:** %d\n**Unchanged:** %d\n**Failures:** %d",
+		final.Source, final.Migrated, final.Skipped, final.Failed))
+}
+
+// unchanged reports whether o is still the object an earlier run copied.
+// Both the ETag and the size the source reports must match; an object the
+// source says nothing about is copied again, as it cannot be told apart
+// from a changed one.
+func unchanged(prev database.MigratedObject, o cloud.Object) bool {
+	if o.ETag == "" && o.Size < 0 {
+		return false
+	}
+	return prev.ETag == o.ETag && (o.Size < 0 || prev.Size == o.Size)
+}
+
+// migrateObject streams one object into the vault. An object migrated
+// before is stored as a new version of the file it became, if that file
+// still exists.
+func (m *Migrator) migrateObject(ctx context.Context, src cloud.Source, o cloud.Object, prev *database.MigratedObject, folders *migrationFolders, owner string) (*database.FileMetadata, error) {
+	dir, name := "", o.Key
+	if i := strings.LastIndex(o.Key, "/"); i >= 0 {
+		dir, name = o.Key[:i], o.Key[i+1:]
+	}
+	folder, err := folders.get(dir)
+	if err != nil {
+		return nil, err
+	}
+	policy := database.DuplicateSuffix
+	if prev != nil {
+		if file, err := m.DB.GetFile(prev.FileID); err == nil {
+			name, policy = file.Name, database.DuplicateVersion
+		}
+	}
+
+	r, err := src.Open(ctx, o.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	s := &vault.Service{Bot: m.Bot, DB: m.DB, Config: m.Bot.Config, Owner: owner, Method: "Migration", Quiet: true}
+	file, err := s.Upload(ctx, name, r, folder, policy)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.DB.RecordMigration(src.String(), o.Key, o.ETag, file.Size, file.ID); err != nil {
+		log.Printf("[JOBS ERR] Recording migration of %s failed: %v", o.Key, err)
+	}
+	return file, nil
+}
+
+// finish ends a migration in state and returns its final status.
+func (m *Migrator) finish(status *MigrationStatus, state string) MigrationStatus {
+	var final MigrationStatus
+	m.update(status, func(s *MigrationStatus) {
+		now := time.Now().UTC()
+		s.State = state
+		s.FinishedAt = &now
+		final = *s
+	})
+	log.Printf("[JOBS] Migration %s %s: %d migrated, %d unchanged, %d failed", final.ID, state, final.Migrated, final.Skipped, final.Failed)
+	return final
+}
+
+// migrationFolders finds or creates the folder for each directory of the
+// source, below the migration's target folder.
+type migrationFolders struct {
+	db    *database.Database
+	owner string
+	root  int
+
+	mu       sync.Mutex
+	children map[int]map[string]int // parent ID -> name -> folder ID
+}
+
+func (m *Migrator) newFolders(owner string, root int) (*migrationFolders, error) {
+	existing, err := m.DB.ListFolders("")
+	if err != nil {
+		return nil, err
+	}
+	f := &migrationFolders{db: m.DB, owner: owner, root: root, children: make(map[int]map[string]int)}
+	for _, folder := range existing {
+		f.child(folder.ParentID)[folder.Name] = folder.ID
+	}
+	return f, nil
+}
+
+func (f *migrationFolders) child(parent int) map[string]int {
+	if f.children[parent] == nil {
+		f.children[parent] = make(map[string]int)
+	}
+	return f.children[parent]
+}
+
+// get returns the folder for the slash-separated directory dir.
+func (f *migrationFolders) get(dir string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.root
+	for _, name := range strings.Split(dir, "/") {
+		if name == "" {
+			continue
+		}
+		next, ok := f.child(id)[name]
+		if !ok {
+			var err error
+			if next, err = f.db.CreateFolder(name, id, f.owner); err != nil {
+				return 0, err
+			}
+			f.child(id)[name] = next
+		}
+		id = next
+	}
+	return id, nil
+}
@@ -0,0 +1,198 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"discordvault/internal/bot"
+	"discordvault/internal/database"
+	"encoding/hex"
+	"fmt"
+	"log"
+	mrand "math/rand/v2"
+	"sync"
+	"time"
+)
+
+// PurgeStatus reports the progress of a paced mass deletion.
+type PurgeStatus struct {
+	ID              string     `json:"id"`
+	State           string     `json:"state"` // running, done, cancelled
+	Files           int        `json:"files"`
+	FilesDone       int        `json:"files_done"`
+	Messages        int        `json:"messages"`
+	MessagesDeleted int        `json:"messages_deleted"`
+	Failed          int        `json:"failed"`
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+}
+
+// Purger deletes large numbers of files slowly, one message at a time with a
+// jittered pause in between, so thousands of deletions don't look like abuse
+// to Discord's heuristics. With a Grace period, purges only move files to
+// purged_files and Expire deletes their messages once the grace period ends.
+type Purger struct {
+	Bot   *bot.Bot
+	DB    *database.Database
+	Pace  time.Duration
+	Grace time.Duration
+
+	mu   sync.Mutex
+	jobs map[string]*PurgeStatus
+}
+
+// Start launches a background purge of fileIDs and returns its job ID.
+func (p *Purger) Start(ctx context.Context, fileIDs []int) string {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	status := &PurgeStatus{
+		ID:        hex.EncodeToString(idBytes),
+		State:     "running",
+		Files:     len(fileIDs),
+		StartedAt: time.Now().UTC(),
+	}
+
+	p.mu.Lock()
+	if p.jobs == nil {
+		p.jobs = make(map[string]*PurgeStatus)
+	}
+	p.jobs[status.ID] = status
+	p.mu.Unlock()
+
+	go p.run(ctx, status, fileIDs)
+	return status.ID
+}
+
+// Status returns a snapshot of a purge job.
+func (p *Purger) Status(id string) (PurgeStatus, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status, ok := p.jobs[id]
+	if !ok {
+		return PurgeStatus{}, false
+	}
+	return *status, true
+}
+
+func (p *Purger) update(status *PurgeStatus, fn func(*PurgeStatus)) {
+	p.mu.Lock()
+	fn(status)
+	p.mu.Unlock()
+}
+
+func (p *Purger) run(ctx context.Context, status *PurgeStatus, fileIDs []int) {
+	log.Printf("[JOBS] Slow purge %s started for %d files (pace %s)", status.ID, len(fileIDs), p.Pace)
+
+	if p.Grace > 0 {
+		for _, id := range fileIDs {
+			if err := p.DB.SoftDeleteFile(id, "purge "+status.ID); err != nil {
+				p.update(status, func(s *PurgeStatus) { s.Failed++ })
+				continue
+			}
+			p.update(status, func(s *PurgeStatus) { s.FilesDone++ })
+		}
+		p.finish(status, "done")
+		p.Bot.Session.ChannelMessageSend(p.Bot.Config.ChannelID, fmt.Sprintf("🧹 **Slow Purge Complete**\n**Files:** %d\n**Failures:** %d\nChunk messages are kept for %s in case the purge needs undoing.",
+			status.FilesDone, status.Failed, p.Grace))
+		return
+	}
+
+	for _, id := range fileIDs {
+		chunks, err := p.DB.ExclusiveChunks(id)
+		if err != nil {
+			p.update(status, func(s *PurgeStatus) { s.Failed++ })
+			continue
+		}
+		p.update(status, func(s *PurgeStatus) { s.Messages += len(chunks) })
+
+		failed := false
+		for _, c := range chunks {
+			if !p.sleep(ctx) || p.Bot.Health.Wait(ctx) != nil {
+				p.finish(status, "cancelled")
+				return
+			}
+			if err := p.Bot.DeleteChunk(c); err != nil {
+				log.Printf("[JOBS ERR] Slow purge %s: message %s: %v", status.ID, c.MessageID, err)
+				failed = true
+				p.update(status, func(s *PurgeStatus) { s.Failed++ })
+				continue
+			}
+			p.update(status, func(s *PurgeStatus) { s.MessagesDeleted++ })
+		}
+
+		// Keep metadata for files with undeleted messages so a rerun can retry them.
+		if !failed {
+			if err := p.DB.DeleteFile(id); err != nil {
+				p.update(status, func(s *PurgeStatus) { s.Failed++ })
+				continue
+			}
+		}
+		p.update(status, func(s *PurgeStatus) { s.FilesDone++ })
+	}
+
+	p.finish(status, "done")
+	p.Bot.Session.ChannelMessageSend(p.Bot.Config.ChannelID, fmt.Sprintf("🧹 **Slow Purge Complete**\n**Files:** %d\n**Messages Deleted:** %d\n**Failures:** %d",
+		status.FilesDone, status.MessagesDeleted, status.Failed))
+}
+
+// Expire deletes the messages of files purged longer than Grace ago, at the
+// same pace as a slow purge, and then forgets them. Files whose messages could
+// not all be deleted are retried on the next run.
+func (p *Purger) Expire(ctx context.Context) error {
+	ids, err := p.DB.ExpiredPurges(time.Now().Add(-p.Grace))
+	if err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, id := range ids {
+		messages, err := p.DB.PurgedMessages(id)
+		if err != nil {
+			return err
+		}
+		failed := false
+		for _, msg := range messages {
+			if !p.sleep(ctx) || p.Bot.Health.Wait(ctx) != nil {
+				return ctx.Err()
+			}
+			if err := p.Bot.DeleteChunk(msg); err != nil {
+				log.Printf("[JOBS ERR] Purge expiry: message %s: %v", msg.MessageID, err)
+				failed = true
+				continue
+			}
+			deleted++
+		}
+		if !failed {
+			if err := p.DB.ForgetPurged(id); err != nil {
+				return err
+			}
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("[JOBS] Purge grace ended for %d files, %d messages deleted", len(ids), deleted)
+	}
+	return nil
+}
+
+func (p *Purger) finish(status *PurgeStatus, state string) {
+	p.update(status, func(s *PurgeStatus) {
+		now := time.Now().UTC()
+		s.State = state
+		s.FinishedAt = &now
+	})
+	log.Printf("[JOBS] Slow purge %s %s: %d/%d files, %d messages deleted", status.ID, state, status.FilesDone, status.Files, status.MessagesDeleted)
+}
+
+// sleep waits one pacing interval with ±25% jitter, returning false if the
+// context was cancelled.
+func (p *Purger) sleep(ctx context.Context) bool {
+	jitter := time.Duration(0)
+	if p.Pace > 0 {
+		jitter = time.Duration(mrand.Int64N(int64(p.Pace)/2+1)) - p.Pace/4
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(p.Pace + jitter):
+		return true
+	}
+}
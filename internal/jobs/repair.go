@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"discordvault/internal/bot"
+	"discordvault/internal/database"
+	"errors"
+	"log"
+)
+
+// RepairReport summarises one repair pass.
+type RepairReport struct {
+	Degraded      int `json:"degraded"`
+	Repaired      int `json:"repaired"`
+	Unrecoverable int `json:"unrecoverable"`
+	Failed        int `json:"failed"`
+	Chunks        int `json:"chunks"` // messages re-uploaded
+}
+
+// Healer repairs files queued as degraded by downloads and verification:
+// lost or damaged chunks are read from a replica or rebuilt from parity and
+// uploaded again.
+type Healer struct {
+	Bot *bot.Bot
+	DB  *database.Database
+}
+
+func (h *Healer) Run(ctx context.Context) error {
+	_, err := h.Heal(ctx)
+	return err
+}
+
+func (h *Healer) Heal(ctx context.Context) (*RepairReport, error) {
+	ids, err := h.DB.DegradedFiles()
+	if err != nil {
+		return nil, err
+	}
+	report := &RepairReport{Degraded: len(ids)}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		file, err := h.DB.GetFile(id)
+		if errors.Is(err, sql.ErrNoRows) {
+			h.DB.ClearRepair(id)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		n, err := h.Bot.RepairFile(ctx, file)
+		report.Chunks += n
+		switch {
+		case errors.Is(err, bot.ErrUnrecoverable):
+			log.Printf("[JOBS ERR] %s (#%d) cannot be repaired: %v", file.Name, file.ID, err)
+			report.Unrecoverable++
+			detail := err.Error()
+			if err = h.DB.SetRepairStatus(id, database.RepairUnrecoverable, detail); err == nil {
+				err = h.DB.SetFileHealth(id, database.HealthCorrupt, detail)
+			}
+		case err != nil:
+			log.Printf("[JOBS ERR] Repair of %s (#%d) failed, retrying next run: %v", file.Name, file.ID, err)
+			report.Failed++
+			err = h.DB.SetRepairStatus(id, database.RepairDegraded, err.Error())
+		default:
+			log.Printf("[JOBS] Repaired %s (#%d): %d chunk(s) re-uploaded", file.Name, file.ID, n)
+			report.Repaired++
+			if err = h.DB.ClearRepair(id); err == nil {
+				err = h.DB.SetFileHealth(id, database.HealthOK, "repaired")
+			}
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if report.Degraded > 0 {
+		log.Printf("[JOBS] Repair pass: %d degraded, %d repaired, %d unrecoverable, %d failed", report.Degraded, report.Repaired, report.Unrecoverable, report.Failed)
+	}
+	return report, ctx.Err()
+}
@@ -0,0 +1,139 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Task is a unit of periodic background work.
+type Task func(ctx context.Context) error
+
+type entry struct {
+	name     string
+	interval time.Duration
+	task     Task
+}
+
+// JobStatus reports the runs of one scheduled task.
+type JobStatus struct {
+	Name       string     `json:"name"`
+	Interval   string     `json:"interval"`
+	Running    bool       `json:"running"`
+	Runs       int        `json:"runs"`
+	Failures   int        `json:"failures"`
+	Skipped    int        `json:"skipped"` // paused vault or previous run still active
+	LastStart  *time.Time `json:"last_start,omitempty"`
+	LastFinish *time.Time `json:"last_finish,omitempty"`
+	LastError  string     `json:"last_error,omitempty"` // of the latest run, "" if it succeeded
+}
+
+// Gate can hold off scheduled work, e.g. during Discord incidents.
+type Gate interface {
+	Paused() bool
+}
+
+// Scheduler runs registered tasks on fixed intervals until its context ends.
+type Scheduler struct {
+	Gate Gate
+
+	mu      sync.Mutex
+	entries []entry
+	running map[string]bool
+	status  map[string]*JobStatus
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{running: make(map[string]bool), status: make(map[string]*JobStatus)}
+}
+
+// Every registers task to run every interval. Non-positive intervals disable
+// the task, which lets callers pass configuration straight through.
+func (s *Scheduler) Every(name string, interval time.Duration, task Task) {
+	if interval <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry{name: name, interval: interval, task: task})
+	s.status[name] = &JobStatus{Name: name, Interval: interval.String()}
+}
+
+// Status returns a snapshot of every registered task, in registration
+// order.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]JobStatus, 0, len(s.entries))
+	for _, e := range s.entries {
+		st := *s.status[e.name]
+		st.Running = s.running[e.name]
+		out = append(out, st)
+	}
+	return out
+}
+
+// Start launches one goroutine per registered task.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		log.Printf("[JOBS] Scheduled %s every %s", e.name, e.interval)
+		go s.loop(ctx, e)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, e entry) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.run(ctx, e)
+		}
+	}
+}
+
+// run executes a task unless a previous run of it is still in progress.
+func (s *Scheduler) run(ctx context.Context, e entry) {
+	if s.Gate != nil && s.Gate.Paused() {
+		s.mu.Lock()
+		s.status[e.name].Skipped++
+		s.mu.Unlock()
+		log.Printf("[JOBS] Skipping %s: vault paused", e.name)
+		return
+	}
+
+	s.mu.Lock()
+	st := s.status[e.name]
+	if s.running[e.name] {
+		st.Skipped++
+		s.mu.Unlock()
+		log.Printf("[JOBS] Skipping %s: previous run still active", e.name)
+		return
+	}
+	s.running[e.name] = true
+	started := time.Now().UTC()
+	st.LastStart = &started
+	s.mu.Unlock()
+
+	err := e.task(ctx)
+	if err != nil {
+		log.Printf("[JOBS ERR] %s failed: %v", e.name, err)
+	}
+
+	s.mu.Lock()
+	s.running[e.name] = false
+	finished := time.Now().UTC()
+	st.Runs++
+	st.LastFinish = &finished
+	st.LastError = ""
+	if err != nil {
+		st.Failures++
+		st.LastError = err.Error()
+	}
+	s.mu.Unlock()
+}
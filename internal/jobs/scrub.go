@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"discordvault/internal/bot"
+	"discordvault/internal/database"
+	"errors"
+	"log"
+	"time"
+)
+
+// ScrubCheckInterval is how often the scrub looks for files due a check, so
+// a pass cut short by a restart resumes soon.
+const ScrubCheckInterval = time.Hour
+
+// Scrubber downloads and verifies every file once per Interval, one chunk
+// every Pace, and records each file's health. Lost chunks it comes across
+// are queued for the repair job.
+type Scrubber struct {
+	Bot      *bot.Bot
+	DB       *database.Database
+	Interval time.Duration
+	Pace     time.Duration
+}
+
+func (s *Scrubber) Run(ctx context.Context) error {
+	ids, err := s.DB.ScrubCandidates(time.Now().Add(-s.Interval))
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		file, err := s.DB.GetFile(id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		report, err := s.Bot.ScrubFile(ctx, file, s.Pace)
+		if errors.Is(err, bot.ErrChunkUnreachable) {
+			// Left unchecked, so the next pass tries it again
+			log.Printf("[JOBS] Scrub skipped %s (#%d): %v", file.Name, file.ID, err)
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return err
+		}
+		health, err := s.Bot.RecordHealth(file, report)
+		if err != nil {
+			return err
+		}
+		if health != database.HealthOK {
+			log.Printf("[JOBS] Scrub: %s (#%d) is %s: %v", file.Name, file.ID, health, report.Problems(file))
+		}
+		counts[health]++
+	}
+
+	log.Printf("[JOBS] Scrub checked %d of %d files: %d ok, %d degraded, %d corrupt",
+		counts[database.HealthOK]+counts[database.HealthDegraded]+counts[database.HealthCorrupt], len(ids),
+		counts[database.HealthOK], counts[database.HealthDegraded], counts[database.HealthCorrupt])
+	return ctx.Err()
+}
@@ -0,0 +1,104 @@
+// Package leader elects the primary among vault instances that share a
+// database. Only the primary connects the bot to the gateway and runs
+// background jobs; a warm standby waits for the primary's lease to lapse and
+// then takes over.
+package leader
+
+import (
+	"context"
+	"discordvault/internal/database"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// LeaseName is the lease the primary holds.
+const LeaseName = "primary"
+
+// ErrLost is returned by Hold when another instance took over the lease.
+var ErrLost = errors.New("primary lease taken over by another instance")
+
+// Elector competes for the primary lease on behalf of one instance.
+type Elector struct {
+	DB  *database.Database
+	ID  string
+	TTL time.Duration
+}
+
+// renewInterval is how often the lease is renewed or retried: two attempts
+// fit before the renew deadline, so a single failed query does not cost the
+// lease.
+func (e *Elector) renewInterval() time.Duration {
+	return e.TTL / 3
+}
+
+// renewDeadline is how long a primary keeps acting as one without renewing
+// its lease. It is a third of the TTL short of it, so the primary has
+// stopped before a standby can take the expired lease.
+func (e *Elector) renewDeadline() time.Duration {
+	return e.TTL * 2 / 3
+}
+
+// Acquire blocks until this instance holds the lease or ctx ends.
+func (e *Elector) Acquire(ctx context.Context) error {
+	ticker := time.NewTicker(e.renewInterval())
+	defer ticker.Stop()
+	waiting := false
+	for {
+		ok, err := e.DB.AcquireLease(LeaseName, e.ID, e.TTL)
+		switch {
+		case err != nil:
+			log.Printf("[HA ERR] Acquiring primary lease failed: %v", err)
+		case ok:
+			log.Printf("[HA] %s is now primary", e.ID)
+			return nil
+		case !waiting:
+			waiting = true
+			if holder, expires, err := e.DB.LeaseHolder(LeaseName); err == nil && holder != "" {
+				log.Printf("[HA] Standby: %s is primary (lease until %s UTC)", holder, expires.Format("15:04:05"))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Hold renews the lease until ctx ends, then releases it so a standby can
+// take over at once. It returns early with an error once the lease is lost:
+// taken over, or not renewed within the renew deadline. The caller must then
+// stop acting as primary.
+func (e *Elector) Hold(ctx context.Context) error {
+	ticker := time.NewTicker(e.renewInterval())
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := e.DB.ReleaseLease(LeaseName, e.ID); err != nil {
+				log.Printf("[HA ERR] Releasing primary lease failed: %v", err)
+			}
+			return nil
+		case <-ticker.C:
+		}
+
+		// The lease runs from before the query, in case it is slow
+		attempt := time.Now()
+		ok, err := e.DB.AcquireLease(LeaseName, e.ID, e.TTL)
+		switch {
+		case err != nil:
+			log.Printf("[HA WARN] Renewing primary lease failed: %v", err)
+			if time.Since(renewed) >= e.renewDeadline() {
+				return fmt.Errorf("primary lease not renewed in time: %w", err)
+			}
+		case !ok:
+			return ErrLost
+		default:
+			renewed = attempt
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Package logging is the output of the standard logger: it drops lines
+// below LOG_LEVEL, formats the rest as text or JSON, and writes them to
+// stderr and an optional rotated file. The level and component of a line
+// come from its "[BOT ERR]"-style prefix, as parsed by logstream.
+package logging
+
+import (
+	"discordvault/internal/logstream"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Writer is an io.Writer for log.SetOutput.
+type Writer struct {
+	Out    io.Writer         // formatted lines go here
+	JSON   bool              // one JSON object per line instead of the text line
+	Level  func() string     // lowest level written; read per line, so it may change
+	Stream *logstream.Buffer // also gets every line written, nil for none
+
+	mu sync.Mutex
+}
+
+// Write writes the lines of p that reach the level. It reports p as
+// written even when lines are dropped, so the logger does not complain.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	min := logstream.LevelInfo
+	if w.Level != nil {
+		min = w.Level()
+	}
+	now := time.Now()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		e := logstream.Parse(line, now)
+		if !logstream.AtLeast(e.Level, min) {
+			continue
+		}
+		if w.Stream != nil {
+			w.Stream.Write([]byte(line + "\n"))
+		}
+		out := []byte(line + "\n")
+		if w.JSON {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return 0, err
+			}
+			out = append(data, '\n')
+		}
+		if _, err := w.Out.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
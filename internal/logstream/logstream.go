@@ -0,0 +1,215 @@
+// Package logstream keeps the most recent log lines in memory, split into
+// level and component from the "[BOT ERR]"-style prefixes used across the
+// vault, and fans new lines out to live subscribers such as the admin log
+// stream.
+package logstream
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Levels, lowest first.
+const (
+	LevelDebug    = "debug"
+	LevelInfo     = "info"
+	LevelWarn     = "warn"
+	LevelError    = "error"
+	LevelCritical = "critical"
+)
+
+var levelRank = map[string]int{LevelDebug: 0, LevelInfo: 1, LevelWarn: 2, LevelError: 3, LevelCritical: 4}
+
+// ValidLevel reports whether level is one of the known levels.
+func ValidLevel(level string) bool {
+	_, ok := levelRank[level]
+	return ok
+}
+
+// components maps the first word of a log prefix to its component.
+var components = map[string]string{
+	"BOT":     "bot",
+	"SERVER":  "server",
+	"SRV":     "server",
+	"JOBS":    "jobs",
+	"DB":      "db",
+	"DEMO":    "demo",
+	"HA":      "ha",
+	"HEALTH":  "health",
+	"RESTORE": "restore",
+	"RETRY":   "retry",
+}
+
+// subscriberBuffer is how many entries a slow subscriber may fall behind
+// before further entries are dropped for it.
+const subscriberBuffer = 256
+
+// Entry is one parsed log line.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Component string    `json:"component"`
+	Message   string    `json:"message"`
+}
+
+// Buffer is an io.Writer for the standard logger that remembers the last
+// entries in a ring.
+type Buffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+	subs    map[chan Entry]struct{}
+}
+
+// New returns a Buffer holding up to capacity entries.
+func New(capacity int) *Buffer {
+	return &Buffer{
+		entries: make([]Entry, max(capacity, 1)),
+		subs:    make(map[chan Entry]struct{}),
+	}
+}
+
+// Write records one or more log lines. It never fails, so it is safe to pair
+// with os.Stderr in an io.MultiWriter.
+func (b *Buffer) Write(p []byte) (int, error) {
+	now := time.Now()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		b.add(Parse(line, now))
+	}
+	return len(p), nil
+}
+
+func (b *Buffer) add(e Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Recent returns the buffered entries, oldest first.
+func (b *Buffer) Recent() []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.recent()
+}
+
+func (b *Buffer) recent() []Entry {
+	if !b.full {
+		return append([]Entry(nil), b.entries[:b.next]...)
+	}
+	out := make([]Entry, 0, len(b.entries))
+	out = append(out, b.entries[b.next:]...)
+	return append(out, b.entries[:b.next]...)
+}
+
+// Subscribe returns the buffered entries and a channel receiving every entry
+// written after them; no entry is missed or repeated between the two. The
+// returned function ends the subscription.
+func (b *Buffer) Subscribe() ([]Entry, <-chan Entry, func()) {
+	ch := make(chan Entry, subscriberBuffer)
+
+	b.mu.Lock()
+	recent := b.recent()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return recent, ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+// Parse splits a line written by the standard logger into an Entry. Lines
+// without a known prefix are attributed to "main" at info level; now is used
+// when the line carries no timestamp.
+func Parse(line string, now time.Time) Entry {
+	e := Entry{Time: now, Level: LevelInfo, Component: "main", Message: line}
+
+	// The standard logger prefixes "2006/01/02 15:04:05 " in local time.
+	const stamp = "2006/01/02 15:04:05"
+	if len(line) > len(stamp) && line[len(stamp)] == ' ' {
+		if t, err := time.ParseInLocation(stamp, line[:len(stamp)], time.Local); err == nil {
+			e.Time, line = t, line[len(stamp)+1:]
+		}
+	}
+	e.Message = line
+
+	if !strings.HasPrefix(line, "[") {
+		return e
+	}
+	tag, rest, ok := strings.Cut(line[1:], "]")
+	if !ok {
+		return e
+	}
+	words := strings.Fields(tag)
+	if len(words) == 0 {
+		return e
+	}
+	if c, ok := components[words[0]]; ok {
+		e.Component = c
+		words = words[1:]
+	}
+	if len(words) > 0 {
+		switch words[0] {
+		case "DEBUG":
+			e.Level = LevelDebug
+		case "WARN":
+			e.Level = LevelWarn
+		case "ERR", "ERROR":
+			e.Level = LevelError
+		case "CRITICAL":
+			e.Level = LevelCritical
+		default:
+			return e
+		}
+		words = words[1:]
+	}
+	if len(words) > 0 {
+		return e
+	}
+	e.Message = strings.TrimSpace(rest)
+	return e
+}
+
+// AtLeast reports whether level is min or above.
+func AtLeast(level, min string) bool {
+	return levelRank[level] >= levelRank[min]
+}
+
+// Filter selects entries at or above MinLevel from the given components.
+type Filter struct {
+	MinLevel   string   // "" = every level
+	Components []string // empty = every component
+}
+
+// Match reports whether e passes the filter.
+func (f Filter) Match(e Entry) bool {
+	if f.MinLevel != "" && !AtLeast(e.Level, f.MinLevel) {
+		return false
+	}
+	if len(f.Components) == 0 {
+		return true
+	}
+	for _, c := range f.Components {
+		if c == e.Component {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+// Package membudget caps how many chunks the process buffers at once across
+// every upload and download. A chunk is up to CHUNK_SIZE of plaintext plus
+// its ciphertext, so without a cap enough parallel transfers run the process
+// out of memory; with one, transfers past the cap wait for a free buffer.
+package membudget
+
+import "context"
+
+// Budget counts chunk buffers in use. A nil Budget never blocks. It is safe
+// for concurrent use.
+type Budget struct {
+	slots chan struct{}
+}
+
+// New returns a budget of n chunk buffers, or nil when n is not positive.
+func New(n int) *Budget {
+	if n <= 0 {
+		return nil
+	}
+	return &Budget{slots: make(chan struct{}, n)}
+}
+
+// Acquire takes one chunk buffer, waiting while all are in use or until
+// ctx is done.
+func (b *Budget) Acquire(ctx context.Context) error {
+	if b == nil {
+		return ctx.Err()
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release returns a buffer taken by Acquire.
+func (b *Budget) Release() {
+	if b != nil {
+		<-b.slots
+	}
+}
+
+// InUse returns the buffers taken and the size of the budget, 0 for
+// unlimited.
+func (b *Budget) InUse() (int, int) {
+	if b == nil {
+		return 0, 0
+	}
+	return len(b.slots), cap(b.slots)
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Bridge forwards notifications to a chat service other than Discord.
+type Bridge interface {
+	Name() string
+	Post(title, body string) error
+}
+
+var bridgeClient = &http.Client{Timeout: 15 * time.Second}
+
+// Slack posts to an incoming webhook.
+type Slack struct {
+	WebhookURL string
+}
+
+func (s *Slack) Name() string { return "slack" }
+
+var discordBold = regexp.MustCompile(`\*\*(.+?)\*\*`)
+
+// slackEscaper escapes the characters Slack reads as control sequences, so
+// a file name like "<!channel>" is shown rather than pinging the channel.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (s *Slack) Post(title, body string) error {
+	// Slack's mrkdwn marks bold with single asterisks.
+	text := discordBold.ReplaceAllString(slackEscaper.Replace(body), "*$1*")
+	if title != "" {
+		text = "*" + slackEscaper.Replace(title) + "*\n" + text
+	}
+	return postJSON(http.MethodPost, s.WebhookURL, nil, map[string]string{"text": text})
+}
+
+// Matrix sends m.room.message events to one room with a bot account's
+// access token.
+type Matrix struct {
+	Homeserver  string
+	RoomID      string
+	AccessToken string
+}
+
+func (m *Matrix) Name() string { return "matrix" }
+
+func (m *Matrix) Post(title, body string) error {
+	text := PlainText(body)
+	if title != "" {
+		text = title + "\n" + text
+	}
+	txn := make([]byte, 8)
+	rand.Read(txn)
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(m.Homeserver, "/"), url.PathEscape(m.RoomID), hex.EncodeToString(txn))
+	header := http.Header{"Authorization": {"Bearer " + m.AccessToken}}
+	return postJSON(http.MethodPut, endpoint, header, map[string]string{"msgtype": "m.notice", "body": text})
+}
+
+func postJSON(method, endpoint string, header http.Header, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := bridgeClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s", method, req.URL.Host, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Kind identifies a notification event.
+type Kind string
+
+const (
+	KindUpload Kind = "upload"
+	KindDelete Kind = "delete"
+	KindError  Kind = "error"
+	KindDigest Kind = "digest"
+)
+
+var kinds = []Kind{KindUpload, KindDelete, KindError, KindDigest}
+
+// Event is the only data exposed to templates. Keep it plain values so
+// operator supplied templates cannot reach into internal state.
+type Event struct {
+	Kind      string
+	Method    string
+	File      string
+	FileID    int
+	Size      string
+	SizeBytes int64
+	Parts     int
+	User      string
+	Error     string
+	Time      string
+
+	// Digest fields
+	Period         string
+	Since          string
+	Uploads        int
+	UploadedSize   string
+	Deletes        int
+	DeletedSize    string
+	Files          int
+	TotalSize      string
+	VerifyFailures int
+	Failures       string // one line per failure, capped
+}
+
+// Message is a rendered notification. When Title is set the notification is
+// posted as an embed, otherwise Body is sent as plain message content.
+type Message struct {
+	Title string
+	Body  string
+	Color int
+}
+
+var defaults = map[Kind]string{
+	KindUpload: "📤 **{{.Method}} Upload Complete**\n**File:** `{{.File}}`\n**Size:** `{{.Size}}`\n**Parts:** {{.Parts}}\n**Time:** `{{.Time}}`\n**Status:** Encrypted & Locked",
+	KindDelete: "🧹 **Asset Purged**\n**File:** `{{.File}}` (#{{.FileID}})\n**By:** {{.Method}}{{if .User}} / <@{{.User}}>{{end}}\n**Time:** `{{.Time}}`",
+	KindError:  "⚠️ **{{.Method}} Operation Failed**\n{{if .File}}**File:** `{{.File}}`\n{{end}}**Error:** `{{.Error}}`\n**Time:** `{{.Time}}`",
+	KindDigest: `{{define "title"}}📊 Vault Digest ({{.Period}}){{end}}{{define "color"}}{{if .VerifyFailures}}#ef4444{{else}}#3b82f6{{end}}{{end}}` +
+		"**Since:** `{{.Since}}`\n**Uploads:** {{.Uploads}} (`{{.UploadedSize}}`)\n**Deletes:** {{.Deletes}} (`{{.DeletedSize}}`)\n**Files:** {{.Files}}\n**Stored:** `{{.TotalSize}}`\n" +
+		"**Verification failures:** {{.VerifyFailures}}{{if .Failures}}\n{{.Failures}}{{end}}",
+}
+
+var funcs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Templates holds one parsed template per event kind.
+type Templates struct {
+	set map[Kind]*template.Template
+}
+
+// Load parses the built-in templates, replacing any kind for which dir
+// contains a <kind>.tmpl file. A template may {{define "title"}} (and
+// optionally "color") to be rendered as an embed instead of plain text.
+func Load(dir string) (*Templates, error) {
+	t := &Templates{set: make(map[Kind]*template.Template)}
+	for _, kind := range kinds {
+		text := defaults[kind]
+		if dir != "" {
+			data, err := os.ReadFile(filepath.Join(dir, string(kind)+".tmpl"))
+			if err == nil {
+				text = string(data)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		}
+		tmpl, err := template.New(string(kind)).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", kind, err)
+		}
+		t.set[kind] = tmpl
+	}
+	return t, nil
+}
+
+// Render executes the template for ev.Kind.
+func (t *Templates) Render(kind Kind, ev Event) (*Message, error) {
+	tmpl := t.set[kind]
+	ev.Kind = string(kind)
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, ev); err != nil {
+		return nil, err
+	}
+	msg := &Message{Body: strings.TrimSpace(body.String())}
+
+	if title := tmpl.Lookup("title"); title != nil {
+		var buf bytes.Buffer
+		if err := title.Execute(&buf, ev); err != nil {
+			return nil, err
+		}
+		msg.Title = strings.TrimSpace(buf.String())
+		msg.Color = 0x3b82f6
+	}
+	if color := tmpl.Lookup("color"); color != nil {
+		var buf bytes.Buffer
+		if err := color.Execute(&buf, ev); err != nil {
+			return nil, err
+		}
+		fmt.Sscanf(strings.TrimPrefix(strings.TrimSpace(buf.String()), "#"), "%x", &msg.Color)
+	}
+	return msg, nil
+}
@@ -0,0 +1,238 @@
+// Package pipeline runs operator-defined post-download steps on a decrypted
+// file, e.g. gunzip → untar into a directory on the vault host.
+package pipeline
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Step types.
+const (
+	StepGunzip = "gunzip" // decompress the stream
+	StepUntar  = "untar"  // extract the stream into Dest; must be last
+	StepWrite  = "write"  // write the stream to Dest; must be last
+)
+
+type Step struct {
+	Type string `json:"type"`
+	Dest string `json:"dest,omitempty"`
+	// StripComponents drops leading path elements from tar entries.
+	StripComponents int `json:"strip_components,omitempty"`
+}
+
+// Pipeline is a named sequence of steps, loaded from PIPELINES_FILE.
+type Pipeline struct {
+	Name         string   `json:"-"`
+	Steps        []Step   `json:"steps"`
+	AllowedUsers []string `json:"allowed_users,omitempty"` // empty = admins only
+}
+
+// Report summarizes a pipeline run.
+type Report struct {
+	Pipeline     string   `json:"pipeline"`
+	BytesIn      int64    `json:"bytes_in"`
+	FilesWritten int      `json:"files_written"`
+	BytesWritten int64    `json:"bytes_written"`
+	Skipped      []string `json:"skipped,omitempty"`
+}
+
+// Load reads pipeline definitions from a JSON file mapping names to
+// pipelines. An empty path yields no pipelines.
+func Load(path string) (map[string]*Pipeline, error) {
+	pipelines := make(map[string]*Pipeline)
+	if path == "" {
+		return pipelines, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &pipelines); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, p := range pipelines {
+		p.Name = name
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("pipeline %q: %w", name, err)
+		}
+	}
+	return pipelines, nil
+}
+
+func (p *Pipeline) validate() error {
+	if len(p.Steps) == 0 {
+		return errors.New("has no steps")
+	}
+	for i, step := range p.Steps {
+		last := i == len(p.Steps)-1
+		switch step.Type {
+		case StepGunzip:
+			if last {
+				return errors.New("must end with an untar or write step")
+			}
+		case StepUntar, StepWrite:
+			if !last {
+				return fmt.Errorf("%s must be the last step", step.Type)
+			}
+			if !filepath.IsAbs(step.Dest) {
+				return fmt.Errorf("%s needs an absolute dest", step.Type)
+			}
+		default:
+			return fmt.Errorf("unknown step type %q", step.Type)
+		}
+	}
+	return nil
+}
+
+// Allows reports whether userID may run the pipeline.
+func (p *Pipeline) Allows(userID string, admin bool) bool {
+	if admin {
+		return true
+	}
+	for _, id := range p.AllowedUsers {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// destLocks holds a *sync.Mutex per destination, so two runs never write
+// into the same place at once.
+var destLocks sync.Map
+
+// Run feeds the decrypted file in r through the pipeline's steps. Runs
+// that write to the same destination take turns.
+func (p *Pipeline) Run(r io.Reader) (*Report, error) {
+	dest := filepath.Clean(p.Steps[len(p.Steps)-1].Dest)
+	mu, _ := destLocks.LoadOrStore(dest, new(sync.Mutex))
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+
+	report := &Report{Pipeline: p.Name}
+	counter := &countingReader{r: r}
+	var stream io.Reader = counter
+
+	for _, step := range p.Steps {
+		switch step.Type {
+		case StepGunzip:
+			gz, err := gzip.NewReader(stream)
+			if err != nil {
+				return report, fmt.Errorf("gunzip: %w", err)
+			}
+			defer gz.Close()
+			stream = gz
+		case StepUntar:
+			err := untar(stream, step, report)
+			report.BytesIn = counter.n
+			return report, err
+		case StepWrite:
+			n, err := writeFile(step.Dest, stream)
+			report.BytesIn = counter.n
+			if err == nil {
+				report.FilesWritten, report.BytesWritten = 1, n
+			}
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// untar extracts regular files and directories into step.Dest. Links,
+// devices, and entries escaping the destination are skipped.
+func untar(r io.Reader, step Step, report *Report) error {
+	if err := os.MkdirAll(step.Dest, 0o755); err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("untar: %w", err)
+		}
+
+		name := stripComponents(hdr.Name, step.StripComponents)
+		if name == "" {
+			continue
+		}
+		target := filepath.Join(step.Dest, filepath.FromSlash(name))
+		if rel, err := filepath.Rel(step.Dest, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			report.Skipped = append(report.Skipped, hdr.Name)
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			n, err := writeFile(target, tr)
+			if err != nil {
+				return err
+			}
+			if mode := hdr.FileInfo().Mode().Perm(); mode != 0 {
+				os.Chmod(target, mode)
+			}
+			report.FilesWritten++
+			report.BytesWritten += n
+		default:
+			report.Skipped = append(report.Skipped, hdr.Name)
+		}
+	}
+}
+
+func stripComponents(name string, n int) string {
+	parts := strings.Split(strings.Trim(filepath.ToSlash(name), "/"), "/")
+	if n >= len(parts) {
+		return ""
+	}
+	return strings.Join(parts[n:], "/")
+}
+
+// writeFile writes r to path through a temporary file so readers never see a
+// partial result.
+func writeFile(path string, r io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pipeline-*")
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return n, err
+	}
+	return n, os.Rename(tmp.Name(), path)
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
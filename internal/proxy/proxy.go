@@ -0,0 +1,57 @@
+// Package proxy routes the vault's traffic to Discord, the REST API, the
+// gateway, and attachment downloads from the CDN, through the proxy of
+// HTTPS_PROXY or SOCKS_PROXY.
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	transport *http.Transport   // nil until Set is given a proxy
+	dialer    *websocket.Dialer // nil until Set is given a proxy
+)
+
+// Client fetches Discord attachments. It uses the proxy once Set has one.
+var Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+	return Transport().RoundTrip(req)
+})}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// Set routes Discord traffic through u, an http:// or socks5:// URL; nil
+// connects directly. It must be called before the first Discord session
+// is created. Demo mode replaces the default transport with its in-memory
+// Discord, which is left alone.
+func Set(u *url.URL) {
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if u == nil || !ok {
+		return
+	}
+	transport = base.Clone()
+	transport.Proxy = http.ProxyURL(u)
+	d := *websocket.DefaultDialer
+	d.Proxy = http.ProxyURL(u)
+	dialer = &d
+}
+
+// Transport returns the round tripper for requests to Discord.
+func Transport() http.RoundTripper {
+	if transport != nil {
+		return transport
+	}
+	return http.DefaultTransport
+}
+
+// Dialer returns the websocket dialer for the Discord gateway.
+func Dialer() *websocket.Dialer {
+	if dialer != nil {
+		return dialer
+	}
+	return websocket.DefaultDialer
+}
@@ -0,0 +1,179 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" at version 1-M, from the worked example of the spec.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomon(data, len(want)); !bytes.Equal(got, want) {
+		t.Errorf("reedSolomon = %v, want %v", got, want)
+	}
+}
+
+// formatBits reads the first copy of the format information.
+func formatBits(c *Code) int {
+	bit := func(x, y int) int {
+		if c.Dark(x, y) {
+			return 1
+		}
+		return 0
+	}
+	var bits int
+	for i := 0; i <= 5; i++ {
+		bits |= bit(8, i) << i
+	}
+	bits |= bit(8, 7)<<6 | bit(8, 8)<<7 | bit(7, 8)<<8
+	for i := 9; i < 15; i++ {
+		bits |= bit(14-i, 8) << i
+	}
+	return bits
+}
+
+func TestFormat(t *testing.T) {
+	// Level M format strings for masks 0-7.
+	want := []int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+	c := &Code{Size: 21, modules: grid(21), fixed: grid(21)}
+	for mask, w := range want {
+		c.drawFormat(mask)
+		if got := formatBits(c); got != w {
+			t.Errorf("mask %d: format %015b, want %015b", mask, got, w)
+		}
+	}
+}
+
+func TestVersionInfo(t *testing.T) {
+	c := &Code{Size: 45, modules: grid(45), fixed: grid(45)}
+	c.drawFunctionPatterns(7)
+	var bits int
+	for i := 0; i < 18; i++ {
+		if c.Dark(c.Size-11+i%3, i/3) {
+			bits |= 1 << i
+		}
+	}
+	if bits != 0x07C94 {
+		t.Errorf("version 7 info = %018b, want %018b", bits, 0x07C94)
+	}
+}
+
+// decode reads a symbol back: it unmasks it, collects the codewords in
+// placement order, checks each block's EC codewords, and parses the
+// byte-mode segment.
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+	ver := (c.Size - 17) / 4
+	v := versions[ver]
+	format := formatBits(c) ^ 0x5412
+	if format>>13 != 0 {
+		t.Fatalf("format level %d, want M", format>>13)
+	}
+
+	plain := &Code{Size: c.Size, modules: grid(c.Size), fixed: c.fixed}
+	for y := range c.modules {
+		copy(plain.modules[y], c.modules[y])
+	}
+	plain.applyMask(format >> 10 & 7)
+
+	var bb bitBuffer
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if !c.fixed[y][x] {
+					dark := 0
+					if plain.modules[y][x] {
+						dark = 1
+					}
+					bb.append(dark, 1)
+				}
+			}
+		}
+	}
+	raw := bb.bytes()
+
+	count := v.blocks1 + v.blocks2
+	blocks := make([][]byte, count)
+	i := 0
+	for pos := 0; pos <= v.data1; pos++ {
+		for b := range blocks {
+			if pos < v.data1 || b >= v.blocks1 {
+				blocks[b] = append(blocks[b], raw[i])
+				i++
+			}
+		}
+	}
+	var data []byte
+	for b, block := range blocks {
+		ec := make([]byte, v.ecLen)
+		for k := range ec {
+			ec[k] = raw[i+k*count+b]
+		}
+		if want := reedSolomon(block, v.ecLen); !bytes.Equal(ec, want) {
+			t.Fatalf("block %d: EC %v, want %v", b, ec, want)
+		}
+		data = append(data, block...)
+	}
+
+	if mode := data[0] >> 4; mode != 0b0100 {
+		t.Fatalf("mode %04b, want byte mode", mode)
+	}
+	var n int
+	var payload []byte
+	if ver < 10 {
+		n = int(data[0]&0x0f)<<4 | int(data[1]>>4)
+		payload = data[1:]
+	} else {
+		n = int(data[0]&0x0f)<<12 | int(data[1])<<4 | int(data[2]>>4)
+		payload = data[2:]
+	}
+	text := make([]byte, n)
+	for k := range text {
+		text[k] = payload[k]<<4 | payload[k+1]>>4
+	}
+	return string(text)
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		text string
+		ver  int
+	}{
+		{"", 1},
+		{"https://dv.example", 2},
+		{strings.Repeat("a", 14), 1},
+		{strings.Repeat("a", 15), 2},
+		{"https://vault.example.com/s/0123456789abcdef0123456789abcdef", 4},
+		{strings.Repeat("ü", 60), 7},
+		{strings.Repeat("x", 213), 10},
+	}
+	for _, tt := range tests {
+		c, err := Encode(tt.text)
+		if err != nil {
+			t.Errorf("Encode(%d bytes): %v", len(tt.text), err)
+			continue
+		}
+		if want := 17 + 4*tt.ver; c.Size != want {
+			t.Errorf("Encode(%d bytes): size %d, want %d", len(tt.text), c.Size, want)
+		}
+		if got := decode(t, c); got != tt.text {
+			t.Errorf("decode(Encode(%q)) = %q", tt.text, got)
+		}
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	if _, err := Encode(strings.Repeat("x", 214)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode = %v, want ErrTooLong", err)
+	}
+}
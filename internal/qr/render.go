@@ -0,0 +1,61 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// quietZone is the light border scanners need around the symbol, in modules.
+const quietZone = 4
+
+// PNG renders the code with scale pixels per module.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	side := (c.Size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, side, side))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			for py := 0; py < scale; py++ {
+				for px := 0; px < scale; px++ {
+					img.SetGray((x+quietZone)*scale+px, (y+quietZone)*scale+py, color.Gray{})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ANSI renders the code for a terminal using background colours, two
+// characters per module, so it scans on light and dark themes alike.
+func (c *Code) ANSI() string {
+	const (
+		light = "\x1b[47m  "
+		dark  = "\x1b[40m  "
+		reset = "\x1b[0m\n"
+	)
+	var sb strings.Builder
+	for y := -quietZone; y < c.Size+quietZone; y++ {
+		for x := -quietZone; x < c.Size+quietZone; x++ {
+			if x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.modules[y][x] {
+				sb.WriteString(dark)
+			} else {
+				sb.WriteString(light)
+			}
+		}
+		sb.WriteString(reset)
+	}
+	return sb.String()
+}
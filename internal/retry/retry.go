@@ -0,0 +1,155 @@
+// Package retry repeats Discord storage calls that failed for a reason that
+// may pass, such as a dropped connection or a 5xx, with exponential backoff
+// and jitter. Client errors like a deleted message are returned at once.
+package retry
+
+import (
+	"context"
+	"discordvault/internal/proxy"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Error classes. Each class has its own attempt limit.
+const (
+	Network   = "network"   // connection failures and timeouts
+	Server    = "server"    // 5xx responses
+	RateLimit = "ratelimit" // 429 responses that outlasted discordgo's own wait
+	Client    = "client"    // other 4xx and everything else; never retried
+)
+
+// Classes lists the retryable classes.
+var Classes = []string{Network, Server, RateLimit}
+
+// StatusError is an unexpected HTTP status from a plain request, such as a
+// CDN attachment download.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string { return e.Status }
+
+// CheckStatus returns a StatusError for responses other than 200 OK.
+func CheckStatus(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// Classify sorts an error into one of the classes.
+func Classify(err error) string {
+	var restErr *discordgo.RESTError
+	var statusErr *StatusError
+	var rateErr *discordgo.RateLimitError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &restErr) && restErr.Response != nil:
+		return classifyStatus(restErr.Response.StatusCode)
+	case errors.As(err, &statusErr):
+		return classifyStatus(statusErr.Code)
+	case errors.As(err, &rateErr):
+		return RateLimit
+	case errors.As(err, &netErr), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, context.DeadlineExceeded):
+		return Network
+	}
+	return Client
+}
+
+func classifyStatus(code int) string {
+	switch {
+	case code == http.StatusTooManyRequests:
+		return RateLimit
+	case code >= 500:
+		return Server
+	}
+	return Client
+}
+
+// IsNotFound reports whether err is a 404 from Discord or the CDN.
+func IsNotFound(err error) bool {
+	var restErr *discordgo.RESTError
+	var statusErr *StatusError
+	switch {
+	case errors.As(err, &restErr) && restErr.Response != nil:
+		return restErr.Response.StatusCode == http.StatusNotFound
+	case errors.As(err, &statusErr):
+		return statusErr.Code == http.StatusNotFound
+	}
+	return false
+}
+
+// Policy says how often and how patiently calls are repeated.
+type Policy struct {
+	Attempts map[string]int // class -> attempts including the first, < 2 = no retry
+	Base     time.Duration  // backoff before the first retry
+	Max      time.Duration  // upper bound of any single backoff
+}
+
+// Do runs fn until it succeeds, fails with an error whose class has no
+// attempts left, or the class is not retried. Backoff doubles per attempt
+// up to Max and is drawn at random below that bound ("full jitter"), so
+// callers that failed together do not retry together. op names the call in
+// logs. A nil Policy runs fn once.
+func (p *Policy) Do(op string, fn func() error) error {
+	if p == nil {
+		return fn()
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		class := Classify(err)
+		limit := p.Attempts[class]
+		if class == Client || attempt >= limit {
+			if attempt > 1 {
+				return fmt.Errorf("%s failed after %d attempts: %w", op, attempt, err)
+			}
+			return err
+		}
+		wait := p.backoff(attempt)
+		log.Printf("[RETRY WARN] %s failed (%s, attempt %d/%d), retrying in %s: %v", op, class, attempt, limit, wait.Round(time.Millisecond), err)
+		time.Sleep(wait)
+	}
+}
+
+// backoff returns a random delay below Base * 2^(attempt-1), capped at Max.
+func (p *Policy) backoff(attempt int) time.Duration {
+	bound := p.Base << min(attempt-1, 30)
+	if bound <= 0 || bound > p.Max {
+		bound = p.Max
+	}
+	if bound <= 0 {
+		return 0
+	}
+	return rand.N(bound) + 1
+}
+
+// Get downloads url with p, treating statuses other than 200 OK as errors.
+// The caller closes the body.
+func (p *Policy) Get(op, url string) (*http.Response, error) {
+	var resp *http.Response
+	err := p.Do(op, func() error {
+		r, err := proxy.Client.Get(url)
+		if err != nil {
+			return err
+		}
+		if err := CheckStatus(r); err != nil {
+			r.Body.Close()
+			return err
+		}
+		resp = r
+		return nil
+	})
+	return resp, err
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"discordvault/internal/database"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// apiKeyPrefix starts every key created through /api/keys, so leaked keys
+// are easy to recognise.
+const apiKeyPrefix = "dv_"
+
+// apiKeyTouchInterval is how stale a key's last-used time may get before a
+// request updates it.
+const apiKeyTouchInterval = time.Minute
+
+type apiKeyRequest struct {
+	Name    string `json:"name"`
+	Scope   string `json:"scope"`
+	OwnerID string `json:"owner_id"`
+}
+
+// createdAPIKey is the answer to a new key, the only time the key itself
+// is shown.
+type createdAPIKey struct {
+	database.APIKey
+	Key string `json:"key"`
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// managedKey resolves a key created through /api/keys. ok is false for
+// keys that are unknown or revoked.
+func (s *Server) managedKey(key string) (p Principal, ok bool) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return p, false
+	}
+	k, err := s.DB.APIKeyByHash(hashAPIKey(key))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("[SRV ERR] API key lookup failed: %v", err)
+		}
+		return p, false
+	}
+	if err := s.DB.TouchAPIKey(k.ID, time.Now().Add(-apiKeyTouchInterval)); err != nil {
+		log.Printf("[SRV WARN] Recording use of API key %s failed: %v", k.ID, err)
+	}
+	return s.apiKeyPrincipal(k), true
+}
+
+// apiKeyPrincipal is the caller a managed key stands for.
+func (s *Server) apiKeyPrincipal(k *database.APIKey) Principal {
+	// Without API_KEYS every caller is an admin, so admin keys are too.
+	admin := k.Scope == database.ScopeAdmin && (s.Config.IsAdmin(k.OwnerID) || len(s.Config.Live().APIKeys) == 0)
+	return Principal{ID: k.OwnerID, Admin: admin, KeyID: k.ID, Scope: k.Scope}
+}
+
+// handleListAPIKeys lists the caller's keys, or with ?all=true every user's
+// keys for an admin.
+func (s *Server) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	p := principalFrom(r)
+	owner := p.ID
+	if p.Admin && r.URL.Query().Get("all") == "true" {
+		owner = ""
+	}
+	keys, err := s.DB.ListAPIKeys(owner)
+	if err != nil {
+		log.Printf("[SRV ERR] ListAPIKeys failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// handleCreateAPIKey creates a key for the caller, or for owner_id when an
+// admin asks. Admin keys can only belong to admins.
+func (s *Server) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	p := principalFrom(r)
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = database.ScopeWrite
+	}
+	switch req.Scope {
+	case database.ScopeRead, database.ScopeWrite, database.ScopeAdmin:
+	default:
+		http.Error(w, "scope must be read, write, or admin", http.StatusBadRequest)
+		return
+	}
+	if req.OwnerID == "" || req.OwnerID == p.ID {
+		req.OwnerID = p.ID
+	} else if !p.Admin {
+		http.Error(w, "Admin access required to create keys for other users", http.StatusForbidden)
+		return
+	}
+	if req.Scope == database.ScopeAdmin && !(p.Admin && (req.OwnerID == p.ID || s.Config.IsAdmin(req.OwnerID))) {
+		http.Error(w, "Admin keys can only be created by and for admins", http.StatusForbidden)
+		return
+	}
+
+	idBytes := make([]byte, 16)
+	secret := make([]byte, 32)
+	rand.Read(idBytes)
+	rand.Read(secret)
+	key := createdAPIKey{
+		APIKey: database.APIKey{ID: hex.EncodeToString(idBytes), OwnerID: req.OwnerID, Name: req.Name, Scope: req.Scope},
+		Key:    apiKeyPrefix + hex.EncodeToString(secret),
+	}
+	if err := s.DB.CreateAPIKey(&key.APIKey, hashAPIKey(key.Key)); err != nil {
+		log.Printf("[SRV ERR] API key creation failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[SERVER] %s created %s API key %s (%s) for %s", p.ID, key.Scope, key.ID, key.Name, key.OwnerID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+// handleRevokeAPIKey revokes one of the caller's keys, or any key for an
+// admin, and ends the web sessions logged in with it. The key stays listed
+// with its revocation time.
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	p := principalFrom(r)
+	key, err := s.DB.GetAPIKey(mux.Vars(r)["id"])
+	if err != nil || !(p.Admin || key.OwnerID == p.ID) {
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("[SRV ERR] GetAPIKey failed: %v", err)
+		}
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	if err := s.DB.RevokeAPIKey(key.ID); err != nil {
+		log.Printf("[SRV ERR] API key revocation failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if _, err := s.DB.DeleteKeyWebSessions(key.ID); err != nil {
+		log.Printf("[SRV ERR] Ending sessions of API key %s failed: %v", key.ID, err)
+	}
+	log.Printf("[SERVER] %s revoked API key %s (%s) of %s", p.ID, key.ID, key.Name, key.OwnerID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"database/sql"
+	"discordvault/internal/database"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// handleUploadArtifact stores a CI build output together with its immutable
+// build metadata: ?project=&branch=&commit=&run_id= plus the multipart file.
+func (s *Server) handleUploadArtifact(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	art := &database.Artifact{
+		Project: q.Get("project"),
+		Branch:  q.Get("branch"),
+		Commit:  q.Get("commit"),
+		RunID:   q.Get("run_id"),
+	}
+	if art.Project == "" || art.Branch == "" || art.Commit == "" || art.RunID == "" {
+		http.Error(w, "project, branch, commit, and run_id are required", http.StatusBadRequest)
+		return
+	}
+
+	file, name := s.receiveUpload(w, r, database.DuplicateSuffix)
+	if file == nil {
+		return
+	}
+
+	art.FileID = file.ID
+	art.Name = name
+	if err := s.DB.SaveArtifact(art); err != nil {
+		log.Printf("[SRV ERR] Artifact metadata save failed: %v", err)
+		s.DB.ReleaseFile(file.ID)
+		http.Error(w, "Registry write failed", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[SERVER] Artifact %s stored for %s@%s run %s", file.Name, art.Project, art.Branch, art.RunID)
+	saved, err := s.DB.LatestArtifact(art.Project, art.Branch, art.Name, file.OwnerID)
+	if err != nil {
+		saved = art
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// artifactOwner limits artifact lookups to the caller's uploads unless they
+// are an admin.
+func artifactOwner(r *http.Request) string {
+	if p := principalFrom(r); !p.Admin {
+		return p.ID
+	}
+	return ""
+}
+
+func (s *Server) handleListArtifacts(w http.ResponseWriter, r *http.Request) {
+	artifacts, err := s.DB.ListArtifacts(r.URL.Query().Get("project"), r.URL.Query().Get("branch"), artifactOwner(r))
+	if err != nil {
+		log.Printf("[SRV ERR] ListArtifacts failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(artifacts)
+}
+
+// latestArtifact resolves ?project=&branch=[&name=] to the newest artifact.
+func (s *Server) latestArtifact(w http.ResponseWriter, r *http.Request) *database.Artifact {
+	q := r.URL.Query()
+	if q.Get("project") == "" || q.Get("branch") == "" {
+		http.Error(w, "project and branch are required", http.StatusBadRequest)
+		return nil
+	}
+	art, err := s.DB.LatestArtifact(q.Get("project"), q.Get("branch"), q.Get("name"), artifactOwner(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "No artifact for that branch", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Artifact lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil
+	}
+	return art
+}
+
+func (s *Server) handleLatestArtifact(w http.ResponseWriter, r *http.Request) {
+	art := s.latestArtifact(w, r)
+	if art == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(art)
+}
+
+func (s *Server) handleDownloadLatestArtifact(w http.ResponseWriter, r *http.Request) {
+	art := s.latestArtifact(w, r)
+	if art == nil {
+		return
+	}
+	file, err := s.DB.GetFile(art.FileID)
+	if err != nil {
+		http.Error(w, "Object not found", http.StatusNotFound)
+		return
+	}
+	p := principalFrom(r)
+	if !p.Admin && s.Bot.TransferCapReached(p.ID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return
+	}
+	w.Header().Set("X-Artifact-Commit", art.Commit)
+	w.Header().Set("X-Artifact-Run", art.RunID)
+	s.recordTransfer(p, 0, s.streamFile(w, r, file))
+}
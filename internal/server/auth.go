@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"discordvault/internal/database"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Principal identifies the caller of an API request.
+type Principal struct {
+	ID    string
+	Admin bool
+	KeyID string // fingerprint of the API_KEYS key used, or the ID of a managed key
+	Scope string // scope of a managed key, "" otherwise
+
+	SessionID string // web session of the request, "" for API keys
+}
+
+type principalKey struct{}
+
+// authenticate resolves the caller from the X-API-Key header (or an
+// Authorization: Bearer header, or the api_key query parameter for plain
+// download links), either a key from API_KEYS or one created through
+// /api/keys, or else from the session cookie of a web login. When no
+// API_KEYS are configured the dashboard keeps its legacy single-operator
+// behaviour with full access for callers without a key or session. Read
+// keys may only make GET requests, and keys needsLogin refuses only work at
+// /api/login.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && key == "" {
+			key = bearer
+		}
+		if key == "" {
+			key = r.URL.Query().Get("api_key")
+		}
+		p, ok := s.keyPrincipal(key)
+		if ok && s.needsLogin(p) {
+			log.Printf("[SRV WARN] Rejected request to %s from %s: %s has two-factor authentication on", r.URL.Path, r.RemoteAddr, p.ID)
+			http.Error(w, "Two-factor authentication is on: log in at /api/login, or use a read or write key from /api/keys", http.StatusUnauthorized)
+			return
+		}
+		if !ok && key == "" {
+			p, ok = s.sessionPrincipal(r)
+		}
+		if !ok && len(s.Config.Live().APIKeys) > 0 {
+			log.Printf("[SRV WARN] Rejected request to %s from %s: invalid API key", r.URL.Path, r.RemoteAddr)
+			http.Error(w, "Access denied", http.StatusUnauthorized)
+			return
+		} else if !ok {
+			p = Principal{ID: "web", Admin: true}
+		}
+		if p.Scope == database.ScopeRead && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Read-only API key", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	})
+}
+
+// needsLogin reports whether a key may only be used to log in, with a
+// second factor, because its owner turned two-factor authentication on.
+// That holds for the owner's keys from API_KEYS and admin keys, so a stolen
+// key does not get past the second factor; read and write keys from
+// /api/keys keep working for scripts.
+func (s *Server) needsLogin(p Principal) bool {
+	if p.Scope != "" && p.Scope != database.ScopeAdmin {
+		return false
+	}
+	t, err := s.DB.GetTOTP(p.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("[SRV ERR] GetTOTP failed: %v", err)
+		return true
+	}
+	return t != nil && t.Enabled
+}
+
+// keyFingerprint identifies an API key in stored records without keeping the
+// key itself.
+func keyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+func principalFrom(r *http.Request) Principal {
+	p, _ := r.Context().Value(principalKey{}).(Principal)
+	return p
+}
+
+func (p Principal) canManage(file *database.FileMetadata) bool {
+	return p.Admin || file.OwnerID == p.ID
+}
+
+// requireAdmin rejects callers without the admin override.
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !principalFrom(r).Admin {
+			http.Error(w, "Admin access required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"database/sql"
+	"discordvault/internal/database"
+	"discordvault/internal/jobs"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type backupJobRequest struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Source   string `json:"source"`
+	FolderID int    `json:"folder_id"`
+	Keep     int    `json:"keep"`
+	Enabled  *bool  `json:"enabled"`
+}
+
+// backupJob validates the request and turns it into a BackupJob due at its
+// next scheduled time.
+func (s *Server) backupJob(req *backupJobRequest) (*database.BackupJob, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if strings.ContainsAny(req.Name, `/\`) {
+		return nil, errors.New("name must not contain slashes")
+	}
+	next, err := s.Backups.NextRun(req.Schedule, time.Now())
+	if err != nil {
+		return nil, errors.New("invalid schedule: " + err.Error())
+	}
+	if err := s.Backups.CheckSource(req.Source); err != nil {
+		return nil, errors.New("invalid source: " + err.Error())
+	}
+	if req.Keep < 0 {
+		return nil, errors.New("keep must not be negative")
+	}
+	if req.FolderID != 0 {
+		if _, err := s.DB.GetFolder(req.FolderID); err != nil {
+			return nil, database.ErrFolderNotFound
+		}
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+	return &database.BackupJob{Name: req.Name, Schedule: req.Schedule, Source: req.Source, FolderID: req.FolderID, Keep: req.Keep, Enabled: enabled, NextRunAt: next}, nil
+}
+
+func (s *Server) handleListBackupJobs(w http.ResponseWriter, r *http.Request) {
+	list, err := s.DB.ListBackupJobs()
+	if err != nil {
+		log.Printf("[SRV ERR] ListBackupJobs failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	last, err := s.DB.LastBackupRuns()
+	if err != nil {
+		log.Printf("[SRV ERR] LastBackupRuns failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	for i := range list {
+		list[i].LastRun = last[list[i].ID]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func (s *Server) handleGetBackupJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	s.writeBackupJob(w, id, http.StatusOK)
+}
+
+func (s *Server) handleCreateBackupJob(w http.ResponseWriter, r *http.Request) {
+	var req backupJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	job, err := s.backupJob(&req)
+	if err != nil {
+		http.Error(w, "Invalid job: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	job.CreatedBy = principalFrom(r).ID
+
+	id, err := s.DB.CreateBackupJob(job)
+	if errors.Is(err, database.ErrJobNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("[SRV ERR] Backup job creation failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	s.writeBackupJob(w, id, http.StatusCreated)
+}
+
+func (s *Server) handleUpdateBackupJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req backupJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	job, err := s.backupJob(&req)
+	if err != nil {
+		http.Error(w, "Invalid job: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	job.ID = id
+
+	if err := s.DB.UpdateBackupJob(job); errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Backup job not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, database.ErrJobNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("[SRV ERR] Backup job update failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	s.writeBackupJob(w, id, http.StatusOK)
+}
+
+func (s *Server) handleDeleteBackupJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := s.DB.DeleteBackupJob(id); errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Backup job not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("[SRV ERR] Backup job delete failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRunBackupJob starts a job right away instead of waiting for its
+// schedule. The run continues in the background; its outcome shows up in
+// the job's run history.
+func (s *Server) handleRunBackupJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	job, err := s.DB.GetBackupJob(id)
+	if err != nil {
+		http.Error(w, "Backup job not found", http.StatusNotFound)
+		return
+	}
+	runID, err := s.Backups.Start(job)
+	if errors.Is(err, jobs.ErrJobRunning) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("[SRV ERR] Backup job %q could not start: %v", job.Name, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]int{"run_id": runID})
+}
+
+func (s *Server) handleListBackupRuns(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.DB.GetBackupJob(id); err != nil {
+		http.Error(w, "Backup job not found", http.StatusNotFound)
+		return
+	}
+	runs, err := s.DB.ListBackupRuns(id)
+	if err != nil {
+		log.Printf("[SRV ERR] ListBackupRuns failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+func (s *Server) writeBackupJob(w http.ResponseWriter, id int, status int) {
+	job, err := s.DB.GetBackupJob(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Backup job not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if runs, err := s.DB.ListBackupRuns(id); err == nil && len(runs) > 0 {
+		job.LastRun = &runs[0]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"discordvault/internal/database"
+	"discordvault/internal/jobs"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const maxBatchOps = 500
+
+type batchOperation struct {
+	Op         string   `json:"op"`
+	FileID     int      `json:"file_id"`
+	Name       string   `json:"name,omitempty"`
+	FolderID   int      `json:"folder_id,omitempty"`
+	AddTags    []string `json:"add_tags,omitempty"`
+	RemoveTags []string `json:"remove_tags,omitempty"`
+	RetainDays int      `json:"retain_days,omitempty"`
+}
+
+type batchRequest struct {
+	Operations []batchOperation `json:"operations"`
+}
+
+type batchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	FileID int    `json:"file_id"`
+	Status string `json:"status"` // ok, failed, rolled_back
+	Error  string `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Applied bool          `json:"applied"`
+	Results []batchResult `json:"results"`
+}
+
+// handleBatch applies rename/move/tag/delete/retain operations in one
+// transaction.
+// If any operation fails nothing is changed and the response marks the
+// failing operation; every other one is reported as rolled back.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOps {
+		http.Error(w, "Batch must contain between 1 and 500 operations", http.StatusBadRequest)
+		return
+	}
+
+	p := principalFrom(r)
+	ops := make([]database.BatchOp, len(req.Operations))
+	deleted := make(map[int]*database.FileMetadata)
+	failed, failure := -1, error(nil)
+
+	for i, o := range req.Operations {
+		file, err := s.DB.GetFile(o.FileID)
+		if err != nil || !p.canManage(file) {
+			failed, failure = i, errors.New("file not found")
+			break
+		}
+		op := database.BatchOp{Op: o.Op, FileID: o.FileID, Name: o.Name, FolderID: o.FolderID}
+		if o.RetainDays > 0 {
+			op.ExpiresAt = time.Now().AddDate(0, 0, o.RetainDays)
+		}
+		for _, tag := range o.AddTags {
+			if tag = database.NormalizeTag(tag); tag != "" {
+				op.AddTags = append(op.AddTags, tag)
+			}
+		}
+		for _, tag := range o.RemoveTags {
+			if tag = database.NormalizeTag(tag); tag != "" {
+				op.RemoveTags = append(op.RemoveTags, tag)
+			}
+		}
+		if o.Op == database.BatchMove && o.FolderID != 0 {
+			folder, err := s.DB.GetFolder(o.FolderID)
+			if err != nil || (!p.Admin && folder.OwnerID != p.ID) {
+				failed, failure = i, database.ErrFolderNotFound
+				break
+			}
+		}
+		if o.Op == database.BatchDelete {
+			deleted[file.ID] = file
+			if s.Config.PurgeGrace > 0 {
+				op.PurgedBy = p.ID
+			}
+		}
+		ops[i] = op
+	}
+
+	if failure == nil {
+		err := s.DB.ApplyBatch(ops)
+		var batchErr *database.BatchError
+		switch {
+		case errors.As(err, &batchErr):
+			failed, failure = batchErr.Index, batchErr.Err
+		case err != nil:
+			log.Printf("[SRV ERR] Batch failed: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp := batchResponse{Applied: failure == nil}
+	for i, o := range req.Operations {
+		res := batchResult{Index: i, Op: o.Op, FileID: o.FileID, Status: "ok"}
+		switch {
+		case i == failed:
+			res.Status, res.Error = "failed", failure.Error()
+		case failure != nil:
+			res.Status = "rolled_back"
+		}
+		resp.Results = append(resp.Results, res)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if failure != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	} else {
+		log.Printf("[SERVER] Batch of %d operations applied", len(ops))
+		for _, file := range deleted {
+			go s.Bot.NotifyDelete(file, "Web", p.ID)
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+type bulkRequest struct {
+	Action     string   `json:"action"`
+	FileIDs    []int    `json:"file_ids"`
+	FolderID   int      `json:"folder_id,omitempty"`
+	AddTags    []string `json:"add_tags,omitempty"`
+	RemoveTags []string `json:"remove_tags,omitempty"`
+	RetainDays int      `json:"retain_days,omitempty"`
+}
+
+// handleStartBulk queues one action for many files as a background job.
+// Unlike a batch, files are changed one at a time: a file that fails is
+// listed in the job's failures and the others still change.
+func (s *Server) handleStartBulk(w http.ResponseWriter, r *http.Request) {
+	var req bulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.FileIDs) == 0 || len(req.FileIDs) > jobs.MaxBulkFiles {
+		http.Error(w, fmt.Sprintf("file_ids must list between 1 and %d files", jobs.MaxBulkFiles), http.StatusBadRequest)
+		return
+	}
+
+	p := principalFrom(r)
+	bulk := jobs.BulkRequest{Action: req.Action, FileIDs: req.FileIDs, Owner: p.ID, Admin: p.Admin}
+	switch req.Action {
+	case jobs.BulkMove:
+		if req.FolderID != 0 {
+			folder, err := s.DB.GetFolder(req.FolderID)
+			if err != nil || (!p.Admin && folder.OwnerID != p.ID) {
+				http.Error(w, "Folder not found", http.StatusNotFound)
+				return
+			}
+		}
+		bulk.FolderID = req.FolderID
+	case jobs.BulkTag:
+		for _, tag := range req.AddTags {
+			if tag = database.NormalizeTag(tag); tag != "" {
+				bulk.AddTags = append(bulk.AddTags, tag)
+			}
+		}
+		for _, tag := range req.RemoveTags {
+			if tag = database.NormalizeTag(tag); tag != "" {
+				bulk.RemoveTags = append(bulk.RemoveTags, tag)
+			}
+		}
+		if len(bulk.AddTags) == 0 && len(bulk.RemoveTags) == 0 {
+			http.Error(w, "add_tags or remove_tags is required", http.StatusBadRequest)
+			return
+		}
+	case jobs.BulkDelete:
+	case jobs.BulkRetain:
+		if req.RetainDays < 0 {
+			http.Error(w, "retain_days must not be negative", http.StatusBadRequest)
+			return
+		}
+		if req.RetainDays > 0 {
+			bulk.ExpiresAt = time.Now().AddDate(0, 0, req.RetainDays)
+		}
+	default:
+		http.Error(w, "action must be move, tag, delete, or retain", http.StatusBadRequest)
+		return
+	}
+
+	id := s.Bulk.Start(bulk)
+	log.Printf("[SERVER] Bulk %s %s queued for %d files", req.Action, id, len(req.FileIDs))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]any{"id": id, "files": len(req.FileIDs)})
+}
+
+func (s *Server) handleBulkStatus(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.Bulk.Status(mux.Vars(r)["id"])
+	if p := principalFrom(r); !ok || (!p.Admin && status.Owner != p.ID) {
+		http.Error(w, "Bulk job not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
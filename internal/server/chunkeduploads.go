@@ -0,0 +1,298 @@
+package server
+
+import (
+	"crypto/sha256"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// Chunked uploads: the client announces a file with POST /api/uploads,
+// sends each chunk of it with PUT /api/uploads/{id}/chunks/{n}, in any order
+// and in parallel, and saves the file with POST /api/uploads/{id}/complete.
+// A failed chunk is sent again on its own. Chunks extend the file's SHA-256
+// as they arrive in order; ones that come before their predecessor are read
+// back once when the upload completes.
+
+type chunkedUploadResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk_size"`
+	Chunks    int    `json:"chunks"`
+	Received  []int  `json:"received"` // numbers of the chunks stored so far
+}
+
+func (s *Server) handleCreateChunkedUpload(w http.ResponseWriter, r *http.Request) {
+	session := s.createUploadSession(w, r, true)
+	if session == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(chunkedResponse(session, nil))
+}
+
+func (s *Server) handleGetChunkedUpload(w http.ResponseWriter, r *http.Request) {
+	session := s.uploadSession(w, r, true)
+	if session == nil {
+		return
+	}
+	stored, err := s.DB.UploadSessionChunks(session.ID)
+	if err != nil {
+		log.Printf("[SRV ERR] Upload session lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chunkedResponse(session, stored))
+}
+
+// handlePutUploadChunk stores chunk n of a chunked upload. The body must be
+// exactly the chunk: chunk_size bytes, or the rest of the file for the last
+// one. Sending a stored chunk again succeeds without storing it twice.
+func (s *Server) handlePutUploadChunk(w http.ResponseWriter, r *http.Request) {
+	session := s.uploadSession(w, r, true)
+	if session == nil {
+		return
+	}
+	p := principalFrom(r)
+	if !p.Admin && s.Bot.TransferCapReached(p.ID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return
+	}
+	count := chunkCount(session)
+	n, err := strconv.Atoi(mux.Vars(r)["n"])
+	if err != nil || n < 0 || n >= count {
+		http.Error(w, fmt.Sprintf("Chunk number must be between 0 and %d", count-1), http.StatusBadRequest)
+		return
+	}
+	size := min(session.ChunkSize, session.Size-int64(n)*session.ChunkSize)
+	if r.ContentLength >= 0 && r.ContentLength != size {
+		http.Error(w, fmt.Sprintf("Chunk %d must be %d bytes", n, size), http.StatusBadRequest)
+		return
+	}
+
+	key := session.ID + "/" + strconv.Itoa(n)
+	if _, busy := s.sessions.LoadOrStore(key, struct{}{}); busy {
+		http.Error(w, "Chunk is already being received", http.StatusConflict)
+		return
+	}
+	defer s.sessions.Delete(key)
+
+	stored, err := s.DB.UploadSessionChunks(session.ID)
+	if err != nil {
+		log.Printf("[SRV ERR] Upload session lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if storedChunk(stored, n) != nil {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(chunkedResponse(session, stored))
+		return
+	}
+
+	// A chunk is stored on its own, so without parity.
+	pool := s.Bot.NewChunkPool(s.Config.ChannelID)
+	pool.SkipParity()
+	pool.ShardBy(session.Name)
+	// Only allocated once the memory budget has room for it
+	var plain []byte
+	err = pool.Reserve(r.Context())
+	if err == nil {
+		plain = make([]byte, size)
+		if _, err = io.ReadFull(r.Body, plain); err != nil {
+			err = fmt.Errorf("body is shorter than %d bytes: %w", size, err)
+		} else if extra, _ := r.Body.Read(make([]byte, 1)); extra > 0 {
+			err = fmt.Errorf("body is longer than %d bytes", size)
+		}
+		if err != nil {
+			pool.Wait()
+			http.Error(w, fmt.Sprintf("Chunk %d %v", n, err), http.StatusBadRequest)
+			return
+		}
+	}
+	var encrypted []byte
+	if err == nil {
+		encrypted, err = crypto.Encrypt(plain, s.Config.EncryptionKey)
+	}
+	if err == nil {
+		err = s.Bot.Health.Wait(r.Context())
+	}
+	if err == nil {
+		err = pool.Submit(r.Context(), encrypted, nil)
+	}
+	chunks, waitErr := pool.Wait()
+	if err == nil {
+		err = waitErr
+	}
+	if err == nil && len(chunks) != 1 {
+		err = fmt.Errorf("%d chunks stored instead of 1", len(chunks))
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Upload session %s chunk %d failed: %v", session.ID, n, err)
+		if r.Context().Err() == nil {
+			http.Error(w, "Chunk could not be stored; send it again", http.StatusServiceUnavailable)
+		}
+		return
+	}
+	chunk := chunks[0]
+	chunk.PartNum = n + 1
+
+	if err := s.recordUploadChunk(session, chunk, plain); err != nil {
+		log.Printf("[SRV ERR] Upload session %s could not record chunk %d: %v", session.ID, n, err)
+		go s.discardChunks([]database.ChunkMetadata{chunk})
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	s.recordTransfer(p, size, 0)
+
+	stored, err = s.DB.UploadSessionChunks(session.ID)
+	if err != nil {
+		log.Printf("[SRV ERR] Upload session lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chunkedResponse(session, stored))
+}
+
+// recordUploadChunk records a stored chunk with the hash state of the file
+// up to its end, if the chunk before it has one. The session's hash lock
+// keeps a chunk and its successor from both missing the other's state.
+func (s *Server) recordUploadChunk(session *database.UploadSession, chunk database.ChunkMetadata, plain []byte) error {
+	mu, _ := s.hashLocks.LoadOrStore(session.ID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+
+	stored, err := s.DB.UploadSessionChunks(session.ID)
+	if err != nil {
+		return err
+	}
+	var state []byte
+	hasher := sha256.New()
+	prev := storedChunk(stored, chunk.PartNum-2)
+	if chunk.PartNum == 1 || (prev != nil && len(prev.HashState) > 0) {
+		if prev != nil {
+			if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(prev.HashState); err != nil {
+				return err
+			}
+		}
+		hasher.Write(plain)
+		if state, err = hasher.(encoding.BinaryMarshaler).MarshalBinary(); err != nil {
+			return err
+		}
+	}
+	return s.DB.AddUploadSessionChunk(session.ID, database.SessionChunk{ChunkMetadata: chunk, PlainSize: int64(len(plain)), HashState: state})
+}
+
+// handleCompleteChunkedUpload saves the file once every chunk is stored.
+func (s *Server) handleCompleteChunkedUpload(w http.ResponseWriter, r *http.Request) {
+	session := s.uploadSession(w, r, true)
+	if session == nil {
+		return
+	}
+	if _, busy := s.sessions.LoadOrStore(session.ID, struct{}{}); busy {
+		http.Error(w, "Upload is already being completed", http.StatusConflict)
+		return
+	}
+	defer s.sessions.Delete(session.ID)
+
+	stored, err := s.DB.UploadSessionChunks(session.ID)
+	if err != nil {
+		log.Printf("[SRV ERR] Upload session lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	var missing []string
+	for n := range chunkCount(session) {
+		if storedChunk(stored, n) == nil {
+			missing = append(missing, strconv.Itoa(n))
+		}
+	}
+	if len(missing) > 0 {
+		http.Error(w, "Chunks missing: "+strings.Join(missing, ", "), http.StatusConflict)
+		return
+	}
+
+	// Continue the hash from the last chunk that arrived in order.
+	hashed := 0
+	for hashed < len(stored) && len(stored[hashed].HashState) > 0 {
+		hashed++
+	}
+	hasher := sha256.New()
+	if hashed > 0 {
+		if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(stored[hashed-1].HashState); err != nil {
+			log.Printf("[SRV ERR] Upload session %s has a corrupt hash state: %v", session.ID, err)
+			http.Error(w, "Upload session is corrupt", http.StatusInternalServerError)
+			return
+		}
+	}
+	if hashed < len(stored) {
+		rest := stored[hashed:]
+		written, err := s.Bot.WriteChunks(r.Context(), hasher, sessionChunks(rest))
+		if err == nil && written != sessionOffset(rest) {
+			err = fmt.Errorf("read %d of %d bytes", written, sessionOffset(rest))
+		}
+		if err != nil {
+			log.Printf("[SRV ERR] Upload session %s could not read back its chunks: %v", session.ID, err)
+			if r.Context().Err() == nil {
+				http.Error(w, "Stored chunks could not be read back; try again", http.StatusServiceUnavailable)
+			}
+			return
+		}
+	}
+
+	s.saveUploadSession(w, session, stored, hasher.Sum(nil))
+	s.hashLocks.Delete(session.ID)
+}
+
+func (s *Server) handleDeleteChunkedUpload(w http.ResponseWriter, r *http.Request) {
+	session := s.uploadSession(w, r, true)
+	if session == nil {
+		return
+	}
+	s.abandonUploadSession(w, session)
+	s.hashLocks.Delete(session.ID)
+}
+
+func chunkedResponse(session *database.UploadSession, stored []database.SessionChunk) chunkedUploadResponse {
+	received := make([]int, len(stored))
+	for i, c := range stored {
+		received[i] = c.PartNum - 1
+	}
+	return chunkedUploadResponse{
+		ID:        session.ID,
+		Name:      session.Name,
+		Size:      session.Size,
+		ChunkSize: session.ChunkSize,
+		Chunks:    chunkCount(session),
+		Received:  received,
+	}
+}
+
+// chunkCount is the number of chunks the session's file is split into.
+func chunkCount(session *database.UploadSession) int {
+	return int((session.Size + session.ChunkSize - 1) / session.ChunkSize)
+}
+
+// storedChunk returns chunk n, counted from 0, if the session stored it.
+func storedChunk(stored []database.SessionChunk, n int) *database.SessionChunk {
+	for i := range stored {
+		if stored[i].PartNum == n+1 {
+			return &stored[i]
+		}
+	}
+	return nil
+}
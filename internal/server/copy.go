@@ -0,0 +1,187 @@
+package server
+
+import (
+	"discordvault/internal/config"
+	"discordvault/internal/database"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// copyRequest names a file on another DiscordVault instance to pull into this
+// one.
+type copyRequest struct {
+	Source      string `json:"source"`  // base URL of the remote vault
+	APIKey      string `json:"api_key"` // key for the remote vault, never stored
+	FileID      int    `json:"file_id"`
+	Name        string `json:"name"` // optional, defaults to the remote name
+	OnDuplicate string `json:"on_duplicate"`
+}
+
+// handleCopy streams a file from a remote vault into this one. The plaintext
+// only passes through this server: it is decrypted by the remote, re-encrypted
+// with the local key, and checked against the remote hash before it is saved.
+func (s *Server) handleCopy(w http.ResponseWriter, r *http.Request) {
+	var req copyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	source, err := url.Parse(strings.TrimSuffix(req.Source, "/"))
+	if err != nil || (source.Scheme != "http" && source.Scheme != "https") || source.Host == "" {
+		http.Error(w, "source must be an http(s) URL", http.StatusBadRequest)
+		return
+	}
+	if req.FileID <= 0 {
+		http.Error(w, "file_id is required", http.StatusBadRequest)
+		return
+	}
+	policy := s.Config.DuplicatePolicy
+	if req.OnDuplicate != "" {
+		if !config.ValidDuplicatePolicy(req.OnDuplicate) {
+			http.Error(w, "on_duplicate must be suffix, version, or reject", http.StatusBadRequest)
+			return
+		}
+		policy = req.OnDuplicate
+	}
+	p := principalFrom(r)
+	if !p.Admin && s.Bot.TransferCapReached(p.ID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return
+	}
+
+	remote, err := remoteFile(r, source, req.APIKey, req.FileID)
+	if err != nil {
+		log.Printf("[SRV ERR] Copy from %s: %v", source.Host, err)
+		http.Error(w, "Source vault request failed", http.StatusBadGateway)
+		return
+	}
+	name := remote.Name
+	if req.Name != "" {
+		name = req.Name
+	}
+
+	resp, err := remoteGet(r, source, req.APIKey, fmt.Sprintf("/api/download/%d", req.FileID))
+	if err != nil {
+		log.Printf("[SRV ERR] Copy from %s: %v", source.Host, err)
+		http.Error(w, "Source vault request failed", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	log.Printf("[SERVER] Copying %s (#%d) from %s", remote.Name, remote.ID, source.Host)
+	file := s.storeUpload(w, r, uploadSource{
+		name:   name,
+		body:   resp.Body,
+		policy: policy,
+		origin: "Copy",
+		check: func(size int64, hash string) error {
+			if size != remote.Size || (remote.Hash != "" && hash != remote.Hash) {
+				return errors.New("Copied data does not match the source file")
+			}
+			return nil
+		},
+	}, nil)
+	if file == nil {
+		return
+	}
+
+	log.Printf("[SERVER] Copied %s from %s as #%d", remote.Name, source.Host, file.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(file)
+}
+
+// copyClient fetches from remote vaults. It only connects to public
+// addresses, after every redirect too, so a copy cannot reach this host,
+// its private network, or a cloud metadata service.
+var copyClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 30 * time.Second, Control: publicAddressOnly}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("redirect to %s URL refused", req.URL.Scheme)
+		}
+		// The remote key is only for the vault it was given for
+		if req.URL.Host != via[0].URL.Host {
+			req.Header.Del("X-API-Key")
+		}
+		return nil
+	},
+}
+
+// cgnat is the shared address space of RFC 6598, private in all but name.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddressOnly refuses connections to addresses that are not public
+// unicast ones. It runs on the resolved address, so a public host name
+// pointing inside the network is refused as well.
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || cgnat.Contains(ip) {
+		return fmt.Errorf("connection to non-public address %s refused", ip)
+	}
+	return nil
+}
+
+// remoteFile looks up a file's metadata on the remote vault through its
+// versions listing, the only endpoint that returns a single file's record.
+func remoteFile(r *http.Request, source *url.URL, apiKey string, id int) (*database.FileMetadata, error) {
+	resp, err := remoteGet(r, source, apiKey, fmt.Sprintf("/api/files/%d/versions", id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var versions []database.FileMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, fmt.Errorf("unreadable file listing: %w", err)
+	}
+	for _, v := range versions {
+		if v.ID == id {
+			return &v, nil
+		}
+	}
+	return nil, fmt.Errorf("file %d not found", id)
+}
+
+// remoteGet sends an authenticated GET to the remote vault, tied to the
+// lifetime of the incoming request.
+func remoteGet(r *http.Request, source *url.URL, apiKey, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, source.String()+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	resp, err := copyClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned %s", path, resp.Status)
+	}
+	return resp, nil
+}
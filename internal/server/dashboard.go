@@ -0,0 +1,178 @@
+package server
+
+import (
+	"discordvault/internal/database"
+	"discordvault/internal/jobs"
+	"discordvault/internal/logstream"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// The admin dashboard endpoints each return one aggregated view of every
+// vault, so a dashboard can load and refresh them independently.
+
+// queryInt returns the integer parameter name, or def if it is missing or
+// outside 1 to limit.
+func queryInt(r *http.Request, name string, def, limit int) int {
+	n, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil || n <= 0 || n > limit {
+		return def
+	}
+	return n
+}
+
+func writeDashboard(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// handleDashboardGrowth reports the vault size at the end of each of the
+// last ?days= (default 30) days, with today's row brought up to date.
+func (s *Server) handleDashboardGrowth(w http.ResponseWriter, r *http.Request) {
+	days := queryInt(r, "days", 30, 365)
+	if err := s.DB.RecordStorageSnapshot(); err != nil {
+		log.Printf("[SRV ERR] Storage snapshot failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	growth, err := s.DB.StorageGrowth(days)
+	if err != nil {
+		log.Printf("[SRV ERR] Storage growth lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeDashboard(w, growth)
+}
+
+type dashboardUser struct {
+	UserID     string `json:"user_id"`
+	Files      int    `json:"files"`
+	Bytes      int64  `json:"bytes"`
+	Uploaded   int64  `json:"uploaded"`
+	Downloaded int64  `json:"downloaded"`
+}
+
+type dashboardUsers struct {
+	Period string          `json:"period"`
+	Cap    int64           `json:"cap,omitempty"`
+	Users  []dashboardUser `json:"users"`
+}
+
+// handleDashboardUsers reports, per user, the stored versions and bytes and
+// the transfer of ?period=YYYY-MM (default: this month), largest storage
+// first.
+func (s *Server) handleDashboardUsers(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = database.UsagePeriod(time.Now())
+	} else if _, err := time.Parse("2006-01", period); err != nil {
+		http.Error(w, "period must look like 2024-05", http.StatusBadRequest)
+		return
+	}
+
+	owners, err := s.DB.StorageByOwner()
+	var transfer []database.TransferUsage
+	if err == nil {
+		transfer, err = s.DB.ListTransfer(period)
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] User usage lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	users := []dashboardUser{}
+	index := make(map[string]int)
+	for _, o := range owners {
+		index[o.OwnerID] = len(users)
+		users = append(users, dashboardUser{UserID: o.OwnerID, Files: o.Files, Bytes: o.Bytes})
+	}
+	for _, t := range transfer {
+		if t.SubjectType != database.UsageUser {
+			continue
+		}
+		i, ok := index[t.SubjectID]
+		if !ok {
+			i = len(users)
+			index[t.SubjectID] = i
+			users = append(users, dashboardUser{UserID: t.SubjectID})
+		}
+		users[i].Uploaded, users[i].Downloaded = t.Uploaded, t.Downloaded
+	}
+	writeDashboard(w, dashboardUsers{Period: period, Cap: s.Config.Live().TransferCap, Users: users})
+}
+
+// handleDashboardActivity reports the latest ?limit= (default 50) uploads,
+// deletions, and verification failures.
+func (s *Server) handleDashboardActivity(w http.ResponseWriter, r *http.Request) {
+	activity, err := s.DB.RecentActivity(queryInt(r, "limit", 50, 500))
+	if err != nil {
+		log.Printf("[SRV ERR] Activity lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeDashboard(w, activity)
+}
+
+type dashboardFailures struct {
+	Verification []database.VaultEvent `json:"verification"`
+	Errors       []logstream.Entry     `json:"errors"` // empty when log streaming is disabled
+}
+
+// handleDashboardFailures reports the latest ?limit= (default 50) failed
+// verifications and the error entries still in the log buffer, newest
+// first.
+func (s *Server) handleDashboardFailures(w http.ResponseWriter, r *http.Request) {
+	limit := queryInt(r, "limit", 50, 500)
+	failed, err := s.DB.RecentEvents(database.EventVerifyFailed, limit)
+	if err != nil {
+		log.Printf("[SRV ERR] Failure lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	resp := dashboardFailures{Verification: failed, Errors: []logstream.Entry{}}
+	if s.Logs != nil {
+		filter := logstream.Filter{MinLevel: logstream.LevelError}
+		for _, e := range slices.Backward(s.Logs.Recent()) {
+			if len(resp.Errors) == limit {
+				break
+			}
+			if filter.Match(e) {
+				resp.Errors = append(resp.Errors, e)
+			}
+		}
+	}
+	writeDashboard(w, resp)
+}
+
+type dashboardJobs struct {
+	Paused bool             `json:"paused"`
+	Reason string           `json:"reason,omitempty"`
+	Jobs   []jobs.JobStatus `json:"jobs"`
+}
+
+// handleDashboardJobs reports the background jobs and whether a Discord
+// outage pauses them.
+func (s *Server) handleDashboardJobs(w http.ResponseWriter, r *http.Request) {
+	resp := dashboardJobs{Jobs: s.Scheduler.Status()}
+	if s.Bot.Health != nil {
+		resp.Paused, resp.Reason = s.Bot.Health.Paused(), s.Bot.Health.Reason()
+	}
+	writeDashboard(w, resp)
+}
+
+// handleDashboardDegraded reports the files whose last scrub found them
+// degraded or corrupt, or that wait for repair.
+func (s *Server) handleDashboardDegraded(w http.ResponseWriter, r *http.Request) {
+	files, err := s.DB.UnhealthyFiles()
+	if err != nil {
+		log.Printf("[SRV ERR] Degraded files lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeDashboard(w, files)
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// handleTokenDownload serves the one-time links the bot's /download command
+// sends for files too large to attach.
+func (s *Server) handleTokenDownload(w http.ResponseWriter, r *http.Request) {
+	// A link refused for the transfer cap still works once the cap resets
+	token, err := s.DB.GetDownloadToken(mux.Vars(r)["token"])
+	if err != nil {
+		http.Error(w, "Link not found or already used", http.StatusNotFound)
+		return
+	}
+	file, err := s.DB.GetFile(token.FileID)
+	if err != nil {
+		http.Error(w, "Object not found", http.StatusNotFound)
+		return
+	}
+
+	p := Principal{ID: token.UserID, Admin: s.Config.IsAdmin(token.UserID)}
+	if !p.Admin && s.Bot.TransferCapReached(p.ID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return
+	}
+	if _, err := s.DB.ConsumeDownloadToken(token.Token); err != nil {
+		http.Error(w, "Link not found or already used", http.StatusNotFound)
+		return
+	}
+
+	s.recordTransfer(p, 0, s.streamFile(w, r, file))
+}
+
+// ExpireDownloadTokens drops used and expired /download links.
+func (s *Server) ExpireDownloadTokens(ctx context.Context) error {
+	n, err := s.DB.ExpireDownloadTokens()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("[SERVER] Expired %d download links", n)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"database/sql"
+	"discordvault/internal/database"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// handleListDuplicates reports groups of files with identical content that
+// still occupy separate chunk messages.
+func (s *Server) handleListDuplicates(w http.ResponseWriter, r *http.Request) {
+	groups, err := s.DB.DuplicateGroups()
+	if err != nil {
+		log.Printf("[SRV ERR] DuplicateGroups failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if groups == nil {
+		groups = []database.DuplicateGroup{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// handleConsolidateDuplicates aliases one group, or every group when no hash
+// is given, onto its oldest file. ?guild= picks the vault of a single group.
+// Freed messages are deleted from Discord by the next compaction run.
+func (s *Server) handleConsolidateDuplicates(w http.ResponseWriter, r *http.Request) {
+	var groups []database.DuplicateGroup
+	if hash := mux.Vars(r)["hash"]; hash != "" {
+		groups = []database.DuplicateGroup{{GuildID: r.URL.Query().Get("guild"), Hash: hash}}
+	} else {
+		var err error
+		if groups, err = s.DB.DuplicateGroups(); err != nil {
+			log.Printf("[SRV ERR] DuplicateGroups failed: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	released := 0
+	for _, g := range groups {
+		n, err := s.DB.ConsolidateDuplicates(g.GuildID, g.Hash)
+		if errors.Is(err, sql.ErrNoRows) && len(groups) == 1 {
+			http.Error(w, "No duplicates with that hash", http.StatusNotFound)
+			return
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("[SRV ERR] Consolidating %s failed: %v", g.Hash, err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		released += n
+	}
+
+	log.Printf("[SERVER] Consolidated %d duplicate groups, %d chunk messages released", len(groups), released)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"groups": len(groups), "released_messages": released})
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// handleExport returns a signed manifest of every file, chunk, and message ID
+// so metadata.db can be rebuilt with `discordvault import-manifest`.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	manifest, err := s.DB.ExportManifest(s.Config.ChannelID)
+	if err != nil {
+		log.Printf("[SRV ERR] Export failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	envelope, err := s.Signer.Seal(manifest)
+	if err != nil {
+		http.Error(w, "Signing failed", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[SERVER] Exported manifest with %d files", len(manifest.Files))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"vault-manifest-%s.json\"", time.Now().UTC().Format("20060102-150405")))
+	json.NewEncoder(w).Encode(envelope)
+}
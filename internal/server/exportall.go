@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"discordvault/internal/jobs"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type exportAllRequest struct {
+	Name string `json:"name"`
+}
+
+// handleStartExportAll writes every file of the vault to a directory below
+// EXPORT_DIR in the background. Posting the name of an earlier export
+// resumes it.
+func (s *Server) handleStartExportAll(w http.ResponseWriter, r *http.Request) {
+	var req exportAllRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The export outlives this request, so it must not inherit its context.
+	id, err := s.Exporter.Start(context.Background(), principalFrom(r).ID, strings.TrimSpace(req.Name))
+	if errors.Is(err, jobs.ErrExportDisabled) {
+		http.Error(w, "Vault export is disabled: "+err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Invalid export: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	status, _ := s.Exporter.Status(id)
+	log.Printf("[SERVER] Vault export %s queued into %s", id, status.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"id": id, "name": status.Name})
+}
+
+func (s *Server) handleExportAllStatus(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.Exporter.Status(mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, "Export job not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"archive/zip"
+	"discordvault/internal/database"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gorilla/mux"
+)
+
+type createFolderRequest struct {
+	Name     string `json:"name"`
+	ParentID int    `json:"parent_id"`
+}
+
+func (s *Server) handleListFolders(w http.ResponseWriter, r *http.Request) {
+	p := principalFrom(r)
+	owner := p.ID
+	if p.Admin {
+		owner = ""
+	}
+	folders, err := s.DB.ListFolders(owner)
+	if err != nil {
+		log.Printf("[SRV ERR] ListFolders failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(folders)
+}
+
+func (s *Server) handleCreateFolder(w http.ResponseWriter, r *http.Request) {
+	var req createFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || strings.Contains(req.Name, "/") {
+		http.Error(w, "Invalid folder name", http.StatusBadRequest)
+		return
+	}
+
+	p := principalFrom(r)
+	if req.ParentID != 0 {
+		parent, err := s.DB.GetFolder(req.ParentID)
+		if err != nil || (!p.Admin && parent.OwnerID != p.ID) {
+			http.Error(w, "Parent folder not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	id, err := s.DB.CreateFolder(req.Name, req.ParentID, p.ID)
+	if err != nil {
+		log.Printf("[SRV ERR] Folder creation failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	folder, err := s.DB.GetFolder(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(folder)
+}
+
+// handleDownloadFolder streams a ZIP of a folder and every folder below it,
+// with their current files. Each file's chunks are fetched and decrypted
+// straight into its archive entry, so no file is held in memory whole.
+// Entries are stored uncompressed; most large files already are
+// compressed. A failure partway ends the response without the ZIP's
+// central directory, so the archive is recognizably incomplete.
+func (s *Server) handleDownloadFolder(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	root, err := s.DB.GetFolder(id)
+	p := principalFrom(r)
+	if err != nil || (!p.Admin && root.OwnerID != p.ID) {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+	if !p.Admin && s.Bot.TransferCapReached(p.ID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return
+	}
+
+	var paths map[int]string
+	var files []database.FileMetadata
+	all, err := s.DB.ListFolders("")
+	if err == nil {
+		paths = folderPaths(root, all)
+		files, err = s.DB.FolderFiles(slices.Collect(maps.Keys(paths)))
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Folder listing failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", contentDisposition(root.Name+".zip"))
+	w.Header().Set("Content-Type", "application/zip")
+	log.Printf("[SERVER] Archiving folder %s (%d files)", root.Name, len(files))
+
+	zw := zip.NewWriter(w)
+	var written int64
+	defer func() { s.recordTransfer(p, 0, written) }()
+	used := make(map[string]bool)
+	for _, folderID := range slices.Sorted(maps.Keys(paths)) {
+		used[paths[folderID]] = true
+		if _, err := zw.CreateHeader(&zip.FileHeader{Name: paths[folderID] + "/", Modified: time.Now()}); err != nil {
+			return
+		}
+	}
+	for i := range files {
+		file := &files[i]
+		if !p.canManage(file) {
+			continue
+		}
+		entry, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     uniqueEntry(used, paths[file.FolderID], archiveName(s.Bot.DownloadName(file))),
+			Method:   zip.Store,
+			Modified: file.CreatedAt,
+		})
+		if err != nil {
+			return
+		}
+		n, err := s.Bot.WriteFile(r.Context(), entry, file)
+		written += n
+		if err == nil && n != file.Size {
+			// WriteFile skips chunks it cannot read
+			err = fmt.Errorf("wrote %d of %d bytes, chunks are missing", n, file.Size)
+		}
+		if err != nil {
+			log.Printf("[SRV ERR] Archive of folder %s stopped at %s: %v", root.Name, file.Name, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("[SRV ERR] Archive of folder %s failed: %v", root.Name, err)
+	}
+}
+
+// folderPaths returns the path of root and of every folder below it, by
+// folder ID, as archive paths starting with root's name. Sibling folders
+// whose names clash get numbered.
+func folderPaths(root *database.Folder, all []database.Folder) map[int]string {
+	paths := map[int]string{root.ID: archiveName(root.Name)}
+	used := map[string]bool{paths[root.ID]: true}
+	queue := []int{root.ID}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, f := range all {
+			if f.ParentID == parent {
+				if _, seen := paths[f.ID]; !seen {
+					paths[f.ID] = uniqueEntry(used, paths[parent], archiveName(f.Name))
+					queue = append(queue, f.ID)
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// archiveName makes name safe as one segment of an archive path, so no
+// entry can climb out of the folder it is extracted to.
+func archiveName(name string) string {
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, name))
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// uniqueEntry returns dir/name, numbered like "name (2).ext" if that path
+// is already in used, and records it.
+func uniqueEntry(used map[string]bool, dir, name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	entry := dir + "/" + name
+	for n := 2; used[entry]; n++ {
+		entry = fmt.Sprintf("%s/%s (%d)%s", dir, base, n, ext)
+	}
+	used[entry] = true
+	return entry
+}
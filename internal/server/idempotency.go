@@ -0,0 +1,103 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+const maxIdempotencyKeyLen = 255
+
+// idempotencyRecorder passes the response through while keeping a copy of it
+// for replay.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+	if rec.status == 0 && status >= 200 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *idempotencyRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(p)
+	return rec.ResponseWriter.Write(p)
+}
+
+// idempotent lets clients retry a mutating request with the same
+// Idempotency-Key header without repeating its effect: the first response is
+// stored for IDEMPOTENCY_TTL and replayed to later requests using that key.
+// Server errors are not stored so the operation can be attempted again.
+func (s *Server) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || s.Config.IdempotencyTTL <= 0 {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			http.Error(w, "Idempotency-Key too long", http.StatusBadRequest)
+			return
+		}
+
+		principal := principalFrom(r).ID
+		fingerprint := r.Method + " " + r.URL.Path
+		prior, err := s.DB.ReserveIdempotencyKey(principal, key, fingerprint, time.Now().Add(-s.Config.IdempotencyTTL))
+		if err != nil {
+			log.Printf("[SRV ERR] Idempotency lookup failed: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if prior != nil {
+			switch {
+			case prior.Fingerprint != fingerprint:
+				http.Error(w, "Idempotency-Key was used for a different request", http.StatusUnprocessableEntity)
+			case prior.Status == 0:
+				http.Error(w, "A request with this Idempotency-Key is still in progress", http.StatusConflict)
+			default:
+				if prior.ContentType != "" {
+					w.Header().Set("Content-Type", prior.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(prior.Status)
+				w.Write(prior.Body)
+			}
+			return
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w}
+		defer func() {
+			if rec.status == 0 || rec.status >= 500 {
+				if err := s.DB.ReleaseIdempotencyKey(principal, key); err != nil {
+					log.Printf("[SRV ERR] Idempotency release failed: %v", err)
+				}
+				return
+			}
+			if err := s.DB.CompleteIdempotencyKey(principal, key, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+				log.Printf("[SRV ERR] Idempotency save failed: %v", err)
+			}
+		}()
+		next(rec, r)
+	}
+}
+
+// ExpireIdempotencyKeys drops stored responses older than IDEMPOTENCY_TTL.
+func (s *Server) ExpireIdempotencyKeys(ctx context.Context) error {
+	n, err := s.DB.ExpireIdempotencyKeys(time.Now().Add(-s.Config.IdempotencyTTL))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("[SERVER] Expired %d idempotency keys", n)
+	}
+	return nil
+}
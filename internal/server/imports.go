@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type importRequest struct {
+	ChannelID string `json:"channel_id"`
+	FolderID  int    `json:"folder_id"`
+}
+
+// handleStartImport copies the attachments posted to a Discord channel into
+// the vault in the background.
+func (s *Server) handleStartImport(w http.ResponseWriter, r *http.Request) {
+	var req importRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.FolderID != 0 {
+		if _, err := s.DB.GetFolder(req.FolderID); err != nil {
+			http.Error(w, "Folder not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	// The import outlives this request, so it must not inherit its context.
+	id, err := s.Importer.Start(context.Background(), strings.TrimSpace(req.ChannelID), req.FolderID)
+	if err != nil {
+		http.Error(w, "Invalid import: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("[SERVER] Channel import %s queued for channel %s", id, req.ChannelID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"id": id})
+}
+
+func (s *Server) handleImportStatus(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.Importer.Status(mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, "Import job not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"database/sql"
+	"discordvault/internal/database"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type policyRequest struct {
+	Name        string `json:"name"`
+	Tag         string `json:"tag"`
+	MaxAgeDays  int    `json:"max_age_days"`
+	MaxVersions int    `json:"max_versions"`
+}
+
+// policy validates the request and turns it into a LifecyclePolicy.
+func (req *policyRequest) policy() (*database.LifecyclePolicy, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if req.MaxAgeDays < 0 || req.MaxVersions < 0 {
+		return nil, errors.New("limits must not be negative")
+	}
+	if req.MaxAgeDays == 0 && req.MaxVersions == 0 {
+		return nil, errors.New("set max_age_days or max_versions")
+	}
+	tag := database.NormalizeTag(req.Tag)
+	if req.Tag != "" && tag == "" {
+		return nil, errors.New("invalid tag")
+	}
+	return &database.LifecyclePolicy{Name: req.Name, Tag: tag, MaxAgeDays: req.MaxAgeDays, MaxVersions: req.MaxVersions}, nil
+}
+
+func (s *Server) handleListPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := s.DB.ListPolicies()
+	if err != nil {
+		log.Printf("[SRV ERR] ListPolicies failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policies)
+}
+
+func (s *Server) handleCreatePolicy(w http.ResponseWriter, r *http.Request) {
+	var req policyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	policy, err := req.policy()
+	if err != nil {
+		http.Error(w, "Invalid policy: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	policy.CreatedBy = principalFrom(r).ID
+
+	id, err := s.DB.CreatePolicy(policy)
+	if err != nil {
+		log.Printf("[SRV ERR] Policy creation failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	s.writePolicy(w, id, http.StatusCreated)
+}
+
+func (s *Server) handleUpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req policyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	policy, err := req.policy()
+	if err != nil {
+		http.Error(w, "Invalid policy: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	policy.ID = id
+
+	if err := s.DB.UpdatePolicy(policy); errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Policy not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("[SRV ERR] Policy update failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	s.writePolicy(w, id, http.StatusOK)
+}
+
+func (s *Server) handleDeletePolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := s.DB.DeletePolicy(id); errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Policy not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("[SRV ERR] Policy delete failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleApplyPolicy runs one policy immediately instead of waiting for the
+// next LIFECYCLE_INTERVAL tick.
+func (s *Server) handleApplyPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	policy, err := s.DB.GetPolicy(id)
+	if err != nil {
+		http.Error(w, "Policy not found", http.StatusNotFound)
+		return
+	}
+	expired, err := s.Lifecycle.Apply(policy)
+	if err != nil {
+		log.Printf("[SRV ERR] Lifecycle policy %q failed: %v", policy.Name, err)
+		http.Error(w, "Policy run failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"expired": expired})
+}
+
+func (s *Server) writePolicy(w http.ResponseWriter, id int, status int) {
+	policy, err := s.DB.GetPolicy(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(policy)
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"discordvault/internal/database"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sessionCookie carries the session of a browser that logged in through
+// /api/login.
+const sessionCookie = "dv_session"
+
+// sessionTouchInterval is how stale a session's last-seen time may get
+// before a request updates it, at most half of SESSION_IDLE_TIMEOUT.
+const sessionTouchInterval = time.Minute
+
+type loginRequest struct {
+	Key  string `json:"key"`
+	Code string `json:"code"`
+}
+
+// keyPrincipal resolves an API key, from API_KEYS or created through
+// /api/keys.
+func (s *Server) keyPrincipal(key string) (Principal, bool) {
+	if p, ok := s.managedKey(key); ok {
+		return p, true
+	}
+	owner, ok := s.Config.Live().APIKeys[key]
+	if !ok {
+		return Principal{}, false
+	}
+	return Principal{ID: owner, Admin: s.Config.IsAdmin(owner), KeyID: keyFingerprint(key)}, true
+}
+
+// sessionPrincipal resolves the session cookie of a request and records
+// that the session is in use.
+func (s *Server) sessionPrincipal(r *http.Request) (Principal, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return Principal{}, false
+	}
+	ws, err := s.DB.WebSessionByHash(hashSessionToken(cookie.Value))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("[SRV ERR] Session lookup failed: %v", err)
+		}
+		return Principal{}, false
+	}
+	idle, age := s.sessionCutoffs()
+	if ws.LastSeenAt.Before(idle) || ws.CreatedAt.Before(age) {
+		return Principal{}, false
+	}
+	p, ok := s.sessionKey(ws)
+	if !ok {
+		return Principal{}, false
+	}
+	touch := sessionTouchInterval
+	if idle := s.Config.SessionIdleTimeout; idle > 0 {
+		touch = min(touch, idle/2)
+	}
+	if err := s.DB.TouchWebSession(ws.ID, time.Now().Add(-touch)); err != nil {
+		log.Printf("[SRV WARN] Recording use of session %s failed: %v", ws.ID, err)
+	}
+	p.SessionID = ws.ID
+	return p, true
+}
+
+// sessionKey resolves the API key a session logged in with again, so the
+// session ends with its key and follows changes to ADMIN_USERS. ok is false
+// once the key is revoked or gone from API_KEYS.
+func (s *Server) sessionKey(ws *database.WebSession) (Principal, bool) {
+	if ws.Scope == "" {
+		for key, owner := range s.Config.Live().APIKeys {
+			if owner == ws.UserID && keyFingerprint(key) == ws.KeyID {
+				return Principal{ID: owner, Admin: s.Config.IsAdmin(owner), KeyID: ws.KeyID}, true
+			}
+		}
+		return Principal{}, false
+	}
+	k, err := s.DB.GetAPIKey(ws.KeyID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("[SRV ERR] API key lookup for session %s failed: %v", ws.ID, err)
+		}
+		return Principal{}, false
+	}
+	if k.RevokedAt != nil || k.OwnerID != ws.UserID {
+		return Principal{}, false
+	}
+	return s.apiKeyPrincipal(k), true
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionCutoffs are the times before which a session counts as idle or
+// too old. A zero cutoff has no limit.
+func (s *Server) sessionCutoffs() (idle, age time.Time) {
+	now := time.Now()
+	if s.Config.SessionIdleTimeout > 0 {
+		idle = now.Add(-s.Config.SessionIdleTimeout)
+	}
+	if s.Config.SessionMaxAge > 0 {
+		age = now.Add(-s.Config.SessionMaxAge)
+	}
+	return idle, age
+}
+
+// handleLogin trades an API key, and a two-factor code once the key's owner
+// enrolled, for a session cookie, so the web UI does not keep the key. The
+// session lasts until SESSION_IDLE_TIMEOUT passes without a request or
+// SESSION_MAX_AGE after login, whichever comes first.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	p, ok := s.keyPrincipal(req.Key)
+	if !ok {
+		log.Printf("[SRV WARN] Rejected login from %s: invalid API key", r.RemoteAddr)
+		http.Error(w, "Access denied", http.StatusUnauthorized)
+		return
+	}
+
+	totp, err := s.DB.GetTOTP(p.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("[SRV ERR] GetTOTP failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if totp != nil && totp.Enabled {
+		if strings.TrimSpace(req.Code) == "" {
+			http.Error(w, "Two-factor code required", http.StatusUnauthorized)
+			return
+		}
+		if !s.secondFactor(w, r, totp, req.Code, http.StatusUnauthorized) {
+			return
+		}
+	}
+
+	idBytes := make([]byte, 16)
+	tokenBytes := make([]byte, 32)
+	rand.Read(idBytes)
+	rand.Read(tokenBytes)
+	token := hex.EncodeToString(tokenBytes)
+	ws := &database.WebSession{
+		ID:         hex.EncodeToString(idBytes),
+		UserID:     p.ID,
+		Admin:      p.Admin,
+		KeyID:      p.KeyID,
+		Scope:      p.Scope,
+		RemoteAddr: r.RemoteAddr,
+		UserAgent:  r.UserAgent(),
+	}
+	if err := s.DB.CreateWebSession(ws, hashSessionToken(token)); err != nil {
+		log.Printf("[SRV ERR] Session creation failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	cookie := &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(s.Config.PublicURL, "https://"),
+		SameSite: http.SameSiteStrictMode,
+	}
+	if s.Config.SessionMaxAge > 0 {
+		cookie.Expires = ws.CreatedAt.Add(s.Config.SessionMaxAge)
+	}
+	http.SetCookie(w, cookie)
+	log.Printf("[SERVER] %s logged in from %s", p.ID, r.RemoteAddr)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"user": p.ID, "admin": p.Admin, "csrf_token": csrfToken(token)})
+}
+
+// handleLogout ends the session of the request, if any.
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if ws, err := s.DB.WebSessionByHash(hashSessionToken(cookie.Value)); err == nil {
+			if err := s.DB.DeleteWebSession(ws.ID); err != nil {
+				log.Printf("[SRV ERR] Logout failed: %v", err)
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	clearSessionCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"discordvault/internal/logstream"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// logKeepAlive is how often an idle log stream sends an SSE comment, so
+// proxies do not close it.
+const logKeepAlive = 30 * time.Second
+
+// handleLogs streams log entries as server-sent events: first the buffered
+// recent entries, then new ones as they are written, until the client
+// disconnects. ?level= sets the minimum level, ?component= a comma-separated
+// list of components.
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if s.Logs == nil {
+		http.Error(w, "Log streaming is disabled", http.StatusNotFound)
+		return
+	}
+	filter := logstream.Filter{
+		MinLevel:   r.URL.Query().Get("level"),
+		Components: splitParam(r.URL.Query().Get("component")),
+	}
+	if filter.MinLevel != "" && !logstream.ValidLevel(filter.MinLevel) {
+		http.Error(w, "level must be info, warn, error, or critical", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	recent, live, unsubscribe := s.Logs.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(e logstream.Entry) error {
+		if !filter.Match(e) {
+			return nil
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		return err
+	}
+
+	for _, e := range recent {
+		if err := send(e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(logKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-live:
+			if err := send(e); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// splitParam splits a comma-separated query parameter, dropping empty items.
+func splitParam(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
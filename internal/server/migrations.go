@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"discordvault/internal/cloud"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type migrateRequest struct {
+	Source      string `json:"source"`
+	Endpoint    string `json:"endpoint"`
+	Region      string `json:"region"`
+	AccessKey   string `json:"access_key"`
+	SecretKey   string `json:"secret_key"`
+	FolderID    int    `json:"folder_id"`
+	Concurrency int    `json:"concurrency"`
+}
+
+// handleStartMigration copies every file of an S3 bucket or a web server's
+// directory listing into the vault in the background.
+func (s *Server) handleStartMigration(w http.ResponseWriter, r *http.Request) {
+	var req migrateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.FolderID != 0 {
+		if _, err := s.DB.GetFolder(req.FolderID); err != nil {
+			http.Error(w, "Folder not found", http.StatusNotFound)
+			return
+		}
+	}
+	src, err := cloud.Open(strings.TrimSpace(req.Source), cloud.S3Options{
+		Endpoint:  req.Endpoint,
+		Region:    req.Region,
+		AccessKey: req.AccessKey,
+		SecretKey: req.SecretKey,
+	}, nil)
+	if err != nil {
+		http.Error(w, "Invalid migration: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The migration outlives this request, so it must not inherit its context.
+	id, err := s.Migrator.Start(context.Background(), src, principalFrom(r).ID, req.FolderID, req.Concurrency)
+	if err != nil {
+		http.Error(w, "Invalid migration: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("[SERVER] Migration %s queued from %s", id, src)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"id": id})
+}
+
+func (s *Server) handleMigrationStatus(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.Migrator.Status(mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, "Migration job not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
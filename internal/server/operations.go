@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"discordvault/internal/database"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// operationRetention is how long a finished operation can still be looked
+// up.
+const operationRetention = 7 * 24 * time.Hour
+
+// operationProgressInterval is how often a running upload records its
+// progress, so a fast upload does not write the database on every chunk.
+const operationProgressInterval = time.Second
+
+// uploadOperation records the progress of an upload in the operations
+// table. It wraps the upload's response to keep the error the client is
+// sent, which becomes the operation's error. Without an ID, when the
+// operation could not be created, nothing is recorded.
+type uploadOperation struct {
+	http.ResponseWriter
+	s      *Server
+	id     string
+	status int
+	body   bytes.Buffer
+	saved  time.Time // when progress was last recorded
+}
+
+// startOperation creates a queued operation for the upload r, under the ID
+// of the request's Operation-Id header if the client chose one, so browsers
+// can poll it while the upload runs. Otherwise the ID is generated and sent
+// in an Operation-Id header right away, in a 103 Early Hints response, for
+// clients that can read it. The final response repeats the header. ok is
+// false when the client's ID is invalid or taken; the error response is
+// then written.
+func (s *Server) startOperation(w http.ResponseWriter, r *http.Request) (op *uploadOperation, ok bool) {
+	op = &uploadOperation{ResponseWriter: w, s: s}
+	id := r.Header.Get("Operation-Id")
+	if id != "" {
+		if !validOperationID(id) {
+			http.Error(w, "Operation-Id must be 16 to 64 letters, digits, '-' or '_'", http.StatusBadRequest)
+			return nil, false
+		}
+		if _, err := s.DB.GetOperation(id); err == nil {
+			http.Error(w, "Operation-Id is already in use", http.StatusConflict)
+			return nil, false
+		}
+	} else {
+		idBytes := make([]byte, 16)
+		if _, err := rand.Read(idBytes); err != nil {
+			log.Printf("[SRV WARN] Upload is not tracked: %v", err)
+			return op, true
+		}
+		id = hex.EncodeToString(idBytes)
+	}
+	record := &database.Operation{
+		ID:            id,
+		Kind:          "upload",
+		Principal:     principalFrom(r).ID,
+		BytesExpected: max(r.ContentLength, 0),
+	}
+	if err := s.DB.CreateOperation(record); err != nil {
+		log.Printf("[SRV WARN] Upload is not tracked: %v", err)
+		return op, true
+	}
+	op.id = record.ID
+	w.Header().Set("Operation-Id", op.id)
+	if r.Header.Get("Operation-Id") == "" {
+		w.WriteHeader(http.StatusEarlyHints)
+	}
+	return op, true
+}
+
+// validOperationID accepts client-chosen IDs long enough to be unguessable
+// and safe in a URL path.
+func validOperationID(id string) bool {
+	if len(id) < 16 || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func (op *uploadOperation) WriteHeader(status int) {
+	if op.status == 0 {
+		op.status = status
+	}
+	op.ResponseWriter.WriteHeader(status)
+}
+
+func (op *uploadOperation) Write(p []byte) (int, error) {
+	if op.status == 0 {
+		op.status = http.StatusOK
+	}
+	if op.status >= 400 {
+		op.body.Write(p)
+	}
+	return op.ResponseWriter.Write(p)
+}
+
+// progress records that done bytes of the file name were sent to Discord,
+// at most once per operationProgressInterval. A nil operation records
+// nothing.
+func (op *uploadOperation) progress(name string, done int64) {
+	if op == nil || op.id == "" || time.Since(op.saved) < operationProgressInterval {
+		return
+	}
+	op.saved = time.Now()
+	if err := op.s.DB.UpdateOperation(op.id, name, database.OpUploading, done); err != nil {
+		log.Printf("[SRV ERR] Operation %s progress not recorded: %v", op.id, err)
+	}
+}
+
+// finish marks the operation complete with file, or failed with the error
+// response the client was sent if file is nil.
+func (op *uploadOperation) finish(file *database.FileMetadata) {
+	if op.id == "" {
+		return
+	}
+	var reason string
+	if file == nil {
+		if reason = strings.TrimSpace(op.body.String()); reason == "" {
+			reason = "upload cancelled"
+		}
+	}
+	err := op.s.DB.FinishOperation(op.id, file, reason)
+	if err != nil {
+		log.Printf("[SRV ERR] Operation %s result not recorded: %v", op.id, err)
+	}
+}
+
+// handleGetOperation reports the state of an upload by the ID its
+// Operation-Id header gave.
+func (s *Server) handleGetOperation(w http.ResponseWriter, r *http.Request) {
+	op, err := s.DB.GetOperation(mux.Vars(r)["id"])
+	p := principalFrom(r)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !p.Admin && op.Principal != p.ID) {
+		http.Error(w, "Operation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Operation lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(op)
+}
+
+// handleListOperations reports the caller's latest ?limit= (default 50)
+// uploads, for clients that lost an Operation-Id along with the
+// connection.
+func (s *Server) handleListOperations(w http.ResponseWriter, r *http.Request) {
+	ops, err := s.DB.ListOperations(principalFrom(r).ID, queryInt(r, "limit", 50, 500))
+	if err != nil {
+		log.Printf("[SRV ERR] Operation lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ops)
+}
+
+// ExpireOperations drops operations finished more than operationRetention
+// ago.
+func (s *Server) ExpireOperations(ctx context.Context) error {
+	n, err := s.DB.ExpireOperations(time.Now().Add(-operationRetention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("[SERVER] Expired %d upload operations", n)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type runPipelineRequest struct {
+	FileID int `json:"file_id"`
+}
+
+// handleRunPipeline decrypts a stored file straight into a named pipeline
+// from PIPELINES_FILE, e.g. to unpack a release archive on this host.
+func (s *Server) handleRunPipeline(w http.ResponseWriter, r *http.Request) {
+	p := principalFrom(r)
+	pl, ok := s.Pipelines[mux.Vars(r)["name"]]
+	if !ok || !pl.Allows(p.ID, p.Admin) {
+		http.Error(w, "Pipeline not found", http.StatusNotFound)
+		return
+	}
+
+	var req runPipelineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	file, err := s.DB.GetFile(req.FileID)
+	if err != nil || !p.canManage(file) {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	if !p.Admin && s.Bot.TransferCapReached(p.ID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return
+	}
+
+	log.Printf("[SERVER] Running pipeline %s on File ID %d", pl.Name, file.ID)
+	pr, pw := io.Pipe()
+	go func() {
+		n, err := s.Bot.WriteFile(r.Context(), pw, file)
+		if err == nil && n != file.Size {
+			// WriteFile skips chunks it cannot read
+			err = fmt.Errorf("read %d of %d bytes, chunks are missing", n, file.Size)
+		}
+		s.recordTransfer(p, 0, n)
+		pw.CloseWithError(err)
+	}()
+	report, err := pl.Run(pr)
+	pr.Close()
+	if err != nil {
+		log.Printf("[SRV ERR] Pipeline %s failed: %v", pl.Name, err)
+		http.Error(w, "Pipeline failed: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	log.Printf("[SERVER] Pipeline %s wrote %d files (%d bytes)", pl.Name, report.FilesWritten, report.BytesWritten)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (s *Server) handleListPipelines(w http.ResponseWriter, r *http.Request) {
+	p := principalFrom(r)
+	names := []string{}
+	for name, pl := range s.Pipelines {
+		if pl.Allows(p.ID, p.Admin) {
+			names = append(names, name)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(names)
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"discordvault/internal/database"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+type preferences struct {
+	Timezone string `json:"timezone"`
+}
+
+func (s *Server) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	tz, err := s.DB.GetTimezone(database.ScopeUser, principalFrom(r).ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if tz == "" {
+		tz = s.Config.Location.String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preferences{Timezone: tz})
+}
+
+func (s *Server) handleSetPreferences(w http.ResponseWriter, r *http.Request) {
+	var req preferences
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	loc, err := time.LoadLocation(req.Timezone)
+	if err != nil {
+		http.Error(w, "Unknown timezone", http.StatusBadRequest)
+		return
+	}
+	if err := s.DB.SetTimezone(database.ScopeUser, principalFrom(r).ID, loc.String()); err != nil {
+		log.Printf("[SRV ERR] Saving preferences failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preferences{Timezone: loc.String()})
+}
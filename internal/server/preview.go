@@ -0,0 +1,100 @@
+package server
+
+import (
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultPreviewKB is how much of a file a preview returns unless ?kb= asks
+// for more or less. A preview never reaches past the first chunk.
+const defaultPreviewKB = 64
+
+// activeTypes are content types a browser would run or render as a page
+// from the vault's origin; previews of them are sent as plain text.
+var activeTypes = []string{"text/html", "image/svg+xml", "application/xhtml+xml", "text/xml", "application/xml", "text/javascript", "application/javascript"}
+
+// handlePreview returns the start of a file, up to ?kb= kilobytes (default
+// 64) of its first chunk, so the web UI can show text, logs, and images
+// without downloading the whole file. Only the first chunk is fetched from
+// Discord. X-Preview-Truncated tells whether the file goes on.
+func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	file, err := s.DB.GetFile(id)
+	p := principalFrom(r)
+	if err != nil || !p.canManage(file) {
+		http.Error(w, "Object not found", http.StatusNotFound)
+		return
+	}
+	if !p.Admin && s.Bot.TransferCapReached(p.ID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return
+	}
+	limit := int64(queryInt(r, "kb", defaultPreviewKB, 1<<20)) * 1024
+
+	chunks, err := s.DB.GetChunks(file.ID)
+	if err != nil {
+		log.Printf("[SRV ERR] Chunk lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	var data []byte
+	if len(chunks) > 0 {
+		if data, err = s.Bot.ReadChunk(r.Context(), file, chunks, 0); err != nil {
+			log.Printf("[SRV ERR] Preview of %s failed: %v", file.Name, err)
+			if r.Context().Err() == nil {
+				http.Error(w, "First chunk could not be read", http.StatusBadGateway)
+			}
+			return
+		}
+	}
+	data = data[:min(int64(len(data)), limit)]
+
+	w.Header().Set("Content-Type", previewType(file.Name, data))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", "inline")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Preview-Truncated", strconv.FormatBool(int64(len(data)) < file.Size))
+	w.Write(data)
+	s.recordTransfer(p, 0, int64(len(data)))
+}
+
+// previewType is the content type of a file by its extension, or else by
+// its first bytes. Text gets a charset, and active content is sent as
+// plain text.
+func previewType(name string, data []byte) string {
+	ctype := mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+	if ctype == "" {
+		ctype = http.DetectContentType(data)
+	}
+	media, _, err := mime.ParseMediaType(ctype)
+	switch {
+	case err != nil:
+		media = "application/octet-stream"
+	case slices.Contains(activeTypes, media):
+		media = "text/plain"
+	}
+	if strings.HasPrefix(media, "text/") && validUTF8(data) {
+		return media + "; charset=utf-8"
+	}
+	return media
+}
+
+// validUTF8 reports whether data is UTF-8, but for a character the preview
+// cut in half at its end.
+func validUTF8(data []byte) bool {
+	for cut := 0; cut < utf8.UTFMax && cut <= len(data); cut++ {
+		if utf8.Valid(data[:len(data)-cut]) {
+			return true
+		}
+	}
+	return false
+}
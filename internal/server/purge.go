@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type purgeRequest struct {
+	FileIDs       []int `json:"file_ids"`
+	OlderThanDays int   `json:"older_than_days"`
+}
+
+// handleStartPurge queues a paced background deletion of the given files, or
+// of every file older than older_than_days.
+func (s *Server) handleStartPurge(w http.ResponseWriter, r *http.Request) {
+	var req purgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ids := req.FileIDs
+	if req.OlderThanDays > 0 {
+		files, err := s.DB.ListFilesOlderThan(time.Now().AddDate(0, 0, -req.OlderThanDays))
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		for _, f := range files {
+			ids = append(ids, f.ID)
+		}
+	}
+	if len(ids) == 0 {
+		http.Error(w, "Nothing to purge", http.StatusBadRequest)
+		return
+	}
+
+	// The purge outlives this request, so it must not inherit its context.
+	id := s.Purger.Start(context.Background(), ids)
+	log.Printf("[SERVER] Slow purge %s queued for %d files", id, len(ids))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]any{"id": id, "files": len(ids)})
+}
+
+func (s *Server) handlePurgeStatus(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.Purger.Status(mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, "Purge job not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleListPurged lists deleted files whose messages are still kept for the
+// PURGE_GRACE period.
+func (s *Server) handleListPurged(w http.ResponseWriter, r *http.Request) {
+	purged, err := s.DB.ListPurged("")
+	if err != nil {
+		log.Printf("[SRV ERR] ListPurged failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purged)
+}
+
+// handleRestorePurged undoes a delete or purge while its grace period lasts.
+func (s *Server) handleRestorePurged(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	file, err := s.DB.RestorePurged(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Purged file not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Restoring purged file %d failed: %v", id, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[SERVER] Restored purged File ID %d as %s", file.ID, file.Name)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(file)
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"database/sql"
+	"discordvault/internal/database"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+var releaseChannelName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+type publishReleaseRequest struct {
+	FileID int `json:"file_id"`
+}
+
+type releaseResponse struct {
+	database.ReleaseChannel
+	URL string `json:"url"`
+}
+
+func (s *Server) releaseResponse(rc *database.ReleaseChannel) releaseResponse {
+	return releaseResponse{ReleaseChannel: *rc, URL: s.Config.PublicURL + "/r/" + rc.Name}
+}
+
+func (s *Server) handleListReleases(w http.ResponseWriter, r *http.Request) {
+	p := principalFrom(r)
+	owner := p.ID
+	if p.Admin {
+		owner = ""
+	}
+	releases, err := s.DB.ListReleases(owner)
+	if err != nil {
+		log.Printf("[SRV ERR] ListReleases failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	resp := make([]releaseResponse, len(releases))
+	for i := range releases {
+		resp[i] = s.releaseResponse(&releases[i])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handlePublishRelease points a channel at one of the caller's file versions.
+// The first publisher owns the channel; only they or an admin can move it.
+func (s *Server) handlePublishRelease(w http.ResponseWriter, r *http.Request) {
+	channel := mux.Vars(r)["channel"]
+	if !releaseChannelName.MatchString(channel) {
+		http.Error(w, "Invalid channel name", http.StatusBadRequest)
+		return
+	}
+	var req publishReleaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	p := principalFrom(r)
+	file, err := s.DB.GetFile(req.FileID)
+	if err != nil || !p.canManage(file) {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	existing, err := s.DB.GetRelease(channel)
+	switch {
+	case err == nil && !p.Admin && existing.OwnerID != p.ID:
+		http.Error(w, "Channel belongs to another user", http.StatusForbidden)
+		return
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		log.Printf("[SRV ERR] GetRelease failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.DB.PublishRelease(channel, file.ID, p.ID); err != nil {
+		log.Printf("[SRV ERR] Release publish failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	rc, err := s.DB.GetRelease(channel)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[SERVER] Channel %s now serves File ID %d (%s v%d)", channel, file.ID, file.Name, file.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.releaseResponse(rc))
+}
+
+func (s *Server) handleDeleteRelease(w http.ResponseWriter, r *http.Request) {
+	channel := mux.Vars(r)["channel"]
+	p := principalFrom(r)
+	rc, err := s.DB.GetRelease(channel)
+	if err != nil || (!p.Admin && rc.OwnerID != p.ID) {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+	if err := s.DB.DeleteRelease(channel); err != nil {
+		log.Printf("[SRV ERR] Release delete failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[SERVER] Channel %s removed", channel)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReleaseDownload serves whatever version is currently published on a
+// channel, so the URL never changes between releases.
+func (s *Server) handleReleaseDownload(w http.ResponseWriter, r *http.Request) {
+	rc, err := s.DB.GetRelease(mux.Vars(r)["channel"])
+	if err != nil {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+	if rc.FileID == 0 {
+		http.Error(w, "Nothing published on this channel", http.StatusNotFound)
+		return
+	}
+	file, err := s.DB.GetFile(rc.FileID)
+	if err != nil {
+		http.Error(w, "Object not found", http.StatusNotFound)
+		return
+	}
+
+	// Public downloads count against the owner's transfer budget.
+	if !s.Config.IsAdmin(file.OwnerID) && s.Bot.TransferCapReached(file.OwnerID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Release-Version", strconv.Itoa(file.Version))
+	s.recordTransfer(Principal{ID: file.OwnerID}, 0, s.streamFile(w, r, file))
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"discordvault/internal/config"
+	"discordvault/internal/database"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mediaExtensions are the extensions a wildcard type such as image/* matches
+// in a file search. Exact types use the system's MIME table.
+var mediaExtensions = map[string][]string{
+	"image": {".png", ".jpg", ".jpeg", ".gif", ".webp", ".bmp", ".svg", ".ico", ".tif", ".tiff", ".heic", ".avif"},
+	"video": {".mp4", ".mkv", ".webm", ".mov", ".avi", ".m4v", ".wmv", ".flv"},
+	"audio": {".mp3", ".flac", ".wav", ".ogg", ".oga", ".m4a", ".aac", ".opus"},
+	"text":  {".txt", ".log", ".md", ".csv", ".tsv", ".json", ".xml", ".yaml", ".yml", ".ini", ".html", ".css", ".js"},
+}
+
+// filterParams are the query parameters of GET /api/files that turn a
+// listing into a filtered search.
+var filterParams = []string{"q", "min_size", "max_size", "after", "before", "type", "tags", "any_tags", "not_tags", "health", "owner", "limit", "offset"}
+
+// fileFilter builds the search a GET /api/files request asks for. ok is
+// false when the request has no filter parameters, so the plain listing
+// applies. Non-admins only ever search their own files.
+func (s *Server) fileFilter(q url.Values, p Principal) (f database.FileFilter, ok bool, err error) {
+	if !slices.ContainsFunc(filterParams, q.Has) {
+		return f, false, nil
+	}
+	f = database.FileFilter{GuildID: database.DefaultVault, Query: q.Get("q"), OwnerID: p.ID}
+	if p.Admin {
+		f.OwnerID = q.Get("owner")
+	}
+
+	for name, size := range map[string]*int64{"min_size": &f.MinSize, "max_size": &f.MaxSize} {
+		if v := q.Get(name); v != "" {
+			if *size, err = config.ParseSize(v); err != nil {
+				return f, true, fmt.Errorf("%s must be a size like 500MB or 2GB", name)
+			}
+		}
+	}
+	if f.After, err = s.filterTime(q.Get("after"), false); err != nil {
+		return f, true, fmt.Errorf("after %w", err)
+	}
+	if f.Before, err = s.filterTime(q.Get("before"), true); err != nil {
+		return f, true, fmt.Errorf("before %w", err)
+	}
+
+	for _, t := range splitParam(q.Get("type")) {
+		exts, err := typeExtensions(t)
+		if err != nil {
+			return f, true, err
+		}
+		f.Extensions = append(f.Extensions, exts...)
+	}
+
+	for name, tags := range map[string]*[]string{"tags": &f.AllTags, "any_tags": &f.AnyTags, "not_tags": &f.NoTags} {
+		for _, tag := range splitParam(q.Get(name)) {
+			if tag = database.NormalizeTag(tag); tag != "" {
+				*tags = append(*tags, tag)
+			}
+		}
+	}
+
+	for _, h := range splitParam(q.Get("health")) {
+		if !slices.Contains([]string{database.HealthOK, database.HealthDegraded, database.HealthCorrupt, database.HealthUnchecked}, h) {
+			return f, true, errors.New("health must be ok, degraded, corrupt, or unchecked")
+		}
+		f.Health = append(f.Health, h)
+	}
+
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 0 {
+			return f, true, errors.New("limit must not be negative")
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if f.Offset, err = strconv.Atoi(v); err != nil || f.Offset < 0 {
+			return f, true, errors.New("offset must not be negative")
+		}
+	}
+	return f, true, nil
+}
+
+// filterTime parses a date, in TIMEZONE, or an RFC 3339 time. A date as the
+// end of a range includes that whole day.
+func (s *Server) filterTime(v string, end bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", v, s.Config.Location)
+	if err != nil {
+		return time.Time{}, errors.New("must be a date like 2024-05-31 or an RFC 3339 time")
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+// typeExtensions returns the file extensions of a MIME type such as
+// application/pdf, or of a whole kind such as image/*.
+func typeExtensions(t string) ([]string, error) {
+	t = strings.ToLower(t)
+	if kind, ok := strings.CutSuffix(t, "/*"); ok {
+		if exts, ok := mediaExtensions[kind]; ok {
+			return exts, nil
+		}
+		return nil, fmt.Errorf("type %s is unknown; use image/*, video/*, audio/*, text/*, or an exact type", t)
+	}
+	exts, _ := mime.ExtensionsByType(t)
+	for _, kind := range mediaExtensions {
+		for _, ext := range kind {
+			if media, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext)); media == t && !slices.Contains(exts, ext) {
+				exts = append(exts, ext)
+			}
+		}
+	}
+	if len(exts) == 0 {
+		return nil, fmt.Errorf("type %s is unknown", t)
+	}
+	return exts, nil
+}
@@ -1,21 +1,26 @@
 package server
 
 import (
-	"bytes"
 	"crypto/sha256"
 	"discordvault/internal/bot"
 	"discordvault/internal/config"
 	"discordvault/internal/crypto"
 	"discordvault/internal/database"
+	"discordvault/internal/jobs"
+	"discordvault/internal/logstream"
+	"discordvault/internal/pipeline"
+	"discordvault/internal/version"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
-	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -24,24 +29,173 @@ type Server struct {
 	Config *config.Config
 	DB     *database.Database
 	Bot    *bot.Bot
+	Signer *crypto.Signer
+
+	Compactor *jobs.Compactor
+	Purger    *jobs.Purger
+	GC        *jobs.OrphanCollector
+	Healer    *jobs.Healer
+	Lifecycle *jobs.Lifecycle
+	Backups   *jobs.BackupJobs
+	Importer  *jobs.Importer
+	Exporter  *jobs.Exporter
+	Migrator  *jobs.Migrator
+	Bulk      *jobs.BulkJobs
+	Scheduler *jobs.Scheduler
+	Pipelines map[string]*pipeline.Pipeline
+	Logs      *logstream.Buffer // nil disables /api/admin/logs
+
+	sessions  sync.Map // IDs of upload sessions, or ID/n of chunks, currently receiving data
+	hashLocks sync.Map // chunked upload session ID -> *sync.Mutex ordering its hash states
+	listening chan struct{}
 }
 
-func New(cfg *config.Config, db *database.Database, vaultBot *bot.Bot) *Server {
+func New(cfg *config.Config, db *database.Database, vaultBot *bot.Bot, signer *crypto.Signer) *Server {
 	return &Server{
 		Config: cfg,
 		DB:     db,
 		Bot:    vaultBot,
+		Signer: signer,
+
+		Compactor: &jobs.Compactor{Bot: vaultBot, DB: db, Retention: cfg.CompactionRetention},
+		Purger:    &jobs.Purger{Bot: vaultBot, DB: db, Pace: cfg.PurgePace, Grace: cfg.PurgeGrace},
+		GC:        &jobs.OrphanCollector{Bot: vaultBot, DB: db, MinAge: cfg.GCMinAge},
+		Healer:    &jobs.Healer{Bot: vaultBot, DB: db},
+		Lifecycle: &jobs.Lifecycle{DB: db},
+		Backups:   &jobs.BackupJobs{Bot: vaultBot, DB: db, Paths: cfg.BackupJobPaths},
+		Importer:  &jobs.Importer{Bot: vaultBot, DB: db},
+		Exporter:  &jobs.Exporter{Bot: vaultBot, DB: db, Dir: cfg.ExportDir},
+		Migrator:  &jobs.Migrator{Bot: vaultBot, DB: db},
+		Bulk:      &jobs.BulkJobs{DB: db, Grace: cfg.PurgeGrace},
+		Scheduler: jobs.NewScheduler(),
+
+		listening: make(chan struct{}),
 	}
 }
 
+// Listening is closed once Start accepts connections.
+func (s *Server) Listening() <-chan struct{} {
+	return s.listening
+}
+
 func (s *Server) Start() error {
 	r := mux.NewRouter()
 
+	// Public Endpoints
+	r.HandleFunc("/api/version", s.handleVersion).Methods("GET")
+	r.HandleFunc("/api/format", s.handleFormat).Methods("GET")
+	r.HandleFunc("/api/login", s.handleLogin).Methods("POST")
+	r.HandleFunc("/api/logout", s.handleLogout).Methods("POST")
+
 	// API Endpoints
-	r.HandleFunc("/api/upload", s.handleUpload).Methods("POST")
-	r.HandleFunc("/api/files", s.handleListFiles).Methods("GET")
-	r.HandleFunc("/api/download/{id}", s.handleDownload).Methods("GET")
-	r.HandleFunc("/api/delete/{id}", s.handleDelete).Methods("POST")
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(s.authenticate, s.checkCSRF)
+	api.HandleFunc("/upload", s.idempotent(s.handleUpload)).Methods("POST")
+	api.HandleFunc("/operations", s.handleListOperations).Methods("GET")
+	api.HandleFunc("/operations/{id}", s.handleGetOperation).Methods("GET")
+	api.HandleFunc("/upload/sessions", s.idempotent(s.handleCreateUploadSession)).Methods("POST")
+	api.HandleFunc("/upload/sessions/{id}", s.handleGetUploadSession).Methods("GET")
+	api.HandleFunc("/upload/sessions/{id}", s.handleResumeUpload).Methods("PUT")
+	api.HandleFunc("/upload/sessions/{id}", s.handleDeleteUploadSession).Methods("DELETE")
+	api.HandleFunc("/uploads", s.idempotent(s.handleCreateChunkedUpload)).Methods("POST")
+	api.HandleFunc("/uploads/{id}", s.handleGetChunkedUpload).Methods("GET")
+	api.HandleFunc("/uploads/{id}", s.handleDeleteChunkedUpload).Methods("DELETE")
+	api.HandleFunc("/uploads/{id}/chunks/{n}", s.handlePutUploadChunk).Methods("PUT")
+	api.HandleFunc("/uploads/{id}/complete", s.handleCompleteChunkedUpload).Methods("POST")
+	api.HandleFunc("/copy", s.idempotent(s.handleCopy)).Methods("POST")
+	api.HandleFunc("/files", s.handleListFiles).Methods("GET")
+	api.HandleFunc("/preview/{id}", s.handlePreview).Methods("GET")
+	api.HandleFunc("/files/bulk", s.handleStartBulk).Methods("POST")
+	api.HandleFunc("/files/bulk/{id}", s.handleBulkStatus).Methods("GET")
+	api.HandleFunc("/files/{id}/versions", s.handleListVersions).Methods("GET")
+	api.HandleFunc("/files/{id}/verify", s.handleVerifyFile).Methods("POST")
+	api.HandleFunc("/download/{id}", s.handleDownload).Methods("GET")
+	api.HandleFunc("/delete/{id}", s.idempotent(s.handleDelete)).Methods("POST")
+	api.HandleFunc("/batch", s.idempotent(s.handleBatch)).Methods("POST")
+	api.HandleFunc("/folders", s.handleListFolders).Methods("GET")
+	api.HandleFunc("/folders", s.handleCreateFolder).Methods("POST")
+	api.HandleFunc("/folders/{id}/download", s.handleDownloadFolder).Methods("GET")
+	api.HandleFunc("/shares", s.idempotent(s.handleCreateShare)).Methods("POST")
+	api.HandleFunc("/artifacts", s.idempotent(s.handleUploadArtifact)).Methods("POST")
+	api.HandleFunc("/artifacts", s.handleListArtifacts).Methods("GET")
+	api.HandleFunc("/artifacts/latest", s.handleLatestArtifact).Methods("GET")
+	api.HandleFunc("/artifacts/latest/download", s.handleDownloadLatestArtifact).Methods("GET")
+	api.HandleFunc("/releases", s.handleListReleases).Methods("GET")
+	api.HandleFunc("/releases/{channel}", s.handlePublishRelease).Methods("PUT")
+	api.HandleFunc("/releases/{channel}", s.handleDeleteRelease).Methods("DELETE")
+	api.HandleFunc("/usage", s.handleUsage).Methods("GET")
+	api.HandleFunc("/pipelines", s.handleListPipelines).Methods("GET")
+	api.HandleFunc("/pipelines/{name}/run", s.handleRunPipeline).Methods("POST")
+	api.HandleFunc("/preferences", s.handleGetPreferences).Methods("GET")
+	api.HandleFunc("/preferences", s.handleSetPreferences).Methods("PUT")
+	api.HandleFunc("/keys", s.handleListAPIKeys).Methods("GET")
+	api.HandleFunc("/keys", s.handleCreateAPIKey).Methods("POST")
+	api.HandleFunc("/keys/{id}", s.handleRevokeAPIKey).Methods("DELETE")
+	api.HandleFunc("/csrf", s.handleCSRFToken).Methods("GET")
+	api.HandleFunc("/sessions", s.handleListWebSessions).Methods("GET")
+	api.HandleFunc("/sessions/{id}", s.handleRevokeWebSession).Methods("DELETE")
+	api.HandleFunc("/logout/everywhere", s.handleLogoutEverywhere).Methods("POST")
+	api.HandleFunc("/2fa", s.handleTOTPStatus).Methods("GET")
+	api.HandleFunc("/2fa/enroll", s.handleEnrollTOTP).Methods("POST")
+	api.HandleFunc("/2fa/confirm", s.handleConfirmTOTP).Methods("POST")
+	api.HandleFunc("/2fa/recovery-codes", s.handleRegenerateRecoveryCodes).Methods("POST")
+	api.HandleFunc("/2fa/disable", s.handleDisableTOTP).Methods("POST")
+
+	// Backup jobs read server paths, so only admins manage them
+	backups := api.PathPrefix("/jobs").Subrouter()
+	backups.Use(requireAdmin)
+	backups.HandleFunc("", s.handleListBackupJobs).Methods("GET")
+	backups.HandleFunc("", s.handleCreateBackupJob).Methods("POST")
+	backups.HandleFunc("/{id}", s.handleGetBackupJob).Methods("GET")
+	backups.HandleFunc("/{id}", s.handleUpdateBackupJob).Methods("PUT")
+	backups.HandleFunc("/{id}", s.handleDeleteBackupJob).Methods("DELETE")
+	backups.HandleFunc("/{id}/run", s.handleRunBackupJob).Methods("POST")
+	backups.HandleFunc("/{id}/runs", s.handleListBackupRuns).Methods("GET")
+	api.Handle("/export", requireAdmin(http.HandlerFunc(s.handleExport))).Methods("GET")
+	api.Handle("/export-all", requireAdmin(http.HandlerFunc(s.handleStartExportAll))).Methods("POST")
+	api.Handle("/export-all/{id}", requireAdmin(http.HandlerFunc(s.handleExportAllStatus))).Methods("GET")
+	api.Handle("/stats", requireAdmin(http.HandlerFunc(s.handleStats))).Methods("GET")
+
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(requireAdmin)
+	admin.HandleFunc("/compact", s.handleCompact).Methods("POST")
+	admin.HandleFunc("/gc", s.handleGC).Methods("POST")
+	admin.HandleFunc("/repair", s.handleRepair).Methods("POST")
+	admin.HandleFunc("/purge", s.handleStartPurge).Methods("POST")
+	admin.HandleFunc("/purge/{id}", s.handlePurgeStatus).Methods("GET")
+	admin.HandleFunc("/policies", s.handleListPolicies).Methods("GET")
+	admin.HandleFunc("/policies", s.handleCreatePolicy).Methods("POST")
+	admin.HandleFunc("/policies/{id}", s.handleUpdatePolicy).Methods("PUT")
+	admin.HandleFunc("/policies/{id}", s.handleDeletePolicy).Methods("DELETE")
+	admin.HandleFunc("/policies/{id}/apply", s.handleApplyPolicy).Methods("POST")
+	admin.HandleFunc("/import", s.handleStartImport).Methods("POST")
+	admin.HandleFunc("/import/{id}", s.handleImportStatus).Methods("GET")
+	admin.HandleFunc("/migrate", s.handleStartMigration).Methods("POST")
+	admin.HandleFunc("/migrate/{id}", s.handleMigrationStatus).Methods("GET")
+	admin.HandleFunc("/purged", s.handleListPurged).Methods("GET")
+	admin.HandleFunc("/purged/{id}/restore", s.handleRestorePurged).Methods("POST")
+	admin.HandleFunc("/duplicates", s.handleListDuplicates).Methods("GET")
+	admin.HandleFunc("/duplicates/consolidate", s.handleConsolidateDuplicates).Methods("POST")
+	admin.HandleFunc("/duplicates/{hash}/consolidate", s.handleConsolidateDuplicates).Methods("POST")
+	admin.HandleFunc("/logs", s.handleLogs).Methods("GET")
+	admin.HandleFunc("/2fa/{user}", s.handleResetTOTP).Methods("DELETE")
+	admin.HandleFunc("/sessions", s.handleListAllWebSessions).Methods("GET")
+	admin.HandleFunc("/sessions", s.handleRevokeUserWebSessions).Methods("DELETE")
+	admin.HandleFunc("/dashboard/growth", s.handleDashboardGrowth).Methods("GET")
+	admin.HandleFunc("/dashboard/users", s.handleDashboardUsers).Methods("GET")
+	admin.HandleFunc("/dashboard/activity", s.handleDashboardActivity).Methods("GET")
+	admin.HandleFunc("/dashboard/failures", s.handleDashboardFailures).Methods("GET")
+	admin.HandleFunc("/dashboard/jobs", s.handleDashboardJobs).Methods("GET")
+	admin.HandleFunc("/dashboard/degraded", s.handleDashboardDegraded).Methods("GET")
+
+	// Share Links
+	r.HandleFunc("/s/{token}", s.handleShareDownload).Methods("GET")
+
+	// One-time Bot Download Links
+	r.HandleFunc("/d/{token}", s.handleTokenDownload).Methods("GET")
+
+	// Release Channels
+	r.HandleFunc("/r/{channel}", s.handleReleaseDownload).Methods("GET")
 
 	// Static Assets
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir("./web/")))
@@ -53,26 +207,192 @@ func (s *Server) Start() error {
 		ReadTimeout:  0,
 	}
 
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	close(s.listening)
 	log.Printf("[SERVER] Neural Link Established at http://localhost:8080")
-	return srv.ListenAndServe()
+	return srv.Serve(ln)
 }
 
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"version":     version.Version,
+		"algorithm":   "ed25519",
+		"public_key":  s.Signer.PublicKeyString(),
+		"fingerprint": s.Signer.Fingerprint(),
+	})
+}
+
+// handleFormat documents the storage format so third-party tooling can
+// recover files without this server.
+func (s *Server) handleFormat(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"cipher":          crypto.Algorithm,
+		"key_bytes":       32,
+		"nonce_bytes":     crypto.NonceSize,
+		"tag_bytes":       crypto.TagSize,
+		"chunk_layout":    "nonce || ciphertext || tag",
+		"max_chunk_bytes": config.MaxChunkSize,
+		"chunk_naming":    "dv1-<sha256 of ciphertext>.vault (older chunks: <sha256 of ciphertext>.vault), one attachment per message",
+		"ordering":        "chunks are concatenated in part_num order after decryption",
+		"file_hash":       "sha256 of the reconstructed plaintext",
+		"recovery":        "discordvault decrypt --key KEY part1.vault part2.vault ... -o file",
+	})
+}
+
+func (s *Server) handleCompact(w http.ResponseWriter, r *http.Request) {
+	report, err := s.Compactor.Compact(r.Context())
+	if err != nil {
+		log.Printf("[SRV ERR] Compaction failed: %v", err)
+		http.Error(w, "Compaction failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleRepair runs a repair pass over the degraded files now.
+func (s *Server) handleRepair(w http.ResponseWriter, r *http.Request) {
+	report, err := s.Healer.Heal(r.Context())
+	if err != nil {
+		log.Printf("[SRV ERR] Repair failed: %v", err)
+		http.Error(w, "Repair failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleGC removes orphaned chunk messages; ?dry_run=true only reports them.
+func (s *Server) handleGC(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	report, err := s.GC.Collect(r.Context(), dryRun)
+	if err != nil {
+		log.Printf("[SRV ERR] Orphan GC failed: %v", err)
+		http.Error(w, "Garbage collection failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleListFiles lists the caller's current files in the default vault; ?q=
+// narrows them to names or tags matching the query. Degraded files carry
+// their repair state.
 func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
-	files, err := s.DB.ListFiles()
+	p := principalFrom(r)
+	filter, filtered, err := s.fileFilter(r.URL.Query(), p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var files []database.FileMetadata
+	switch {
+	case filtered:
+		var total int
+		files, total, err = s.DB.FilterFiles(filter)
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	case p.Admin:
+		files, err = s.DB.ListFiles(database.DefaultVault)
+	default:
+		files, err = s.DB.ListFilesByOwner(database.DefaultVault, p.ID)
+	}
 	if err != nil {
 		log.Printf("[SRV ERR] ListFiles failed: %v", err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
+	repairs, err := s.DB.FileRepairs()
+	if err != nil {
+		log.Printf("[SRV ERR] Repair lookup failed: %v", err)
+	}
+	for i := range files {
+		files[i].Repair = repairs[files[i].ID]
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(files)
 }
 
+// verifyResponse is a verification report with the health it recorded.
+type verifyResponse struct {
+	*bot.VerifyReport
+	Health string
+}
+
+// handleVerifyFile downloads every chunk of a file and checks its SHA-256
+// like /verify, recording the file's health.
+func (s *Server) handleVerifyFile(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	file, err := s.DB.GetFile(id)
+	if err != nil || !principalFrom(r).canManage(file) {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	report, err := s.Bot.ScrubFile(r.Context(), file, 0)
+	if errors.Is(err, bot.ErrChunkUnreachable) {
+		log.Printf("[SRV WARN] Verify of #%d incomplete: %v", file.ID, err)
+		http.Error(w, "Some chunks could not be fetched from Discord; try again later", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Verify of #%d failed: %v", file.ID, err)
+		http.Error(w, "Verification failed", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[SERVER] Verified #%d: %d chunks, %d missing, %d corrupted, %d rebuilt, hash match %v",
+		file.ID, report.Chunks, len(report.Missing), len(report.Corrupted), len(report.Rebuilt), report.Match)
+	health, err := s.Bot.RecordHealth(file, report)
+	if err != nil {
+		log.Printf("[SRV ERR] Recording health of #%d failed: %v", file.ID, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verifyResponse{report, health})
+}
+
+func (s *Server) handleListVersions(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	file, err := s.DB.GetFile(id)
+	if err != nil || !principalFrom(r).canManage(file) {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	versions, err := s.DB.ListVersions(file.GuildID, file.Name)
+	if err != nil {
+		log.Printf("[SRV ERR] ListVersions failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
 func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, _ := strconv.Atoi(vars["id"])
 
-	chunks, err := s.DB.GetChunks(id)
+	file, err := s.DB.GetFile(id)
+	if err != nil || !principalFrom(r).canManage(file) {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	if s.Config.PurgeGrace > 0 {
+		if err := s.DB.SoftDeleteFile(id, principalFrom(r).ID); err != nil {
+			log.Printf("[SRV ERR] Metadata purge failed: %v", err)
+			http.Error(w, "Registry purge failed", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[SERVER] File ID %d deleted; chunks kept for %s", id, s.Config.PurgeGrace)
+		go s.Bot.NotifyDelete(file, "Web", principalFrom(r).ID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	chunks, err := s.DB.ExclusiveChunks(id)
 	if err != nil {
 		http.Error(w, "File not found", http.StatusNotFound)
 		return
@@ -85,12 +405,12 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 
 	for _, chunk := range chunks {
 		wg.Add(1)
-		go func(msgID string) {
+		go func(c database.ChunkMetadata) {
 			defer wg.Done()
 			semaphore <- struct{}{}
-			_ = s.Bot.Session.ChannelMessageDelete(s.Config.ChannelID, msgID)
+			_ = s.Bot.DeleteChunk(c)
 			<-semaphore
-		}(chunk.MessageID)
+		}(chunk)
 	}
 	wg.Wait()
 
@@ -101,86 +421,225 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("[SERVER] File ID %d successfully erased from cluster.", id)
+	go s.Bot.NotifyDelete(file, "Web", principalFrom(r).ID)
 	w.WriteHeader(http.StatusOK)
 }
 
 func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	policy := s.Config.DuplicatePolicy
+	if p := r.URL.Query().Get("on_duplicate"); p != "" {
+		if !config.ValidDuplicatePolicy(p) {
+			http.Error(w, "on_duplicate must be suffix, version, or reject", http.StatusBadRequest)
+			return
+		}
+		policy = p
+	}
+
+	file, _ := s.receiveUpload(w, r, policy)
+	if file == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(file)
+}
+
+// receiveUpload streams the multipart "file" field into encrypted chunks and
+// records it under the given duplicate policy, returning the stored file and
+// the name it was uploaded as. On failure it writes the error response itself
+// and returns nil. Its progress is tracked as an operation.
+func (s *Server) receiveUpload(w http.ResponseWriter, r *http.Request, policy string) (*database.FileMetadata, string) {
+	op, ok := s.startOperation(w, r)
+	if !ok {
+		return nil, ""
+	}
+	file, name := s.streamUpload(op, r, policy, op)
+	op.finish(file)
+	return file, name
+}
+
+func (s *Server) streamUpload(w http.ResponseWriter, r *http.Request, policy string, op *uploadOperation) (*database.FileMetadata, string) {
+	if p := principalFrom(r); !p.Admin && s.Bot.TransferCapReached(p.ID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return nil, ""
+	}
+
 	mr, err := r.MultipartReader()
 	if err != nil {
 		http.Error(w, "Stream initialization failed", http.StatusBadRequest)
-		return
+		return nil, ""
 	}
 
-	var filename string
-	var totalSize int64
-	var messageIDs []string
-	hasher := sha256.New()
+	// Large or explicitly deferred uploads only start off-peak. A request is
+	// not held open until then; the client is told when to come back.
+	offPeak := s.Config.OffPeak != nil && (r.URL.Query().Get("offpeak") == "true" ||
+		(s.Config.OffPeakMinSize > 0 && r.ContentLength >= s.Config.OffPeakMinSize))
+	if wait := s.Bot.UntilOffPeak(); offPeak && wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		http.Error(w, fmt.Sprintf("Deferred uploads only run in the off-peak window %s", s.Config.OffPeak), http.StatusServiceUnavailable)
+		return nil, ""
+	}
 
 	for {
 		part, err := mr.NextPart()
 		if err == io.EOF {
 			break
 		}
+		if err != nil {
+			log.Printf("[SRV ERR] Upload stream broken: %v", err)
+			http.Error(w, "Upload stream interrupted", http.StatusBadRequest)
+			return nil, ""
+		}
 		if part.FormName() == "file" {
-			filename = part.FileName()
-			buffer := make([]byte, bot.ChunkSize)
-			partNum := 1
-
+			filename := part.FileName()
 			log.Printf("[SERVER] Receiving transmission: %s", filename)
+			file := s.storeUpload(w, r, uploadSource{
+				name:   filename,
+				body:   part,
+				policy: policy,
+				origin: "Web",
+			}, op)
+			if file == nil {
+				return nil, ""
+			}
+			log.Printf("[SERVER] Transmission complete: %s (ID: #%d, version %d)", file.Name, file.ID, file.Version)
+			return file, filename
+		}
+	}
+
+	http.Error(w, "Payload empty", http.StatusBadRequest)
+	return nil, ""
+}
+
+// uploadSource is a plaintext stream to store as one file.
+type uploadSource struct {
+	name   string
+	body   io.Reader
+	policy string // duplicate policy
+	origin string // where the upload came from, for notifications
+	// check, when set, vets the size and SHA-256 of the whole stream before
+	// the file is recorded. Its error is shown to the caller.
+	check func(size int64, hash string) error
+}
+
+// storeUpload encrypts src into chunks, sends them to Discord, and records
+// the file for the caller. On failure it writes the error response itself,
+// removes the chunks already sent, and returns nil. op may be nil for
+// untracked uploads.
+func (s *Server) storeUpload(w http.ResponseWriter, r *http.Request, src uploadSource, op *uploadOperation) *database.FileMetadata {
+	owner := principalFrom(r)
+	var totalSize int64
+	chunkSize := s.Bot.NextChunkSize()
+	pool := s.Bot.NewChunkPool(s.Config.ChannelID)
+	pool.ShardBy(src.name)
+	pool.DeltaFrom(database.DefaultVault, src.name, owner.ID)
+	hasher := sha256.New()
+
+	// Chunks already sent are removed again unless the metadata commits.
+	committed := false
+	defer func() {
+		if !committed {
+			go pool.Discard()
+		}
+	}()
+
+	buffer := make([]byte, chunkSize)
+	partNum := 1
+	for {
+		// Wait for room in the memory budget before buffering a chunk
+		if err := pool.Reserve(r.Context()); err != nil {
+			http.Error(w, "Upload cancelled", http.StatusServiceUnavailable)
+			return nil
+		}
+		n, err := io.ReadFull(src.body, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			log.Printf("[SRV ERR] Upload of %s interrupted at chunk %d: %v", src.name, partNum, err)
+			http.Error(w, "Upload stream interrupted", http.StatusBadRequest)
+			return nil
+		}
+		if n > 0 {
+			chunkData := buffer[:n]
+			totalSize += int64(n)
+			hasher.Write(chunkData)
+
+			// Parts the stored version already holds are not sent again
+			if pool.Reuse(chunkData) {
+				op.progress(src.name, totalSize)
+				partNum++
+				continue
+			}
 
-			for {
-				n, err := io.ReadFull(part, buffer)
-				if n > 0 {
-					chunkData := buffer[:n]
-					totalSize += int64(n)
-					hasher.Write(chunkData)
-
-					// Encrypt payload
-					encrypted, err := crypto.Encrypt(chunkData, s.Config.EncryptionKey)
-					if err != nil {
-						log.Printf("[SRV ERR] Encryption failed: %v", err)
-						http.Error(w, "Security fault", http.StatusInternalServerError)
-						return
-					}
-
-					// Sent to Discord storage
-					msg, err := s.Bot.Session.ChannelFileSend(s.Config.ChannelID, fmt.Sprintf("%x.vault", sha256.Sum256(encrypted)), bytes.NewReader(encrypted))
-					if err != nil {
-						log.Printf("[SRV ERR] Discord rejection at chunk %d: %v", partNum, err)
-						http.Error(w, "Decentralized storage rejection", http.StatusInternalServerError)
-						return
-					}
-
-					messageIDs = append(messageIDs, msg.ID)
-					log.Printf("[SERVER] Chunk %d secured (%d bytes)", partNum, len(encrypted))
-					partNum++
-
-					// Rate limit protection
-					time.Sleep(800 * time.Millisecond)
-				}
-				if err == io.EOF || err == io.ErrUnexpectedEOF {
-					break
-				}
+			// Encrypt payload
+			encrypted, err := crypto.Encrypt(chunkData, s.Config.EncryptionKey)
+			if err != nil {
+				log.Printf("[SRV ERR] Encryption failed: %v", err)
+				http.Error(w, "Security fault", http.StatusInternalServerError)
+				return nil
 			}
+
+			// Queue while Discord is in an incident
+			if err := s.Bot.Health.Wait(r.Context()); err != nil {
+				http.Error(w, "Upload cancelled during Discord outage", http.StatusServiceUnavailable)
+				return nil
+			}
+
+			// Sent to Discord storage alongside the chunks still in flight
+			err = pool.Submit(r.Context(), encrypted, func(chunk database.ChunkMetadata) {
+				log.Printf("[SERVER DEBUG] Chunk %d secured (%d bytes)", chunk.PartNum, chunk.Size)
+			})
+			if r.Context().Err() != nil {
+				http.Error(w, "Upload cancelled", http.StatusServiceUnavailable)
+				return nil
+			}
+			if err != nil {
+				log.Printf("[SRV ERR] Discord rejection during upload of %s: %v", src.name, err)
+				go s.Bot.NotifyError(src.origin, src.name, err)
+				http.Error(w, "Decentralized storage rejection", http.StatusInternalServerError)
+				return nil
+			}
+			op.progress(src.name, totalSize)
+			partNum++
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
 		}
 	}
 
-	if len(messageIDs) == 0 {
+	chunks, err := pool.Wait()
+	if err != nil {
+		log.Printf("[SRV ERR] Discord rejection during upload of %s: %v", src.name, err)
+		go s.Bot.NotifyError(src.origin, src.name, err)
+		http.Error(w, "Decentralized storage rejection", http.StatusInternalServerError)
+		return nil
+	}
+	if len(chunks) == 0 {
 		http.Error(w, "Payload empty", http.StatusBadRequest)
-		return
+		return nil
 	}
 
 	hashStr := hex.EncodeToString(hasher.Sum(nil))
-	fileID, err := s.DB.SaveFile(filename, totalSize, hashStr)
-	if err == nil {
-		for idx, msgID := range messageIDs {
-			s.DB.SaveChunk(fileID, msgID, idx+1)
+	if src.check != nil {
+		if err := src.check(totalSize, hashStr); err != nil {
+			log.Printf("[SRV ERR] Upload of %s rejected (%d bytes, %s): %v", src.name, totalSize, hashStr, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return nil
 		}
-		go s.Bot.NotifyUpload(filename, totalSize, len(messageIDs), "Web")
 	}
-
-	log.Printf("[SERVER] Transmission complete: %s (ID: #%d)", filename, fileID)
-	w.WriteHeader(http.StatusOK)
+	file, err := s.DB.SaveUpload(database.DefaultVault, src.name, totalSize, int64(chunkSize), hashStr, owner.ID, chunks, src.policy)
+	if errors.Is(err, database.ErrNameTaken) {
+		http.Error(w, "A file with that name already exists", http.StatusConflict)
+		return nil
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Metadata save failed: %v", err)
+		go s.Bot.NotifyError(src.origin, src.name, err)
+		http.Error(w, "Registry write failed", http.StatusInternalServerError)
+		return nil
+	}
+	committed = true
+	pool.Commit(file)
+	s.recordTransfer(owner, totalSize, 0)
+	go s.Bot.NotifyUpload(file, len(chunks), src.origin)
+	return file
 }
 
 func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
@@ -188,41 +647,83 @@ func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(vars["id"])
 
 	file, err := s.DB.GetFile(id)
-	if err != nil {
+	p := principalFrom(r)
+	if err != nil || !p.canManage(file) {
 		http.Error(w, "Object not found", http.StatusNotFound)
 		return
 	}
+	if !p.Admin && s.Bot.TransferCapReached(p.ID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return
+	}
 
-	chunks, _ := s.DB.GetChunks(id)
+	s.recordTransfer(p, 0, s.streamFile(w, r, file))
+}
 
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", file.Name))
+// streamFile fetches, decrypts, and writes every chunk of file to w in order.
+// It returns the number of plaintext bytes written.
+func (s *Server) streamFile(w http.ResponseWriter, r *http.Request, file *database.FileMetadata) int64 {
+	w.Header().Set("Content-Disposition", contentDisposition(s.Bot.DownloadName(file)))
 	w.Header().Set("Content-Type", "application/octet-stream")
+	if length, ok := s.plaintextLength(file); ok {
+		w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	}
 
 	log.Printf("[SERVER] Reconstructing object: %s", file.Name)
+	written, err := s.Bot.WriteFile(r.Context(), w, file)
+	if err != nil {
+		log.Printf("[SRV ERR] %v", err)
+		return written
+	}
+	log.Printf("[SERVER] Object %s successfully delivered.", file.Name)
+	return written
+}
 
-	for _, chunk := range chunks {
-		msg, err := s.Bot.Session.ChannelMessage(s.Config.ChannelID, chunk.MessageID)
-		if err != nil || len(msg.Attachments) == 0 {
-			log.Printf("[SRV ERR] Fragment missing: %d", chunk.PartNum)
-			continue
+// contentDisposition builds an attachment header that every browser reads
+// correctly: an ASCII filename for old clients plus the exact UTF-8 name as
+// an RFC 5987 filename* parameter.
+func contentDisposition(name string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
 		}
+		return r
+	}, name)
+	header := fmt.Sprintf("attachment; filename=\"%s\"", fallback)
+	if fallback != name {
+		header += "; filename*=UTF-8''" + rfc5987Escape(name)
+	}
+	return header
+}
 
-		resp, err := http.Get(msg.Attachments[0].URL)
-		if err != nil {
-			log.Printf("[SRV ERR] Fragment fetch failed: %v", err)
-			continue
+// rfc5987Escape percent-encodes everything except RFC 5987 attr-chars.
+func rfc5987Escape(s string) string {
+	var sb strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			strings.IndexByte("!#$&+-.^_`|~", c) >= 0:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
 		}
+	}
+	return sb.String()
+}
 
-		encrypted, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-
-		decrypted, err := crypto.Decrypt(encrypted, s.Config.EncryptionKey)
-		if err != nil {
-			log.Printf("[SRV ERR] Decryption fault at chunk %d: %v", chunk.PartNum, err)
-			return
+// plaintextLength derives the download size from the recorded chunk sizes.
+// It reports false for files uploaded before chunk sizes were stored.
+func (s *Server) plaintextLength(file *database.FileMetadata) (int64, bool) {
+	chunks, err := s.DB.GetChunks(file.ID)
+	if err != nil || len(chunks) == 0 {
+		return 0, false
+	}
+	var total int64
+	for _, c := range chunks {
+		if c.Size == 0 {
+			return 0, false
 		}
-
-		w.Write(decrypted)
+		total += c.Size - crypto.NonceSize - crypto.TagSize
 	}
-	log.Printf("[SERVER] Object %s successfully delivered.", file.Name)
+	return total, total == file.Size
 }
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"discordvault/internal/sharelink"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type createShareRequest struct {
+	FileID         int    `json:"file_id"`
+	ExpiresInHours int    `json:"expires_in_hours"`
+	NotifyOwner    bool   `json:"notify_owner"`
+	EmailTo        string `json:"email_to"` // optional recipient of the link
+}
+
+type shareResponse struct {
+	Token       string     `json:"token"`
+	URL         string     `json:"url"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	PayloadHash string     `json:"payload_hash"`
+	Signature   string     `json:"signature"`
+}
+
+func (s *Server) handleCreateShare(w http.ResponseWriter, r *http.Request) {
+	var req createShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	file, err := s.DB.GetFile(req.FileID)
+	if err != nil || !principalFrom(r).canManage(file) {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	if req.EmailTo != "" {
+		if s.Bot.Mailer == nil {
+			http.Error(w, "Email delivery is not configured", http.StatusBadRequest)
+			return
+		}
+		addr, err := mail.ParseAddress(req.EmailTo)
+		if err != nil {
+			http.Error(w, "Invalid email_to address", http.StatusBadRequest)
+			return
+		}
+		req.EmailTo = addr.Address
+	}
+
+	share, payloadHash, err := sharelink.Create(s.DB, s.Signer, file, principalFrom(r).ID,
+		time.Duration(req.ExpiresInHours)*time.Hour, req.NotifyOwner)
+	if err != nil {
+		log.Printf("[SRV ERR] Share creation failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[SERVER] Share link issued for File ID %d", file.ID)
+	url := sharelink.URL(s.Config.PublicURL, share.Token)
+	if req.EmailTo != "" {
+		go func() {
+			if err := s.Bot.EmailShare(req.EmailTo, file, url, share.ExpiresAt); err != nil {
+				log.Printf("[SRV ERR] Share email for File ID %d failed: %v", file.ID, err)
+			}
+		}()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shareResponse{
+		Token:       share.Token,
+		URL:         url,
+		ExpiresAt:   share.ExpiresAt,
+		PayloadHash: payloadHash,
+		Signature:   share.Signature,
+	})
+}
+
+func (s *Server) handleShareDownload(w http.ResponseWriter, r *http.Request) {
+	share, err := s.DB.GetShareByToken(mux.Vars(r)["token"])
+	if err != nil {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+	if share.ExpiresAt != nil && time.Now().After(*share.ExpiresAt) {
+		http.Error(w, "Link expired", http.StatusGone)
+		return
+	}
+
+	file, err := s.DB.GetFile(share.FileID)
+	if err != nil {
+		http.Error(w, "Object not found", http.StatusNotFound)
+		return
+	}
+
+	// Public downloads count against the owner's transfer budget.
+	if !s.Config.IsAdmin(file.OwnerID) && s.Bot.TransferCapReached(file.OwnerID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return
+	}
+
+	s.DB.RecordShareDownload(share.ID)
+	if share.NotifyOwner {
+		go s.Bot.NotifyShareDownload(file, time.Now(), truncateIP(r.RemoteAddr), truncateUserAgent(r.UserAgent()))
+	}
+
+	s.recordTransfer(Principal{ID: file.OwnerID}, 0, s.streamFile(w, r, file))
+}
+
+// truncateIP keeps only the network part of the address (/24 for IPv4,
+// /48 for IPv6) so notices identify a region without exposing the client.
+func truncateIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "unknown"
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
+
+func truncateUserAgent(ua string) string {
+	const max = 60
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return "unknown"
+	}
+	if r := []rune(ua); len(r) > max {
+		return string(r[:max]) + "…"
+	}
+	return ua
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"discordvault/internal/database"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// statsResponse adds this month's transfer usage of every user and key to
+// the vault totals.
+type statsResponse struct {
+	*database.VaultStats
+	Transfer usageResponse `json:"transfer"`
+}
+
+// handleStats reports vault growth: totals, uploads per day for ?days= (default
+// 30), the ?top= (default 10) largest files, and storage per owner. It covers
+// every vault unless ?guild= names one (empty for the default vault).
+// Transfer usage is always for the whole server.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if err != nil || days <= 0 || days > 365 {
+		days = 30
+	}
+	top, err := strconv.Atoi(r.URL.Query().Get("top"))
+	if err != nil || top <= 0 || top > 100 {
+		top = 10
+	}
+
+	vault := database.AllVaults
+	if q := r.URL.Query(); q.Has("guild") {
+		vault = q.Get("guild")
+	}
+	stats, err := s.DB.Stats(vault, days, top)
+	if err != nil {
+		log.Printf("[SRV ERR] Stats failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	period := database.UsagePeriod(time.Now())
+	usage, err := s.DB.ListTransfer(period)
+	if err != nil {
+		log.Printf("[SRV ERR] Usage lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statsResponse{stats, usageResponse{Period: period, Cap: s.Config.Live().TransferCap, Usage: usage}})
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"discordvault/internal/database"
+	"discordvault/internal/qr"
+	"discordvault/internal/totp"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// totpIssuer names the vault in authenticator apps.
+const totpIssuer = "DiscordVault"
+
+// recoveryCodeCount is how many recovery codes a user gets at a time.
+const recoveryCodeCount = 10
+
+const (
+	// totpFreeAttempts is how many wrong two-factor codes in a row a user
+	// may send before being locked out for a while.
+	totpFreeAttempts = 5
+	totpMaxLockout   = time.Hour
+)
+
+type totpCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// newRecoveryCodes returns fresh recovery codes, like "k3m9x-p2q7w", and
+// the hashes to store.
+func newRecoveryCodes() (codes, hashes []string) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for range recoveryCodeCount {
+		b := make([]byte, 7)
+		rand.Read(b)
+		code := strings.ToLower(enc.EncodeToString(b))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes
+}
+
+// hashRecoveryCode hashes a recovery code as typed, ignoring case, dashes,
+// and spaces.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkSecondFactor accepts a current code from the authenticator app,
+// each at most once, or an unused recovery code, which it uses up.
+func (s *Server) checkSecondFactor(t *database.TOTP, code string) (bool, error) {
+	if step, ok := totp.Verify(t.Secret, code, time.Now(), t.LastStep); ok {
+		return s.DB.UseTOTPStep(t.UserID, step)
+	}
+	return s.DB.UseRecoveryCode(t.UserID, hashRecoveryCode(code))
+}
+
+// secondFactor checks a code like checkSecondFactor and answers the request
+// itself unless the code is right, with invalidStatus for a wrong one.
+// After totpFreeAttempts wrong codes in a row the user is locked out for a
+// minute, twice as long after each further one up to totpMaxLockout, and
+// codes sent meanwhile are refused without being checked.
+func (s *Server) secondFactor(w http.ResponseWriter, r *http.Request, t *database.TOTP, code string, invalidStatus int) bool {
+	if t.LockedUntil != nil && time.Now().Before(*t.LockedUntil) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(*t.LockedUntil).Seconds())+1))
+		http.Error(w, "Too many wrong two-factor codes, try again later", http.StatusTooManyRequests)
+		return false
+	}
+	ok, err := s.checkSecondFactor(t, code)
+	if err == nil && ok {
+		err = s.DB.ResetTOTPFailures(t.UserID)
+	} else if err == nil {
+		var failures int
+		if failures, err = s.DB.RecordTOTPFailure(t.UserID); err == nil && failures >= totpFreeAttempts {
+			lock := min(time.Minute<<min(failures-totpFreeAttempts, 10), totpMaxLockout)
+			err = s.DB.LockTOTP(t.UserID, time.Now().Add(lock))
+			log.Printf("[SRV WARN] Two-factor codes of %s locked for %v after %d wrong ones", t.UserID, lock, failures)
+		}
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Two-factor check failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		log.Printf("[SRV WARN] Rejected two-factor code of %s from %s on %s", t.UserID, r.RemoteAddr, r.URL.Path)
+		http.Error(w, "Invalid two-factor code", invalidStatus)
+	}
+	return ok
+}
+
+// enabledTOTP returns the caller's two-factor secret if it is enabled, or
+// answers the request itself.
+func (s *Server) enabledTOTP(w http.ResponseWriter, userID string) *database.TOTP {
+	t, err := s.DB.GetTOTP(userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !t.Enabled) {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return nil
+	} else if err != nil {
+		log.Printf("[SRV ERR] GetTOTP failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil
+	}
+	return t
+}
+
+// handleTOTPStatus tells whether the caller has two-factor authentication
+// and how many recovery codes are left.
+func (s *Server) handleTOTPStatus(w http.ResponseWriter, r *http.Request) {
+	p := principalFrom(r)
+	t, err := s.DB.GetTOTP(p.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("[SRV ERR] GetTOTP failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	left, err := s.DB.RecoveryCodesLeft(p.ID)
+	if err != nil {
+		log.Printf("[SRV ERR] RecoveryCodesLeft failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"enabled":             t != nil && t.Enabled,
+		"recovery_codes_left": left,
+	})
+}
+
+// handleEnrollTOTP starts enrollment with a new secret, shown as text, as
+// an otpauth:// URI, and as a QR code. It only takes effect once a code
+// from it is confirmed.
+func (s *Server) handleEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	p := principalFrom(r)
+	if t, err := s.DB.GetTOTP(p.ID); err == nil && t.Enabled {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("[SRV ERR] GetTOTP failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	secret := totp.NewSecret()
+	if err := s.DB.SetPendingTOTP(p.ID, secret); err != nil {
+		log.Printf("[SRV ERR] Two-factor enrollment failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	uri := totp.URI(totpIssuer, p.ID, secret)
+	resp := map[string]string{"secret": secret, "uri": uri}
+	if code, err := qr.Encode(uri); err == nil {
+		if png, err := code.PNG(4); err == nil {
+			resp["qr"] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleConfirmTOTP enables two-factor authentication with the first code
+// of the pending secret and returns the recovery codes, the only time they
+// are shown.
+func (s *Server) handleConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var req totpCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	p := principalFrom(r)
+	t, err := s.DB.GetTOTP(p.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Start enrollment first", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("[SRV ERR] GetTOTP failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if t.Enabled {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	step, ok := totp.Verify(t.Secret, req.Code, time.Now(), t.LastStep)
+	if !ok {
+		http.Error(w, "Invalid two-factor code", http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes := newRecoveryCodes()
+	if err := s.DB.EnableTOTP(p.ID, step, hashes); err != nil {
+		log.Printf("[SRV ERR] Enabling two-factor authentication failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[SERVER] %s enabled two-factor authentication", p.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"recovery_codes": codes})
+}
+
+// handleRegenerateRecoveryCodes replaces the caller's recovery codes after
+// checking a current code.
+func (s *Server) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req totpCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	p := principalFrom(r)
+	t := s.enabledTOTP(w, p.ID)
+	if t == nil {
+		return
+	}
+	if !s.secondFactor(w, r, t, req.Code, http.StatusBadRequest) {
+		return
+	}
+
+	codes, hashes := newRecoveryCodes()
+	if err := s.DB.ReplaceRecoveryCodes(p.ID, hashes); err != nil {
+		log.Printf("[SRV ERR] Replacing recovery codes failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[SERVER] %s replaced their recovery codes", p.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"recovery_codes": codes})
+}
+
+// handleDisableTOTP turns the caller's two-factor authentication off after
+// checking a current code or a recovery code.
+func (s *Server) handleDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	var req totpCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	p := principalFrom(r)
+	t := s.enabledTOTP(w, p.ID)
+	if t == nil {
+		return
+	}
+	if !s.secondFactor(w, r, t, req.Code, http.StatusBadRequest) {
+		return
+	}
+	s.disableTOTP(w, p.ID, p.ID)
+}
+
+// handleResetTOTP lets an admin turn off two-factor authentication for a
+// user who lost both their device and their recovery codes.
+func (s *Server) handleResetTOTP(w http.ResponseWriter, r *http.Request) {
+	user := mux.Vars(r)["user"]
+	if s.enabledTOTP(w, user) == nil {
+		return
+	}
+	s.disableTOTP(w, user, principalFrom(r).ID)
+}
+
+func (s *Server) disableTOTP(w http.ResponseWriter, user, by string) {
+	if err := s.DB.DisableTOTP(user); err != nil {
+		log.Printf("[SRV ERR] Disabling two-factor authentication failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[SERVER] %s disabled two-factor authentication of %s", by, user)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,404 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"discordvault/internal/config"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Resumable uploads: the client announces a file with POST
+// /api/upload/sessions and sends its bytes with PUT, starting at the offset
+// the server reports in Upload-Offset. Only whole chunks are kept, so after
+// an interruption GET returns where to continue, even across restarts.
+
+type createUploadSessionRequest struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	OnDuplicate string `json:"on_duplicate"`
+}
+
+type uploadSessionResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	Offset    int64  `json:"offset"`
+	ChunkSize int64  `json:"chunk_size"`
+}
+
+func (s *Server) handleCreateUploadSession(w http.ResponseWriter, r *http.Request) {
+	session := s.createUploadSession(w, r, false)
+	if session == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Upload-Offset", "0")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sessionResponse(session, 0))
+}
+
+// createUploadSession opens a session for the file announced in the request
+// body, or writes an error and returns nil.
+func (s *Server) createUploadSession(w http.ResponseWriter, r *http.Request, chunked bool) *database.UploadSession {
+	p := principalFrom(r)
+	if !p.Admin && s.Bot.TransferCapReached(p.ID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return nil
+	}
+
+	var req createUploadSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil
+	}
+	if req.Name == "" || req.Size <= 0 {
+		http.Error(w, "name and a positive size are required", http.StatusBadRequest)
+		return nil
+	}
+	policy := s.Config.DuplicatePolicy
+	if req.OnDuplicate != "" {
+		if !config.ValidDuplicatePolicy(req.OnDuplicate) {
+			http.Error(w, "on_duplicate must be suffix, version, or reject", http.StatusBadRequest)
+			return nil
+		}
+		policy = req.OnDuplicate
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		http.Error(w, "Session ID generation failed", http.StatusInternalServerError)
+		return nil
+	}
+	session := &database.UploadSession{
+		ID:        hex.EncodeToString(idBytes),
+		Principal: p.ID,
+		Name:      req.Name,
+		Size:      req.Size,
+		ChunkSize: int64(s.Bot.NextChunkSize()),
+		Policy:    policy,
+		Chunked:   chunked,
+	}
+	if err := s.DB.CreateUploadSession(session); err != nil {
+		log.Printf("[SRV ERR] Upload session creation failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil
+	}
+
+	log.Printf("[SERVER] Upload session %s opened for %s (%d bytes)", session.ID, session.Name, session.Size)
+	return session
+}
+
+func (s *Server) handleGetUploadSession(w http.ResponseWriter, r *http.Request) {
+	session := s.uploadSession(w, r, false)
+	if session == nil {
+		return
+	}
+	stored, err := s.DB.UploadSessionChunks(session.ID)
+	if err != nil {
+		log.Printf("[SRV ERR] Upload session lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	offset := sessionOffset(stored[:storedRun(stored)])
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	json.NewEncoder(w).Encode(sessionResponse(session, offset))
+}
+
+// handleResumeUpload stores the request body from the session's offset on.
+// Once the last byte arrives the file is saved and returned; until then the
+// response is the session with its new offset.
+func (s *Server) handleResumeUpload(w http.ResponseWriter, r *http.Request) {
+	session := s.uploadSession(w, r, false)
+	if session == nil {
+		return
+	}
+	p := principalFrom(r)
+	if !p.Admin && s.Bot.TransferCapReached(p.ID) {
+		http.Error(w, "Monthly transfer cap reached", http.StatusTooManyRequests)
+		return
+	}
+	if _, busy := s.sessions.LoadOrStore(session.ID, struct{}{}); busy {
+		http.Error(w, "Upload session is already receiving data", http.StatusConflict)
+		return
+	}
+	defer s.sessions.Delete(session.ID)
+
+	// Chunks after a gap were posted while an earlier one failed; they are
+	// sent again from the gap on.
+	stored, err := s.DB.UploadSessionChunks(session.ID)
+	if err != nil {
+		log.Printf("[SRV ERR] Upload session lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if run := storedRun(stored); run < len(stored) {
+		if err := s.DB.TruncateUploadSession(session.ID, run); err != nil {
+			log.Printf("[SRV ERR] Upload session truncation failed: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		go s.discardChunks(sessionChunks(stored[run:]))
+		stored = stored[:run]
+	}
+
+	offset := sessionOffset(stored)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	if r.Header.Get("Upload-Offset") != strconv.FormatInt(offset, 10) {
+		http.Error(w, "Upload-Offset does not match the session; resume from "+strconv.FormatInt(offset, 10), http.StatusConflict)
+		return
+	}
+
+	hasher := sha256.New()
+	if len(stored) > 0 {
+		if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(stored[len(stored)-1].HashState); err != nil {
+			log.Printf("[SRV ERR] Upload session %s has a corrupt hash state: %v", session.ID, err)
+			http.Error(w, "Upload session is corrupt", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// A session's chunks arrive over several requests, so it is stored
+	// without parity.
+	pool := s.Bot.NewChunkPool(s.Config.ChannelID)
+	pool.SkipParity()
+	pool.ShardBy(session.Name)
+	body := io.LimitReader(r.Body, session.Size-offset)
+	buffer := make([]byte, session.ChunkSize)
+	received := offset
+	var streamErr error
+	for received < session.Size {
+		if err := pool.Reserve(r.Context()); err != nil {
+			streamErr = err
+			break
+		}
+		n, err := io.ReadFull(body, buffer)
+		// A partial chunk is only kept when it ends the file.
+		if n == len(buffer) || (n > 0 && received+int64(n) == session.Size) {
+			hasher.Write(buffer[:n])
+			state, err := hasher.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				streamErr = err
+				break
+			}
+			encrypted, err := crypto.Encrypt(buffer[:n], s.Config.EncryptionKey)
+			if err != nil {
+				streamErr = err
+				break
+			}
+			if err := s.Bot.Health.Wait(r.Context()); err != nil {
+				streamErr = err
+				break
+			}
+
+			plainSize, base := int64(n), len(stored)
+			err = pool.Submit(r.Context(), encrypted, func(chunk database.ChunkMetadata) {
+				chunk.PartNum += base
+				c := database.SessionChunk{ChunkMetadata: chunk, PlainSize: plainSize, HashState: state}
+				if err := s.DB.AddUploadSessionChunk(session.ID, c); err != nil {
+					log.Printf("[SRV ERR] Upload session %s could not record chunk %d: %v", session.ID, chunk.PartNum, err)
+				}
+			})
+			if err != nil {
+				streamErr = err
+				break
+			}
+			received += plainSize
+		}
+		if err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				streamErr = err
+			}
+			break
+		}
+	}
+
+	_, err = pool.Wait()
+	if err == nil {
+		err = streamErr
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Upload session %s interrupted: %v", session.ID, err)
+		w.Header().Del("Upload-Offset")
+		if r.Context().Err() == nil {
+			http.Error(w, "Upload interrupted; resume from the session offset", http.StatusServiceUnavailable)
+		}
+		return
+	}
+	s.recordTransfer(p, received-offset, 0)
+
+	if received < session.Size {
+		log.Printf("[SERVER] Upload session %s at %d of %d bytes", session.ID, received, session.Size)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Upload-Offset", strconv.FormatInt(received, 10))
+		json.NewEncoder(w).Encode(sessionResponse(session, received))
+		return
+	}
+
+	all, err := s.DB.UploadSessionChunks(session.ID)
+	if err != nil || storedRun(all) != len(all) || sessionOffset(all) != session.Size {
+		log.Printf("[SRV ERR] Upload session %s is missing chunks after the last byte (err: %v)", session.ID, err)
+		http.Error(w, "Upload session is incomplete; resume from the session offset", http.StatusInternalServerError)
+		return
+	}
+	s.saveUploadSession(w, session, all, hasher.Sum(nil))
+}
+
+// saveUploadSession turns a session holding every chunk of its file into
+// the file, closes the session, and responds with the file.
+func (s *Server) saveUploadSession(w http.ResponseWriter, session *database.UploadSession, stored []database.SessionChunk, hash []byte) {
+	chunks := sessionChunks(stored)
+	file, err := s.DB.SaveUpload(database.DefaultVault, session.Name, session.Size, session.ChunkSize, hex.EncodeToString(hash), session.Principal, chunks, session.Policy)
+	if errors.Is(err, database.ErrNameTaken) {
+		http.Error(w, "A file with that name already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Metadata save failed: %v", err)
+		go s.Bot.NotifyError("Web", session.Name, err)
+		http.Error(w, "Registry write failed", http.StatusInternalServerError)
+		return
+	}
+	if err := s.DB.DeleteUploadSession(session.ID); err != nil {
+		log.Printf("[SRV ERR] Upload session %s could not be closed: %v", session.ID, err)
+	}
+	go s.Bot.NotifyUpload(file, len(chunks), "Web")
+
+	log.Printf("[SERVER] Transmission complete: %s (ID: #%d, version %d, session %s)", file.Name, file.ID, file.Version, session.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(file)
+}
+
+// handleDeleteUploadSession abandons a session and deletes its chunks.
+func (s *Server) handleDeleteUploadSession(w http.ResponseWriter, r *http.Request) {
+	session := s.uploadSession(w, r, false)
+	if session == nil {
+		return
+	}
+	s.abandonUploadSession(w, session)
+}
+
+func (s *Server) abandonUploadSession(w http.ResponseWriter, session *database.UploadSession) {
+	if _, busy := s.sessions.Load(session.ID); busy {
+		http.Error(w, "Upload session is receiving data", http.StatusConflict)
+		return
+	}
+	stored, err := s.DB.UploadSessionChunks(session.ID)
+	if err == nil {
+		err = s.DB.DeleteUploadSession(session.ID)
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Upload session delete failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	go s.discardChunks(sessionChunks(stored))
+	log.Printf("[SERVER] Upload session %s abandoned", session.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// uploadSession returns the session named in the route if the caller owns
+// it and it is of the API asking, chunked or streamed, or writes a 404.
+func (s *Server) uploadSession(w http.ResponseWriter, r *http.Request, chunked bool) *database.UploadSession {
+	p := principalFrom(r)
+	session, err := s.DB.GetUploadSession(mux.Vars(r)["id"])
+	if err != nil || (!p.Admin && session.Principal != p.ID) || session.Chunked != chunked {
+		http.Error(w, "Upload session not found", http.StatusNotFound)
+		return nil
+	}
+	return session
+}
+
+// ExpireUploadSessions abandons sessions idle for longer than
+// UPLOAD_SESSION_TTL and deletes the chunks they stored.
+func (s *Server) ExpireUploadSessions(ctx context.Context) error {
+	chunks, err := s.DB.ExpireUploadSessions(time.Now().Add(-s.Config.UploadSessionTTL))
+	if err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	// A session whose file was saved just before a crash shares its chunks
+	// with that file.
+	ids := make([]string, len(chunks))
+	for i, c := range chunks {
+		ids[i] = c.MessageID
+	}
+	known, err := s.DB.KnownMessageIDs(ids)
+	if err != nil {
+		return err
+	}
+	var orphans []database.ChunkMetadata
+	for _, c := range chunks {
+		if !known[c.MessageID] {
+			orphans = append(orphans, c)
+		}
+	}
+	log.Printf("[SERVER] Expired upload sessions holding %d chunks", len(chunks))
+	s.discardChunks(orphans)
+	return nil
+}
+
+// discardChunks deletes chunk messages that no file references, per
+// storage channel.
+func (s *Server) discardChunks(chunks []database.ChunkMetadata) {
+	byChannel := make(map[string][]string)
+	for _, c := range chunks {
+		channelID := s.Bot.ChunkChannel(c)
+		byChannel[channelID] = append(byChannel[channelID], c.MessageID)
+	}
+	for channelID, ids := range byChannel {
+		s.Bot.DiscardChunks(channelID, ids)
+	}
+}
+
+func sessionResponse(session *database.UploadSession, offset int64) uploadSessionResponse {
+	return uploadSessionResponse{
+		ID:        session.ID,
+		Name:      session.Name,
+		Size:      session.Size,
+		Offset:    offset,
+		ChunkSize: session.ChunkSize,
+	}
+}
+
+// storedRun counts the session's chunks that follow each other from part 1.
+func storedRun(stored []database.SessionChunk) int {
+	n := 0
+	for n < len(stored) && stored[n].PartNum == n+1 {
+		n++
+	}
+	return n
+}
+
+// sessionOffset is the number of file bytes the chunks hold.
+func sessionOffset(stored []database.SessionChunk) int64 {
+	var offset int64
+	for _, c := range stored {
+		offset += c.PlainSize
+	}
+	return offset
+}
+
+func sessionChunks(stored []database.SessionChunk) []database.ChunkMetadata {
+	chunks := make([]database.ChunkMetadata, len(stored))
+	for i, c := range stored {
+		chunks[i] = c.ChunkMetadata
+	}
+	return chunks
+}
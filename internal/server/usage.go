@@ -0,0 +1,63 @@
+package server
+
+import (
+	"discordvault/internal/database"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+type usageResponse struct {
+	Period string                   `json:"period"`
+	Cap    int64                    `json:"cap,omitempty"`
+	Usage  []database.TransferUsage `json:"usage"`
+}
+
+// recordTransfer books transferred bytes against the caller's user and, when
+// one was used, API key.
+func (s *Server) recordTransfer(p Principal, uploaded, downloaded int64) {
+	if uploaded == 0 && downloaded == 0 {
+		return
+	}
+	if err := s.DB.RecordTransfer(database.UsageUser, p.ID, uploaded, downloaded); err != nil {
+		log.Printf("[SRV ERR] Transfer accounting failed: %v", err)
+	}
+	if p.KeyID != "" {
+		if err := s.DB.RecordTransfer(database.UsageKey, p.KeyID, uploaded, downloaded); err != nil {
+			log.Printf("[SRV ERR] Transfer accounting failed: %v", err)
+		}
+	}
+}
+
+// handleUsage reports transfer usage for ?period=YYYY-MM (default: this
+// month). Admins see every user and key; others only themselves.
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = database.UsagePeriod(time.Now())
+	} else if _, err := time.Parse("2006-01", period); err != nil {
+		http.Error(w, "period must look like 2024-05", http.StatusBadRequest)
+		return
+	}
+
+	p := principalFrom(r)
+	resp := usageResponse{Period: period, Cap: s.Config.Live().TransferCap}
+	var err error
+	if p.Admin {
+		resp.Usage, err = s.DB.ListTransfer(period)
+	} else {
+		var own *database.TransferUsage
+		if own, err = s.DB.GetTransfer(period, database.UsageUser, p.ID); err == nil {
+			resp.Usage = []database.TransferUsage{*own}
+		}
+	}
+	if err != nil {
+		log.Printf("[SRV ERR] Usage lookup failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"discordvault/internal/database"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type webSessionResponse struct {
+	database.WebSession
+	Current bool `json:"current"`
+}
+
+func (s *Server) writeWebSessions(w http.ResponseWriter, r *http.Request, userID string) {
+	idle, age := s.sessionCutoffs()
+	sessions, err := s.DB.ListWebSessions(userID, idle, age)
+	if err != nil {
+		log.Printf("[SRV ERR] ListWebSessions failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	current := principalFrom(r).SessionID
+	list := make([]webSessionResponse, len(sessions))
+	for i, ws := range sessions {
+		list[i] = webSessionResponse{WebSession: ws, Current: ws.ID == current}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleListWebSessions lists the caller's active web logins. current marks
+// the one the request came from.
+func (s *Server) handleListWebSessions(w http.ResponseWriter, r *http.Request) {
+	s.writeWebSessions(w, r, principalFrom(r).ID)
+}
+
+// handleListAllWebSessions lists every active web login, or one user's
+// with ?user=.
+func (s *Server) handleListAllWebSessions(w http.ResponseWriter, r *http.Request) {
+	s.writeWebSessions(w, r, r.URL.Query().Get("user"))
+}
+
+// handleRevokeWebSession ends one of the caller's sessions, or any session
+// for an admin.
+func (s *Server) handleRevokeWebSession(w http.ResponseWriter, r *http.Request) {
+	p := principalFrom(r)
+	ws, err := s.DB.GetWebSession(mux.Vars(r)["id"])
+	if err != nil || !(p.Admin || ws.UserID == p.ID) {
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("[SRV ERR] GetWebSession failed: %v", err)
+		}
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if err := s.DB.DeleteWebSession(ws.ID); err != nil {
+		log.Printf("[SRV ERR] Session revocation failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[SERVER] %s ended session %s of %s", p.ID, ws.ID, ws.UserID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleLogoutEverywhere ends every session of the caller, the current one
+// included.
+func (s *Server) handleLogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	clearSessionCookie(w)
+	s.endUserSessions(w, principalFrom(r).ID, principalFrom(r).ID)
+}
+
+// handleRevokeUserWebSessions lets an admin end every session of the user
+// named by ?user=.
+func (s *Server) handleRevokeUserWebSessions(w http.ResponseWriter, r *http.Request) {
+	user := r.URL.Query().Get("user")
+	if user == "" {
+		http.Error(w, "user is required", http.StatusBadRequest)
+		return
+	}
+	s.endUserSessions(w, user, principalFrom(r).ID)
+}
+
+func (s *Server) endUserSessions(w http.ResponseWriter, user, by string) {
+	n, err := s.DB.DeleteUserWebSessions(user)
+	if err != nil {
+		log.Printf("[SRV ERR] Ending sessions failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[SERVER] %s ended %d sessions of %s", by, n, user)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"ended": n})
+}
+
+// ExpireWebSessions drops sessions past SESSION_IDLE_TIMEOUT or
+// SESSION_MAX_AGE.
+func (s *Server) ExpireWebSessions(ctx context.Context) error {
+	idle, age := s.sessionCutoffs()
+	n, err := s.DB.ExpireWebSessions(idle, age)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("[SERVER] Expired %d web sessions", n)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Package sharelink issues signed public share links. The web API, the bot's
+// /share command, and the share CLI all go through it.
+package sharelink
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Create stores a new share for file, signed by the vault identity key, and
+// returns it with its payload hash. A zero expiresIn never expires.
+func Create(db *database.Database, signer *crypto.Signer, file *database.FileMetadata, createdBy string, expiresIn time.Duration, notifyOwner bool) (*database.Share, string, error) {
+	tokenBytes := make([]byte, 24)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, "", fmt.Errorf("token generation failed: %w", err)
+	}
+
+	share := &database.Share{
+		Token:       hex.EncodeToString(tokenBytes),
+		FileID:      file.ID,
+		CreatedBy:   createdBy,
+		NotifyOwner: notifyOwner,
+	}
+	if expiresIn > 0 {
+		expires := time.Now().UTC().Add(expiresIn)
+		share.ExpiresAt = &expires
+	}
+
+	payloadHash := PayloadHash(share, file)
+	share.Signature = signer.Sign([]byte(payloadHash))
+
+	id, err := db.CreateShare(share)
+	if err != nil {
+		return nil, "", err
+	}
+	share.ID = id
+	return share, payloadHash, nil
+}
+
+// PayloadHash binds a share token to the exact file content and expiry.
+func PayloadHash(share *database.Share, file *database.FileMetadata) string {
+	expires := ""
+	if share.ExpiresAt != nil {
+		expires = share.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%d\n%s\n%s", share.Token, file.ID, file.Hash, expires)))
+	return hex.EncodeToString(sum[:])
+}
+
+// URL returns the public download link of a share token.
+func URL(publicURL, token string) string {
+	return publicURL + "/s/" + token
+}
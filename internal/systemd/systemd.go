@@ -0,0 +1,67 @@
+// Package systemd talks to systemd when the vault runs as a Type=notify
+// service: it reports readiness and pings the watchdog, following
+// sd_notify(3). Outside systemd every call does nothing.
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notify sends state, such as "READY=1", to the service manager. It does
+// nothing when NOTIFY_SOCKET is not set.
+func Notify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:] // abstract socket
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval returns how often the watchdog should be pinged, half of
+// the service's WatchdogSec, or 0 when the watchdog is off or meant for
+// another process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// Watchdog pings the watchdog every interval while alive reports true, so
+// systemd restarts the service once alive stays false past WatchdogSec. It
+// returns when ctx is done, or at once when the watchdog is off.
+func Watchdog(ctx context.Context, alive func() bool) {
+	interval := WatchdogInterval()
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if alive() {
+				Notify("WATCHDOG=1")
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// secret is the SHA-1 seed of RFC 6238 appendix B, "12345678901234567890",
+// in base32.
+const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// The last six digits of the RFC's eight-digit codes.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+		if err != nil || got != tt.want {
+			t.Errorf("Code at %d = %q, %v; want %q", tt.unix, got, err, tt.want)
+		}
+	}
+}
+
+func TestCodeAcceptsLowerCaseAndPadding(t *testing.T) {
+	got, err := Code("gezdgnbvgy3tqojqgezdgnbvgy3tqojq====", 1)
+	if err != nil || got != "287082" {
+		t.Errorf("Code = %q, %v; want 287082", got, err)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := Step(now)
+	code := func(step int64) string {
+		c, err := Code(secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	for _, s := range []int64{step - 1, step, step + 1} {
+		if got, ok := Verify(secret, code(s), now, 0); !ok || got != s {
+			t.Errorf("Verify(step %+d) = %d, %v; want %d, true", s-step, got, ok, s)
+		}
+	}
+	if _, ok := Verify(secret, code(step+2), now, 0); ok {
+		t.Error("Verify accepted a code two steps ahead")
+	}
+	if _, ok := Verify(secret, code(step), now, step); ok {
+		t.Error("Verify accepted a code that was already used")
+	}
+	if _, ok := Verify(secret, "081 804", now, 0); !ok {
+		t.Error("Verify refused a code with a space")
+	}
+}
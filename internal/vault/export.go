@@ -0,0 +1,266 @@
+package vault
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"discordvault/internal/database"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ExportStateFile is written into the export directory and lists the files
+// exported so far, so an interrupted export resumes where it stopped.
+const ExportStateFile = ".vault-export"
+
+// ExportFile is one file of an export and where it is written.
+type ExportFile struct {
+	ID   int
+	Path string // slash-separated, below the export directory
+	Size int64
+	Hash string // hex SHA-256 of the contents, "" if unknown
+}
+
+// Exportable is what an export reads: a Service, or a vault server's API.
+type Exportable interface {
+	ExportFiles() ([]ExportFile, error)
+	OpenFile(ctx context.Context, id int) (io.ReadCloser, error)
+}
+
+// ExportProgress counts the files an export has handled.
+type ExportProgress struct {
+	Files    int   `json:"files"`
+	Exported int   `json:"exported"`
+	Skipped  int   `json:"skipped"` // exported by an earlier run and unchanged
+	Failed   int   `json:"failed"`
+	Bytes    int64 `json:"bytes"`
+}
+
+// exportRecord is one line of the state file.
+type exportRecord struct {
+	ID   int    `json:"id"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	Hash string `json:"hash"`
+}
+
+// ExportPaths places files below folder directories named like the vault's
+// folders. Names that cannot be used as is on disk have their slashes and
+// control characters replaced, and a name taken by a file with a lower ID
+// gets the file's ID appended, so the layout is the same on every run.
+func ExportPaths(files []database.FileMetadata, folders []database.Folder) []ExportFile {
+	byID := make(map[int]database.Folder, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+	dirs := make(map[int]string)
+	var dirOf func(id int, depth int) string
+	dirOf = func(id int, depth int) string {
+		folder, ok := byID[id]
+		if !ok || depth > len(folders) {
+			return ""
+		}
+		if dir, ok := dirs[id]; ok {
+			return dir
+		}
+		dir := path.Join(dirOf(folder.ParentID, depth+1), exportName(folder.Name))
+		dirs[id] = dir
+		return dir
+	}
+
+	sorted := slices.Clone(files)
+	slices.SortFunc(sorted, func(a, b database.FileMetadata) int { return a.ID - b.ID })
+	used := map[string]bool{ExportStateFile: true}
+	out := make([]ExportFile, 0, len(sorted))
+	for _, f := range sorted {
+		p := path.Join(dirOf(f.FolderID, 0), exportName(f.Name))
+		if used[strings.ToLower(p)] {
+			ext := path.Ext(p)
+			p = fmt.Sprintf("%s (#%d)%s", strings.TrimSuffix(p, ext), f.ID, ext)
+		}
+		used[strings.ToLower(p)] = true
+		out = append(out, ExportFile{ID: f.ID, Path: p, Size: f.Size, Hash: f.Hash})
+	}
+	return out
+}
+
+func exportName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		name = "_" + name
+	}
+	return name
+}
+
+// Export writes every file of src below dir and checks each one's size and
+// SHA-256 before moving it into place. Files recorded in dir's state file
+// with the same path, size, and hash are skipped, so running it again after
+// an interruption picks up the remaining files. A file that fails is logged
+// and counted, and retried by the next run. progress, if not nil, is called
+// after every file.
+func Export(ctx context.Context, src Exportable, dir string, progress func(ExportProgress)) (ExportProgress, error) {
+	var p ExportProgress
+	files, err := src.ExportFiles()
+	if err != nil {
+		return p, err
+	}
+	p.Files = len(files)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return p, err
+	}
+	done, err := readExportState(dir)
+	if err != nil {
+		return p, err
+	}
+	state, err := os.OpenFile(filepath.Join(dir, ExportStateFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return p, err
+	}
+	defer state.Close()
+
+	for _, f := range files {
+		if ctx.Err() != nil {
+			return p, ctx.Err()
+		}
+		target := filepath.Join(dir, filepath.FromSlash(f.Path))
+		record := exportRecord{ID: f.ID, Path: f.Path, Size: f.Size, Hash: f.Hash}
+		if done[f.ID] == record && exported(target, f.Size) {
+			p.Skipped++
+		} else if err := exportFile(ctx, src, f, target); err != nil {
+			if ctx.Err() != nil {
+				return p, ctx.Err()
+			}
+			log.Printf("[VAULT ERR] Export of %s (#%d) failed: %v", f.Path, f.ID, err)
+			p.Failed++
+		} else {
+			line, _ := json.Marshal(record)
+			if _, err := state.Write(append(line, '\n')); err != nil {
+				return p, err
+			}
+			p.Exported++
+			p.Bytes += f.Size
+		}
+		if progress != nil {
+			progress(p)
+		}
+	}
+	return p, nil
+}
+
+func exported(target string, size int64) bool {
+	info, err := os.Stat(target)
+	return err == nil && info.Mode().IsRegular() && info.Size() == size
+}
+
+// readExportState returns the last record of each file in dir's state file.
+func readExportState(dir string) (map[int]exportRecord, error) {
+	done := make(map[int]exportRecord)
+	f, err := os.Open(filepath.Join(dir, ExportStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record exportRecord
+		// A line cut short by a crash is ignored; its file is exported again.
+		if json.Unmarshal(scanner.Bytes(), &record) == nil {
+			done[record.ID] = record
+		}
+	}
+	return done, scanner.Err()
+}
+
+// exportFile downloads f into a temporary file next to target and renames
+// it into place once its contents check out.
+func exportFile(ctx context.Context, src Exportable, f ExportFile, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	r, err := src.OpenFile(ctx, f.ID)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tmp := target + ".export-part"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(out, hasher), r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != f.Size {
+		err = fmt.Errorf("got %d of %d bytes", written, f.Size)
+	}
+	if err == nil && f.Hash != "" && hex.EncodeToString(hasher.Sum(nil)) != f.Hash {
+		err = errors.New("SHA-256 does not match")
+	}
+	if err == nil {
+		err = os.Rename(tmp, target)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// ExportFiles lists the current files of the default vault with their
+// paths in an export.
+func (s *Service) ExportFiles() ([]ExportFile, error) {
+	files, err := s.DB.ListFiles(database.DefaultVault)
+	if err != nil {
+		return nil, err
+	}
+	folders, err := s.DB.ListFolders("")
+	if err != nil {
+		return nil, err
+	}
+	return ExportPaths(files, folders), nil
+}
+
+// OpenFile decrypts the file with the given ID as the caller reads it.
+// Closing the reader early stops the download.
+func (s *Service) OpenFile(ctx context.Context, id int) (io.ReadCloser, error) {
+	if _, err := s.File(id); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := s.Download(ctx, id, pw)
+		pw.CloseWithError(err)
+	}()
+	return &pipeBody{pr, cancel}, nil
+}
+
+// pipeBody stops the download when the reader is closed early.
+type pipeBody struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (b *pipeBody) Close() error {
+	b.cancel()
+	return b.PipeReader.Close()
+}
@@ -0,0 +1,254 @@
+// Package vault offers the vault's file operations as a library that talks
+// to Discord and the metadata database directly. It serves callers that run
+// without the web server, such as vaultctl in direct mode on a laptop: no
+// bot goes online and no port is opened, Discord is only used over REST.
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"discordvault/internal/bot"
+	"discordvault/internal/config"
+	"discordvault/internal/crypto"
+	"discordvault/internal/database"
+	"discordvault/internal/proxy"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// ErrNotFound means no file or folder has the given ID.
+var ErrNotFound = errors.New("not found")
+
+// Service runs file operations for one user of the default vault.
+type Service struct {
+	Bot    *bot.Bot
+	DB     *database.Database
+	Config *config.Config
+	Owner  string // recorded as the owner of new files and folders
+	Method string // names the caller in channel notifications
+	Quiet  bool   // skip the channel notification of each upload
+	// OffPeak pauses uploads while the OFFPEAK_WINDOW is closed.
+	OffPeak bool
+}
+
+// Open connects to the metadata database and Discord as configured,
+// through the proxy of HTTPS_PROXY or SOCKS_PROXY if one is set.
+func Open(cfg *config.Config, owner string) (*Service, error) {
+	proxy.Set(cfg.Proxy)
+	signer, err := crypto.LoadOrCreateSigner(cfg.SigningKeyPath, cfg.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	db, err := database.Open(cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	b, err := bot.New(cfg, db, signer)
+	if err != nil {
+		db.Conn.Close()
+		return nil, err
+	}
+	return &Service{Bot: b, DB: db, Config: cfg, Owner: owner, Method: "CLI"}, nil
+}
+
+// Close releases the database.
+func (s *Service) Close() error {
+	return s.DB.Conn.Close()
+}
+
+// File returns the file with the given ID.
+func (s *Service) File(id int) (*database.FileMetadata, error) {
+	file, err := s.DB.GetFile(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("file %d: %w", id, ErrNotFound)
+	}
+	return file, err
+}
+
+// Files lists the current files of the default vault, or those whose name
+// or tags match query.
+func (s *Service) Files(query string) ([]database.FileMetadata, error) {
+	if query != "" {
+		files, _, err := s.DB.SearchFiles(database.DefaultVault, query, "", 0, 0)
+		return files, err
+	}
+	return s.DB.ListFiles(database.DefaultVault)
+}
+
+// Upload encrypts r into chunks, stores it as name under the duplicate
+// policy, "" for DUPLICATE_POLICY, and moves it into folder unless 0.
+func (s *Service) Upload(ctx context.Context, name string, r io.Reader, folder int, policy string) (*database.FileMetadata, error) {
+	if policy == "" {
+		policy = s.Config.DuplicatePolicy
+	}
+	if !config.ValidDuplicatePolicy(policy) {
+		return nil, errors.New("duplicate policy must be suffix, version, or reject")
+	}
+	if folder != 0 {
+		if _, err := s.DB.GetFolder(folder); err != nil {
+			return nil, fmt.Errorf("folder %d: %w", folder, ErrNotFound)
+		}
+	}
+
+	pool := s.Bot.NewChunkPool(s.Config.ChannelID)
+	pool.ShardBy(name)
+	pool.DeltaFrom(database.DefaultVault, name, s.Owner)
+	committed := false
+	defer func() {
+		if !committed {
+			pool.Discard()
+		}
+	}()
+
+	chunkSize := s.Bot.NextChunkSize()
+	buffer := make([]byte, chunkSize)
+	hasher := sha256.New()
+	var size int64
+	for {
+		if err := pool.Reserve(ctx); err != nil {
+			return nil, err
+		}
+		n, err := io.ReadFull(r, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		if n > 0 {
+			size += int64(n)
+			hasher.Write(buffer[:n])
+			if pool.Reuse(buffer[:n]) {
+				continue
+			}
+			encrypted, err := crypto.Encrypt(buffer[:n], s.Config.EncryptionKey)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.Bot.Health.Wait(ctx); err != nil {
+				return nil, err
+			}
+			if s.OffPeak {
+				if err := s.Bot.WaitOffPeak(ctx, "upload of "+name); err != nil {
+					return nil, err
+				}
+			}
+			if err := pool.Submit(ctx, encrypted, nil); err != nil {
+				return nil, err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+	chunks, err := pool.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	file, err := s.DB.SaveUpload(database.DefaultVault, name, size, int64(chunkSize), hex.EncodeToString(hasher.Sum(nil)), s.Owner, chunks, policy)
+	if err != nil {
+		return nil, err
+	}
+	committed = true
+	pool.Commit(file)
+	log.Printf("[VAULT] Stored %s (#%d, %d chunks)", file.Name, file.ID, len(chunks))
+
+	if folder != 0 {
+		if err := s.DB.ApplyBatch([]database.BatchOp{{Op: database.BatchMove, FileID: file.ID, FolderID: folder}}); err != nil {
+			return file, fmt.Errorf("moving into folder %d: %w", folder, err)
+		}
+		file.FolderID = folder
+	}
+	if err := s.DB.RecordTransfer(database.UsageUser, s.Owner, size, 0); err != nil {
+		log.Printf("[VAULT ERR] Transfer accounting failed: %v", err)
+	}
+	if !s.Quiet {
+		s.Bot.NotifyUpload(file, len(chunks), s.Method)
+	}
+	return file, nil
+}
+
+// Download decrypts the file with the given ID into w.
+func (s *Service) Download(ctx context.Context, id int, w io.Writer) (int64, error) {
+	file, err := s.File(id)
+	if err != nil {
+		return 0, err
+	}
+	written, err := s.Bot.WriteFile(ctx, w, file)
+	if err == nil && written != file.Size {
+		err = fmt.Errorf("wrote %d of %d bytes, chunks are missing", written, file.Size)
+	}
+	if recordErr := s.DB.RecordTransfer(database.UsageUser, s.Owner, 0, written); recordErr != nil {
+		log.Printf("[VAULT ERR] Transfer accounting failed: %v", recordErr)
+	}
+	return written, err
+}
+
+// Delete removes a file: at once, or after PURGE_GRACE with its chunks kept
+// until then.
+func (s *Service) Delete(id int) error {
+	file, err := s.File(id)
+	if err != nil {
+		return err
+	}
+	if s.Config.PurgeGrace > 0 {
+		if err := s.DB.SoftDeleteFile(id, s.Owner); err != nil {
+			return err
+		}
+		s.Bot.NotifyDelete(file, s.Method, s.Owner)
+		return nil
+	}
+
+	chunks, err := s.DB.ExclusiveChunks(id)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 8)
+	for _, c := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			s.Bot.DeleteChunk(c)
+			<-semaphore
+		}()
+	}
+	wg.Wait()
+	if err := s.DB.DeleteFile(id); err != nil {
+		return err
+	}
+	s.Bot.NotifyDelete(file, s.Method, s.Owner)
+	return nil
+}
+
+// Verify checks every chunk of a file and its SHA-256 like /verify, and
+// records and returns its health.
+func (s *Service) Verify(ctx context.Context, id int) (*bot.VerifyReport, string, error) {
+	file, err := s.File(id)
+	if err != nil {
+		return nil, "", err
+	}
+	report, err := s.Bot.ScrubFile(ctx, file, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	health, err := s.Bot.RecordHealth(file, report)
+	return report, health, err
+}
+
+// CreateFolder creates a folder under parent, 0 for the vault root.
+func (s *Service) CreateFolder(name string, parent int) (int, error) {
+	if parent != 0 {
+		if _, err := s.DB.GetFolder(parent); err != nil {
+			return 0, fmt.Errorf("folder %d: %w", parent, ErrNotFound)
+		}
+	}
+	return s.DB.CreateFolder(name, parent, s.Owner)
+}
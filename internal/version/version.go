@@ -0,0 +1,4 @@
+package version
+
+// Version is overridden at build time via -ldflags "-X discordvault/internal/version.Version=...".
+var Version = "dev"
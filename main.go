@@ -1,65 +1,307 @@
 package main
 
 import (
+	"context"
 	"discordvault/internal/bot"
 	"discordvault/internal/config"
+	"discordvault/internal/crypto"
 	"discordvault/internal/database"
+	"discordvault/internal/demo"
+	"discordvault/internal/jobs"
+	"discordvault/internal/leader"
+	"discordvault/internal/logging"
+	"discordvault/internal/logstream"
+	"discordvault/internal/pipeline"
+	"discordvault/internal/proxy"
 	"discordvault/internal/server"
+	"discordvault/internal/systemd"
+	"errors"
+	"flag"
+	"io"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+	_ "time/tzdata"
 
-	"github.com/joho/godotenv"
+	"github.com/bwmarrin/discordgo"
 )
 
 func main() {
 	// Load environment variables
-	if err := godotenv.Load(); err != nil {
+	env, err := loadDotenv()
+	if err != nil {
 		log.Println("Note: .env file not found, using system environment variables.")
 	}
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(os.Args[2:]); err != nil {
+				log.Fatalf("[CRITICAL] Migration failed: %v", err)
+			}
+			return
+		case "decrypt":
+			if err := runDecrypt(os.Args[2:]); err != nil {
+				log.Fatalf("[CRITICAL] Decrypt failed: %v", err)
+			}
+			return
+		case "share":
+			if err := runShare(os.Args[2:]); err != nil {
+				log.Fatalf("[CRITICAL] Share failed: %v", err)
+			}
+			return
+		case "duplicates":
+			if err := runDuplicates(os.Args[2:]); err != nil {
+				log.Fatalf("[CRITICAL] Duplicate scan failed: %v", err)
+			}
+			return
+		case "check":
+			if err := runCheck(os.Args[2:]); err != nil {
+				log.Fatalf("[CRITICAL] Check failed: %v", err)
+			}
+			return
+		case "import-manifest":
+			if err := runImportManifest(os.Args[2:]); err != nil {
+				log.Fatalf("[CRITICAL] Import failed: %v", err)
+			}
+			return
+		}
+	}
+
+	restoreMetadata := flag.Bool("restore-metadata", false, "restore metadata.db from the newest backup in Discord before starting")
+	demoMode := flag.Bool("demo", false, "run the web UI and API against in-memory storage, without a Discord bot")
+	flag.Parse()
+
+	// Demo Mode: in-memory Discord and metadata, nothing touches disk
+	if *demoMode {
+		if *restoreMetadata {
+			log.Fatalf("[CRITICAL] --restore-metadata cannot be combined with --demo")
+		}
+		cleanup, err := demo.Setup()
+		if err != nil {
+			log.Fatalf("[CRITICAL] Demo setup failed: %v", err)
+		}
+		defer cleanup()
+	}
+
 	// Load Configuration
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("[CRITICAL] Config load failed: %v", err)
 	}
 
+	// Logging: LOG_LEVEL, LOG_FORMAT, LOG_FILE, and recent lines for the admin log stream
+	var logs *logstream.Buffer
+	if cfg.LogBuffer > 0 {
+		logs = logstream.New(cfg.LogBuffer)
+	}
+	var logOut io.Writer = os.Stderr
+	if cfg.LogFile != "" {
+		file, err := logging.OpenFile(cfg.LogFile, cfg.LogMaxSize, cfg.LogMaxFiles)
+		if err != nil {
+			log.Fatalf("[CRITICAL] Log file init failed: %v", err)
+		}
+		defer file.Close()
+		logOut = io.MultiWriter(os.Stderr, file)
+	}
+	log.SetOutput(&logging.Writer{
+		Out:    logOut,
+		JSON:   cfg.LogFormat == config.LogJSON,
+		Level:  func() string { return cfg.Live().LogLevel },
+		Stream: logs,
+	})
+
+	// Route Discord traffic through HTTPS_PROXY or SOCKS_PROXY
+	if cfg.Proxy != nil {
+		proxy.Set(cfg.Proxy)
+		log.Printf("Routing Discord traffic through %s://%s", cfg.Proxy.Scheme, cfg.Proxy.Host)
+	}
+
+	if *restoreMetadata {
+		path, ok := database.SQLitePath(cfg.DatabaseURL)
+		if !ok {
+			log.Fatalf("[CRITICAL] --restore-metadata only supports SQLite databases")
+		}
+		// Restore before the identity key is created, so a host that lost
+		// it can still restore; the snapshot's encryption authenticates it.
+		restoreSigner, err := crypto.LoadSigner(cfg.SigningKeyPath, cfg.EncryptionKey)
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("[RESTORE] No identity key at %s; the backup is only checked by decrypting it", cfg.SigningKeyPath)
+		} else if err != nil {
+			log.Fatalf("[CRITICAL] Signing key init failed: %v", err)
+		}
+		session, err := discordgo.New("Bot " + cfg.DiscordToken)
+		if err != nil {
+			log.Fatalf("[CRITICAL] Discord session init failed: %v", err)
+		}
+		session.Client.Transport = proxy.Transport()
+		if err := jobs.RestoreLatestBackup(session, cfg.BackupChannelID, cfg.EncryptionKey, restoreSigner, path); err != nil {
+			log.Fatalf("[CRITICAL] Metadata restore failed: %v", err)
+		}
+	}
+
+	// Load Vault Identity
+	signer, err := crypto.LoadOrCreateSigner(cfg.SigningKeyPath, cfg.EncryptionKey)
+	if err != nil {
+		log.Fatalf("[CRITICAL] Signing key init failed: %v", err)
+	}
+	log.Printf("Vault identity fingerprint: %s", signer.Fingerprint())
+
 	// Initialize Database
-	db, err := database.Initialize("./metadata.db")
+	db, err := database.Open(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("[CRITICAL] Database init failed: %v", err)
 	}
 	defer db.Conn.Close()
 
 	// Initialize Bot
-	vaultBot, err := bot.New(cfg, db)
+	vaultBot, err := bot.New(cfg, db, signer)
 	if err != nil {
 		log.Fatalf("[CRITICAL] Bot init failed: %v", err)
 	}
 
 	// Initialize Server
-	srv := server.New(cfg, db, vaultBot)
+	srv := server.New(cfg, db, vaultBot, signer)
+	srv.Logs = logs
+	if srv.Pipelines, err = pipeline.Load(cfg.PipelinesFile); err != nil {
+		log.Fatalf("[CRITICAL] Pipeline config invalid: %v", err)
+	}
 
-	// Start Server in background
+	// Uploads the server starts from here on belong to this run
+	started := time.Now()
+
+	// Reload access, quotas, and rate limits on SIGHUP
+	go reloadOnHangup(cfg, env)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+
+	// Warm Standby: wait until this instance holds the primary lease
+	var elector *leader.Elector
+	stopStandby := func() {}
+	if cfg.LeaseTTL > 0 {
+		elector = &leader.Elector{DB: db, ID: cfg.InstanceID, TTL: cfg.LeaseTTL}
+		// A standby is up as far as systemd is concerned, or it would time out
+		systemd.Notify("READY=1\nSTATUS=Standby, waiting for the primary lease")
+		// and alive while it can still reach the database it would take over
+		var standbyCtx context.Context
+		standbyCtx, stopStandby = context.WithCancel(ctx)
+		go systemd.Watchdog(standbyCtx, func() bool { return db.Conn.Ping() == nil })
+		waitCtx, stopWaiting := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-sc:
+				stopWaiting()
+			case <-waitCtx.Done():
+			}
+		}()
+		err := elector.Acquire(waitCtx)
+		stopWaiting()
+		if err != nil {
+			log.Println("Standby shutting down.")
+			return
+		}
+	}
+
+	// Start Server in background, only once this instance may write
 	go func() {
 		if err := srv.Start(); err != nil {
 			log.Fatalf("[CRITICAL] Server failed: %v", err)
 		}
 	}()
 
+	if *demoMode {
+		<-srv.Listening()
+		systemd.Notify("READY=1\nSTATUS=Demo")
+		go systemd.Watchdog(ctx, func() bool { return true })
+		log.Printf("Demo is running at %s (the Discord bot is disabled).", cfg.PublicURL)
+		<-sc
+		systemd.Notify("STOPPING=1")
+		log.Println("Shutting down demo...")
+		return
+	}
+
+	// Uploads a previous run or a failed primary left unfinished
+	if n, err := db.AbandonOperations(started); err != nil {
+		log.Printf("[DB] Could not fail interrupted uploads: %v", err)
+	} else if n > 0 {
+		log.Printf("[DB] Marked %d interrupted uploads as failed", n)
+	}
+
 	// Start Bot
 	if err := vaultBot.Start(); err != nil {
 		log.Fatalf("[CRITICAL] Bot failed: %v", err)
 	}
 
+	// Background Jobs
+	go vaultBot.Health.Poll(ctx, cfg.StatusPollInterval)
+	scheduler := srv.Scheduler
+	scheduler.Gate = vaultBot.Health
+	scheduler.Every("chunk compaction", cfg.CompactionInterval, srv.Compactor.Run)
+	scheduler.Every("orphan gc", cfg.GCInterval, srv.GC.Run)
+	scheduler.Every("chunk repair", cfg.RepairInterval, srv.Healer.Run)
+	if cfg.ScrubInterval > 0 {
+		scrub := &jobs.Scrubber{Bot: vaultBot, DB: db, Interval: cfg.ScrubInterval, Pace: cfg.ScrubPace}
+		scheduler.Every("integrity scrub", min(jobs.ScrubCheckInterval, cfg.ScrubInterval), scrub.Run)
+	}
+	scheduler.Every("idempotency key expiry", time.Hour, srv.ExpireIdempotencyKeys)
+	scheduler.Every("download link expiry", time.Hour, srv.ExpireDownloadTokens)
+	scheduler.Every("upload operation expiry", time.Hour, srv.ExpireOperations)
+	scheduler.Every("web session expiry", time.Hour, srv.ExpireWebSessions)
+	if cfg.UploadSessionTTL > 0 {
+		scheduler.Every("upload session expiry", time.Hour, srv.ExpireUploadSessions)
+	}
+	if cfg.PurgeGrace > 0 {
+		scheduler.Every("purge grace expiry", time.Hour, srv.Purger.Expire)
+	}
+	if len(cfg.ArtifactKeep) > 0 {
+		retention := &jobs.ArtifactRetention{DB: db, Rules: cfg.ArtifactKeep}
+		scheduler.Every("artifact retention", time.Hour, retention.Run)
+	}
+	scheduler.Every("storage snapshot", time.Hour, func(context.Context) error { return db.RecordStorageSnapshot() })
+	scheduler.Every("lifecycle policies", cfg.LifecycleInterval, srv.Lifecycle.Run)
+	scheduler.Every("backup jobs", jobs.BackupJobCheckInterval, srv.Backups.Run)
+	backup := &jobs.MetadataBackup{Bot: vaultBot, DB: db, Signer: signer, ChannelID: cfg.BackupChannelID, Keep: cfg.BackupKeep}
+	scheduler.Every("metadata backup", cfg.BackupInterval, backup.Run)
+	if cfg.DigestInterval > 0 {
+		digest := &jobs.Digest{Bot: vaultBot, DB: db, Interval: cfg.DigestInterval}
+		scheduler.Every("vault digest", min(jobs.DigestCheckInterval, cfg.DigestInterval), digest.Run)
+	}
+	scheduler.Start(ctx)
+
+	// systemd Type=notify: ready once the bot and the web server are up, and
+	// restarted by the watchdog if the gateway session hangs
+	<-srv.Listening()
+	systemd.Notify("READY=1\nSTATUS=Operational")
+	stopStandby()
+	go systemd.Watchdog(ctx, vaultBot.Alive)
+
 	log.Println("Discord Vault is fully operational.")
 
+	// Keep the primary lease; a standby takes over if it is lost
+	held := make(chan error, 1)
+	if elector != nil {
+		holdCtx, release := context.WithCancel(ctx)
+		defer func() {
+			release()
+			<-held
+		}()
+		go func() { held <- elector.Hold(holdCtx) }()
+	}
+
 	// Wait for termination signal
-	sc := make(chan os.Signal, 1)
-	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
-	<-sc
+	select {
+	case <-sc:
+	case err := <-held:
+		vaultBot.Session.Close()
+		log.Fatalf("[CRITICAL] No longer primary: %v", err)
+	}
 
+	systemd.Notify("STOPPING=1")
 	log.Println("Shutting down gracefully...")
 	vaultBot.Session.Close()
 }
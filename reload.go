@@ -0,0 +1,74 @@
+package main
+
+import (
+	"discordvault/internal/config"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+// dotenv tracks the variables .env adds to the environment, so a reload can
+// pick up edits to it without overriding variables set by the caller.
+type dotenv struct {
+	preset map[string]bool   // set before .env was read; .env never overrides them
+	loaded map[string]string // set from .env
+}
+
+// loadDotenv reads .env into the environment, like godotenv.Load.
+func loadDotenv() (*dotenv, error) {
+	d := &dotenv{preset: make(map[string]bool), loaded: make(map[string]string)}
+	for _, kv := range os.Environ() {
+		k, _, _ := strings.Cut(kv, "=")
+		d.preset[k] = true
+	}
+	return d, d.reload()
+}
+
+// reload applies the current contents of .env: new and changed variables
+// are set and ones removed from it are unset. A missing .env counts as
+// empty, though the error is still returned.
+func (d *dotenv) reload() error {
+	values, err := godotenv.Read()
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for k := range d.loaded {
+		if _, ok := values[k]; !ok {
+			os.Unsetenv(k)
+			delete(d.loaded, k)
+		}
+	}
+	for k, v := range values {
+		if d.preset[k] {
+			continue
+		}
+		os.Setenv(k, v)
+		d.loaded[k] = v
+	}
+	return err
+}
+
+// reloadOnHangup re-reads .env and the config file whenever the process
+// receives SIGHUP and applies the options that can change while running.
+// A configuration that fails to load leaves the current one in place.
+func reloadOnHangup(cfg *config.Config, env *dotenv) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		if err := env.reload(); err != nil && !os.IsNotExist(err) {
+			log.Printf("Configuration reload failed, keeping the current settings: %v", err)
+			continue
+		}
+		fresh, err := config.Load()
+		if err != nil {
+			log.Printf("Configuration reload failed, keeping the current settings: %v", err)
+			continue
+		}
+		cfg.Reload(fresh)
+		log.Println("Configuration reloaded (allowlist, roles, command permissions and cooldowns, API keys, transfer cap, log level).")
+	}
+}